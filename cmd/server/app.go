@@ -1,9 +1,14 @@
 package main
 
-import "github.com/gin-gonic/gin"
+import (
+	"bedrock/internal/job"
+	"bedrock/pkg/ginx/router"
+)
 
 type App struct {
-	engine *gin.Engine
+	// router 按照 API 版本转发请求，作为 http.Server 的 Handler
+	router *router.Router
 	//consumers []events.Consumer
 	//cron      *cron.Cron
+	scheduler *job.Scheduler
 }
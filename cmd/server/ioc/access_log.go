@@ -0,0 +1,86 @@
+package ioc
+
+import (
+	"bedrock/internal/web/middleware/jwt"
+	ginxmw "bedrock/pkg/ginx/middleware"
+	"bedrock/pkg/logger"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+type accessLogCfg struct {
+	ReqBody  bool `mapstructure:"req_body"`
+	RespBody bool `mapstructure:"resp_body"`
+	Headers  bool `mapstructure:"headers"`
+	// RedactFields、RedactReqFields、RedactHeaders 在默认的脱敏规则之外追加，RedactReqFields 只对请求体生效
+	RedactFields    []string `mapstructure:"redact_fields"`
+	RedactReqFields []string `mapstructure:"redact_req_fields"`
+	RedactHeaders   []string `mapstructure:"redact_headers"`
+	// Allow、Deny 控制哪些路由记录请求体和响应体，路径不带版本前缀
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+	// Sample 按照状态码分类的采样率，例如 2xx: 0.1
+	Sample map[string]float64 `mapstructure:"sample"`
+}
+
+func initAccessLog(l logger.Logger) gin.HandlerFunc {
+	cfg := accessLogCfg{ReqBody: true, RespBody: true}
+	if err := viper.UnmarshalKey("access_log", &cfg); err != nil {
+		panic(err)
+	}
+	logFn := func(ctx context.Context, al ginxmw.AccessLog) {
+		fields := []logger.Field{
+			logger.String("path", al.Path),
+			logger.String("method", al.Method),
+			logger.String("req_body", al.ReqBody),
+			logger.Int("status", al.Status),
+			logger.String("resp_body", al.RespBody),
+			logger.Int64("duration_ms", al.Duration.Milliseconds()),
+			logger.String("client_ip", al.ClientIP),
+			logger.String("user_id", al.UserID),
+			logger.String("trace_id", al.TraceID),
+			logger.Int64("req_size", al.ReqSize),
+			logger.String("user_agent", al.UserAgent),
+		}
+		if cfg.Headers {
+			fields = append(fields, logger.Any("req_headers", al.ReqHeaders), logger.Any("resp_headers", al.RespHeaders))
+		}
+		l.Info(ctx, "access log ", fields...)
+	}
+	b := ginxmw.NewAccessLogBuilder(logFn).
+		RedactFields(cfg.RedactFields...).
+		RedactReqFields(cfg.RedactReqFields...).
+		RedactHeaders(cfg.RedactHeaders...).
+		Allow(cfg.Allow...).
+		Deny(cfg.Deny...).
+		UserID(func(ctx *gin.Context) string {
+			if uc, ok := ctx.Get("user"); ok {
+				if claims, ok := uc.(jwt.UserClaims); ok {
+					return strconv.FormatInt(claims.Uid, 10)
+				}
+			}
+			return ""
+		})
+	if cfg.ReqBody {
+		b = b.AllowReqBody()
+	}
+	if cfg.RespBody {
+		b = b.AllowRespBody()
+	}
+	if cfg.Headers {
+		b = b.AllowHeaders()
+	}
+	for class, rate := range cfg.Sample {
+		c, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(class), "xx"))
+		if err != nil || c < 1 || c > 5 {
+			panic(fmt.Errorf("access_log.sample 格式错误: %s", class))
+		}
+		b = b.Sample(c, rate)
+	}
+	return b.Build()
+}
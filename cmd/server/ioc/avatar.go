@@ -0,0 +1,20 @@
+package ioc
+
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+
+	"github.com/spf13/viper"
+)
+
+func InitAvatarService(storageSvc storage.Provider, userSvc service.UserService, moderationSvc service.ModerationService, l logger.Logger) service.AvatarService {
+	cfg := service.AvatarConfig{
+		MaxWidth:  4096,
+		MaxHeight: 4096,
+	}
+	if err := viper.UnmarshalKey("avatar", &cfg); err != nil {
+		panic(err)
+	}
+	return service.NewAvatarService(storageSvc, userSvc, moderationSvc, l, cfg)
+}
@@ -0,0 +1,95 @@
+package ioc
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	"bedrock/internal/service"
+	"bedrock/internal/service/channel"
+	emailmemory "bedrock/internal/service/email/memory"
+	"bedrock/internal/service/sms"
+	voicememory "bedrock/internal/service/voice/memory"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// InitCodePolicies 从配置中读取各个业务的验证码策略，策略里只能使用已经注册的投递渠道
+func InitCodePolicies(channels []channel.Channel) *service.CodePolicyRegistry {
+	type policyConfig struct {
+		TplId          string        `mapstructure:"tpl_id"`
+		Length         int           `mapstructure:"length"`
+		Alphabet       string        `mapstructure:"alphabet"`
+		TTL            time.Duration `mapstructure:"ttl"`
+		ResendInterval time.Duration `mapstructure:"resend_interval"`
+		MaxAttempts    int           `mapstructure:"max_attempts"`
+		// DailyLimit 没有配置时为 nil，继承默认策略；0 表示不限制
+		DailyLimit *int     `mapstructure:"daily_limit"`
+		Channels   []string `mapstructure:"channels"`
+	}
+	type codeConfig struct {
+		Default  policyConfig            `mapstructure:"default"`
+		Policies map[string]policyConfig `mapstructure:"policies"`
+	}
+	toDomain := func(c policyConfig) domain.CodePolicy {
+		// 小于 0 的 DailyLimit 由 CodePolicyRegistry 换成默认值
+		dailyLimit := -1
+		if c.DailyLimit != nil {
+			dailyLimit = *c.DailyLimit
+		}
+		return domain.CodePolicy{
+			TplId:          c.TplId,
+			Length:         c.Length,
+			Alphabet:       c.Alphabet,
+			TTL:            c.TTL,
+			ResendInterval: c.ResendInterval,
+			MaxAttempts:    c.MaxAttempts,
+			DailyLimit:     dailyLimit,
+			Channels:       c.Channels,
+		}
+	}
+	var cfg codeConfig
+	if err := viper.UnmarshalKey("code", &cfg); err != nil {
+		panic(err)
+	}
+	r := service.NewCodePolicyRegistry(toDomain(cfg.Default))
+	for biz, p := range cfg.Policies {
+		r.Register(biz, toDomain(p))
+	}
+	names := make([]string, 0, len(channels))
+	for _, c := range channels {
+		names = append(names, c.Name())
+	}
+	if err := r.Validate(names); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// InitCodeChannels 验证码的投递渠道，短信一直可用，语音和邮件只有配置了服务商才注册
+// 语音和邮件目前还没有接入真实的服务商，只有内存实现，它不会真的投递，只能在开发和测试环境里配置
+func InitCodeChannels(smsSvc sms.Service, users repository.UserRepository) []channel.Channel {
+	type providerConfig struct {
+		Voice string `mapstructure:"voice"`
+		Email string `mapstructure:"email"`
+	}
+	var cfg providerConfig
+	if err := viper.UnmarshalKey("code.providers", &cfg); err != nil {
+		panic(err)
+	}
+	res := []channel.Channel{channel.NewSMSChannel(smsSvc)}
+	switch cfg.Voice {
+	case "":
+	case "memory":
+		res = append(res, channel.NewVoiceChannel(voicememory.NewService()))
+	default:
+		panic("未知的语音服务商: " + cfg.Voice)
+	}
+	switch cfg.Email {
+	case "":
+	case "memory":
+		res = append(res, channel.NewEmailChannel(emailmemory.NewService(), users))
+	default:
+		panic("未知的邮件服务商: " + cfg.Email)
+	}
+	return res
+}
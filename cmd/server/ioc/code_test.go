@@ -0,0 +1,206 @@
+package ioc
+
+import (
+	"bedrock/internal/service"
+	"bedrock/internal/service/channel"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInitCodePolicies 读取的是全局的 viper，不能并行执行
+func TestInitCodePolicies(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+		biz    string
+
+		wantDailyLimit int
+		wantPanic      bool
+	}{
+		{
+			name: "policy omits daily limit",
+			config: `
+code:
+  default:
+    daily_limit: 20
+  policies:
+    login:
+      length: 8
+`,
+			biz:            "login",
+			wantDailyLimit: 20,
+		},
+		{
+			name: "policy sets daily limit",
+			config: `
+code:
+  default:
+    daily_limit: 20
+  policies:
+    reset:
+      daily_limit: 5
+`,
+			biz:            "reset",
+			wantDailyLimit: 5,
+		},
+		{
+			name: "policy unlimited",
+			config: `
+code:
+  default:
+    daily_limit: 20
+  policies:
+    bind:
+      daily_limit: 0
+`,
+			biz:            "bind",
+			wantDailyLimit: 0,
+		},
+		{
+			name: "default omits daily limit",
+			config: `
+code:
+  policies:
+    login:
+      length: 8
+`,
+			biz:            "login",
+			wantDailyLimit: service.DefaultCodePolicy.DailyLimit,
+		},
+		{
+			name:           "not configured",
+			config:         `server: {}`,
+			biz:            "login",
+			wantDailyLimit: service.DefaultCodePolicy.DailyLimit,
+		},
+		{
+			// 登录接口只接受 4 到 16 位的验证码
+			name: "length too short",
+			config: `
+code:
+  policies:
+    login:
+      length: 3
+`,
+			wantPanic: true,
+		},
+		{
+			name: "length too long",
+			config: `
+code:
+  default:
+    length: 17
+`,
+			wantPanic: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.SetConfigType("yaml")
+			require.NoError(t, viper.ReadConfig(strings.NewReader(tc.config)))
+
+			channels := []channel.Channel{channel.NewSMSChannel(nil)}
+			if tc.wantPanic {
+				assert.Panics(t, func() { InitCodePolicies(channels) })
+				return
+			}
+			r := InitCodePolicies(channels)
+			assert.Equal(t, tc.wantDailyLimit, r.Get(tc.biz).DailyLimit)
+		})
+	}
+}
+
+// TestInitCodeChannels 语音和邮件只有配置了服务商才注册，策略里不能使用没有注册的渠道
+func TestInitCodeChannels(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+
+		wantChannels []string
+		wantPanic    bool
+	}{
+		{
+			name: "没有配置服务商只注册短信",
+			config: `
+code:
+  default:
+    channels: ["sms"]
+`,
+			wantChannels: []string{channel.SMS},
+		},
+		{
+			name: "配置了服务商",
+			config: `
+code:
+  providers:
+    voice: memory
+    email: memory
+  policies:
+    login:
+      channels: ["sms", "voice", "email"]
+`,
+			wantChannels: []string{channel.SMS, channel.Voice, channel.Email},
+		},
+		{
+			name: "策略使用了没有注册的渠道",
+			config: `
+code:
+  providers:
+    voice: memory
+  policies:
+    login:
+      channels: ["sms", "voice", "email"]
+`,
+			wantPanic: true,
+		},
+		{
+			name: "默认策略使用了没有注册的渠道",
+			config: `
+code:
+  default:
+    channels: ["voice"]
+`,
+			wantPanic: true,
+		},
+		{
+			name: "未知的服务商",
+			config: `
+code:
+  providers:
+    voice: twilio
+`,
+			wantPanic: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.SetConfigType("yaml")
+			require.NoError(t, viper.ReadConfig(strings.NewReader(tc.config)))
+
+			build := func() []channel.Channel {
+				channels := InitCodeChannels(nil, nil)
+				InitCodePolicies(channels)
+				return channels
+			}
+			if tc.wantPanic {
+				assert.Panics(t, func() { build() })
+				return
+			}
+			var names []string
+			for _, c := range build() {
+				names = append(names, c.Name())
+			}
+			assert.Equal(t, tc.wantChannels, names)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package ioc
+
+import (
+	"bedrock/internal/repository"
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+
+	"github.com/spf13/viper"
+)
+
+func InitFileService(repo repository.FileRepository, storageSvc storage.Provider, moderationSvc service.ModerationService, l logger.Logger) service.FileService {
+	var cfg service.FileConfig
+	if err := viper.UnmarshalKey("file", &cfg); err != nil {
+		panic(err)
+	}
+	return service.NewFileService(repo, storageSvc, moderationSvc, l, cfg)
+}
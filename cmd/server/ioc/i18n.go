@@ -0,0 +1,32 @@
+package ioc
+
+import (
+	"bedrock/internal/web/locales"
+	"bedrock/pkg/i18n"
+
+	"github.com/spf13/viper"
+)
+
+type i18nCfg struct {
+	// Default 默认语言，Accept-Language 都不支持时使用
+	Default string `mapstructure:"default"`
+	// Dir 不为空时从这个目录加载翻译，覆盖内置的同名 key，方便不发版修改文案
+	Dir string `mapstructure:"dir"`
+}
+
+func InitI18n() *i18n.Bundle {
+	cfg := i18nCfg{Default: locales.Default}
+	if err := viper.UnmarshalKey("i18n", &cfg); err != nil {
+		panic(err)
+	}
+	b, err := locales.NewBundle(cfg.Default)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Dir != "" {
+		if err = b.LoadDir(cfg.Dir); err != nil {
+			panic(err)
+		}
+	}
+	return b
+}
@@ -0,0 +1,50 @@
+package ioc
+
+import (
+	"bedrock/internal/web/middleware/jwt"
+	ginxmw "bedrock/pkg/ginx/middleware"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+type idempotencyCfg struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL 保存响应的时间，客户端在这段时间内重试都会拿到同一个响应
+	TTL time.Duration `mapstructure:"ttl"`
+	// LockTTL 一次请求最长的执行时间，超过之后锁自动释放
+	LockTTL time.Duration `mapstructure:"lock_ttl"`
+	// Required 为 true 时 POST、PATCH 请求必须带 Idempotency-Key
+	Required bool `mapstructure:"required"`
+	// MaxReqBody 带 Idempotency-Key 的请求体上限，要比上传接口自己的上限大，超过返回 413
+	MaxReqBody int64 `mapstructure:"max_req_body"`
+}
+
+// initIdempotency 放在 API 版本的中间件里，排在鉴权后面，这样可以按照登录用户区分 key
+// 没有开启时返回 nil
+func initIdempotency(cmd redis.Cmdable) gin.HandlerFunc {
+	cfg := idempotencyCfg{TTL: 24 * time.Hour, LockTTL: 30 * time.Second, MaxReqBody: 128 << 20}
+	if err := viper.UnmarshalKey("idempotency", &cfg); err != nil {
+		panic(err)
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	b := ginxmw.NewIdempotencyBuilder(cmd).TTL(cfg.TTL).LockTTL(cfg.LockTTL).MaxReqBody(cfg.MaxReqBody).
+		Scope(func(ctx *gin.Context) string {
+			// 刷新 token 之后 Authorization 会变，用户 ID 不会
+			if uc, ok := ctx.Get("user"); ok {
+				if claims, ok := uc.(jwt.UserClaims); ok {
+					return "uid:" + strconv.FormatInt(claims.Uid, 10)
+				}
+			}
+			return "ip:" + ctx.ClientIP()
+		})
+	if cfg.Required {
+		b = b.Required()
+	}
+	return b.Build()
+}
@@ -0,0 +1,53 @@
+package ioc
+
+import (
+	"bedrock/internal/job"
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func InitJobs(avatarSvc service.AvatarService, fileSvc service.FileService, moderationSvc service.ModerationService,
+	notificationSvc service.NotificationService, l logger.Logger) *job.Scheduler {
+	s := job.NewScheduler(l)
+	avatarCfg := cleanupConfig("avatar.cleanup")
+	s.Register(job.NewAvatarCleanupJob(avatarSvc, l, avatarCfg.Grace), avatarCfg.Interval)
+	fileCfg := cleanupConfig("file.cleanup")
+	s.Register(job.NewFileCleanupJob(fileSvc, l, fileCfg.Grace), fileCfg.Interval)
+	// 重新扫描要及时，不然用户上传的内容迟迟不能公开
+	rescanCfg := cleanupCfg{
+		Interval: 5 * time.Minute,
+		Grace:    5 * time.Minute,
+	}
+	if err := viper.UnmarshalKey("moderation.rescan", &rescanCfg); err != nil {
+		panic(err)
+	}
+	s.Register(job.NewModerationRescanJob(moderationSvc, l, rescanCfg.Grace), rescanCfg.Interval)
+	// 群发活动创建之后等这个任务来发送，间隔就是最长的等待时间
+	dispatchCfg := cleanupCfg{Interval: 10 * time.Second}
+	if err := viper.UnmarshalKey("notification.dispatch", &dispatchCfg); err != nil {
+		panic(err)
+	}
+	s.Register(job.NewCampaignDispatchJob(notificationSvc, l), dispatchCfg.Interval)
+	return s
+}
+
+type cleanupCfg struct {
+	// Interval 清理间隔，不大于 0 时关闭清理
+	Interval time.Duration `mapstructure:"interval"`
+	// Grace 上传之后多久才允许清理
+	Grace time.Duration `mapstructure:"grace"`
+}
+
+func cleanupConfig(key string) cleanupCfg {
+	cfg := cleanupCfg{
+		Interval: time.Hour,
+		Grace:    24 * time.Hour,
+	}
+	if err := viper.UnmarshalKey(key, &cfg); err != nil {
+		panic(err)
+	}
+	return cfg
+}
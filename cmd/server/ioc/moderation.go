@@ -0,0 +1,54 @@
+package ioc
+
+import (
+	"bedrock/internal/repository"
+	"bedrock/internal/service"
+	"bedrock/internal/web"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/scanner/clamav"
+	"bedrock/pkg/scanner/moderation"
+	"bedrock/pkg/storage"
+
+	"github.com/spf13/viper"
+)
+
+type moderationCfg struct {
+	service.ModerationConfig `mapstructure:",squash"`
+	// ClamAV 病毒扫描，address 为空时不扫描
+	ClamAV clamav.Config `mapstructure:"clamav"`
+	// Image 图片内容审核，endpoint 为空时不审核
+	Image struct {
+		moderation.HTTPConfig `mapstructure:",squash"`
+		moderation.Config     `mapstructure:",squash"`
+	} `mapstructure:"image"`
+}
+
+func InitModerationService(repo repository.ModerationRepository, storageSvc storage.Provider, l logger.Logger) service.ModerationService {
+	var cfg moderationCfg
+	if err := viper.UnmarshalKey("moderation", &cfg); err != nil {
+		panic(err)
+	}
+	var scanners []scanner.Scanner
+	if cfg.ClamAV.Address != "" {
+		scanners = append(scanners, clamav.NewClient(cfg.ClamAV))
+	}
+	if cfg.Image.Endpoint != "" {
+		reviewer := moderation.NewHTTPReviewer(cfg.Image.HTTPConfig)
+		scanners = append(scanners, moderation.NewScanner(reviewer, cfg.Image.Config))
+	}
+	// 什么都没有配置时所有内容直接通过，和上线审核之前的行为一致
+	var s scanner.Scanner
+	if len(scanners) > 0 {
+		s = scanner.Chain(scanners...)
+	}
+	return service.NewModerationService(repo, storageSvc, s, l, cfg.ModerationConfig)
+}
+
+func InitModerationHandler(svc service.ModerationService) *web.ModerationHandler {
+	var admins []int64
+	if err := viper.UnmarshalKey("moderation.admins", &admins); err != nil {
+		panic(err)
+	}
+	return web.NewModerationHandler(svc, admins)
+}
@@ -0,0 +1,46 @@
+package ioc
+
+import (
+	"bedrock/internal/repository"
+	"bedrock/internal/service"
+	"bedrock/internal/service/sms"
+	"bedrock/internal/service/sms/opentelemetry"
+	"bedrock/internal/service/sms/throttle"
+	"bedrock/internal/web"
+	"bedrock/pkg/logger"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+)
+
+// InitNotificationService 群发短信和验证码共用同一套服务商路由，
+// 在外面再装饰一层限速，避免群发把服务商的额度打满影响验证码
+func InitNotificationService(repo repository.NotificationRepository, smsSvc sms.Service, l logger.Logger) service.NotificationService {
+	type notificationConfig struct {
+		service.NotificationConfig `mapstructure:",squash"`
+		// Rate 每秒最多发送的号码数
+		Rate float64 `mapstructure:"rate"`
+	}
+	cfg := notificationConfig{
+		NotificationConfig: service.NotificationConfig{BatchSize: 200},
+		Rate:               100,
+	}
+	if err := viper.UnmarshalKey("notification", &cfg); err != nil {
+		panic(err)
+	}
+	svc := opentelemetry.NewDecorator(smsSvc, otel.Tracer("bedrock/notification"))
+	svc = throttle.NewService(svc, cfg.Rate, cfg.BatchSize)
+	res, err := service.NewNotificationService(repo, svc, l, cfg.NotificationConfig)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func InitNotificationHandler(svc service.NotificationService, userSvc service.UserService) *web.NotificationHandler {
+	var admins []int64
+	if err := viper.UnmarshalKey("notification.admins", &admins); err != nil {
+		panic(err)
+	}
+	return web.NewNotificationHandler(svc, userSvc, admins)
+}
@@ -0,0 +1,46 @@
+package ioc
+
+import (
+	ginxmw "bedrock/pkg/ginx/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+type replayCfg struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Required 为 true 时所有请求都必须签名，否则只校验带了 X-App-Id 的请求
+	Required bool              `mapstructure:"required"`
+	Skew     time.Duration     `mapstructure:"skew"`
+	Clients  []replayClientCfg `mapstructure:"clients"`
+	// MaxBody 签名请求的请求体上限，超过返回 413
+	MaxBody int64 `mapstructure:"max_body"`
+}
+
+type replayClientCfg struct {
+	AppID  string `mapstructure:"app_id"`
+	Secret string `mapstructure:"secret"`
+}
+
+// initReplayProtect 防重放放在 API 版本中间件的最前面，签名不对的请求不需要再查登录态
+// 没有开启时返回 nil
+func initReplayProtect(cmd redis.Cmdable) gin.HandlerFunc {
+	cfg := replayCfg{Skew: 5 * time.Minute, MaxBody: 128 << 20}
+	if err := viper.UnmarshalKey("replay", &cfg); err != nil {
+		panic(err)
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	clients := make(map[string][]byte, len(cfg.Clients))
+	for _, c := range cfg.Clients {
+		clients[c.AppID] = []byte(c.Secret)
+	}
+	b := ginxmw.NewReplayProtectBuilder(cmd, clients).Skew(cfg.Skew).MaxBody(cfg.MaxBody)
+	if cfg.Required {
+		b = b.Required()
+	}
+	return b.Build()
+}
@@ -2,19 +2,63 @@ package ioc
 
 import (
 	"bedrock/internal/service/sms"
-	"bedrock/internal/service/sms/memory"
+	"bedrock/internal/service/sms/region"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/service/sms/tencent"
+	"github.com/spf13/viper"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	tencentSMS "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 	"os"
 )
 
-func InitSMSService() sms.Service {
+func InitSMSService(sim *simulator.Service) sms.Service {
 	//return ratelimit.NewRateLimitSMSService(localsms.NewService(), limiter.NewRedisSlidingWindowLimiter())
-	// 如果有需要，就可以用这个
-	//return initTencentSMSService()
-	return memory.NewService()
+	// 按照国家码选择服务商，例如 +86 走腾讯云，其余走国际短信服务商
+	type smsConfig struct {
+		// Routes 国家码（不带 +）到服务商名字的映射
+		Routes map[string]string `mapstructure:"routes"`
+		// International 没有命中 Routes 的号码使用的服务商
+		International string `mapstructure:"international"`
+	}
+	cfg := smsConfig{
+		Routes:        map[string]string{"86": "simulator"},
+		International: "simulator",
+	}
+	if err := viper.UnmarshalKey("sms", &cfg); err != nil {
+		panic(err)
+	}
+	providers := make(map[string]sms.Service, 2)
+	provider := func(name string) sms.Service {
+		if svc, ok := providers[name]; ok {
+			return svc
+		}
+		var svc sms.Service
+		switch name {
+		case "simulator":
+			svc = sim
+		case "tencent":
+			svc = initTencentSMSService()
+		default:
+			panic("未知的短信服务商: " + name)
+		}
+		providers[name] = svc
+		return svc
+	}
+	routes := make(map[string]sms.Service, len(cfg.Routes))
+	for cc, name := range cfg.Routes {
+		routes[cc] = provider(name)
+	}
+	return region.NewService(routes, provider(cfg.International))
+}
+
+// InitSMSSimulator 本地短信模拟器，收到的短信可以通过 /dev/sms/inbox 查看
+func InitSMSSimulator() *simulator.Service {
+	var cfg simulator.Config
+	if err := viper.UnmarshalKey("sms.simulator", &cfg); err != nil {
+		panic(err)
+	}
+	return simulator.NewService(cfg)
 }
 
 func initTencentSMSService() sms.Service {
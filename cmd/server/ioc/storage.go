@@ -2,16 +2,47 @@ package ioc
 
 import (
 	"bedrock/pkg/storage"
-	"bedrock/pkg/storage/local"
+	"bedrock/pkg/storage/factory"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/spf13/viper"
 )
 
 func InitStorageService() storage.Provider {
-	// 这里可以根据配置决定使用本地存储还是 OSS 等
-	// 目前先硬编码为本地存储
-	// 实际项目中应该从配置文件读取
-	c := local.Config{
-		RootPath: "./uploads",
-		BaseURL:  "http://localhost:8080/uploads",
+	// 类型和凭证都来自配置，格式见 pkg/storage/factory.Config
+	c := factory.Config{
+		Type: factory.TypeLocal,
+		Local: factory.LocalConfig{
+			RootPath: "./uploads",
+			BaseURL:  "http://localhost:8080/uploads",
+		},
+	}
+	if err := viper.UnmarshalKey("storage", &c); err != nil {
+		panic(err)
+	}
+	fillLocalSecret(&c)
+	p, err := factory.New(c)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// fillLocalSecret 本地存储没有配置签名密钥时每次启动随机生成，重启之后之前签发的链接会失效
+func fillLocalSecret(c *factory.Config) {
+	if c.Local.Secret == "" {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		c.Local.Secret = hex.EncodeToString(b)
+	}
+	for i := range c.Mirror.Backends {
+		fillLocalSecret(&c.Mirror.Backends[i])
+	}
+	if c.Tiered.Hot != nil {
+		fillLocalSecret(c.Tiered.Hot)
+	}
+	if c.Tiered.Cold != nil {
+		fillLocalSecret(c.Tiered.Cold)
 	}
-	return local.NewProvider(c)
 }
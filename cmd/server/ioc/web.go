@@ -1,61 +1,172 @@
 package ioc
 
 import (
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/middleware"
 	"bedrock/internal/web/middleware/jwt"
 	"bedrock/pkg/ginx"
 	ginxmw "bedrock/pkg/ginx/middleware"
+	"bedrock/pkg/ginx/openapi"
+	"bedrock/pkg/ginx/router"
+	"bedrock/pkg/ginx/tus"
+	"bedrock/pkg/i18n"
 	"bedrock/pkg/logger"
-	"context"
+	"bedrock/pkg/storage"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func InitWebEngine(middlewares []gin.HandlerFunc, l logger.Logger, userHdl *web.UserHandler) *gin.Engine {
+func InitWebEngine(middlewares []gin.HandlerFunc, l logger.Logger, bundle *i18n.Bundle, jwtHdl jwt.Handler, cmd redis.Cmdable, storageSvc storage.Provider, userHdl *web.UserHandler, notificationHdl *web.NotificationHandler, fileHdl *web.FileHandler, moderationHdl *web.ModerationHandler, smsInboxHdl *simulator.Handler) *router.Router {
 	ginx.SetLogger(l)
+	ginxmw.SetLogger(l)
+	// Result.Msg 按照请求的 Accept-Language 翻译
+	ginx.SetI18n(bundle)
+	// 老客户端按照 HTTP 200 + Result.Code 判断结果，升级完之前打开
+	ginx.SetLegacyStatus(viper.GetBool("server.legacy_status"))
 	gin.ForceConsoleColor()
-	engine := gin.Default()
-	engine.Static("/uploads", "./uploads")
+	engine := gin.New()
+	// 兜底的 recovery，覆盖下面直接注册在 engine 上的路由和全局中间件本身，业务接口的 panic 由 InitGinMiddlewares 里的 recovery 处理
+	engine.Use(gin.Logger(), ginxmw.NewRecoveryBuilder(l).Build())
+	// 本地存储的文件由我们自己提供服务，S3、OSS 的文件客户端直接访问对应的域名
+	if _, ok := storageSvc.(storage.DownloadVerifier); ok {
+		registerUploads(engine, storageSvc)
+	}
+	// 监控指标不需要登录
+	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// 接口文档由注册路由时记录下来的信息生成，不需要登录
+	if viper.GetBool("openapi.enabled") {
+		engine.GET("/openapi.json", openapi.Handler(web.OpenAPIInfo))
+		engine.GET("/swagger", openapi.UIHandler("/openapi.json"))
+	}
 	engine.Use(middlewares...)
-	userHdl.RegisterRoutes(engine)
-	//wechatHdl.RegisterRoutes(engine)//, wechatHdl *web.OAuth2WechatHandler
-	return engine
+	// 每个版本注册的 Handler，新版本的接口写成新的 Handler 注册到新版本，旧版本保持不变
+	handlers := map[string][]web.Handler{
+		"v1": {userHdl, notificationHdl, fileHdl, moderationHdl},
+	}
+	var versionMiddlewares []gin.HandlerFunc
+	if replay := initReplayProtect(cmd); replay != nil {
+		versionMiddlewares = append(versionMiddlewares, replay)
+	}
+	versionMiddlewares = append(versionMiddlewares, middleware.NewJWTAuth(jwtHdl).Middleware())
+	if idem := initIdempotency(cmd); idem != nil {
+		versionMiddlewares = append(versionMiddlewares, idem)
+	}
+	r := initRouter(engine, versionMiddlewares...)
+	for version, hdls := range handlers {
+		for _, hdl := range hdls {
+			hdl.RegisterRoutes(r.Group(version))
+		}
+	}
+	// 短信收件箱只在非生产环境暴露
+	if viper.GetString("server.mode") != gin.ReleaseMode {
+		smsInboxHdl.RegisterRoutes(engine)
+	}
+	//wechatHdl.RegisterRoutes(r.Group("v1"))//, wechatHdl *web.OAuth2WechatHandler
+	return r
+}
+
+type apiCfg struct {
+	router.Config `mapstructure:",squash"`
+	Versions      []apiVersionCfg `mapstructure:"versions"`
 }
 
-func InitGinMiddlewares(jwtHdl jwt.Handler, l logger.Logger) []gin.HandlerFunc {
+type apiVersionCfg struct {
+	Name string `mapstructure:"name"`
+	// Deprecation、Sunset 是 RFC3339 格式的时间，为空表示没有废弃
+	Deprecation string `mapstructure:"deprecation"`
+	Sunset      string `mapstructure:"sunset"`
+	Link        string `mapstructure:"link"`
+}
+
+// initRouter 按照配置创建 API 版本，防重放、鉴权、幂等放在版本的中间件里，不需要登录的路径所有版本共用
+func initRouter(engine *gin.Engine, middlewares ...gin.HandlerFunc) *router.Router {
+	cfg := apiCfg{Config: router.Config{Prefix: "/api", Default: "v1", Legacy: true}}
+	if err := viper.UnmarshalKey("api", &cfg); err != nil {
+		panic(err)
+	}
+	if len(cfg.Versions) == 0 {
+		cfg.Versions = []apiVersionCfg{{Name: "v1"}}
+	}
+	versions := make([]router.Version, 0, len(cfg.Versions))
+	for _, v := range cfg.Versions {
+		version := router.Version{
+			Name:        v.Name,
+			Link:        v.Link,
+			Middlewares: middlewares,
+		}
+		version.Deprecation = parseTime("api.versions.deprecation", v.Deprecation)
+		version.Sunset = parseTime("api.versions.sunset", v.Sunset)
+		versions = append(versions, version)
+	}
+	return router.New(engine, cfg.Config, versions...)
+}
+
+func parseTime(key, val string) time.Time {
+	if val == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		panic(fmt.Errorf("%s 格式错误: %w", key, err))
+	}
+	return t
+}
+
+// registerUploads 挂载存储的文件服务，路径和 URL 里的路径保持一致
+// 通过存储层读取文件，这样上传时设置的 ContentType、缓存策略才能原样返回，私有文件要带签名才能下载
+// PUT 用于客户端直传，请求本身带签名，所以注册在鉴权中间件之前
+func registerUploads(engine *gin.Engine, p storage.Provider) {
+	prefix := "/uploads"
+	if u, err := url.Parse(p.URL("")); err == nil && strings.Trim(u.Path, "/") != "" {
+		prefix = "/" + strings.Trim(u.Path, "/")
+	}
+	uploads := gin.WrapH(http.StripPrefix(prefix, storage.NewHTTPHandler(p)))
+	engine.GET(prefix+"/*key", uploads)
+	engine.HEAD(prefix+"/*key", uploads)
+	engine.PUT(prefix+"/*key", uploads)
+}
+
+func InitGinMiddlewares(l logger.Logger) []gin.HandlerFunc {
 	corsMiddleware := cors.New(cors.Config{
 		// 在生产环境中，您应该将 AllowAllOrigins 设置为 false，并具体指定允许的前端域名
 		// 例如: AllowOrigins: []string{"http://your-frontend.com"},
 		AllowAllOrigins: true,
 		AllowMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:    []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		// 断点续传（tus）需要额外的请求头和响应头
+		AllowHeaders: append([]string{"Origin", "Content-Length", "Content-Type", "Authorization", "Accept-Language", "X-API-Version", ginxmw.IdempotencyKeyHeader,
+			ginxmw.AppIDHeader, ginxmw.TimestampHeader, ginxmw.NonceHeader, ginxmw.SignatureHeader}, tus.AllowHeaders...),
 		// 允许前端访问后端设置的响应头
-		ExposeHeaders: []string{"X-Jwt-Token", "X-Refresh-Token"},
+		// API 版本相关的响应头，前端据此提示用户升级
+		ExposeHeaders: append([]string{"X-Jwt-Token", "X-Refresh-Token", "X-API-Version", "Deprecation", "Sunset", "Link", ginxmw.IdempotentReplayedHeader}, tus.ExposeHeaders...),
 		// 允许携带 Cookie
 		AllowCredentials: true,
 		// preflight 请求的缓存时间
 		MaxAge: 12 * time.Hour,
 	})
-	logFn := func(ctx context.Context, al ginxmw.AccessLog) {
-		fields := []logger.Field{
-			logger.String("path", al.Path),
-			logger.String("method", al.Method),
-			logger.String("req_body", al.ReqBody),
-			logger.Int("status", al.Status),
-			logger.String("resp_body", al.RespBody),
-			logger.Int64("duration_ms", al.Duration.Milliseconds()),
-		}
-		l.Info(ctx, "access log ", fields...)
-	}
-	accessLogMiddleware := ginxmw.NewAccessLogBuilder(logFn).AllowReqBody().AllowRespBody().Build()
+	accessLogMiddleware := initAccessLog(l)
+	respTimeMiddleware := ginxmw.NewPrometheusBuilder("bedrock", "web", "http", "HTTP 接口的响应时间").BuildResponseTime()
+	panics := ginxmw.NewPanicCounter("bedrock", "web")
+	prometheus.MustRegister(panics)
+	recoveryMiddleware := ginxmw.NewRecoveryBuilder(l).Counter(panics).Build()
+	// 鉴权在 API 版本的中间件里，访问日志和监控是全局的，没有登录被拒绝的请求也会记录下来
+	// recovery 在 otelgin 里面才能拿到 span，在访问日志和监控里面它们才能记录到 500
 	return []gin.HandlerFunc{
 		otelgin.Middleware("bedrock"),
 		corsMiddleware,
-		middleware.NewJWTAuth(jwtHdl).Middleware(),
+		respTimeMiddleware,
 		accessLogMiddleware,
+		recoveryMiddleware,
 	}
 }
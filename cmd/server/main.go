@@ -25,11 +25,12 @@ func main() {
 	}()
 
 	app := InitApp()
+	app.scheduler.Start()
 
 	// 初始化 HTTP Server
 	srv := &http.Server{
 		Addr:    ":8080",
-		Handler: app.engine,
+		Handler: app.router,
 	}
 
 	// 1. 在 goroutine 中启动服务器
@@ -58,6 +59,8 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		fmt.Println("Server forced to shutdown:", err.Error())
 	}
+	// 等待正在执行的定时任务退出
+	app.scheduler.Stop()
 
 	fmt.Println("Server exiting")
 }
@@ -8,6 +8,7 @@ import (
 	"bedrock/internal/repository/cache"
 	"bedrock/internal/repository/dao"
 	"bedrock/internal/service"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/middleware/jwt"
 
@@ -26,15 +27,37 @@ var userSvc = wire.NewSet(
 	dao.NewGORMUserDAO,
 	repository.NewCachedUserRepository,
 	service.NewUserService,
+	ioc2.InitAvatarService,
 )
 
 var codeSvc = wire.NewSet(
 	cache.NewRedisCodeCache,
 	repository.NewCachedCodeRepository,
+	ioc2.InitSMSSimulator,
 	ioc2.InitSMSService,
+	ioc2.InitCodePolicies,
+	ioc2.InitCodeChannels,
 	service.NewCodeService,
 )
 
+var notificationSvc = wire.NewSet(
+	dao.NewGORMNotificationDAO,
+	repository.NewNotificationRepository,
+	ioc2.InitNotificationService,
+)
+
+var moderationSvc = wire.NewSet(
+	dao.NewGORMModerationDAO,
+	repository.NewModerationRepository,
+	ioc2.InitModerationService,
+)
+
+var fileSvc = wire.NewSet(
+	dao.NewGORMFileDAO,
+	repository.NewFileRepository,
+	ioc2.InitFileService,
+)
+
 //var wechatSvc = wire.NewSet(
 //	ioc.InitWechatService,
 //)
@@ -45,14 +68,23 @@ func InitApp() *App {
 
 		userSvc,
 		codeSvc,
+		notificationSvc,
+		moderationSvc,
+		fileSvc,
 		//wechatSvc,
 
 		jwt.NewRedisJWTHandler,
 		web.NewUserHandler,
+		ioc2.InitNotificationHandler,
+		web.NewFileHandler,
+		ioc2.InitModerationHandler,
+		simulator.NewHandler,
 		//web.NewOAuth2WechatHandler,
 
+		ioc2.InitI18n,
 		ioc2.InitWebEngine,
 		ioc2.InitGinMiddlewares,
+		ioc2.InitJobs,
 		wire.Struct(new(App), "*"),
 	)
 	return new(App)
@@ -12,6 +12,7 @@ import (
 	"bedrock/internal/repository/cache"
 	"bedrock/internal/repository/dao"
 	"bedrock/internal/service"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/middleware/jwt"
 	"github.com/google/wire"
@@ -20,10 +21,12 @@ import (
 // Injectors from wire.go:
 
 func InitApp() *App {
+	logger := ioc.InitLogger()
+	v := ioc.InitGinMiddlewares(logger)
+	bundle := ioc.InitI18n()
 	cmdable := ioc.InitRedis()
 	handler := jwt.NewRedisJWTHandler(cmdable)
-	logger := ioc.InitLogger()
-	v := ioc.InitGinMiddlewares(handler, logger)
+	provider := ioc.InitStorageService()
 	db := ioc.InitMySQL(logger)
 	userDAO := dao.NewGORMUserDAO(db)
 	userCache := cache.NewRedisUserCache(cmdable)
@@ -31,13 +34,31 @@ func InitApp() *App {
 	userService := service.NewUserService(logger, userRepository)
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
-	smsService := ioc.InitSMSService()
-	codeService := service.NewCodeService(codeRepository, smsService)
-	provider := ioc.InitStorageService()
-	userHandler := web.NewUserHandler(logger, userService, codeService, provider, handler)
-	engine := ioc.InitWebEngine(v, logger, userHandler)
+	simulatorService := ioc.InitSMSSimulator()
+	smsService := ioc.InitSMSService(simulatorService)
+	v2 := ioc.InitCodeChannels(smsService, userRepository)
+	codePolicyRegistry := ioc.InitCodePolicies(v2)
+	codeService := service.NewCodeService(codeRepository, v2, codePolicyRegistry)
+	moderationDAO := dao.NewGORMModerationDAO(db)
+	moderationRepository := repository.NewModerationRepository(moderationDAO)
+	moderationService := ioc.InitModerationService(moderationRepository, provider, logger)
+	avatarService := ioc.InitAvatarService(provider, userService, moderationService, logger)
+	userHandler := web.NewUserHandler(logger, userService, codeService, provider, avatarService, handler)
+	notificationDAO := dao.NewGORMNotificationDAO(db)
+	notificationRepository := repository.NewNotificationRepository(notificationDAO)
+	notificationService := ioc.InitNotificationService(notificationRepository, smsService, logger)
+	notificationHandler := ioc.InitNotificationHandler(notificationService, userService)
+	fileDAO := dao.NewGORMFileDAO(db)
+	fileRepository := repository.NewFileRepository(fileDAO)
+	fileService := ioc.InitFileService(fileRepository, provider, moderationService, logger)
+	fileHandler := web.NewFileHandler(fileService)
+	moderationHandler := ioc.InitModerationHandler(moderationService)
+	simulatorHandler := simulator.NewHandler(simulatorService)
+	router := ioc.InitWebEngine(v, logger, bundle, handler, cmdable, provider, userHandler, notificationHandler, fileHandler, moderationHandler, simulatorHandler)
+	scheduler := ioc.InitJobs(avatarService, fileService, moderationService, notificationService, logger)
 	app := &App{
-		engine: engine,
+		router:    router,
+		scheduler: scheduler,
 	}
 	return app
 }
@@ -46,6 +67,12 @@ func InitApp() *App {
 
 var thirdParty = wire.NewSet(ioc.InitLogger, ioc.InitMySQL, ioc.InitRedis, ioc.InitStorageService)
 
-var userSvc = wire.NewSet(cache.NewRedisUserCache, dao.NewGORMUserDAO, repository.NewCachedUserRepository, service.NewUserService)
+var userSvc = wire.NewSet(cache.NewRedisUserCache, dao.NewGORMUserDAO, repository.NewCachedUserRepository, service.NewUserService, ioc.InitAvatarService)
+
+var codeSvc = wire.NewSet(cache.NewRedisCodeCache, repository.NewCachedCodeRepository, ioc.InitSMSSimulator, ioc.InitSMSService, ioc.InitCodePolicies, ioc.InitCodeChannels, service.NewCodeService)
+
+var notificationSvc = wire.NewSet(dao.NewGORMNotificationDAO, repository.NewNotificationRepository, ioc.InitNotificationService)
+
+var moderationSvc = wire.NewSet(dao.NewGORMModerationDAO, repository.NewModerationRepository, ioc.InitModerationService)
 
-var codeSvc = wire.NewSet(cache.NewRedisCodeCache, repository.NewCachedCodeRepository, ioc.InitSMSService, service.NewCodeService)
+var fileSvc = wire.NewSet(dao.NewGORMFileDAO, repository.NewFileRepository, ioc.InitFileService)
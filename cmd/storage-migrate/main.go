@@ -0,0 +1,117 @@
+// storage-migrate 把一个存储里的对象全部复制到另一个存储，例如从本地磁盘迁移到 S3
+//
+// 两个存储都在配置文件里配置，格式和 pkg/storage/factory.Config 一致：
+//
+//	go run ./cmd/storage-migrate --config configs/dev.yaml --from migrate.from --to migrate.to
+//
+// 默认跳过目标里已经存在并且大小一样的对象，中断之后重新执行即可继续
+package main
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/factory"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func main() {
+	var (
+		file         = pflag.String("config", "configs/dev.yaml", "配置文件路径")
+		from         = pflag.String("from", "migrate.from", "源存储在配置文件里的路径")
+		to           = pflag.String("to", "migrate.to", "目标存储在配置文件里的路径")
+		prefix       = pflag.String("prefix", "", "只迁移以它开头的对象")
+		concurrency  = pflag.Int("concurrency", 4, "同时复制的对象数量")
+		skipExisting = pflag.Bool("skip-existing", true, "跳过目标里已经存在并且大小一样的对象")
+		dryRun       = pflag.Bool("dry-run", false, "只统计需要复制的对象，不复制")
+		interval     = pflag.Duration("progress", 2*time.Second, "输出进度的间隔")
+	)
+	pflag.Parse()
+
+	viper.SetConfigType("yaml")
+	viper.SetConfigFile(*file)
+	if err := viper.ReadInConfig(); err != nil {
+		exit("读取配置失败: %v", err)
+	}
+	src := newProvider(*from)
+	dst := newProvider(*to)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		last storage.MigrateProgress
+		done = make(chan struct{})
+	)
+	go func() {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				p := last
+				mu.Unlock()
+				report(p)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	res, err := storage.Migrate(ctx, src, dst, storage.MigrateOptions{
+		Prefix:       *prefix,
+		Concurrency:  *concurrency,
+		SkipExisting: *skipExisting,
+		DryRun:       *dryRun,
+		Progress: func(p storage.MigrateProgress) {
+			if p.Err != nil {
+				fmt.Fprintln(os.Stderr, "失败:", p.Err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			// 多个 goroutine 同时回调，只保留最新的
+			if p.Listed >= last.Listed && p.Elapsed >= last.Elapsed {
+				last = p
+			}
+		},
+	})
+	close(done)
+	report(res)
+	if err != nil {
+		exit("迁移没有全部完成，重新执行可以继续: %v", err)
+	}
+	fmt.Println("迁移完成")
+}
+
+func newProvider(key string) storage.Provider {
+	var c factory.Config
+	if err := viper.UnmarshalKey(key, &c); err != nil {
+		exit("解析 %s 失败: %v", key, err)
+	}
+	p, err := factory.New(c)
+	if err != nil {
+		exit("创建 %s 失败: %v", key, err)
+	}
+	return p
+}
+
+func report(p storage.MigrateProgress) {
+	speed := float64(p.Bytes) / max(p.Elapsed.Seconds(), 0.001) / (1 << 20)
+	fmt.Printf("[%s] 已列举 %d，已复制 %d，跳过 %d，失败 %d，%.1f MB（%.1f MB/s）\n",
+		p.Elapsed.Truncate(time.Second), p.Listed, p.Copied, p.Skipped, p.Failed,
+		float64(p.Bytes)/(1<<20), speed)
+}
+
+func exit(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
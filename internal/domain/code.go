@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// CodePolicy 验证码策略，不同的业务（登录、重置密码、绑定手机）可以有不同的规则
+type CodePolicy struct {
+	// TplId 短信模板
+	TplId string
+	// Length 验证码长度
+	Length int
+	// Alphabet 验证码字符集，例如 "0123456789"
+	Alphabet string
+	// TTL 验证码有效期
+	TTL time.Duration
+	// ResendInterval 两次发送之间的最小间隔
+	ResendInterval time.Duration
+	// MaxAttempts 一个验证码最多可以验证几次
+	MaxAttempts int
+	// DailyLimit 同一个号码 24 小时内最多发送几次，0 表示不限制
+	DailyLimit int
+	// Channels 投递渠道的优先顺序，前一个渠道失败时依次尝试后面的，例如 ["sms", "voice", "email"]
+	Channels []string
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// File 用户上传的文件，内容相同的文件共用存储里的同一个对象
+type File struct {
+	ID      int64
+	OwnerID int64
+	// Name 上传时的文件名，只用于展示和下载
+	Name string
+	// Key 存储中的 key，由可见性和内容的 SHA-256 决定
+	Key  string
+	Size int64
+	// Hash 内容的 SHA-256，十六进制
+	Hash     string
+	MimeType string
+	// Public 公开文件直接通过 Provider.URL 访问，私有文件只能通过签名链接访问
+	Public bool
+	// Refs 被其他实体引用的次数，没有被引用的文件会被定期清理
+	Refs int64
+	// Status 审核状态，通过之前只有所有者可以访问
+	Status ModerationStatus
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+// FileRef 引用文件的实体，例如帖子的附件是 {Biz: "post", BizID: 帖子 ID}
+type FileRef struct {
+	Biz   string
+	BizID int64
+}
+
+// FileUsage 用户的存储用量，单位字节
+type FileUsage struct {
+	Used int64
+	// Quota 配额，<= 0 表示不限制
+	Quota int64
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+type ModerationStatus uint8
+
+const (
+	ModerationStatusUnknown ModerationStatus = iota
+	// ModerationStatusQuarantined 隔离中，等待扫描或者人工审核，这期间内容对其他人不可见
+	ModerationStatusQuarantined
+	// ModerationStatusApproved 审核通过，内容已经公开
+	ModerationStatusApproved
+	// ModerationStatusRejected 审核不通过，内容已经删除
+	ModerationStatusRejected
+)
+
+func (s ModerationStatus) String() string {
+	switch s {
+	case ModerationStatusQuarantined:
+		return "quarantined"
+	case ModerationStatusApproved:
+		return "approved"
+	case ModerationStatusRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	ModerationBizAvatar = "avatar"
+	ModerationBizFile   = "file"
+)
+
+// Moderation 一次内容审核，上传的内容先放在隔离区，审核通过之后才由业务方公开
+type Moderation struct {
+	ID int64
+	// Biz/BizID 被审核的业务和业务里的 ID，例如文件 ID，头像没有单独的 ID，为 0
+	Biz   string
+	BizID int64
+	// Uid 上传内容的用户
+	Uid int64
+	// Key 隔离区里被扫描的对象
+	Key    string
+	Status ModerationStatus
+	// Scanned 隔离中的内容是否已经有了扫描结论，没有的（例如扫描服务不可用）由后台任务重新扫描，有的等待人工审核
+	Scanned bool
+	// Reason 扫描结果或者审核意见
+	Reason string
+	// Attempts 扫描失败的次数
+	Attempts int
+	// ReviewerID 人工审核的管理员，自动审核时为 0
+	ReviewerID int64
+	Ctime      time.Time
+	Utime      time.Time
+}
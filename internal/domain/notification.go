@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+type CampaignStatus uint8
+
+const (
+	CampaignStatusUnknown CampaignStatus = iota
+	// CampaignStatusRunning 正在分批发送
+	CampaignStatusRunning
+	// CampaignStatusFinished 所有批次都处理完了
+	CampaignStatusFinished
+)
+
+// Campaign 一次营销/通知短信的群发活动
+type Campaign struct {
+	ID    int64
+	Name  string
+	TplId string
+	Args  []string
+	Stats CampaignStats
+	// Status 活动状态
+	Status CampaignStatus
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+// CampaignRecipient 群发活动还没有处理完的接收人，处理完之后删除
+type CampaignRecipient struct {
+	ID         int64
+	CampaignID int64
+	Phone      string
+	// SendAfter 最早的发送时间，处于免打扰时段的接收人是时段结束的时间
+	SendAfter time.Time
+	// Deferred 因为免打扰时段推迟过，已经计入了 CampaignStats.Deferred
+	Deferred bool
+}
+
+// CampaignStats 群发活动的统计
+type CampaignStats struct {
+	// Total 去重之后的接收人数
+	Total int64
+	// Success 发送成功的人数
+	Success int64
+	// Failed 服务商返回失败的人数
+	Failed int64
+	// OptedOut 用户退订而跳过的人数
+	OptedOut int64
+	// Deferred 处于接收人当地免打扰时段、正在等待时段结束的人数
+	Deferred int64
+}
+
+// SMSPreference 用户对营销/通知短信的偏好，按手机号存储
+type SMSPreference struct {
+	Phone string
+	// OptOut 是否退订
+	OptOut bool
+	// Timezone IANA 时区，例如 Asia/Shanghai，为空时根据手机号的国家码推断
+	Timezone string
+}
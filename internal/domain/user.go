@@ -11,14 +11,24 @@ type User struct {
 	Email      string
 	Password   string
 	Nickname   string
-	Avatar     string    // 头像
+	Avatar     string    // 头像在存储中的 key，早期的数据存的是完整 URL
 	Birthday   time.Time // YYYY-MM-DD
 	AboutMe    string
 	Phone      string
 	Ctime      time.Time // UTC 0 的时区
 	WechatInfo WechatInfo
 	//Addr Address
+
+	// AvatarVariants 头像的各个尺寸，按边长从小到大排列
+	AvatarVariants []AvatarVariant
+}
+
+// AvatarVariant 头像缩略图，都是从原图中间裁出来的正方形
+type AvatarVariant struct {
+	Size int    // 边长，单位像素
+	Key  string // 存储中的 key，访问地址由存储决定
 }
+
 type WechatInfo struct {
 	UnionID string
 	OpenID  string
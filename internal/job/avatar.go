@@ -0,0 +1,34 @@
+package job
+
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"context"
+	"time"
+)
+
+// AvatarCleanupJob 清理存储里没有被引用的头像文件
+type AvatarCleanupJob struct {
+	svc service.AvatarService
+	l   logger.Logger
+	// grace 上传之后多久才允许清理，给直传确认和数据库更新留出时间
+	grace time.Duration
+}
+
+var _ Job = &AvatarCleanupJob{}
+
+func NewAvatarCleanupJob(svc service.AvatarService, l logger.Logger, grace time.Duration) *AvatarCleanupJob {
+	return &AvatarCleanupJob{svc: svc, l: l, grace: grace}
+}
+
+func (j *AvatarCleanupJob) Name() string {
+	return "avatar_cleanup"
+}
+
+func (j *AvatarCleanupJob) Run(ctx context.Context) error {
+	deleted, err := j.svc.CleanOrphans(ctx, time.Now().Add(-j.grace))
+	if deleted > 0 {
+		j.l.Info(ctx, "清理孤儿头像", logger.Int("deleted", deleted))
+	}
+	return err
+}
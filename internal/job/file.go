@@ -0,0 +1,34 @@
+package job
+
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"context"
+	"time"
+)
+
+// FileCleanupJob 清理没有被引用的文件和上传失败残留的临时文件
+type FileCleanupJob struct {
+	svc service.FileService
+	l   logger.Logger
+	// grace 上传或者取消引用之后多久才允许清理，给业务引用文件留出时间
+	grace time.Duration
+}
+
+var _ Job = &FileCleanupJob{}
+
+func NewFileCleanupJob(svc service.FileService, l logger.Logger, grace time.Duration) *FileCleanupJob {
+	return &FileCleanupJob{svc: svc, l: l, grace: grace}
+}
+
+func (j *FileCleanupJob) Name() string {
+	return "file_cleanup"
+}
+
+func (j *FileCleanupJob) Run(ctx context.Context) error {
+	deleted, err := j.svc.CleanOrphans(ctx, time.Now().Add(-j.grace))
+	if deleted > 0 {
+		j.l.Info(ctx, "清理孤儿文件", logger.Int("deleted", deleted))
+	}
+	return err
+}
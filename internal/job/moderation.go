@@ -0,0 +1,34 @@
+package job
+
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"context"
+	"time"
+)
+
+// ModerationRescanJob 重新扫描上传时没有扫描成功的内容，例如扫描服务超时或者不可用
+type ModerationRescanJob struct {
+	svc service.ModerationService
+	l   logger.Logger
+	// grace 提交之后多久才重新扫描，避免和上传时的同步扫描重复
+	grace time.Duration
+}
+
+var _ Job = &ModerationRescanJob{}
+
+func NewModerationRescanJob(svc service.ModerationService, l logger.Logger, grace time.Duration) *ModerationRescanJob {
+	return &ModerationRescanJob{svc: svc, l: l, grace: grace}
+}
+
+func (j *ModerationRescanJob) Name() string {
+	return "moderation_rescan"
+}
+
+func (j *ModerationRescanJob) Run(ctx context.Context) error {
+	done, err := j.svc.Rescan(ctx, time.Now().Add(-j.grace))
+	if done > 0 {
+		j.l.Info(ctx, "重新扫描隔离区内容", logger.Int("done", done))
+	}
+	return err
+}
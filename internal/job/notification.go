@@ -0,0 +1,31 @@
+package job
+
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"context"
+)
+
+// CampaignDispatchJob 发送群发活动里到了发送时间的接收人，包括免打扰时段结束的和进程重启之前没有发完的
+type CampaignDispatchJob struct {
+	svc service.NotificationService
+	l   logger.Logger
+}
+
+var _ Job = &CampaignDispatchJob{}
+
+func NewCampaignDispatchJob(svc service.NotificationService, l logger.Logger) *CampaignDispatchJob {
+	return &CampaignDispatchJob{svc: svc, l: l}
+}
+
+func (j *CampaignDispatchJob) Name() string {
+	return "campaign_dispatch"
+}
+
+func (j *CampaignDispatchJob) Run(ctx context.Context) error {
+	handled, err := j.svc.Dispatch(ctx)
+	if handled > 0 {
+		j.l.Info(ctx, "发送群发短信", logger.Int("handled", handled))
+	}
+	return err
+}
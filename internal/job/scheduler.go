@@ -0,0 +1,80 @@
+package job
+
+import (
+	"bedrock/pkg/logger"
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler 按固定间隔执行后台任务
+// 同一个任务上一次还没跑完的时候，这一次就跳过，不会并发执行
+type Scheduler struct {
+	l       logger.Logger
+	entries []entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type entry struct {
+	job      Job
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewScheduler(l logger.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{l: l, ctx: ctx, cancel: cancel}
+}
+
+// Register 注册任务，必须在 Start 之前调用，interval 不大于 0 的任务会被忽略
+// 每次执行的超时时间和间隔一样
+func (s *Scheduler) Register(j Job, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.entries = append(s.entries, entry{job: j, interval: interval, timeout: interval})
+}
+
+func (s *Scheduler) Start() {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Stop 停止调度并等待正在执行的任务退出
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(e entry) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			// 在同一个 goroutine 里执行，执行期间错过的 tick 会被 Ticker 丢弃
+			s.run(e)
+		}
+	}
+}
+
+func (s *Scheduler) run(e entry) {
+	ctx, cancel := context.WithTimeout(s.ctx, e.timeout)
+	defer cancel()
+	start := time.Now()
+	err := e.job.Run(ctx)
+	if err != nil {
+		s.l.Error(ctx, "执行定时任务失败", logger.String("job", e.job.Name()), logger.Error(err))
+		return
+	}
+	s.l.Debug(ctx, "执行定时任务成功", logger.String("job", e.job.Name()),
+		logger.String("duration", time.Since(start).String()))
+}
@@ -0,0 +1,80 @@
+package job
+
+import (
+	"bedrock/pkg/logger"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type funcJob struct {
+	run func(ctx context.Context) error
+}
+
+func (f funcJob) Name() string {
+	return "test"
+}
+
+func (f funcJob) Run(ctx context.Context) error {
+	return f.run(ctx)
+}
+
+func TestScheduler(t *testing.T) {
+	t.Parallel()
+	var (
+		calls   atomic.Int32
+		running atomic.Int32
+		overlap atomic.Bool
+	)
+	s := NewScheduler(logger.NewNopLogger())
+	s.Register(funcJob{run: func(ctx context.Context) error {
+		if running.Add(1) > 1 {
+			overlap.Store(true)
+		}
+		defer running.Add(-1)
+		calls.Add(1)
+		// 执行时间比间隔长，不能并发执行
+		time.Sleep(15 * time.Millisecond)
+		return errors.New("失败了也要继续调度")
+	}}, 5*time.Millisecond)
+	// 间隔不合法的任务直接忽略
+	s.Register(funcJob{run: func(ctx context.Context) error {
+		t.Error("不应该执行")
+		return nil
+	}}, 0)
+
+	s.Start()
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, time.Millisecond)
+	s.Stop()
+	n := calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, n, calls.Load(), "Stop 之后不能再执行")
+	assert.False(t, overlap.Load())
+}
+
+func TestScheduler_StopCancelsRunning(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	s := NewScheduler(logger.NewNopLogger())
+	s.Register(funcJob{run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}, time.Millisecond)
+	s.Start()
+	<-started
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop 没有等到任务退出")
+	}
+}
@@ -0,0 +1,9 @@
+package job
+
+import "context"
+
+// Job 后台定时任务
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
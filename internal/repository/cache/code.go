@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bedrock/internal/domain"
 	"context"
 	_ "embed"
 	"errors"
@@ -18,11 +19,12 @@ var (
 	ErrCodeSendTooMany   = errors.New("发送太频繁")
 	ErrCodeVerifyTooMany = errors.New("验证太频繁")
 	ErrCodeExpired       = errors.New("验证码已失效或不存在")
+	ErrCodeDailyLimit    = errors.New("今日发送次数已达上限")
 )
 
 //go:generate mockgen -source=./code.go -package=mocks -destination=./mocks/code_mock.go CodeCache
 type CodeCache interface {
-	Set(ctx context.Context, biz, phone, code string) error
+	Set(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error
 	Verify(ctx context.Context, biz, phone, code string) (bool, error)
 }
 
@@ -36,14 +38,18 @@ func NewRedisCodeCache(cmd redis.Cmdable) CodeCache {
 	}
 }
 
-func (c *RedisCodeCache) Set(ctx context.Context, biz, phone, code string) error {
-	res, err := c.cmd.Eval(ctx, luaSetCode, []string{c.key(biz, phone)}, code).Int()
+func (c *RedisCodeCache) Set(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
+	res, err := c.cmd.Eval(ctx, luaSetCode, []string{c.key(biz, phone), c.dailyKey(biz, phone)},
+		code, int64(policy.TTL.Seconds()), int64(policy.ResendInterval.Seconds()),
+		policy.MaxAttempts, policy.DailyLimit).Int()
 	// 打印日志
 	if err != nil {
 		// 调用 redis 出了问题
 		return err
 	}
 	switch res {
+	case -3:
+		return ErrCodeDailyLimit
 	case -2:
 		return errors.New("验证码存在，但是没有过期时间")
 	case -1:
@@ -74,3 +80,7 @@ func (c *RedisCodeCache) Verify(ctx context.Context, biz, phone, code string) (b
 func (c *RedisCodeCache) key(biz, phone string) string {
 	return fmt.Sprintf("phone_code:%s:%s", biz, phone)
 }
+
+func (c *RedisCodeCache) dailyKey(biz, phone string) string {
+	return fmt.Sprintf("phone_code_daily:%s:%s", biz, phone)
+}
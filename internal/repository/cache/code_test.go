@@ -1,9 +1,11 @@
 package cache
 
 import (
+	"bedrock/internal/domain"
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redismock/v9"
 	"github.com/stretchr/testify/assert"
@@ -26,7 +28,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 			phone: "12345678901",
 			code:  "123456",
 			mock: func(mock redismock.ClientMock) {
-				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901"}, "123456").SetVal(int64(0))
+				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901", "phone_code_daily:login:12345678901"}, "123456", int64(600), int64(60), 3, 10).SetVal(int64(0))
 			},
 			wantErr: nil,
 		},
@@ -36,17 +38,27 @@ func TestRedisCodeCache_Set(t *testing.T) {
 			phone: "12345678901",
 			code:  "123456",
 			mock: func(mock redismock.ClientMock) {
-				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901"}, "123456").SetVal(int64(-1))
+				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901", "phone_code_daily:login:12345678901"}, "123456", int64(600), int64(60), 3, 10).SetVal(int64(-1))
 			},
 			wantErr: ErrCodeSendTooMany,
 		},
+		{
+			name:  "daily limit",
+			biz:   "login",
+			phone: "12345678901",
+			code:  "123456",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901", "phone_code_daily:login:12345678901"}, "123456", int64(600), int64(60), 3, 10).SetVal(int64(-3))
+			},
+			wantErr: ErrCodeDailyLimit,
+		},
 		{
 			name:  "code exists no expire",
 			biz:   "login",
 			phone: "12345678901",
 			code:  "123456",
 			mock: func(mock redismock.ClientMock) {
-				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901"}, "123456").SetVal(int64(-2))
+				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901", "phone_code_daily:login:12345678901"}, "123456", int64(600), int64(60), 3, 10).SetVal(int64(-2))
 			},
 			wantErr: errors.New("验证码存在，但是没有过期时间"),
 		},
@@ -56,7 +68,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 			phone: "12345678901",
 			code:  "123456",
 			mock: func(mock redismock.ClientMock) {
-				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901"}, "123456").SetErr(errors.New("redis error"))
+				mock.ExpectEval(luaSetCode, []string{"phone_code:login:12345678901", "phone_code_daily:login:12345678901"}, "123456", int64(600), int64(60), 3, 10).SetErr(errors.New("redis error"))
 			},
 			wantErr: errors.New("redis error"),
 		},
@@ -69,7 +81,12 @@ func TestRedisCodeCache_Set(t *testing.T) {
 			db, mock := redismock.NewClientMock()
 			tc.mock(mock)
 			c := NewRedisCodeCache(db)
-			err := c.Set(context.Background(), tc.biz, tc.phone, tc.code)
+			err := c.Set(context.Background(), tc.biz, tc.phone, tc.code, domain.CodePolicy{
+				TTL:            10 * time.Minute,
+				ResendInterval: time.Minute,
+				MaxAttempts:    3,
+				DailyLimit:     10,
+			})
 			assert.Equal(t, tc.wantErr, err)
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -1,21 +1,42 @@
+-- KEYS[1]: 验证码的主键，例如 phone_code:login:+8613800138000
+-- KEYS[2]: 24 小时内发送次数的计数 key
+-- ARGV[1]: 验证码
+-- ARGV[2]: 有效期，单位秒
+-- ARGV[3]: 重发间隔，单位秒
+-- ARGV[4]: 最多可以验证几次
+-- ARGV[5]: 24 小时内最多发送几次，0 表示不限制
 local key = KEYS[1]
 local cntKey = key..":cnt"
+local dailyKey = KEYS[2]
 -- 你准备的存储的验证码
 local val = ARGV[1]
+local expiration = tonumber(ARGV[2])
+local interval = tonumber(ARGV[3])
+local maxAttempts = tonumber(ARGV[4])
+local dailyLimit = tonumber(ARGV[5])
 
 local ttl = tonumber(redis.call("ttl", key))
 if ttl == -1 then
     --    key 存在，但是没有过期时间
     return -2
-elseif ttl == -2 or ttl < 540 then
+elseif ttl == -2 or ttl < expiration - interval then
+    if dailyLimit > 0 then
+        local sent = tonumber(redis.call("get", dailyKey))
+        if sent ~= nil and sent >= dailyLimit then
+            -- 超过每日上限
+            return -3
+        end
+    end
     --    可以发验证码
     redis.call("set", key, val)
-    -- 600 秒
-    redis.call("expire", key, 600)
-    redis.call("set", cntKey, 3)
-    redis.call("expire", cntKey, 600)
+    redis.call("expire", key, expiration)
+    redis.call("set", cntKey, maxAttempts)
+    redis.call("expire", cntKey, expiration)
+    if dailyLimit > 0 and redis.call("incr", dailyKey) == 1 then
+        redis.call("expire", dailyKey, 86400)
+    end
     return 0
 else
     -- 发送太频繁
     return -1
-end
\ No newline at end of file
+end
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"bedrock/internal/domain"
 	"bedrock/internal/repository/cache"
 	"context"
 )
@@ -8,10 +9,11 @@ import (
 var ErrCodeVerifyTooMany = cache.ErrCodeVerifyTooMany
 var ErrCodeSendTooMany = cache.ErrCodeSendTooMany
 var ErrCodeExpired = cache.ErrCodeExpired
+var ErrCodeDailyLimit = cache.ErrCodeDailyLimit
 
 //go:generate mockgen -source=./code.go -package=mocks -destination=./mocks/code_mock.go CodeRepository
 type CodeRepository interface {
-	Set(ctx context.Context, biz, phone, code string) error
+	Set(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error
 	Verify(ctx context.Context, biz, phone, code string) (bool, error)
 }
 
@@ -25,8 +27,8 @@ func NewCachedCodeRepository(c cache.CodeCache) CodeRepository {
 	}
 }
 
-func (c *CachedCodeRepository) Set(ctx context.Context, biz, phone, code string) error {
-	return c.cache.Set(ctx, biz, phone, code)
+func (c *CachedCodeRepository) Set(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
+	return c.cache.Set(ctx, biz, phone, code, policy)
 }
 
 func (c *CachedCodeRepository) Verify(ctx context.Context, biz, phone, code string) (bool, error) {
@@ -1,15 +1,27 @@
 package repository
 
 import (
+	"bedrock/internal/domain"
 	cachemocks "bedrock/internal/repository/cache/mocks"
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
 
+var testPolicy = domain.CodePolicy{
+	TplId:          "1877556",
+	Length:         6,
+	Alphabet:       "0123456789",
+	TTL:            10 * time.Minute,
+	ResendInterval: time.Minute,
+	MaxAttempts:    3,
+	DailyLimit:     10,
+}
+
 func TestCachedCodeRepository_Set(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -29,7 +41,7 @@ func TestCachedCodeRepository_Set(t *testing.T) {
 			code:  "123456",
 			mock: func(ctrl *gomock.Controller) *cachemocks.MockCodeCache {
 				c := cachemocks.NewMockCodeCache(ctrl)
-				c.EXPECT().Set(gomock.Any(), "login", "12345678901", "123456").Return(nil)
+				c.EXPECT().Set(gomock.Any(), "login", "12345678901", "123456", testPolicy).Return(nil)
 				return c
 			},
 			wantErr: nil,
@@ -42,7 +54,7 @@ func TestCachedCodeRepository_Set(t *testing.T) {
 			code:  "123456",
 			mock: func(ctrl *gomock.Controller) *cachemocks.MockCodeCache {
 				c := cachemocks.NewMockCodeCache(ctrl)
-				c.EXPECT().Set(gomock.Any(), "login", "12345678901", "123456").Return(errors.New("redis error"))
+				c.EXPECT().Set(gomock.Any(), "login", "12345678901", "123456", testPolicy).Return(errors.New("redis error"))
 				return c
 			},
 			wantErr: errors.New("redis error"),
@@ -58,7 +70,7 @@ func TestCachedCodeRepository_Set(t *testing.T) {
 
 			c := tc.mock(ctrl)
 			repo := NewCachedCodeRepository(c)
-			err := repo.Set(tc.ctx, tc.biz, tc.phone, tc.code)
+			err := repo.Set(tc.ctx, tc.biz, tc.phone, tc.code, testPolicy)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -0,0 +1,223 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// File 文件的元数据，同一个用户重复上传相同内容（并且可见性相同）只有一条记录
+type File struct {
+	ID       int64  `gorm:"primaryKey,autoIncrement"`
+	OwnerID  int64  `gorm:"uniqueIndex:uk_owner_hash"`
+	Hash     string `gorm:"type:char(64);uniqueIndex:uk_owner_hash"`
+	Public   bool   `gorm:"uniqueIndex:uk_owner_hash"`
+	Name     string `gorm:"type:varchar(256)"`
+	Key      string `gorm:"type:varchar(256);index"` // 内容相同的文件共用一个 key
+	Size     int64
+	MimeType string `gorm:"type:varchar(128)"`
+	Refs     int64  `gorm:"index:idx_refs_utime"` // 引用计数
+	// Status 审核状态，对应 domain.ModerationStatus，审核功能上线之前的文件都视为审核通过
+	Status uint8 `gorm:"default:2"`
+	Ctime  int64
+	Utime  int64 `gorm:"index:idx_refs_utime"`
+}
+
+// FileRef 文件被哪些实体引用，同一个实体只记录一次，File.Refs 是这张表里的记录数
+type FileRef struct {
+	ID     int64  `gorm:"primaryKey,autoIncrement"`
+	FileID int64  `gorm:"uniqueIndex:uk_file_biz"`
+	Biz    string `gorm:"type:varchar(64);uniqueIndex:uk_file_biz"`
+	BizID  int64  `gorm:"uniqueIndex:uk_file_biz"`
+	Ctime  int64
+}
+
+// FileUsage 每个用户的存储用量，上传和删除文件的时候在同一个事务里更新
+type FileUsage struct {
+	OwnerID int64 `gorm:"primaryKey"`
+	Used    int64
+	Ctime   int64
+	Utime   int64
+}
+
+var (
+	ErrQuotaExceeded = errors.New("存储空间不足")
+	ErrDuplicateFile = errors.New("文件重复")
+	ErrFileInUse     = errors.New("文件正在被引用")
+)
+
+//go:generate mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go FileDAO
+type FileDAO interface {
+	// Insert 在同一个事务里占用配额并插入记录，quota <= 0 表示不限制
+	// 超过配额时返回 ErrQuotaExceeded，同一个用户重复上传相同的内容时返回 ErrDuplicateFile
+	Insert(ctx context.Context, f File, quota int64) (int64, error)
+	FindById(ctx context.Context, id int64) (File, error)
+	FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (File, error)
+	// AddRef 记录实体对文件的引用并增加引用计数，同一个实体重复引用不重复计数，文件不存在时返回 ErrRecordNotFound
+	AddRef(ctx context.Context, ref FileRef) error
+	// RemoveRef 删除实体对文件的引用并减少引用计数，没有引用过时什么都不做
+	RemoveRef(ctx context.Context, ref FileRef) error
+	// Delete 删除没有被引用的文件并释放配额，返回被删除的记录，被引用时返回 ErrFileInUse
+	Delete(ctx context.Context, id int64) (File, error)
+	CountByKey(ctx context.Context, key string) (int64, error)
+	// UpdateStatus 更新审核状态，审核通过之后文件会从隔离区挪到正式的 key
+	UpdateStatus(ctx context.Context, id int64, key string, status uint8) error
+	// FindUnreferenced 按照 ID 从小到大查询 afterID 之后、before 之前更新过的没有被引用的文件
+	FindUnreferenced(ctx context.Context, before int64, afterID int64, limit int) ([]File, error)
+	GetUsage(ctx context.Context, ownerID int64) (int64, error)
+}
+
+type GORMFileDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMFileDAO(db *gorm.DB) FileDAO {
+	return &GORMFileDAO{
+		db: db,
+	}
+}
+
+func (g *GORMFileDAO) Insert(ctx context.Context, f File, quota int64) (int64, error) {
+	now := time.Now().UnixMilli()
+	f.Ctime = now
+	f.Utime = now
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 第一次上传时创建用量记录
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&FileUsage{OwnerID: f.OwnerID, Ctime: now, Utime: now}).Error
+		if err != nil {
+			return err
+		}
+		// 检查和占用配额在一条语句里完成，并发上传也不会超出配额
+		query := tx.Model(&FileUsage{}).Where("owner_id = ?", f.OwnerID)
+		if quota > 0 {
+			query = query.Where("used + ? <= ?", f.Size, quota)
+		}
+		res := query.Updates(map[string]any{
+			"used":  gorm.Expr("used + ?", f.Size),
+			"utime": now,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrQuotaExceeded
+		}
+		err = tx.Create(&f).Error
+		var e *mysql.MySQLError
+		if errors.As(err, &e) && e.Number == 1062 {
+			return ErrDuplicateFile
+		}
+		return err
+	})
+	return f.ID, err
+}
+
+func (g *GORMFileDAO) FindById(ctx context.Context, id int64) (File, error) {
+	var res File
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (g *GORMFileDAO) FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (File, error) {
+	var res File
+	err := g.db.WithContext(ctx).
+		Where("owner_id = ? AND hash = ? AND public = ?", ownerID, hash, public).
+		First(&res).Error
+	return res, err
+}
+
+func (g *GORMFileDAO) AddRef(ctx context.Context, ref FileRef) error {
+	now := time.Now().UnixMilli()
+	ref.Ctime = now
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ref)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		// 更新计数会锁住文件记录，和 Delete 里检查引用计数互斥
+		res = tx.Model(&File{}).Where("id = ?", ref.FileID).Updates(map[string]any{
+			"refs":  gorm.Expr("refs + 1"),
+			"utime": now,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (g *GORMFileDAO) RemoveRef(ctx context.Context, ref FileRef) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("file_id = ? AND biz = ? AND biz_id = ?", ref.FileID, ref.Biz, ref.BizID).Delete(&FileRef{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		// 计数变成 0 之后 utime 是取消引用的时间，CleanOrphans 从这个时间开始计算
+		return tx.Model(&File{}).Where("id = ?", ref.FileID).Updates(map[string]any{
+			"refs":  gorm.Expr("GREATEST(refs - 1, 0)"),
+			"utime": time.Now().UnixMilli(),
+		}).Error
+	})
+}
+
+func (g *GORMFileDAO) Delete(ctx context.Context, id int64) (File, error) {
+	var f File
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁住记录，避免检查完引用计数之后又被引用
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&f).Error
+		if err != nil {
+			return err
+		}
+		if f.Refs > 0 {
+			return ErrFileInUse
+		}
+		if err = tx.Where("id = ?", id).Delete(&File{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&FileUsage{}).Where("owner_id = ?", f.OwnerID).Updates(map[string]any{
+			"used":  gorm.Expr("GREATEST(used - ?, 0)", f.Size),
+			"utime": time.Now().UnixMilli(),
+		}).Error
+	})
+	return f, err
+}
+
+func (g *GORMFileDAO) CountByKey(ctx context.Context, key string) (int64, error) {
+	var cnt int64
+	err := g.db.WithContext(ctx).Model(&File{}).Where("`key` = ?", key).Count(&cnt).Error
+	return cnt, err
+}
+
+func (g *GORMFileDAO) UpdateStatus(ctx context.Context, id int64, key string, status uint8) error {
+	return g.db.WithContext(ctx).Model(&File{}).Where("id = ?", id).Updates(map[string]any{
+		"key":    key,
+		"status": status,
+		"utime":  time.Now().UnixMilli(),
+	}).Error
+}
+
+func (g *GORMFileDAO) FindUnreferenced(ctx context.Context, before int64, afterID int64, limit int) ([]File, error) {
+	var res []File
+	err := g.db.WithContext(ctx).
+		Where("refs = 0 AND utime < ? AND id > ?", before, afterID).
+		Order("id").Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMFileDAO) GetUsage(ctx context.Context, ownerID int64) (int64, error) {
+	var u FileUsage
+	err := g.db.WithContext(ctx).Where("owner_id = ?", ownerID).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return u.Used, err
+}
@@ -0,0 +1,290 @@
+package dao
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestGORMFileDAO_Insert(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		mock  func(t *testing.T, mock sqlmock.Sqlmock)
+		quota int64
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_usages`.*ON DUPLICATE KEY UPDATE.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `file_usages` SET .* WHERE owner_id = \\? AND used \\+ \\? <= \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `files`.*").
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			quota:  1024,
+			wantId: 3,
+		},
+		{
+			name: "不限制配额",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_usages`.*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `file_usages` SET .* WHERE owner_id = \\?$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `files`.*").
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectCommit()
+			},
+			wantId: 3,
+		},
+		{
+			name: "超过配额",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_usages`.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `file_usages`.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			quota:   1024,
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "重复上传",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_usages`.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `file_usages`.*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `files`.*").
+					WillReturnError(&mysql.MySQLError{
+						Number:  1062,
+						Message: "Duplicate entry for key 'uk_owner_hash'",
+					})
+				mock.ExpectRollback()
+			},
+			quota:   1024,
+			wantErr: ErrDuplicateFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			id, err := NewGORMFileDAO(gormDB).Insert(context.Background(), File{
+				OwnerID: 1,
+				Hash:    "abc",
+				Key:     "files/private/ab/abc",
+				Size:    100,
+			}, tc.quota)
+			assert.Equal(t, tc.wantErr, err)
+			if err == nil {
+				assert.Equal(t, tc.wantId, id)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMFileDAO_Delete(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"id", "owner_id", "hash", "key", "size", "refs"}
+	testCases := []struct {
+		name string
+		mock func(t *testing.T, mock sqlmock.Sqlmock)
+
+		wantFile File
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `files` WHERE id = \\? .*FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "abc", "files/private/ab/abc", 100, 0))
+				mock.ExpectExec("DELETE FROM `files` WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `file_usages` SET `used`=GREATEST\\(used - \\?, 0\\).*").
+					WithArgs(int64(100), sqlmock.AnyArg(), int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantFile: File{ID: 3, OwnerID: 1, Hash: "abc", Key: "files/private/ab/abc", Size: 100},
+		},
+		{
+			name: "正在被引用",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `files`.*FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "abc", "files/private/ab/abc", 100, 2))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrFileInUse,
+		},
+		{
+			name: "不存在",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `files`.*FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			f, err := NewGORMFileDAO(gormDB).Delete(context.Background(), 3)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err == nil {
+				assert.Equal(t, tc.wantFile, f)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMFileDAO_AddRef(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mock func(t *testing.T, mock sqlmock.Sqlmock)
+
+		wantErr error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_refs`.*ON DUPLICATE KEY UPDATE.*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE `files` SET `refs`=refs \\+ 1.*WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "同一个实体重复引用",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_refs`.*ON DUPLICATE KEY UPDATE.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "文件不存在",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `file_refs`.*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE `files`.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			err := NewGORMFileDAO(gormDB).AddRef(context.Background(), FileRef{FileID: 3, Biz: "post", BizID: 7})
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMFileDAO_RemoveRef(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mock func(t *testing.T, mock sqlmock.Sqlmock)
+
+		wantErr error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `file_refs` WHERE file_id = \\? AND biz = \\? AND biz_id = \\?").
+					WithArgs(int64(3), "post", int64(7)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `files` SET `refs`=GREATEST\\(refs - 1, 0\\).*WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "没有引用过",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `file_refs`.*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			err := NewGORMFileDAO(gormDB).RemoveRef(context.Background(), FileRef{FileID: 3, Biz: "post", BizID: 7})
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func newFileMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	gormDB, err := gorm.Open(gormmysql.New(gormmysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return gormDB, mock
+}
@@ -5,5 +5,12 @@ import "gorm.io/gorm"
 func InitTables(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&User{},
+		&SMSPreference{},
+		&SMSCampaign{},
+		&SMSCampaignRecipient{},
+		&File{},
+		&FileRef{},
+		&FileUsage{},
+		&Moderation{},
 	)
 }
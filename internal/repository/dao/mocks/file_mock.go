@@ -0,0 +1,189 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./file.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	dao "bedrock/internal/repository/dao"
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFileDAO is a mock of FileDAO interface.
+type MockFileDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockFileDAOMockRecorder is the mock recorder for MockFileDAO.
+type MockFileDAOMockRecorder struct {
+	mock *MockFileDAO
+}
+
+// NewMockFileDAO creates a new mock instance.
+func NewMockFileDAO(ctrl *gomock.Controller) *MockFileDAO {
+	mock := &MockFileDAO{ctrl: ctrl}
+	mock.recorder = &MockFileDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileDAO) EXPECT() *MockFileDAOMockRecorder {
+	return m.recorder
+}
+
+// AddRef mocks base method.
+func (m *MockFileDAO) AddRef(ctx context.Context, ref dao.FileRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRef", ctx, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRef indicates an expected call of AddRef.
+func (mr *MockFileDAOMockRecorder) AddRef(ctx, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRef", reflect.TypeOf((*MockFileDAO)(nil).AddRef), ctx, ref)
+}
+
+// CountByKey mocks base method.
+func (m *MockFileDAO) CountByKey(ctx context.Context, key string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByKey", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByKey indicates an expected call of CountByKey.
+func (mr *MockFileDAOMockRecorder) CountByKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByKey", reflect.TypeOf((*MockFileDAO)(nil).CountByKey), ctx, key)
+}
+
+// Delete mocks base method.
+func (m *MockFileDAO) Delete(ctx context.Context, id int64) (dao.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(dao.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFileDAOMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFileDAO)(nil).Delete), ctx, id)
+}
+
+// FindByHash mocks base method.
+func (m *MockFileDAO) FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (dao.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByHash", ctx, ownerID, hash, public)
+	ret0, _ := ret[0].(dao.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByHash indicates an expected call of FindByHash.
+func (mr *MockFileDAOMockRecorder) FindByHash(ctx, ownerID, hash, public any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHash", reflect.TypeOf((*MockFileDAO)(nil).FindByHash), ctx, ownerID, hash, public)
+}
+
+// FindById mocks base method.
+func (m *MockFileDAO) FindById(ctx context.Context, id int64) (dao.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(dao.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockFileDAOMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockFileDAO)(nil).FindById), ctx, id)
+}
+
+// FindUnreferenced mocks base method.
+func (m *MockFileDAO) FindUnreferenced(ctx context.Context, before, afterID int64, limit int) ([]dao.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUnreferenced", ctx, before, afterID, limit)
+	ret0, _ := ret[0].([]dao.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUnreferenced indicates an expected call of FindUnreferenced.
+func (mr *MockFileDAOMockRecorder) FindUnreferenced(ctx, before, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnreferenced", reflect.TypeOf((*MockFileDAO)(nil).FindUnreferenced), ctx, before, afterID, limit)
+}
+
+// GetUsage mocks base method.
+func (m *MockFileDAO) GetUsage(ctx context.Context, ownerID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsage", ctx, ownerID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsage indicates an expected call of GetUsage.
+func (mr *MockFileDAOMockRecorder) GetUsage(ctx, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsage", reflect.TypeOf((*MockFileDAO)(nil).GetUsage), ctx, ownerID)
+}
+
+// Insert mocks base method.
+func (m *MockFileDAO) Insert(ctx context.Context, f dao.File, quota int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, f, quota)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockFileDAOMockRecorder) Insert(ctx, f, quota any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockFileDAO)(nil).Insert), ctx, f, quota)
+}
+
+// RemoveRef mocks base method.
+func (m *MockFileDAO) RemoveRef(ctx context.Context, ref dao.FileRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRef", ctx, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRef indicates an expected call of RemoveRef.
+func (mr *MockFileDAOMockRecorder) RemoveRef(ctx, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRef", reflect.TypeOf((*MockFileDAO)(nil).RemoveRef), ctx, ref)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockFileDAO) UpdateStatus(ctx context.Context, id int64, key string, status uint8) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, id, key, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockFileDAOMockRecorder) UpdateStatus(ctx, id, key, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockFileDAO)(nil).UpdateStatus), ctx, id, key, status)
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./moderation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./moderation.go -package=mocks -destination=./mocks/moderation_mock.go ModerationDAO
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	dao "bedrock/internal/repository/dao"
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockModerationDAO is a mock of ModerationDAO interface.
+type MockModerationDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockModerationDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockModerationDAOMockRecorder is the mock recorder for MockModerationDAO.
+type MockModerationDAOMockRecorder struct {
+	mock *MockModerationDAO
+}
+
+// NewMockModerationDAO creates a new mock instance.
+func NewMockModerationDAO(ctrl *gomock.Controller) *MockModerationDAO {
+	mock := &MockModerationDAO{ctrl: ctrl}
+	mock.recorder = &MockModerationDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModerationDAO) EXPECT() *MockModerationDAOMockRecorder {
+	return m.recorder
+}
+
+// FindById mocks base method.
+func (m *MockModerationDAO) FindById(ctx context.Context, id int64) (dao.Moderation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(dao.Moderation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockModerationDAOMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockModerationDAO)(nil).FindById), ctx, id)
+}
+
+// FindByStatus mocks base method.
+func (m *MockModerationDAO) FindByStatus(ctx context.Context, status uint8, scanned bool, before, afterID int64, limit int) ([]dao.Moderation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByStatus", ctx, status, scanned, before, afterID, limit)
+	ret0, _ := ret[0].([]dao.Moderation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByStatus indicates an expected call of FindByStatus.
+func (mr *MockModerationDAOMockRecorder) FindByStatus(ctx, status, scanned, before, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByStatus", reflect.TypeOf((*MockModerationDAO)(nil).FindByStatus), ctx, status, scanned, before, afterID, limit)
+}
+
+// Insert mocks base method.
+func (m_2 *MockModerationDAO) Insert(ctx context.Context, m dao.Moderation) (int64, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Insert", ctx, m)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockModerationDAOMockRecorder) Insert(ctx, m any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockModerationDAO)(nil).Insert), ctx, m)
+}
+
+// Update mocks base method.
+func (m_2 *MockModerationDAO) Update(ctx context.Context, m dao.Moderation, from uint8) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Update", ctx, m, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockModerationDAOMockRecorder) Update(ctx, m, from any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockModerationDAO)(nil).Update), ctx, m, from)
+}
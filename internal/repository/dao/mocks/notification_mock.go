@@ -0,0 +1,159 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./notification.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./notification.go -package=mocks -destination=./mocks/notification_mock.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	dao "bedrock/internal/repository/dao"
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNotificationDAO is a mock of NotificationDAO interface.
+type MockNotificationDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationDAOMockRecorder
+	isgomock struct{}
+}
+
+// MockNotificationDAOMockRecorder is the mock recorder for MockNotificationDAO.
+type MockNotificationDAOMockRecorder struct {
+	mock *MockNotificationDAO
+}
+
+// NewMockNotificationDAO creates a new mock instance.
+func NewMockNotificationDAO(ctrl *gomock.Controller) *MockNotificationDAO {
+	mock := &MockNotificationDAO{ctrl: ctrl}
+	mock.recorder = &MockNotificationDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationDAO) EXPECT() *MockNotificationDAOMockRecorder {
+	return m.recorder
+}
+
+// ClaimRecipients mocks base method.
+func (m *MockNotificationDAO) ClaimRecipients(ctx context.Context, now, leaseUntil int64, limit int) ([]dao.SMSCampaignRecipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimRecipients", ctx, now, leaseUntil, limit)
+	ret0, _ := ret[0].([]dao.SMSCampaignRecipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimRecipients indicates an expected call of ClaimRecipients.
+func (mr *MockNotificationDAOMockRecorder) ClaimRecipients(ctx, now, leaseUntil, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRecipients", reflect.TypeOf((*MockNotificationDAO)(nil).ClaimRecipients), ctx, now, leaseUntil, limit)
+}
+
+// FindCampaign mocks base method.
+func (m *MockNotificationDAO) FindCampaign(ctx context.Context, id int64) (dao.SMSCampaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCampaign", ctx, id)
+	ret0, _ := ret[0].(dao.SMSCampaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCampaign indicates an expected call of FindCampaign.
+func (mr *MockNotificationDAOMockRecorder) FindCampaign(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCampaign", reflect.TypeOf((*MockNotificationDAO)(nil).FindCampaign), ctx, id)
+}
+
+// FindPreferences mocks base method.
+func (m *MockNotificationDAO) FindPreferences(ctx context.Context, phones []string) ([]dao.SMSPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPreferences", ctx, phones)
+	ret0, _ := ret[0].([]dao.SMSPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPreferences indicates an expected call of FindPreferences.
+func (mr *MockNotificationDAOMockRecorder) FindPreferences(ctx, phones any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPreferences", reflect.TypeOf((*MockNotificationDAO)(nil).FindPreferences), ctx, phones)
+}
+
+// FinishCampaigns mocks base method.
+func (m *MockNotificationDAO) FinishCampaigns(ctx context.Context, running, finished uint8) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishCampaigns", ctx, running, finished)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishCampaigns indicates an expected call of FinishCampaigns.
+func (mr *MockNotificationDAOMockRecorder) FinishCampaigns(ctx, running, finished any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishCampaigns", reflect.TypeOf((*MockNotificationDAO)(nil).FinishCampaigns), ctx, running, finished)
+}
+
+// InsertCampaign mocks base method.
+func (m *MockNotificationDAO) InsertCampaign(ctx context.Context, c dao.SMSCampaign, recipients []dao.SMSCampaignRecipient) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCampaign", ctx, c, recipients)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertCampaign indicates an expected call of InsertCampaign.
+func (mr *MockNotificationDAOMockRecorder) InsertCampaign(ctx, c, recipients any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCampaign", reflect.TypeOf((*MockNotificationDAO)(nil).InsertCampaign), ctx, c, recipients)
+}
+
+// ReleaseRecipients mocks base method.
+func (m *MockNotificationDAO) ReleaseRecipients(ctx context.Context, ids []int64, sendAfter int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseRecipients", ctx, ids, sendAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseRecipients indicates an expected call of ReleaseRecipients.
+func (mr *MockNotificationDAOMockRecorder) ReleaseRecipients(ctx, ids, sendAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseRecipients", reflect.TypeOf((*MockNotificationDAO)(nil).ReleaseRecipients), ctx, ids, sendAfter)
+}
+
+// SettleRecipients mocks base method.
+func (m *MockNotificationDAO) SettleRecipients(ctx context.Context, campaignID int64, delta dao.SMSCampaign, done []int64, deferred []dao.SMSCampaignRecipient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SettleRecipients", ctx, campaignID, delta, done, deferred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SettleRecipients indicates an expected call of SettleRecipients.
+func (mr *MockNotificationDAOMockRecorder) SettleRecipients(ctx, campaignID, delta, done, deferred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SettleRecipients", reflect.TypeOf((*MockNotificationDAO)(nil).SettleRecipients), ctx, campaignID, delta, done, deferred)
+}
+
+// UpsertPreference mocks base method.
+func (m *MockNotificationDAO) UpsertPreference(ctx context.Context, p dao.SMSPreference) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertPreference", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertPreference indicates an expected call of UpsertPreference.
+func (mr *MockNotificationDAOMockRecorder) UpsertPreference(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPreference", reflect.TypeOf((*MockNotificationDAO)(nil).UpsertPreference), ctx, p)
+}
@@ -116,17 +116,17 @@ func (mr *MockUserDAOMockRecorder) Insert(ctx, user any) *gomock.Call {
 }
 
 // UpdateAvatar mocks base method.
-func (m *MockUserDAO) UpdateAvatar(ctx context.Context, id int64, avatar string) error {
+func (m *MockUserDAO) UpdateAvatar(ctx context.Context, id int64, avatar, variants string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, id, avatar)
+	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, id, avatar, variants)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateAvatar indicates an expected call of UpdateAvatar.
-func (mr *MockUserDAOMockRecorder) UpdateAvatar(ctx, id, avatar any) *gomock.Call {
+func (mr *MockUserDAOMockRecorder) UpdateAvatar(ctx, id, avatar, variants any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockUserDAO)(nil).UpdateAvatar), ctx, id, avatar)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockUserDAO)(nil).UpdateAvatar), ctx, id, avatar, variants)
 }
 
 // UpdateById mocks base method.
@@ -142,3 +142,17 @@ func (mr *MockUserDAOMockRecorder) UpdateById(ctx, entity any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateById", reflect.TypeOf((*MockUserDAO)(nil).UpdateById), ctx, entity)
 }
+
+// UpdatePhone mocks base method.
+func (m *MockUserDAO) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePhone", ctx, id, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePhone indicates an expected call of UpdatePhone.
+func (mr *MockUserDAOMockRecorder) UpdatePhone(ctx, id, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePhone", reflect.TypeOf((*MockUserDAO)(nil).UpdatePhone), ctx, id, phone)
+}
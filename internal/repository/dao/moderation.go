@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Moderation 内容审核记录，隔离中的记录由后台任务重新扫描或者等待人工审核
+type Moderation struct {
+	ID         int64  `gorm:"primaryKey,autoIncrement"`
+	Biz        string `gorm:"type:varchar(32);index:idx_biz_id"`
+	BizID      int64  `gorm:"index:idx_biz_id"`
+	Uid        int64
+	Key        string `gorm:"type:varchar(256)"`
+	Status     uint8  `gorm:"index:idx_status_scanned"`
+	Scanned    bool   `gorm:"index:idx_status_scanned"`
+	Reason     string `gorm:"type:varchar(512)"`
+	Attempts   int
+	ReviewerID int64
+	Ctime      int64
+	Utime      int64
+}
+
+// ErrModerationHandled 记录已经不在隔离状态，例如被另一个管理员审核过了
+var ErrModerationHandled = errors.New("审核记录已经处理过了")
+
+//go:generate mockgen -source=./moderation.go -package=mocks -destination=./mocks/moderation_mock.go ModerationDAO
+type ModerationDAO interface {
+	Insert(ctx context.Context, m Moderation) (int64, error)
+	FindById(ctx context.Context, id int64) (Moderation, error)
+	// FindByStatus 按照 ID 从小到大查询 afterID 之后、before 之前创建的记录
+	FindByStatus(ctx context.Context, status uint8, scanned bool, before int64, afterID int64, limit int) ([]Moderation, error)
+	// Update 更新处理结果，只有当前状态是 from 的记录才会被更新，否则返回 ErrModerationHandled
+	Update(ctx context.Context, m Moderation, from uint8) error
+}
+
+type GORMModerationDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMModerationDAO(db *gorm.DB) ModerationDAO {
+	return &GORMModerationDAO{
+		db: db,
+	}
+}
+
+func (g *GORMModerationDAO) Insert(ctx context.Context, m Moderation) (int64, error) {
+	now := time.Now().UnixMilli()
+	m.Ctime = now
+	m.Utime = now
+	err := g.db.WithContext(ctx).Create(&m).Error
+	return m.ID, err
+}
+
+func (g *GORMModerationDAO) FindById(ctx context.Context, id int64) (Moderation, error) {
+	var res Moderation
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (g *GORMModerationDAO) FindByStatus(ctx context.Context, status uint8, scanned bool, before int64, afterID int64, limit int) ([]Moderation, error) {
+	var res []Moderation
+	err := g.db.WithContext(ctx).
+		Where("status = ? AND scanned = ? AND ctime < ? AND id > ?", status, scanned, before, afterID).
+		Order("id").Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMModerationDAO) Update(ctx context.Context, m Moderation, from uint8) error {
+	// 用状态做乐观锁，自动审核和人工审核同时处理时只有一方能成功
+	res := g.db.WithContext(ctx).Model(&Moderation{}).
+		Where("id = ? AND status = ?", m.ID, from).
+		Updates(map[string]any{
+			"status":      m.Status,
+			"scanned":     m.Scanned,
+			"reason":      m.Reason,
+			"attempts":    m.Attempts,
+			"reviewer_id": m.ReviewerID,
+			"utime":       time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrModerationHandled
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGORMModerationDAO_Update(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mock func(t *testing.T, mock sqlmock.Sqlmock)
+
+		wantErr error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `moderations` SET .* WHERE id = \\? AND status = \\?").
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1), uint8(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// 状态已经变了，说明被别人处理过了
+			name: "handled",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `moderations` SET .* WHERE id = \\? AND status = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			wantErr: ErrModerationHandled,
+		},
+		{
+			name: "db error",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `moderations` SET .* WHERE id = \\? AND status = \\?").
+					WillReturnError(errors.New("db error"))
+				mock.ExpectRollback()
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			err := NewGORMModerationDAO(gormDB).Update(context.Background(), Moderation{
+				ID:      1,
+				Status:  2,
+				Scanned: true,
+			}, 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
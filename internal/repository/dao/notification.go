@@ -0,0 +1,187 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SMSPreference 用户的短信偏好，营销/通知短信发送前需要检查
+type SMSPreference struct {
+	ID       int64  `gorm:"primaryKey,autoIncrement"`
+	Phone    string `gorm:"type:varchar(32);uniqueIndex"`
+	OptOut   bool
+	Timezone string `gorm:"type:varchar(64)"`
+	Ctime    int64
+	Utime    int64
+}
+
+// SMSCampaign 群发活动，统计字段会在每一批发送完成之后累加
+type SMSCampaign struct {
+	ID       int64  `gorm:"primaryKey,autoIncrement"`
+	Name     string `gorm:"type:varchar(256)"`
+	TplId    string `gorm:"type:varchar(128)"`
+	Args     string `gorm:"type:varchar(1024)"` // JSON 数组
+	Total    int64
+	Success  int64
+	Failed   int64
+	OptedOut int64
+	Deferred int64
+	Status   uint8
+	Ctime    int64
+	Utime    int64
+}
+
+// SMSCampaignRecipient 群发活动还没有处理完的接收人，发送、跳过或者失败之后删除，
+// 处于免打扰时段的更新 SendAfter 等待下一次领取，进程重启之后由定时任务接着处理
+type SMSCampaignRecipient struct {
+	ID         int64  `gorm:"primaryKey,autoIncrement"`
+	CampaignID int64  `gorm:"index"`
+	Phone      string `gorm:"type:varchar(32)"`
+	// SendAfter 最早的发送时间（毫秒），领取之后改成租约到期的时间，处理到一半进程退出的接收人到期之后会被重新领取
+	SendAfter int64 `gorm:"index"`
+	// Deferred 因为免打扰时段推迟过，已经计入了 SMSCampaign.Deferred
+	Deferred bool
+	Ctime    int64
+}
+
+//go:generate mockgen -source=./notification.go -package=mocks -destination=./mocks/notification_mock.go NotificationDAO
+type NotificationDAO interface {
+	FindPreferences(ctx context.Context, phones []string) ([]SMSPreference, error)
+	UpsertPreference(ctx context.Context, p SMSPreference) error
+	// InsertCampaign 在同一个事务里插入活动和所有接收人
+	InsertCampaign(ctx context.Context, c SMSCampaign, recipients []SMSCampaignRecipient) (int64, error)
+	// ClaimRecipients 领取 SendAfter 不晚于 now 的接收人，SendAfter 改成 leaseUntil，
+	// 在此之前其他实例不会领取到同样的接收人
+	ClaimRecipients(ctx context.Context, now, leaseUntil int64, limit int) ([]SMSCampaignRecipient, error)
+	// ReleaseRecipients 提前结束租约，接收人从 sendAfter 开始可以被重新领取
+	ReleaseRecipients(ctx context.Context, ids []int64, sendAfter int64) error
+	// SettleRecipients 在同一个事务里删除处理完的接收人、推迟处于免打扰时段的接收人，并在现有统计上累加 delta 中的计数
+	SettleRecipients(ctx context.Context, campaignID int64, delta SMSCampaign, done []int64, deferred []SMSCampaignRecipient) error
+	// FinishCampaigns 把接收人全部处理完的活动从 running 改成 finished，返回更新的活动数
+	FinishCampaigns(ctx context.Context, running, finished uint8) (int64, error)
+	FindCampaign(ctx context.Context, id int64) (SMSCampaign, error)
+}
+
+type GORMNotificationDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMNotificationDAO(db *gorm.DB) NotificationDAO {
+	return &GORMNotificationDAO{
+		db: db,
+	}
+}
+
+func (g *GORMNotificationDAO) FindPreferences(ctx context.Context, phones []string) ([]SMSPreference, error) {
+	var res []SMSPreference
+	err := g.db.WithContext(ctx).Where("phone IN ?", phones).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMNotificationDAO) UpsertPreference(ctx context.Context, p SMSPreference) error {
+	now := time.Now().UnixMilli()
+	p.Ctime = now
+	p.Utime = now
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"opt_out":  p.OptOut,
+			"timezone": p.Timezone,
+			"utime":    now,
+		}),
+	}).Create(&p).Error
+}
+
+func (g *GORMNotificationDAO) InsertCampaign(ctx context.Context, c SMSCampaign, recipients []SMSCampaignRecipient) (int64, error) {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&c).Error; err != nil {
+			return err
+		}
+		for i := range recipients {
+			recipients[i].CampaignID = c.ID
+			recipients[i].Ctime = now
+		}
+		return tx.CreateInBatches(recipients, 1000).Error
+	})
+	return c.ID, err
+}
+
+func (g *GORMNotificationDAO) ClaimRecipients(ctx context.Context, now, leaseUntil int64, limit int) ([]SMSCampaignRecipient, error) {
+	var res []SMSCampaignRecipient
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED 让多个实例同时领取时互不等待，也不会领到同一批
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("send_after <= ?", now).
+			Order("send_after, id").Limit(limit).
+			Find(&res).Error
+		if err != nil || len(res) == 0 {
+			return err
+		}
+		ids := make([]int64, 0, len(res))
+		for _, r := range res {
+			ids = append(ids, r.ID)
+		}
+		return tx.Model(&SMSCampaignRecipient{}).Where("id IN ?", ids).Update("send_after", leaseUntil).Error
+	})
+	return res, err
+}
+
+func (g *GORMNotificationDAO) ReleaseRecipients(ctx context.Context, ids []int64, sendAfter int64) error {
+	return g.db.WithContext(ctx).Model(&SMSCampaignRecipient{}).
+		Where("id IN ?", ids).Update("send_after", sendAfter).Error
+}
+
+func (g *GORMNotificationDAO) SettleRecipients(ctx context.Context, campaignID int64, delta SMSCampaign, done []int64, deferred []SMSCampaignRecipient) error {
+	// 同一个时区的接收人推迟到同一个时间，合并成一条语句
+	groups := make(map[int64][]int64)
+	for _, r := range deferred {
+		groups[r.SendAfter] = append(groups[r.SendAfter], r.ID)
+	}
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(done) > 0 {
+			if err := tx.Where("id IN ?", done).Delete(&SMSCampaignRecipient{}).Error; err != nil {
+				return err
+			}
+		}
+		for sendAfter, ids := range groups {
+			err := tx.Model(&SMSCampaignRecipient{}).Where("id IN ?", ids).Updates(map[string]any{
+				"send_after": sendAfter,
+				"deferred":   true,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&SMSCampaign{}).Where("id = ?", campaignID).Updates(
+			map[string]any{
+				"success":   gorm.Expr("success + ?", delta.Success),
+				"failed":    gorm.Expr("failed + ?", delta.Failed),
+				"opted_out": gorm.Expr("opted_out + ?", delta.OptedOut),
+				"deferred":  gorm.Expr("deferred + ?", delta.Deferred),
+				"utime":     time.Now().UnixMilli(),
+			}).Error
+	})
+}
+
+func (g *GORMNotificationDAO) FinishCampaigns(ctx context.Context, running, finished uint8) (int64, error) {
+	// 已经被领取、还没有处理完的接收人也在表里，不会提前结束
+	res := g.db.WithContext(ctx).Model(&SMSCampaign{}).
+		Where("status = ? AND NOT EXISTS (?)", running,
+			g.db.Model(&SMSCampaignRecipient{}).Select("1").Where("campaign_id = sms_campaigns.id")).
+		Updates(map[string]any{
+			"status": finished,
+			"utime":  time.Now().UnixMilli(),
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (g *GORMNotificationDAO) FindCampaign(ctx context.Context, id int64) (SMSCampaign, error) {
+	var res SMSCampaign
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
@@ -0,0 +1,178 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGORMNotificationDAO_ClaimRecipients(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mock func(t *testing.T, mock sqlmock.Sqlmock)
+
+		wantRecipients []SMSCampaignRecipient
+		wantErr        error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"id", "campaign_id", "phone", "send_after", "deferred"}).
+					AddRow(1, 9, "+8613800138000", 100, false).
+					AddRow(2, 9, "+8613800138001", 200, true)
+				mock.ExpectQuery("SELECT \\* FROM `sms_campaign_recipients` WHERE send_after <= \\? ORDER BY send_after, id LIMIT \\? FOR UPDATE SKIP LOCKED").
+					WithArgs(int64(1000), 2).
+					WillReturnRows(rows)
+				mock.ExpectExec("UPDATE `sms_campaign_recipients` SET `send_after`=\\? WHERE id IN \\(\\?,\\?\\)").
+					WithArgs(int64(2000), int64(1), int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			wantRecipients: []SMSCampaignRecipient{
+				{ID: 1, CampaignID: 9, Phone: "+8613800138000", SendAfter: 100},
+				{ID: 2, CampaignID: 9, Phone: "+8613800138001", SendAfter: 200, Deferred: true},
+			},
+		},
+		{
+			name: "没有到时间的接收人",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `sms_campaign_recipients`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectCommit()
+			},
+			wantRecipients: []SMSCampaignRecipient{},
+		},
+		{
+			name: "续租失败",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `sms_campaign_recipients`.*").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE `sms_campaign_recipients`.*").
+					WillReturnError(errors.New("db error"))
+				mock.ExpectRollback()
+			},
+			wantRecipients: []SMSCampaignRecipient{{ID: 1}},
+			wantErr:        errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			res, err := NewGORMNotificationDAO(gormDB).ClaimRecipients(context.Background(), 1000, 2000, 2)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRecipients, res)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMNotificationDAO_SettleRecipients(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		mock     func(t *testing.T, mock sqlmock.Sqlmock)
+		done     []int64
+		deferred []SMSCampaignRecipient
+
+		wantErr error
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `sms_campaign_recipients` WHERE id IN \\(\\?,\\?\\)").
+					WithArgs(int64(1), int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				// 推迟到同一个时间的接收人合并成一条语句
+				mock.ExpectExec("UPDATE `sms_campaign_recipients` SET `deferred`=\\?,`send_after`=\\? WHERE id IN \\(\\?,\\?\\)").
+					WithArgs(true, int64(5000), int64(3), int64(4)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE `sms_campaigns` SET `deferred`=deferred \\+ \\?,`failed`=failed \\+ \\?,`opted_out`=opted_out \\+ \\?,`success`=success \\+ \\?,`utime`=\\? WHERE id = \\?").
+					WithArgs(int64(2), int64(0), int64(1), int64(1), sqlmock.AnyArg(), int64(9)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			done:     []int64{1, 2},
+			deferred: []SMSCampaignRecipient{{ID: 3, SendAfter: 5000}, {ID: 4, SendAfter: 5000}},
+		},
+		{
+			name: "只更新统计",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `sms_campaigns`.*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "更新统计失败时回滚",
+			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM `sms_campaign_recipients`.*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `sms_campaigns`.*").
+					WillReturnError(errors.New("db error"))
+				mock.ExpectRollback()
+			},
+			done:    []int64{1},
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gormDB, mock := newFileMockDB(t)
+			tc.mock(t, mock)
+
+			err := NewGORMNotificationDAO(gormDB).SettleRecipients(context.Background(), 9,
+				SMSCampaign{Success: 1, OptedOut: 1, Deferred: 2}, tc.done, tc.deferred)
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMNotificationDAO_FinishCampaigns(t *testing.T) {
+	t.Parallel()
+	gormDB, mock := newFileMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sms_campaigns` SET `status`=\\?,`utime`=\\? WHERE status = \\? AND NOT EXISTS \\(SELECT 1 FROM `sms_campaign_recipients` WHERE campaign_id = sms_campaigns.id\\)").
+		WithArgs(uint8(2), sqlmock.AnyArg(), uint8(1)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	n, err := NewGORMNotificationDAO(gormDB).FinishCampaigns(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGORMNotificationDAO_ReleaseRecipients(t *testing.T) {
+	t.Parallel()
+	gormDB, mock := newFileMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sms_campaign_recipients` SET `send_after`=\\? WHERE id IN \\(\\?,\\?\\)").
+		WithArgs(int64(1000), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := NewGORMNotificationDAO(gormDB).ReleaseRecipients(context.Background(), []int64{1, 2}, 1000)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
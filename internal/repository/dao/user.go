@@ -27,6 +27,9 @@ type User struct {
 	WechatUnionId sql.NullString
 	Ctime         int64 // 创建时间 // 时区，UTC 0 的毫秒数
 	Utime         int64 // 更新时间
+
+	// AvatarVariants 头像各个尺寸的 JSON
+	AvatarVariants string `gorm:"type:varchar(4096)"`
 	// json 存储
 	//Addr string
 }
@@ -42,10 +45,11 @@ var (
 type UserDAO interface {
 	Insert(ctx context.Context, user User) error
 	FindByEmail(ctx context.Context, email string) (User, error)
-	UpdateAvatar(ctx context.Context, id int64, avatar string) error
+	UpdateAvatar(ctx context.Context, id int64, avatar string, variants string) error
 	UpdateById(ctx context.Context, entity User) error
 	FindById(ctx context.Context, uid int64) (User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
+	UpdatePhone(ctx context.Context, id int64, phone string) error
 	FindByWechat(ctx context.Context, openId string) (User, error)
 }
 
@@ -91,11 +95,12 @@ func (g *GORMUserDAO) FindByEmail(ctx context.Context, email string) (User, erro
 	return u, err
 }
 
-func (g *GORMUserDAO) UpdateAvatar(ctx context.Context, id int64, avatar string) error {
+func (g *GORMUserDAO) UpdateAvatar(ctx context.Context, id int64, avatar string, variants string) error {
 	return g.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(
 		map[string]any{
-			"avatar": avatar,
-			"utime":  time.Now().UnixMilli(),
+			"avatar":          avatar,
+			"avatar_variants": variants,
+			"utime":           time.Now().UnixMilli(),
 		}).Error
 }
 func (g *GORMUserDAO) UpdateById(ctx context.Context, user User) error {
@@ -122,6 +127,14 @@ func (g *GORMUserDAO) FindByPhone(ctx context.Context, phone string) (User, erro
 	return res, err
 }
 
+func (g *GORMUserDAO) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	return g.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(
+		map[string]any{
+			"phone": phone,
+			"utime": time.Now().UnixMilli(),
+		}).Error
+}
+
 func (g *GORMUserDAO) FindByWechat(ctx context.Context, openID string) (User, error) {
 	var u User
 	err := g.db.WithContext(ctx).Where("wechat_open_id=?", openID).First(&u).Error
@@ -190,21 +190,23 @@ func TestGORMUserDAO_UpdateAvatar(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		name    string
-		mock    func(t *testing.T, mock sqlmock.Sqlmock)
-		ctx     context.Context
-		id      int64
-		avatar  string
-		wantErr error
+		name     string
+		mock     func(t *testing.T, mock sqlmock.Sqlmock)
+		ctx      context.Context
+		id       int64
+		avatar   string
+		variants string
+		wantErr  error
 	}{
 		{
-			name:   "success",
-			ctx:    context.Background(),
-			id:     1,
-			avatar: "new_avatar.jpg",
+			name:     "success",
+			ctx:      context.Background(),
+			id:       1,
+			avatar:   "new_avatar.jpg",
+			variants: `[{"Size":64,"Key":"new_avatar_64.jpg"}]`,
 			mock: func(t *testing.T, mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectExec("UPDATE .*users.* SET .*avatar.*=\\?,.*utime.*=\\? WHERE id = \\?").
+				mock.ExpectExec("UPDATE .*users.* SET .*avatar.*=\\?,.*avatar_variants.*=\\?,.*utime.*=\\? WHERE id = \\?").
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
@@ -245,7 +247,7 @@ func TestGORMUserDAO_UpdateAvatar(t *testing.T) {
 			tc.mock(t, mock)
 
 			dao := NewGORMUserDAO(gormDB)
-			err = dao.UpdateAvatar(tc.ctx, tc.id, tc.avatar)
+			err = dao.UpdateAvatar(tc.ctx, tc.id, tc.avatar, tc.variants)
 			assert.Equal(t, tc.wantErr, err)
 
 			assert.NoError(t, mock.ExpectationsWereMet())
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository/dao"
+	"context"
+	"time"
+)
+
+var (
+	ErrFileNotFound  = dao.ErrRecordNotFound
+	ErrQuotaExceeded = dao.ErrQuotaExceeded
+	ErrDuplicateFile = dao.ErrDuplicateFile
+	ErrFileInUse     = dao.ErrFileInUse
+)
+
+//go:generate mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go FileRepository
+type FileRepository interface {
+	// Create 保存文件并占用配额，quota <= 0 表示不限制
+	Create(ctx context.Context, f domain.File, quota int64) (domain.File, error)
+	FindById(ctx context.Context, id int64) (domain.File, error)
+	FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (domain.File, error)
+	// AddRef 记录 ref 对文件的引用，同一个实体重复引用只算一次
+	AddRef(ctx context.Context, id int64, ref domain.FileRef) error
+	// RemoveRef 取消 ref 对文件的引用，没有引用过时什么都不做
+	RemoveRef(ctx context.Context, id int64, ref domain.FileRef) error
+	// Delete 删除没有被引用的文件并释放配额，返回被删除的文件
+	Delete(ctx context.Context, id int64) (domain.File, error)
+	// CountByKey 还有多少文件在使用存储里的这个对象
+	CountByKey(ctx context.Context, key string) (int64, error)
+	UpdateStatus(ctx context.Context, id int64, key string, status domain.ModerationStatus) error
+	FindUnreferenced(ctx context.Context, before time.Time, afterID int64, limit int) ([]domain.File, error)
+	GetUsage(ctx context.Context, ownerID int64) (int64, error)
+}
+
+type DBFileRepository struct {
+	dao dao.FileDAO
+}
+
+func NewFileRepository(d dao.FileDAO) FileRepository {
+	return &DBFileRepository{
+		dao: d,
+	}
+}
+
+func (r *DBFileRepository) Create(ctx context.Context, f domain.File, quota int64) (domain.File, error) {
+	id, err := r.dao.Insert(ctx, r.toEntity(f), quota)
+	if err != nil {
+		return domain.File{}, err
+	}
+	f.ID = id
+	return f, nil
+}
+
+func (r *DBFileRepository) FindById(ctx context.Context, id int64) (domain.File, error) {
+	f, err := r.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.File{}, err
+	}
+	return r.toDomain(f), nil
+}
+
+func (r *DBFileRepository) FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (domain.File, error) {
+	f, err := r.dao.FindByHash(ctx, ownerID, hash, public)
+	if err != nil {
+		return domain.File{}, err
+	}
+	return r.toDomain(f), nil
+}
+
+func (r *DBFileRepository) AddRef(ctx context.Context, id int64, ref domain.FileRef) error {
+	return r.dao.AddRef(ctx, dao.FileRef{FileID: id, Biz: ref.Biz, BizID: ref.BizID})
+}
+
+func (r *DBFileRepository) RemoveRef(ctx context.Context, id int64, ref domain.FileRef) error {
+	return r.dao.RemoveRef(ctx, dao.FileRef{FileID: id, Biz: ref.Biz, BizID: ref.BizID})
+}
+
+func (r *DBFileRepository) Delete(ctx context.Context, id int64) (domain.File, error) {
+	f, err := r.dao.Delete(ctx, id)
+	if err != nil {
+		return domain.File{}, err
+	}
+	return r.toDomain(f), nil
+}
+
+func (r *DBFileRepository) CountByKey(ctx context.Context, key string) (int64, error) {
+	return r.dao.CountByKey(ctx, key)
+}
+
+func (r *DBFileRepository) UpdateStatus(ctx context.Context, id int64, key string, status domain.ModerationStatus) error {
+	return r.dao.UpdateStatus(ctx, id, key, uint8(status))
+}
+
+func (r *DBFileRepository) FindUnreferenced(ctx context.Context, before time.Time, afterID int64, limit int) ([]domain.File, error) {
+	files, err := r.dao.FindUnreferenced(ctx, before.UnixMilli(), afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.File, 0, len(files))
+	for _, f := range files {
+		res = append(res, r.toDomain(f))
+	}
+	return res, nil
+}
+
+func (r *DBFileRepository) GetUsage(ctx context.Context, ownerID int64) (int64, error) {
+	return r.dao.GetUsage(ctx, ownerID)
+}
+
+func (r *DBFileRepository) toEntity(f domain.File) dao.File {
+	return dao.File{
+		ID:       f.ID,
+		OwnerID:  f.OwnerID,
+		Hash:     f.Hash,
+		Public:   f.Public,
+		Name:     f.Name,
+		Key:      f.Key,
+		Size:     f.Size,
+		MimeType: f.MimeType,
+		Refs:     f.Refs,
+		Status:   uint8(f.Status),
+	}
+}
+
+func (r *DBFileRepository) toDomain(f dao.File) domain.File {
+	return domain.File{
+		ID:       f.ID,
+		OwnerID:  f.OwnerID,
+		Name:     f.Name,
+		Key:      f.Key,
+		Size:     f.Size,
+		Hash:     f.Hash,
+		MimeType: f.MimeType,
+		Public:   f.Public,
+		Refs:     f.Refs,
+		Status:   domain.ModerationStatus(f.Status),
+		Ctime:    time.UnixMilli(f.Ctime),
+		Utime:    time.UnixMilli(f.Utime),
+	}
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	domain "bedrock/internal/domain"
 	context "context"
 	reflect "reflect"
 
@@ -41,17 +42,17 @@ func (m *MockCodeRepository) EXPECT() *MockCodeRepositoryMockRecorder {
 }
 
 // Set mocks base method.
-func (m *MockCodeRepository) Set(ctx context.Context, biz, phone, code string) error {
+func (m *MockCodeRepository) Set(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", ctx, biz, phone, code)
+	ret := m.ctrl.Call(m, "Set", ctx, biz, phone, code, policy)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Set indicates an expected call of Set.
-func (mr *MockCodeRepositoryMockRecorder) Set(ctx, biz, phone, code any) *gomock.Call {
+func (mr *MockCodeRepositoryMockRecorder) Set(ctx, biz, phone, code, policy any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCodeRepository)(nil).Set), ctx, biz, phone, code)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockCodeRepository)(nil).Set), ctx, biz, phone, code, policy)
 }
 
 // Verify mocks base method.
@@ -0,0 +1,190 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./file.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "bedrock/internal/domain"
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFileRepository is a mock of FileRepository interface.
+type MockFileRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockFileRepositoryMockRecorder is the mock recorder for MockFileRepository.
+type MockFileRepositoryMockRecorder struct {
+	mock *MockFileRepository
+}
+
+// NewMockFileRepository creates a new mock instance.
+func NewMockFileRepository(ctrl *gomock.Controller) *MockFileRepository {
+	mock := &MockFileRepository{ctrl: ctrl}
+	mock.recorder = &MockFileRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileRepository) EXPECT() *MockFileRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddRef mocks base method.
+func (m *MockFileRepository) AddRef(ctx context.Context, id int64, ref domain.FileRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRef", ctx, id, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRef indicates an expected call of AddRef.
+func (mr *MockFileRepositoryMockRecorder) AddRef(ctx, id, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRef", reflect.TypeOf((*MockFileRepository)(nil).AddRef), ctx, id, ref)
+}
+
+// CountByKey mocks base method.
+func (m *MockFileRepository) CountByKey(ctx context.Context, key string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByKey", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByKey indicates an expected call of CountByKey.
+func (mr *MockFileRepositoryMockRecorder) CountByKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByKey", reflect.TypeOf((*MockFileRepository)(nil).CountByKey), ctx, key)
+}
+
+// Create mocks base method.
+func (m *MockFileRepository) Create(ctx context.Context, f domain.File, quota int64) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, f, quota)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockFileRepositoryMockRecorder) Create(ctx, f, quota any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockFileRepository)(nil).Create), ctx, f, quota)
+}
+
+// Delete mocks base method.
+func (m *MockFileRepository) Delete(ctx context.Context, id int64) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFileRepositoryMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFileRepository)(nil).Delete), ctx, id)
+}
+
+// FindByHash mocks base method.
+func (m *MockFileRepository) FindByHash(ctx context.Context, ownerID int64, hash string, public bool) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByHash", ctx, ownerID, hash, public)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByHash indicates an expected call of FindByHash.
+func (mr *MockFileRepositoryMockRecorder) FindByHash(ctx, ownerID, hash, public any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHash", reflect.TypeOf((*MockFileRepository)(nil).FindByHash), ctx, ownerID, hash, public)
+}
+
+// FindById mocks base method.
+func (m *MockFileRepository) FindById(ctx context.Context, id int64) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockFileRepositoryMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockFileRepository)(nil).FindById), ctx, id)
+}
+
+// FindUnreferenced mocks base method.
+func (m *MockFileRepository) FindUnreferenced(ctx context.Context, before time.Time, afterID int64, limit int) ([]domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUnreferenced", ctx, before, afterID, limit)
+	ret0, _ := ret[0].([]domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUnreferenced indicates an expected call of FindUnreferenced.
+func (mr *MockFileRepositoryMockRecorder) FindUnreferenced(ctx, before, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnreferenced", reflect.TypeOf((*MockFileRepository)(nil).FindUnreferenced), ctx, before, afterID, limit)
+}
+
+// GetUsage mocks base method.
+func (m *MockFileRepository) GetUsage(ctx context.Context, ownerID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsage", ctx, ownerID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsage indicates an expected call of GetUsage.
+func (mr *MockFileRepositoryMockRecorder) GetUsage(ctx, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsage", reflect.TypeOf((*MockFileRepository)(nil).GetUsage), ctx, ownerID)
+}
+
+// RemoveRef mocks base method.
+func (m *MockFileRepository) RemoveRef(ctx context.Context, id int64, ref domain.FileRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRef", ctx, id, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRef indicates an expected call of RemoveRef.
+func (mr *MockFileRepositoryMockRecorder) RemoveRef(ctx, id, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRef", reflect.TypeOf((*MockFileRepository)(nil).RemoveRef), ctx, id, ref)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockFileRepository) UpdateStatus(ctx context.Context, id int64, key string, status domain.ModerationStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, id, key, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockFileRepositoryMockRecorder) UpdateStatus(ctx, id, key, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockFileRepository)(nil).UpdateStatus), ctx, id, key, status)
+}
@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./moderation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./moderation.go -package=mocks -destination=./mocks/moderation_mock.go ModerationRepository
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "bedrock/internal/domain"
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockModerationRepository is a mock of ModerationRepository interface.
+type MockModerationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockModerationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockModerationRepositoryMockRecorder is the mock recorder for MockModerationRepository.
+type MockModerationRepositoryMockRecorder struct {
+	mock *MockModerationRepository
+}
+
+// NewMockModerationRepository creates a new mock instance.
+func NewMockModerationRepository(ctrl *gomock.Controller) *MockModerationRepository {
+	mock := &MockModerationRepository{ctrl: ctrl}
+	mock.recorder = &MockModerationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModerationRepository) EXPECT() *MockModerationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m_2 *MockModerationRepository) Create(ctx context.Context, m domain.Moderation) (domain.Moderation, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Create", ctx, m)
+	ret0, _ := ret[0].(domain.Moderation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockModerationRepositoryMockRecorder) Create(ctx, m any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockModerationRepository)(nil).Create), ctx, m)
+}
+
+// FindById mocks base method.
+func (m *MockModerationRepository) FindById(ctx context.Context, id int64) (domain.Moderation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.Moderation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockModerationRepositoryMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockModerationRepository)(nil).FindById), ctx, id)
+}
+
+// FindQuarantined mocks base method.
+func (m *MockModerationRepository) FindQuarantined(ctx context.Context, scanned bool, before time.Time, afterID int64, limit int) ([]domain.Moderation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindQuarantined", ctx, scanned, before, afterID, limit)
+	ret0, _ := ret[0].([]domain.Moderation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindQuarantined indicates an expected call of FindQuarantined.
+func (mr *MockModerationRepositoryMockRecorder) FindQuarantined(ctx, scanned, before, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindQuarantined", reflect.TypeOf((*MockModerationRepository)(nil).FindQuarantined), ctx, scanned, before, afterID, limit)
+}
+
+// Update mocks base method.
+func (m_2 *MockModerationRepository) Update(ctx context.Context, m domain.Moderation, from domain.ModerationStatus) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "Update", ctx, m, from)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockModerationRepositoryMockRecorder) Update(ctx, m, from any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockModerationRepository)(nil).Update), ctx, m, from)
+}
@@ -0,0 +1,160 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./notification.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./notification.go -package=mocks -destination=./mocks/notification_mock.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "bedrock/internal/domain"
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNotificationRepository is a mock of NotificationRepository interface.
+type MockNotificationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockNotificationRepositoryMockRecorder is the mock recorder for MockNotificationRepository.
+type MockNotificationRepositoryMockRecorder struct {
+	mock *MockNotificationRepository
+}
+
+// NewMockNotificationRepository creates a new mock instance.
+func NewMockNotificationRepository(ctrl *gomock.Controller) *MockNotificationRepository {
+	mock := &MockNotificationRepository{ctrl: ctrl}
+	mock.recorder = &MockNotificationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationRepository) EXPECT() *MockNotificationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimRecipients mocks base method.
+func (m *MockNotificationRepository) ClaimRecipients(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.CampaignRecipient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimRecipients", ctx, now, leaseUntil, limit)
+	ret0, _ := ret[0].([]domain.CampaignRecipient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimRecipients indicates an expected call of ClaimRecipients.
+func (mr *MockNotificationRepositoryMockRecorder) ClaimRecipients(ctx, now, leaseUntil, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRecipients", reflect.TypeOf((*MockNotificationRepository)(nil).ClaimRecipients), ctx, now, leaseUntil, limit)
+}
+
+// CreateCampaign mocks base method.
+func (m *MockNotificationRepository) CreateCampaign(ctx context.Context, c domain.Campaign, phones []string, sendAfter time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCampaign", ctx, c, phones, sendAfter)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCampaign indicates an expected call of CreateCampaign.
+func (mr *MockNotificationRepositoryMockRecorder) CreateCampaign(ctx, c, phones, sendAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCampaign", reflect.TypeOf((*MockNotificationRepository)(nil).CreateCampaign), ctx, c, phones, sendAfter)
+}
+
+// FindCampaign mocks base method.
+func (m *MockNotificationRepository) FindCampaign(ctx context.Context, id int64) (domain.Campaign, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCampaign", ctx, id)
+	ret0, _ := ret[0].(domain.Campaign)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCampaign indicates an expected call of FindCampaign.
+func (mr *MockNotificationRepositoryMockRecorder) FindCampaign(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCampaign", reflect.TypeOf((*MockNotificationRepository)(nil).FindCampaign), ctx, id)
+}
+
+// FindPreferences mocks base method.
+func (m *MockNotificationRepository) FindPreferences(ctx context.Context, phones []string) (map[string]domain.SMSPreference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPreferences", ctx, phones)
+	ret0, _ := ret[0].(map[string]domain.SMSPreference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPreferences indicates an expected call of FindPreferences.
+func (mr *MockNotificationRepositoryMockRecorder) FindPreferences(ctx, phones any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPreferences", reflect.TypeOf((*MockNotificationRepository)(nil).FindPreferences), ctx, phones)
+}
+
+// FinishCampaigns mocks base method.
+func (m *MockNotificationRepository) FinishCampaigns(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishCampaigns", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishCampaigns indicates an expected call of FinishCampaigns.
+func (mr *MockNotificationRepositoryMockRecorder) FinishCampaigns(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishCampaigns", reflect.TypeOf((*MockNotificationRepository)(nil).FinishCampaigns), ctx)
+}
+
+// ReleaseRecipients mocks base method.
+func (m *MockNotificationRepository) ReleaseRecipients(ctx context.Context, ids []int64, sendAfter time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseRecipients", ctx, ids, sendAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseRecipients indicates an expected call of ReleaseRecipients.
+func (mr *MockNotificationRepositoryMockRecorder) ReleaseRecipients(ctx, ids, sendAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseRecipients", reflect.TypeOf((*MockNotificationRepository)(nil).ReleaseRecipients), ctx, ids, sendAfter)
+}
+
+// SavePreference mocks base method.
+func (m *MockNotificationRepository) SavePreference(ctx context.Context, p domain.SMSPreference) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePreference", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePreference indicates an expected call of SavePreference.
+func (mr *MockNotificationRepositoryMockRecorder) SavePreference(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePreference", reflect.TypeOf((*MockNotificationRepository)(nil).SavePreference), ctx, p)
+}
+
+// SettleRecipients mocks base method.
+func (m *MockNotificationRepository) SettleRecipients(ctx context.Context, campaignID int64, delta domain.CampaignStats, done []int64, deferred []domain.CampaignRecipient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SettleRecipients", ctx, campaignID, delta, done, deferred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SettleRecipients indicates an expected call of SettleRecipients.
+func (mr *MockNotificationRepositoryMockRecorder) SettleRecipients(ctx, campaignID, delta, done, deferred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SettleRecipients", reflect.TypeOf((*MockNotificationRepository)(nil).SettleRecipients), ctx, campaignID, delta, done, deferred)
+}
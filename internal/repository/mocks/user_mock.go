@@ -116,17 +116,17 @@ func (mr *MockUserRepositoryMockRecorder) FindByWechat(ctx, openID any) *gomock.
 }
 
 // UpdateAvatar mocks base method.
-func (m *MockUserRepository) UpdateAvatar(ctx context.Context, id int64, avatar string) error {
+func (m *MockUserRepository) UpdateAvatar(ctx context.Context, id int64, avatar string, variants []domain.AvatarVariant) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, id, avatar)
+	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, id, avatar, variants)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateAvatar indicates an expected call of UpdateAvatar.
-func (mr *MockUserRepositoryMockRecorder) UpdateAvatar(ctx, id, avatar any) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) UpdateAvatar(ctx, id, avatar, variants any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockUserRepository)(nil).UpdateAvatar), ctx, id, avatar)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockUserRepository)(nil).UpdateAvatar), ctx, id, avatar, variants)
 }
 
 // UpdateNonZeroFields mocks base method.
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository/dao"
+	"context"
+	"time"
+)
+
+var (
+	ErrModerationNotFound = dao.ErrRecordNotFound
+	ErrModerationHandled  = dao.ErrModerationHandled
+)
+
+//go:generate mockgen -source=./moderation.go -package=mocks -destination=./mocks/moderation_mock.go ModerationRepository
+type ModerationRepository interface {
+	Create(ctx context.Context, m domain.Moderation) (domain.Moderation, error)
+	FindById(ctx context.Context, id int64) (domain.Moderation, error)
+	// FindQuarantined 按照 ID 从小到大查询 afterID 之后、before 之前进入隔离区的记录
+	// scanned 为 false 时是等待重新扫描的，为 true 时是等待人工审核的
+	FindQuarantined(ctx context.Context, scanned bool, before time.Time, afterID int64, limit int) ([]domain.Moderation, error)
+	// Update 保存处理结果，只有当前状态是 from 的记录才会被更新，否则返回 ErrModerationHandled
+	Update(ctx context.Context, m domain.Moderation, from domain.ModerationStatus) error
+}
+
+type DBModerationRepository struct {
+	dao dao.ModerationDAO
+}
+
+func NewModerationRepository(d dao.ModerationDAO) ModerationRepository {
+	return &DBModerationRepository{
+		dao: d,
+	}
+}
+
+func (r *DBModerationRepository) Create(ctx context.Context, m domain.Moderation) (domain.Moderation, error) {
+	id, err := r.dao.Insert(ctx, r.toEntity(m))
+	if err != nil {
+		return domain.Moderation{}, err
+	}
+	m.ID = id
+	return m, nil
+}
+
+func (r *DBModerationRepository) FindById(ctx context.Context, id int64) (domain.Moderation, error) {
+	m, err := r.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.Moderation{}, err
+	}
+	return r.toDomain(m), nil
+}
+
+func (r *DBModerationRepository) FindQuarantined(ctx context.Context, scanned bool, before time.Time, afterID int64, limit int) ([]domain.Moderation, error) {
+	ms, err := r.dao.FindByStatus(ctx, uint8(domain.ModerationStatusQuarantined), scanned, before.UnixMilli(), afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Moderation, 0, len(ms))
+	for _, m := range ms {
+		res = append(res, r.toDomain(m))
+	}
+	return res, nil
+}
+
+func (r *DBModerationRepository) Update(ctx context.Context, m domain.Moderation, from domain.ModerationStatus) error {
+	return r.dao.Update(ctx, r.toEntity(m), uint8(from))
+}
+
+func (r *DBModerationRepository) toEntity(m domain.Moderation) dao.Moderation {
+	return dao.Moderation{
+		ID:         m.ID,
+		Biz:        m.Biz,
+		BizID:      m.BizID,
+		Uid:        m.Uid,
+		Key:        m.Key,
+		Status:     uint8(m.Status),
+		Scanned:    m.Scanned,
+		Reason:     m.Reason,
+		Attempts:   m.Attempts,
+		ReviewerID: m.ReviewerID,
+	}
+}
+
+func (r *DBModerationRepository) toDomain(m dao.Moderation) domain.Moderation {
+	return domain.Moderation{
+		ID:         m.ID,
+		Biz:        m.Biz,
+		BizID:      m.BizID,
+		Uid:        m.Uid,
+		Key:        m.Key,
+		Status:     domain.ModerationStatus(m.Status),
+		Scanned:    m.Scanned,
+		Reason:     m.Reason,
+		Attempts:   m.Attempts,
+		ReviewerID: m.ReviewerID,
+		Ctime:      time.UnixMilli(m.Ctime),
+		Utime:      time.UnixMilli(m.Utime),
+	}
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository/dao"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+var ErrCampaignNotFound = dao.ErrRecordNotFound
+
+//go:generate mockgen -source=./notification.go -package=mocks -destination=./mocks/notification_mock.go NotificationRepository
+type NotificationRepository interface {
+	// FindPreferences 批量查询偏好，没有设置过偏好的号码不会出现在结果里
+	FindPreferences(ctx context.Context, phones []string) (map[string]domain.SMSPreference, error)
+	SavePreference(ctx context.Context, p domain.SMSPreference) error
+	// CreateCampaign 保存活动和所有接收人，接收人从 sendAfter 开始可以被领取
+	CreateCampaign(ctx context.Context, c domain.Campaign, phones []string, sendAfter time.Time) (int64, error)
+	// ClaimRecipients 领取到了发送时间的接收人，leaseUntil 之前没有处理完的会被重新领取
+	ClaimRecipients(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.CampaignRecipient, error)
+	// ReleaseRecipients 提前结束租约，用于确定没有发出去的接收人，不用等到租约到期
+	ReleaseRecipients(ctx context.Context, ids []int64, sendAfter time.Time) error
+	// SettleRecipients 删除处理完的接收人，按照 SendAfter 推迟 deferred 里的接收人，同时累加统计
+	SettleRecipients(ctx context.Context, campaignID int64, delta domain.CampaignStats, done []int64, deferred []domain.CampaignRecipient) error
+	// FinishCampaigns 结束接收人全部处理完的活动，返回结束的活动数
+	FinishCampaigns(ctx context.Context) (int64, error)
+	FindCampaign(ctx context.Context, id int64) (domain.Campaign, error)
+}
+
+type DBNotificationRepository struct {
+	dao dao.NotificationDAO
+}
+
+func NewNotificationRepository(d dao.NotificationDAO) NotificationRepository {
+	return &DBNotificationRepository{
+		dao: d,
+	}
+}
+
+func (r *DBNotificationRepository) FindPreferences(ctx context.Context, phones []string) (map[string]domain.SMSPreference, error) {
+	if len(phones) == 0 {
+		return map[string]domain.SMSPreference{}, nil
+	}
+	prefs, err := r.dao.FindPreferences(ctx, phones)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]domain.SMSPreference, len(prefs))
+	for _, p := range prefs {
+		res[p.Phone] = domain.SMSPreference{
+			Phone:    p.Phone,
+			OptOut:   p.OptOut,
+			Timezone: p.Timezone,
+		}
+	}
+	return res, nil
+}
+
+func (r *DBNotificationRepository) SavePreference(ctx context.Context, p domain.SMSPreference) error {
+	return r.dao.UpsertPreference(ctx, dao.SMSPreference{
+		Phone:    p.Phone,
+		OptOut:   p.OptOut,
+		Timezone: p.Timezone,
+	})
+}
+
+func (r *DBNotificationRepository) CreateCampaign(ctx context.Context, c domain.Campaign, phones []string, sendAfter time.Time) (int64, error) {
+	args, err := json.Marshal(c.Args)
+	if err != nil {
+		return 0, err
+	}
+	recipients := make([]dao.SMSCampaignRecipient, 0, len(phones))
+	for _, p := range phones {
+		recipients = append(recipients, dao.SMSCampaignRecipient{
+			Phone:     p,
+			SendAfter: sendAfter.UnixMilli(),
+		})
+	}
+	return r.dao.InsertCampaign(ctx, dao.SMSCampaign{
+		Name:     c.Name,
+		TplId:    c.TplId,
+		Args:     string(args),
+		Total:    c.Stats.Total,
+		Success:  c.Stats.Success,
+		Failed:   c.Stats.Failed,
+		OptedOut: c.Stats.OptedOut,
+		Deferred: c.Stats.Deferred,
+		Status:   uint8(c.Status),
+	}, recipients)
+}
+
+func (r *DBNotificationRepository) ClaimRecipients(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.CampaignRecipient, error) {
+	recipients, err := r.dao.ClaimRecipients(ctx, now.UnixMilli(), leaseUntil.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.CampaignRecipient, 0, len(recipients))
+	for _, rc := range recipients {
+		res = append(res, domain.CampaignRecipient{
+			ID:         rc.ID,
+			CampaignID: rc.CampaignID,
+			Phone:      rc.Phone,
+			SendAfter:  time.UnixMilli(rc.SendAfter),
+			Deferred:   rc.Deferred,
+		})
+	}
+	return res, nil
+}
+
+func (r *DBNotificationRepository) ReleaseRecipients(ctx context.Context, ids []int64, sendAfter time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.dao.ReleaseRecipients(ctx, ids, sendAfter.UnixMilli())
+}
+
+func (r *DBNotificationRepository) SettleRecipients(ctx context.Context, campaignID int64, delta domain.CampaignStats,
+	done []int64, deferred []domain.CampaignRecipient) error {
+	entities := make([]dao.SMSCampaignRecipient, 0, len(deferred))
+	for _, rc := range deferred {
+		entities = append(entities, dao.SMSCampaignRecipient{
+			ID:        rc.ID,
+			SendAfter: rc.SendAfter.UnixMilli(),
+		})
+	}
+	return r.dao.SettleRecipients(ctx, campaignID, dao.SMSCampaign{
+		Success:  delta.Success,
+		Failed:   delta.Failed,
+		OptedOut: delta.OptedOut,
+		Deferred: delta.Deferred,
+	}, done, entities)
+}
+
+func (r *DBNotificationRepository) FinishCampaigns(ctx context.Context) (int64, error) {
+	return r.dao.FinishCampaigns(ctx, uint8(domain.CampaignStatusRunning), uint8(domain.CampaignStatusFinished))
+}
+
+func (r *DBNotificationRepository) FindCampaign(ctx context.Context, id int64) (domain.Campaign, error) {
+	c, err := r.dao.FindCampaign(ctx, id)
+	if err != nil {
+		return domain.Campaign{}, err
+	}
+	var args []string
+	if c.Args != "" {
+		if err = json.Unmarshal([]byte(c.Args), &args); err != nil {
+			return domain.Campaign{}, err
+		}
+	}
+	return domain.Campaign{
+		ID:    c.ID,
+		Name:  c.Name,
+		TplId: c.TplId,
+		Args:  args,
+		Stats: domain.CampaignStats{
+			Total:    c.Total,
+			Success:  c.Success,
+			Failed:   c.Failed,
+			OptedOut: c.OptedOut,
+			Deferred: c.Deferred,
+		},
+		Status: domain.CampaignStatus(c.Status),
+		Ctime:  time.UnixMilli(c.Ctime),
+		Utime:  time.UnixMilli(c.Utime),
+	}, nil
+}
@@ -5,8 +5,10 @@ import (
 	"bedrock/internal/repository/cache"
 	"bedrock/internal/repository/dao"
 	"bedrock/pkg/logger"
+	"bedrock/pkg/phone"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -25,7 +27,7 @@ var (
 type UserRepository interface {
 	Create(ctx context.Context, user domain.User) error
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
-	UpdateAvatar(ctx context.Context, id int64, avatar string) error
+	UpdateAvatar(ctx context.Context, id int64, avatar string, variants []domain.AvatarVariant) error
 	UpdateNonZeroFields(ctx context.Context, user domain.User) error
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 
@@ -59,9 +61,9 @@ func (c *CachedUserRepository) FindByEmail(ctx context.Context, email string) (d
 	return c.toDomain(u), nil
 }
 
-func (c *CachedUserRepository) UpdateAvatar(ctx context.Context, id int64, avatar string) error {
+func (c *CachedUserRepository) UpdateAvatar(ctx context.Context, id int64, avatar string, variants []domain.AvatarVariant) error {
 	// 更新数据库
-	err := c.dao.UpdateAvatar(ctx, id, avatar)
+	err := c.dao.UpdateAvatar(ctx, id, avatar, c.variantsToEntity(variants))
 	if err != nil {
 		return err
 	}
@@ -87,14 +89,40 @@ func (c *CachedUserRepository) UpdateNonZeroFields(ctx context.Context, user dom
 	})
 	return c.cache.Delete(ctx, user.ID)
 }
-func (c *CachedUserRepository) FindByPhone(ctx context.Context, phone string) (domain.User, error) {
-	u, err := c.dao.FindByPhone(ctx, phone)
+func (c *CachedUserRepository) FindByPhone(ctx context.Context, number string) (domain.User, error) {
+	u, err := c.dao.FindByPhone(ctx, number)
+	if errors.Is(err, dao.ErrRecordNotFound) {
+		// 历史数据里的中国大陆号码是 11 位国内格式，找不到的时候再按旧格式查一次
+		return c.findByLegacyPhone(ctx, number)
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
 	return c.toDomain(u), nil
 }
 
+// findByLegacyPhone 按旧格式查找，找到之后顺手把号码迁移成 E.164 格式
+func (c *CachedUserRepository) findByLegacyPhone(ctx context.Context, number string) (domain.User, error) {
+	legacy := phone.Legacy(number)
+	if legacy == "" {
+		return domain.User{}, ErrUserNotFound
+	}
+	u, err := c.dao.FindByPhone(ctx, legacy)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err = c.dao.UpdatePhone(ctx, u.ID, number); err != nil {
+		// 迁移失败不影响本次查询，下次查询还会再迁移
+		c.l.Warn(ctx, "迁移旧格式手机号失败", logger.Error(err), logger.Int64("uid", u.ID))
+		return c.toDomain(u), nil
+	}
+	if err = c.cache.Delete(ctx, u.ID); err != nil {
+		c.l.Warn(ctx, "迁移手机号后删除用户缓存失败", logger.Error(err), logger.Int64("uid", u.ID))
+	}
+	u.Phone = sql.NullString{String: number, Valid: true}
+	return c.toDomain(u), nil
+}
+
 // FindById 获取用户详情（带缓存击穿防护）
 func (c *CachedUserRepository) FindById(ctx context.Context, uid int64) (domain.User, error) {
 	// 1. 先查询缓存
@@ -209,7 +237,8 @@ func (c *CachedUserRepository) toEntity(user domain.User) dao.User {
 			Int64: user.Birthday.UnixMilli(),
 			Valid: !user.Birthday.IsZero(), // 表示这个值是有效的，不是 NULL
 		},
-		Avatar: user.Avatar,
+		Avatar:         user.Avatar,
+		AvatarVariants: c.variantsToEntity(user.AvatarVariants),
 		WechatUnionId: sql.NullString{
 			String: user.WechatInfo.UnionID,
 			Valid:  user.WechatInfo.UnionID != "",
@@ -238,9 +267,30 @@ func (c *CachedUserRepository) toDomain(u dao.User) domain.User {
 		Birthday: birthday,
 		Avatar:   u.Avatar,
 		Ctime:    time.UnixMilli(u.Ctime),
+		// 解析失败就当作没有缩略图，不影响读取用户信息
+		AvatarVariants: c.variantsToDomain(u.AvatarVariants),
 		WechatInfo: domain.WechatInfo{
 			OpenID:  u.WechatOpenId.String,
 			UnionID: u.WechatUnionId.String,
 		},
 	}
 }
+
+func (c *CachedUserRepository) variantsToEntity(variants []domain.AvatarVariant) string {
+	if len(variants) == 0 {
+		return ""
+	}
+	val, _ := json.Marshal(variants)
+	return string(val)
+}
+
+func (c *CachedUserRepository) variantsToDomain(val string) []domain.AvatarVariant {
+	if val == "" {
+		return nil
+	}
+	var res []domain.AvatarVariant
+	if err := json.Unmarshal([]byte(val), &res); err != nil {
+		return nil
+	}
+	return res
+}
@@ -301,6 +301,46 @@ func TestCachedUserRepository_FindByPhone(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:  "按旧格式找到并迁移",
+			ctx:   context.Background(),
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+8612345678901").Return(dao.User{}, dao.ErrRecordNotFound)
+				d.EXPECT().FindByPhone(gomock.Any(), "12345678901").Return(dao.User{
+					ID: 1,
+					Phone: sql.NullString{
+						String: "12345678901",
+						Valid:  true,
+					},
+					Ctime: now.UnixMilli(),
+				}, nil)
+				d.EXPECT().UpdatePhone(gomock.Any(), int64(1), "+8612345678901").Return(nil)
+				c.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
+				return d, c
+			},
+			wantUser: domain.User{
+				ID:    1,
+				Phone: "+8612345678901",
+				Ctime: now,
+			},
+			wantErr: nil,
+		},
+		{
+			name:  "国际号码找不到",
+			ctx:   context.Background(),
+			phone: "+14155552671",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().FindByPhone(gomock.Any(), "+14155552671").Return(dao.User{}, dao.ErrRecordNotFound)
+				return d, c
+			},
+			wantUser: domain.User{},
+			wantErr:  ErrUserNotFound,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -379,22 +419,24 @@ func TestCachedUserRepository_FindByWechat(t *testing.T) {
 func TestCachedUserRepository_UpdateAvatar(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
-		name    string
-		mock    func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache)
-		ctx     context.Context
-		id      int64
-		avatar  string
-		wantErr error
+		name     string
+		mock     func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache)
+		ctx      context.Context
+		id       int64
+		avatar   string
+		variants []domain.AvatarVariant
+		wantErr  error
 	}{
 		{
-			name:   "success",
-			ctx:    context.Background(),
-			id:     1,
-			avatar: "avatar.jpg",
+			name:     "success",
+			ctx:      context.Background(),
+			id:       1,
+			avatar:   "avatar.jpg",
+			variants: []domain.AvatarVariant{{Size: 64, Key: "avatar_64.jpg"}},
 			mock: func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache) {
 				d := daomocks.NewMockUserDAO(ctrl)
 				c := cachemocks.NewMockUserCache(ctrl)
-				d.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatar.jpg").Return(nil)
+				d.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatar.jpg", `[{"Size":64,"Key":"avatar_64.jpg"}]`).Return(nil)
 				c.EXPECT().Delete(gomock.Any(), int64(1)).Return(nil)
 				return d, c
 			},
@@ -408,7 +450,7 @@ func TestCachedUserRepository_UpdateAvatar(t *testing.T) {
 			mock: func(ctrl *gomock.Controller) (dao.UserDAO, *cachemocks.MockUserCache) {
 				d := daomocks.NewMockUserDAO(ctrl)
 				c := cachemocks.NewMockUserCache(ctrl)
-				d.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatar.jpg").Return(errors.New("db error"))
+				d.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatar.jpg", "").Return(errors.New("db error"))
 				return d, c
 			},
 			wantErr: errors.New("db error"),
@@ -424,7 +466,7 @@ func TestCachedUserRepository_UpdateAvatar(t *testing.T) {
 
 			d, c := tc.mock(ctrl)
 			repo := NewCachedUserRepository(d, c, logger.NewNopLogger())
-			err := repo.UpdateAvatar(tc.ctx, tc.id, tc.avatar)
+			err := repo.UpdateAvatar(tc.ctx, tc.id, tc.avatar, tc.variants)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	"bedrock/pkg/imagex"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAvatarTooLarge   = errors.New("头像文件太大")
+	ErrAvatarInvalid    = errors.New("头像格式不支持或者文件已损坏")
+	ErrAvatarResolution = errors.New("头像分辨率太大")
+	ErrAvatarRejected   = errors.New("头像没有通过审核")
+	// ErrAvatarPending 头像已经保存，等待审核，通过之后自动更新
+	ErrAvatarPending = errors.New("头像正在审核")
+)
+
+// AvatarConfig 头像处理的配置
+type AvatarConfig struct {
+	// MaxSize 原图的大小上限（字节）
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxWidth/MaxHeight 原图的分辨率上限，解码之前就会检查
+	MaxWidth  int `mapstructure:"max_width"`
+	MaxHeight int `mapstructure:"max_height"`
+	// Sizes 生成的缩略图边长，最大的一个同时作为默认头像
+	Sizes []int `mapstructure:"sizes"`
+	// Format 缩略图的格式，webp（无损）或者 jpeg
+	Format string `mapstructure:"format"`
+	// Quality JPEG 的压缩质量，1~100
+	Quality int `mapstructure:"quality"`
+}
+
+const (
+	// avatarPrefix 头像都存放在 avatars/<uid>/ 下面
+	avatarPrefix = "avatars/"
+	// avatarQuarantinePrefix 审核通过之前缩略图放在 quarantine/avatars/<uid>/<id>/<size>.<ext>，
+	// 上传时指定 ACLPrivate，不依赖 bucket 策略或者 private_prefixes 的配置
+	avatarQuarantinePrefix = "quarantine/avatars/"
+	// avatarUploadPrefix 直传的原图放在 uploads/avatars/<uid>/<nonce>.<ext>，和正在使用的头像分开，
+	// 确认头像时只会读取、删除这个目录下的文件
+	avatarUploadPrefix = "uploads/avatars/"
+)
+
+// avatarUploadKeyRegexp 只接受 NewAvatarUploadKey 生成的 key，nonce 是小写的 UUID
+var avatarUploadKeyRegexp = regexp.MustCompile(`^uploads/avatars/(\d+)/[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.[a-z]+$`)
+
+//go:generate mockgen -source=./avatar.go -package=mocks -destination=./mocks/avatar_mock.go AvatarService
+type AvatarService interface {
+	// Upload 校验头像原图，裁剪成各个尺寸的缩略图放进隔离区提交审核，通过之后更新用户头像，返回按尺寸从小到大排列的缩略图
+	// 审核不通过返回 ErrAvatarRejected，需要人工审核或者暂时无法扫描时返回 ErrAvatarPending，通过之后自动更新
+	// 原图只用来生成缩略图，不会保存，EXIF 等元数据（包括拍摄位置）也就不会泄露出去
+	// 更新成功之后会删除旧头像，删除失败的留给 CleanOrphans 处理
+	Upload(ctx context.Context, uid int64, r io.Reader) ([]domain.AvatarVariant, error)
+	// CleanOrphans 对比存储里的文件和用户引用的头像，删除 before 之前上传、没有被引用的文件，返回删除的数量
+	// 包括删除失败的旧头像、并发上传时被覆盖的头像以及直传之后没有确认的原图
+	CleanOrphans(ctx context.Context, before time.Time) (int, error)
+}
+
+type DefaultAvatarService struct {
+	storage       storage.Provider
+	userSvc       UserService
+	moderationSvc ModerationService
+	l             logger.Logger
+	cfg           AvatarConfig
+	format        imagex.Format
+}
+
+var _ ModerationHandler = &DefaultAvatarService{}
+
+// NewAvatarService 创建头像服务，同时注册为头像审核的处理器
+func NewAvatarService(storageSvc storage.Provider, userSvc UserService, moderationSvc ModerationService, l logger.Logger, cfg AvatarConfig) AvatarService {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 5 << 20
+	}
+	cfg.Sizes = slices.DeleteFunc(slices.Clone(cfg.Sizes), func(size int) bool { return size <= 0 })
+	if len(cfg.Sizes) == 0 {
+		cfg.Sizes = []int{64, 128, 512}
+	}
+	slices.Sort(cfg.Sizes)
+	cfg.Sizes = slices.Compact(cfg.Sizes)
+	if cfg.Quality <= 0 || cfg.Quality > 100 {
+		cfg.Quality = 85
+	}
+	format := imagex.FormatJPEG
+	if cfg.Format == string(imagex.FormatWebP) {
+		format = imagex.FormatWebP
+	}
+	svc := &DefaultAvatarService{
+		storage:       storageSvc,
+		userSvc:       userSvc,
+		moderationSvc: moderationSvc,
+		l:             l,
+		cfg:           cfg,
+		format:        format,
+	}
+	moderationSvc.Register(domain.ModerationBizAvatar, svc)
+	return svc
+}
+
+func (svc *DefaultAvatarService) Upload(ctx context.Context, uid int64, r io.Reader) ([]domain.AvatarVariant, error) {
+	// 多读一个字节，读满了就说明超过了上限
+	data, err := io.ReadAll(io.LimitReader(r, svc.cfg.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > svc.cfg.MaxSize {
+		return nil, ErrAvatarTooLarge
+	}
+	img, _, err := imagex.Decode(data, imagex.Limits{MaxWidth: svc.cfg.MaxWidth, MaxHeight: svc.cfg.MaxHeight})
+	switch {
+	case errors.Is(err, imagex.ErrTooLarge):
+		return nil, ErrAvatarResolution
+	case err != nil:
+		return nil, fmt.Errorf("%w: %w", ErrAvatarInvalid, err)
+	}
+
+	// 同一次上传的缩略图共用一个 id，key 每次都不一样，可以让浏览器和 CDN 永久缓存
+	id := uuid.New().String()
+	keys := make([]string, 0, len(svc.cfg.Sizes))
+	for _, size := range svc.cfg.Sizes {
+		var buf bytes.Buffer
+		if err = imagex.Encode(&buf, imagex.Thumbnail(img, size), svc.format, svc.cfg.Quality); err != nil {
+			svc.discard(ctx, keys)
+			return nil, err
+		}
+		key := fmt.Sprintf("%s%d/%s/%d%s", avatarQuarantinePrefix, uid, id, size, svc.format.Ext())
+		_, err = svc.storage.Upload(ctx, key, &buf, int64(buf.Len()), storage.UploadOptions{
+			ContentType:  svc.format.ContentType(),
+			CacheControl: "public, max-age=31536000, immutable",
+			ACL:          storage.ACLPrivate,
+		})
+		if err != nil {
+			svc.discard(ctx, keys)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	// 审核最大的一张，和用户看到的内容一致
+	m, err := svc.moderationSvc.Submit(ctx, domain.Moderation{
+		Biz: domain.ModerationBizAvatar,
+		Uid: uid,
+		Key: keys[len(keys)-1],
+	})
+	if err != nil {
+		svc.discard(ctx, keys)
+		return nil, err
+	}
+	switch m.Status {
+	case domain.ModerationStatusApproved:
+		// 审核通过时已经由 OnApproved 更新了用户头像
+		variants := make([]domain.AvatarVariant, 0, len(svc.cfg.Sizes))
+		for _, size := range svc.cfg.Sizes {
+			variants = append(variants, domain.AvatarVariant{Size: size, Key: avatarKey(uid, id, size, svc.format.Ext())})
+		}
+		return variants, nil
+	case domain.ModerationStatusRejected:
+		return nil, ErrAvatarRejected
+	default:
+		return nil, ErrAvatarPending
+	}
+}
+
+// OnApproved 把隔离区里的缩略图复制到正式的 key，然后更新用户头像
+func (svc *DefaultAvatarService) OnApproved(ctx context.Context, m domain.Moderation) error {
+	dir := path.Dir(m.Key) + "/"
+	id := path.Base(dir)
+	objs, err := svc.listAll(ctx, dir)
+	if err != nil {
+		return err
+	}
+	// 已经处理过了
+	if len(objs) == 0 {
+		return nil
+	}
+	variants := make([]domain.AvatarVariant, 0, len(objs))
+	keys := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		name := path.Base(obj.Key)
+		ext := path.Ext(name)
+		size, err := strconv.Atoi(strings.TrimSuffix(name, ext))
+		if err != nil {
+			continue
+		}
+		key := avatarKey(m.Uid, id, size, ext)
+		// 正式的头像使用默认权限，不会沿用隔离区的 ACLPrivate
+		if err = svc.storage.Copy(ctx, obj.Key, key, storage.CopyOptions{}); err != nil {
+			return err
+		}
+		variants = append(variants, domain.AvatarVariant{Size: size, Key: key})
+		keys = append(keys, obj.Key)
+	}
+	slices.SortFunc(variants, func(a, b domain.AvatarVariant) int { return a.Size - b.Size })
+	if len(variants) == 0 {
+		return nil
+	}
+
+	// 记下旧头像，更新成功之后删除
+	old, err := svc.userSvc.FindById(ctx, m.Uid)
+	if err != nil {
+		return err
+	}
+	if err = svc.userSvc.UpdateAvatarPath(ctx, m.Uid, variants[len(variants)-1].Key, variants); err != nil {
+		// 删掉复制出来的缩略图，隔离区里的保留下来等待重试
+		svc.discard(ctx, keysOf(variants))
+		return err
+	}
+	// 数据库更新成功之后再删除旧头像和隔离区里的缩略图，删除失败不影响这次上传
+	stale := svc.referenced(old)
+	for _, v := range variants {
+		delete(stale, v.Key)
+	}
+	svc.discard(ctx, slices.Collect(maps.Keys(stale)))
+	svc.discard(ctx, keys)
+	return nil
+}
+
+// OnRejected 删除隔离区里的缩略图
+func (svc *DefaultAvatarService) OnRejected(ctx context.Context, m domain.Moderation) error {
+	objs, err := svc.listAll(ctx, path.Dir(m.Key)+"/")
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err = svc.storage.Delete(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *DefaultAvatarService) listAll(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var (
+		res  []storage.ObjectInfo
+		opts = storage.ListOptions{Prefix: prefix}
+	)
+	for {
+		page, err := svc.storage.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page.Objects...)
+		if !page.Truncated {
+			return res, nil
+		}
+		opts.Marker = page.NextMarker
+	}
+}
+
+func (svc *DefaultAvatarService) CleanOrphans(ctx context.Context, before time.Time) (int, error) {
+	deleted, err := svc.cleanAvatars(ctx, before)
+	if err != nil {
+		return deleted, err
+	}
+	n, err := svc.cleanUploads(ctx, before)
+	return deleted + n, err
+}
+
+// cleanAvatars 删除 avatars/<uid>/ 下面没有被用户引用的缩略图
+func (svc *DefaultAvatarService) cleanAvatars(ctx context.Context, before time.Time) (int, error) {
+	var (
+		deleted int
+		marker  string
+		owner   int64 = -1
+		refs    map[string]struct{}
+	)
+	for {
+		res, err := svc.storage.List(ctx, storage.ListOptions{Prefix: avatarPrefix, Marker: marker})
+		if err != nil {
+			return deleted, err
+		}
+		for _, obj := range res.Objects {
+			uid, ok := avatarOwner(obj.Key)
+			// 早期的头像没有按用户划分目录，无法判断归属，保守起见不删
+			// 刚上传的文件可能还没来得及更新到数据库，也不删
+			if !ok || !obj.LastModified.Before(before) {
+				continue
+			}
+			// 列举结果按 key 排序，同一个用户的文件是连续的
+			if uid != owner {
+				if refs, err = svc.findReferenced(ctx, uid); err != nil {
+					return deleted, err
+				}
+				owner = uid
+			}
+			if _, ok = refs[obj.Key]; ok {
+				continue
+			}
+			if err = svc.storage.Delete(ctx, obj.Key); err != nil {
+				svc.l.Warn(ctx, "删除孤儿头像失败", logger.Error(err), logger.String("key", obj.Key))
+				continue
+			}
+			deleted++
+		}
+		if !res.Truncated {
+			return deleted, nil
+		}
+		marker = res.NextMarker
+	}
+}
+
+// cleanUploads 直传的原图确认之后就会删除，before 之前还留着的都是没有确认的
+func (svc *DefaultAvatarService) cleanUploads(ctx context.Context, before time.Time) (int, error) {
+	var (
+		deleted int
+		marker  string
+	)
+	for {
+		res, err := svc.storage.List(ctx, storage.ListOptions{Prefix: avatarUploadPrefix, Marker: marker})
+		if err != nil {
+			return deleted, err
+		}
+		for _, obj := range res.Objects {
+			if !obj.LastModified.Before(before) {
+				continue
+			}
+			if err = svc.storage.Delete(ctx, obj.Key); err != nil {
+				svc.l.Warn(ctx, "删除没有确认的头像原图失败", logger.Error(err), logger.String("key", obj.Key))
+				continue
+			}
+			deleted++
+		}
+		if !res.Truncated {
+			return deleted, nil
+		}
+		marker = res.NextMarker
+	}
+}
+
+// findReferenced 查询用户正在使用的头像，用户不存在时所有文件都没有被引用
+func (svc *DefaultAvatarService) findReferenced(ctx context.Context, uid int64) (map[string]struct{}, error) {
+	u, err := svc.userSvc.FindById(ctx, uid)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return svc.referenced(u), nil
+}
+
+// referenced 用户引用的所有头像 key，早期存的 URL 会转换成 key
+func (svc *DefaultAvatarService) referenced(u domain.User) map[string]struct{} {
+	res := make(map[string]struct{}, len(u.AvatarVariants)+1)
+	for _, key := range append([]string{u.Avatar}, keysOf(u.AvatarVariants)...) {
+		if strings.Contains(key, "://") {
+			var ok bool
+			if key, ok = storage.KeyFromURL(svc.storage, key); !ok {
+				continue
+			}
+		}
+		if key != "" {
+			res[key] = struct{}{}
+		}
+	}
+	return res
+}
+
+func (svc *DefaultAvatarService) discard(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := svc.storage.Delete(ctx, key); err != nil {
+			svc.l.Warn(ctx, "删除头像文件失败", logger.Error(err), logger.String("key", key))
+		}
+	}
+}
+
+// avatarKey 审核通过之后缩略图的 key，avatars/<uid>/<id>_<size>.<ext>
+func avatarKey(uid int64, id string, size int, ext string) string {
+	return fmt.Sprintf("%s%d/%s_%d%s", avatarPrefix, uid, id, size, ext)
+}
+
+// NewAvatarUploadKey 直传原图的 key，uploads/avatars/<uid>/<nonce><ext>，ext 带上点号
+func NewAvatarUploadKey(uid int64, ext string) string {
+	return fmt.Sprintf("%s%d/%s%s", avatarUploadPrefix, uid, uuid.New().String(), ext)
+}
+
+// IsAvatarUploadKey key 是不是 NewAvatarUploadKey 为 uid 生成的，
+// 只有这种 key 可以确认成头像，也只有这种 key 可以在确认之后删除
+func IsAvatarUploadKey(uid int64, key string) bool {
+	m := avatarUploadKeyRegexp.FindStringSubmatch(key)
+	return m != nil && m[1] == strconv.FormatInt(uid, 10)
+}
+
+// avatarOwner 从 avatars/<uid>/xxx 中解析出 uid
+func avatarOwner(key string) (int64, bool) {
+	rest, ok := strings.CutPrefix(key, avatarPrefix)
+	if !ok {
+		return 0, false
+	}
+	dir, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return 0, false
+	}
+	uid, err := strconv.ParseInt(dir, 10, 64)
+	return uid, err == nil
+}
+
+func keysOf(variants []domain.AvatarVariant) []string {
+	res := make([]string, 0, len(variants))
+	for _, v := range variants {
+		res = append(res, v.Key)
+	}
+	return res
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	repoMocks "bedrock/internal/repository/mocks"
+	svcMocks "bedrock/internal/service/mocks"
+	"bedrock/pkg/imagex"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/local"
+	"bedrock/pkg/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAvatarService_Upload(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for i := range src.Pix {
+		src.Pix[i] = 0xcc
+	}
+	src.SetNRGBA(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	require.NoError(t, png.Encode(&buf, src))
+	pngData := buf.Bytes()
+	dbErr := errors.New("db error")
+	// 旧头像一个是早期存的 URL，一个是 key，上传成功之后都要删掉
+	oldKeys := []string{"avatars/1/old_64.jpg", "avatars/legacy.jpg"}
+	oldUser := domain.User{
+		ID:             1,
+		Avatar:         "https://cdn.example.com/avatars/legacy.jpg",
+		AvatarVariants: []domain.AvatarVariant{{Size: 64, Key: "avatars/1/old_64.jpg"}},
+	}
+
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) UserService
+		cfg     AvatarConfig
+		data    []byte
+		verdict scanner.Verdict
+
+		wantFormat imagex.Format
+		wantSizes  []int
+		wantErr    error
+		// wantQuarantined 失败之后留在隔离区等待审核的缩略图数量
+		wantQuarantined int
+	}{
+		{
+			name: "生成 JPEG 缩略图",
+			mock: func(ctrl *gomock.Controller) UserService {
+				userSvc := svcMocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(1)).Return(oldUser, nil)
+				userSvc.EXPECT().UpdateAvatarPath(gomock.Any(), int64(1), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, avatar string, variants []domain.AvatarVariant) error {
+						require.Len(t, variants, 3)
+						assert.Equal(t, variants[2].Key, avatar)
+						return nil
+					})
+				return userSvc
+			},
+			// 尺寸乱序、重复、非法都会被整理
+			cfg:        AvatarConfig{Sizes: []int{128, 64, 512, 64, 0}},
+			data:       pngData,
+			wantFormat: imagex.FormatJPEG,
+			wantSizes:  []int{64, 128, 512},
+		},
+		{
+			name: "生成 WebP 缩略图",
+			mock: func(ctrl *gomock.Controller) UserService {
+				userSvc := svcMocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(1)).Return(oldUser, nil)
+				userSvc.EXPECT().UpdateAvatarPath(gomock.Any(), int64(1), gomock.Any(), gomock.Any()).Return(nil)
+				return userSvc
+			},
+			cfg:        AvatarConfig{Sizes: []int{32}, Format: "webp"},
+			data:       pngData,
+			wantFormat: imagex.FormatWebP,
+			wantSizes:  []int{32},
+		},
+		{
+			name: "文件太大",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			cfg:     AvatarConfig{MaxSize: int64(len(pngData) - 1)},
+			data:    pngData,
+			wantErr: ErrAvatarTooLarge,
+		},
+		{
+			name: "分辨率太大",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			cfg:     AvatarConfig{MaxWidth: 299},
+			data:    pngData,
+			wantErr: ErrAvatarResolution,
+		},
+		{
+			name: "不是图片",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			data:    []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+			wantErr: ErrAvatarInvalid,
+		},
+		{
+			name: "审核不通过",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			data:    pngData,
+			verdict: scanner.VerdictReject,
+			wantErr: ErrAvatarRejected,
+		},
+		{
+			name: "等待人工审核",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			data:            pngData,
+			verdict:         scanner.VerdictReview,
+			wantErr:         ErrAvatarPending,
+			wantQuarantined: 3,
+		},
+		{
+			// 留在隔离区，由后台任务重试
+			name: "更新用户头像失败",
+			mock: func(ctrl *gomock.Controller) UserService {
+				userSvc := svcMocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(1)).Return(oldUser, nil)
+				userSvc.EXPECT().UpdateAvatarPath(gomock.Any(), int64(1), gomock.Any(), gomock.Any()).Return(dbErr)
+				return userSvc
+			},
+			data:            pngData,
+			wantErr:         ErrAvatarPending,
+			wantQuarantined: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			for _, key := range oldKeys {
+				_, err := store.Upload(context.Background(), key, strings.NewReader("old"), 3, storage.UploadOptions{})
+				require.NoError(t, err)
+			}
+			moderationSvc := newModerationService(ctrl, store, tc.verdict)
+			svc := NewAvatarService(store, tc.mock(ctrl), moderationSvc, logger.NewNopLogger(), tc.cfg)
+			variants, err := svc.Upload(context.Background(), 1, bytes.NewReader(tc.data))
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				// 失败时除了隔离区不能留下新文件，也不能动旧头像
+				var quarantined []string
+				for _, key := range listKeys(t, store) {
+					if strings.HasPrefix(key, "quarantine/avatars/1/") {
+						quarantined = append(quarantined, key)
+					}
+				}
+				assert.Len(t, quarantined, tc.wantQuarantined)
+				assert.ElementsMatch(t, append(oldKeys, quarantined...), listKeys(t, store))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, variants, len(tc.wantSizes))
+			assert.ElementsMatch(t, keysOf(variants), listKeys(t, store))
+			for i, v := range variants {
+				assert.Equal(t, tc.wantSizes[i], v.Size)
+				key := v.Key
+				assert.True(t, strings.HasPrefix(key, "avatars/1/"))
+				assert.True(t, strings.HasSuffix(key, tc.wantFormat.Ext()))
+
+				r, info, err := store.Get(context.Background(), key)
+				require.NoError(t, err)
+				data, err := io.ReadAll(r)
+				require.NoError(t, err)
+				_ = r.Close()
+				assert.Equal(t, tc.wantFormat.ContentType(), info.ContentType)
+				img, format, err := imagex.Decode(data, imagex.Limits{})
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantFormat, format)
+				assert.Equal(t, image.Rect(0, 0, v.Size, v.Size), img.Bounds())
+			}
+		})
+	}
+}
+
+// TestAvatarService_QuarantineACL 隔离区的缩略图不依赖 private_prefixes 的配置，没有签名不能下载
+func TestAvatarService_QuarantineACL(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 100, 100))))
+	store := local.NewProvider(local.Config{RootPath: t.TempDir(), BaseURL: "http://localhost/static", Secret: "secret"})
+	svc := NewAvatarService(store, svcMocks.NewMockUserService(ctrl), newModerationService(ctrl, store, scanner.VerdictReview),
+		logger.NewNopLogger(), AvatarConfig{Sizes: []int{64}})
+	_, err := svc.Upload(context.Background(), 1, &buf)
+	require.ErrorIs(t, err, ErrAvatarPending)
+
+	keys := listKeys(t, store)
+	require.Len(t, keys, 1)
+	assert.True(t, strings.HasPrefix(keys[0], "quarantine/avatars/1/"))
+	verifier := store.(storage.DownloadVerifier)
+	assert.Equal(t, storage.ErrInvalidSignature, verifier.VerifyDownload(keys[0], url.Values{}))
+	// 签名的链接可以下载
+	signed, err := store.GetPrivateURL(context.Background(), keys[0], 60)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.NoError(t, verifier.VerifyDownload(keys[0], u.Query()))
+}
+
+func TestAvatarService_CleanOrphans(t *testing.T) {
+	t.Parallel()
+	keys := []string{
+		"avatars/1/a_64.jpg",
+		"avatars/1/b_64.jpg",
+		"avatars/2/c.png",
+		"avatars/3/d.jpg",
+		"avatars/legacy.jpg",
+		"files/e.txt",
+		"uploads/avatars/1/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41.png",
+	}
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) UserService
+		before time.Time
+
+		wantDeleted int
+		wantKeys    []string
+		wantErr     error
+	}{
+		{
+			name: "删除没有被引用的文件",
+			mock: func(ctrl *gomock.Controller) UserService {
+				userSvc := svcMocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Avatar:         "avatars/1/a_64.jpg",
+					AvatarVariants: []domain.AvatarVariant{{Size: 64, Key: "avatars/1/a_64.jpg"}},
+				}, nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{}, repository.ErrUserNotFound)
+				// 早期存的 URL 也算引用
+				userSvc.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.User{
+					Avatar: "https://cdn.example.com/avatars/3/d.jpg",
+				}, nil)
+				return userSvc
+			},
+			before: time.Now().Add(time.Hour),
+			// 没有确认的直传原图也删掉
+			wantDeleted: 3,
+			// 没有按用户划分目录的旧文件和其他目录的文件都不动
+			wantKeys: []string{"avatars/1/a_64.jpg", "avatars/3/d.jpg", "avatars/legacy.jpg", "files/e.txt"},
+		},
+		{
+			name: "刚上传的文件不删",
+			mock: func(ctrl *gomock.Controller) UserService {
+				return svcMocks.NewMockUserService(ctrl)
+			},
+			before:   time.Now().Add(-time.Hour),
+			wantKeys: keys,
+		},
+		{
+			name: "查询用户失败",
+			mock: func(ctrl *gomock.Controller) UserService {
+				userSvc := svcMocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{}, errors.New("db error"))
+				return userSvc
+			},
+			before:   time.Now().Add(time.Hour),
+			wantKeys: keys,
+			wantErr:  errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			for _, key := range keys {
+				_, err := store.Upload(context.Background(), key, strings.NewReader("x"), 1, storage.UploadOptions{})
+				require.NoError(t, err)
+			}
+			svc := NewAvatarService(store, tc.mock(ctrl), newModerationService(ctrl, store, scanner.VerdictClean), logger.NewNopLogger(), AvatarConfig{})
+			deleted, err := svc.CleanOrphans(context.Background(), tc.before)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDeleted, deleted)
+			assert.Equal(t, tc.wantKeys, listKeys(t, store))
+		})
+	}
+}
+
+func TestIsAvatarUploadKey(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name string
+		uid  int64
+		key  string
+
+		want bool
+	}{
+		{name: "生成的 key", uid: 1, key: NewAvatarUploadKey(1, ".png"), want: true},
+		{name: "别人的 key", uid: 2, key: NewAvatarUploadKey(1, ".png")},
+		{name: "uid 前缀相同", uid: 1, key: NewAvatarUploadKey(12, ".png")},
+		{name: "正在使用的头像", uid: 1, key: "avatars/1/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41_512.jpg"},
+		{name: "隔离区", uid: 1, key: "quarantine/avatars/1/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41/512.jpg"},
+		{name: "nonce 不是 UUID", uid: 1, key: "uploads/avatars/1/abc.png"},
+		{name: "路径穿越", uid: 1, key: "uploads/avatars/1/../../avatars/1/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41_512.jpg"},
+		{name: "多一级目录", uid: 1, key: "uploads/avatars/1/x/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41.png"},
+		{name: "没有扩展名", uid: 1, key: "uploads/avatars/1/0b6f2a1c-2d0e-4c55-9a3e-7f1c9d2b8e41"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, IsAvatarUploadKey(tc.uid, tc.key))
+		})
+	}
+}
+
+func listKeys(t *testing.T, p storage.Provider) []string {
+	res, err := p.List(context.Background(), storage.ListOptions{})
+	require.NoError(t, err)
+	keys := make([]string, 0, len(res.Objects))
+	for _, obj := range res.Objects {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+// newModerationService 扫描结果固定为 verdict 的审核服务，不关心审核记录怎么保存
+func newModerationService(ctrl *gomock.Controller, store storage.Provider, verdict scanner.Verdict) ModerationService {
+	repo := repoMocks.NewMockModerationRepository(ctrl)
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, m domain.Moderation) (domain.Moderation, error) {
+			m.ID = 1
+			return m, nil
+		}).AnyTimes()
+	repo.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return NewModerationService(repo, store, scanner.Func(func(ctx context.Context, obj scanner.Object) (scanner.Result, error) {
+		return scanner.Result{Verdict: verdict}, nil
+	}), logger.NewNopLogger(), ModerationConfig{})
+}
@@ -0,0 +1,43 @@
+package channel
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/service/email"
+	"context"
+	"fmt"
+)
+
+var _ Channel = &EmailChannel{}
+
+// UserFinder 通过手机号找到用户，从而拿到用户绑定的邮箱
+type UserFinder interface {
+	FindByPhone(ctx context.Context, phone string) (domain.User, error)
+}
+
+type EmailChannel struct {
+	svc   email.Service
+	users UserFinder
+}
+
+func NewEmailChannel(svc email.Service, users UserFinder) Channel {
+	return &EmailChannel{
+		svc:   svc,
+		users: users,
+	}
+}
+
+func (c *EmailChannel) Name() string {
+	return Email
+}
+
+func (c *EmailChannel) Deliver(ctx context.Context, tplId, phone, code string) error {
+	u, err := c.users.FindByPhone(ctx, phone)
+	if err != nil {
+		// 新用户还没有注册，自然也没有邮箱
+		return fmt.Errorf("%w: %w", ErrNoContact, err)
+	}
+	if u.Email == "" {
+		return ErrNoContact
+	}
+	return c.svc.Send(ctx, u.Email, "您的验证码", fmt.Sprintf("您的验证码是 %s，请勿泄露给他人。", code))
+}
@@ -0,0 +1,24 @@
+package channel
+
+import (
+	"bedrock/internal/service/sms"
+	"context"
+)
+
+var _ Channel = &SMSChannel{}
+
+type SMSChannel struct {
+	svc sms.Service
+}
+
+func NewSMSChannel(svc sms.Service) Channel {
+	return &SMSChannel{svc: svc}
+}
+
+func (c *SMSChannel) Name() string {
+	return SMS
+}
+
+func (c *SMSChannel) Deliver(ctx context.Context, tplId, phone, code string) error {
+	return c.svc.Send(ctx, tplId, []string{code}, phone)
+}
@@ -0,0 +1,25 @@
+package channel
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	SMS   = "sms"
+	Voice = "voice"
+	Email = "email"
+)
+
+// ErrNoContact 用户在该渠道上没有可用的联系方式，例如没有绑定邮箱
+var ErrNoContact = errors.New("用户没有该渠道的联系方式")
+
+// Channel 验证码的投递渠道
+//
+//go:generate mockgen -source=./type.go -package=mocks -destination=./mocks/channel_mock.go Channel
+type Channel interface {
+	// Name 渠道名字，例如 sms、voice、email
+	Name() string
+	// Deliver 把验证码投递给手机号对应的用户
+	Deliver(ctx context.Context, tplId, phone, code string) error
+}
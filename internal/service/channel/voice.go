@@ -0,0 +1,24 @@
+package channel
+
+import (
+	"bedrock/internal/service/voice"
+	"context"
+)
+
+var _ Channel = &VoiceChannel{}
+
+type VoiceChannel struct {
+	svc voice.Service
+}
+
+func NewVoiceChannel(svc voice.Service) Channel {
+	return &VoiceChannel{svc: svc}
+}
+
+func (c *VoiceChannel) Name() string {
+	return Voice
+}
+
+func (c *VoiceChannel) Deliver(ctx context.Context, tplId, phone, code string) error {
+	return c.svc.Call(ctx, tplId, []string{code}, phone)
+}
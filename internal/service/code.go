@@ -1,49 +1,105 @@
 package service
 
 import (
+	"bedrock/internal/domain"
 	"bedrock/internal/repository"
-	"bedrock/internal/service/sms"
+	"bedrock/internal/service/channel"
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"slices"
+	"strings"
 )
 
 var ErrCodeSendTooMany = repository.ErrCodeSendTooMany
 var ErrCodeVerifyTooMany = repository.ErrCodeVerifyTooMany
 var ErrCodeExpired = repository.ErrCodeExpired
+var ErrCodeDailyLimit = repository.ErrCodeDailyLimit
+var ErrCodeChannelUnsupported = errors.New("不支持的验证码投递渠道")
 
 //go:generate mockgen -source=./code.go -package=mocks -destination=./mocks/code_mock.go CodeService
 type CodeService interface {
-	Send(ctx context.Context, biz, phone string) error
+	// Send 生成并投递验证码，channelHint 是用户期望的渠道，为空时按照策略里的顺序投递
+	Send(ctx context.Context, biz, phone, channelHint string) error
 	Verify(ctx context.Context, biz, phone, inputCode string) (bool, error)
 }
 
 type DefaultCodeService struct {
-	repo   repository.CodeRepository
-	smsSvc sms.Service
+	repo     repository.CodeRepository
+	channels map[string]channel.Channel
+	policies *CodePolicyRegistry
 }
 
-func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service) CodeService {
+func NewCodeService(repo repository.CodeRepository, channels []channel.Channel, policies *CodePolicyRegistry) CodeService {
+	m := make(map[string]channel.Channel, len(channels))
+	for _, c := range channels {
+		m[c.Name()] = c
+	}
 	return &DefaultCodeService{
-		repo:   repo,
-		smsSvc: smsSvc,
+		repo:     repo,
+		channels: m,
+		policies: policies,
 	}
 }
 
-func (svc *DefaultCodeService) Send(ctx context.Context, biz, phone string) error {
-	code := svc.generate()
-	err := svc.repo.Set(ctx, biz, phone, code)
+func (svc *DefaultCodeService) Send(ctx context.Context, biz, phone, channelHint string) error {
+	policy := svc.policies.Get(biz)
+	order, err := svc.order(policy, channelHint)
+	if err != nil {
+		return err
+	}
+	code, err := svc.generate(policy)
+	if err != nil {
+		return err
+	}
+	err = svc.repo.Set(ctx, biz, phone, normalizeCode(code), policy)
 	// 你在这儿，是不是要开始发送验证码了？
 	if err != nil {
 		return err
 	}
-	const codeTplId = "1877556"
-	return svc.smsSvc.Send(ctx, codeTplId, []string{code}, phone)
+	// 同一个验证码依次尝试各个渠道，直到有一个投递成功
+	var errs []error
+	for _, c := range order {
+		err = c.Deliver(ctx, policy.TplId, phone, code)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			// 整个请求已经超时或者被取消了，没必要再换渠道
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// order 计算本次投递的渠道顺序，用户指定的渠道排在最前面
+func (svc *DefaultCodeService) order(policy domain.CodePolicy, hint string) ([]channel.Channel, error) {
+	names := policy.Channels
+	if hint != "" {
+		if !slices.Contains(names, hint) {
+			return nil, ErrCodeChannelUnsupported
+		}
+		names = append([]string{hint}, slices.DeleteFunc(slices.Clone(names), func(n string) bool {
+			return n == hint
+		})...)
+	}
+	res := make([]channel.Channel, 0, len(names))
+	for _, name := range names {
+		if c, ok := svc.channels[name]; ok {
+			res = append(res, c)
+		}
+	}
+	if len(res) == 0 {
+		return nil, ErrCodeChannelUnsupported
+	}
+	return res, nil
 }
 
 func (svc *DefaultCodeService) Verify(ctx context.Context, biz, phone, inputCode string) (bool, error) {
-	ok, err := svc.repo.Verify(ctx, biz, phone, inputCode)
+	ok, err := svc.repo.Verify(ctx, biz, phone, normalizeCode(inputCode))
 	if errors.Is(err, repository.ErrCodeVerifyTooMany) || errors.Is(err, repository.ErrCodeExpired) {
 		// 相当于，我们对外面屏蔽了验证次数过多的错误，我们就是告诉调用者，你这个不对
 		return false, err
@@ -51,8 +107,23 @@ func (svc *DefaultCodeService) Verify(ctx context.Context, biz, phone, inputCode
 	return ok, err
 }
 
-func (svc *DefaultCodeService) generate() string {
-	// 0-999999
-	code := rand.Intn(1000000)
-	return fmt.Sprintf("%06d", code)
+// generate 使用 crypto/rand 按照策略里的长度和字符集生成验证码
+func (svc *DefaultCodeService) generate(policy domain.CodePolicy) (string, error) {
+	alphabet := []rune(policy.Alphabet)
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]rune, policy.Length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeCode 验证码不区分大小写，保存和校验之前都统一转成大写
+// 字母验证码用户经常输入成小写，投递出去的还是原样的验证码
+func normalizeCode(code string) string {
+	return strings.ToUpper(code)
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/service/channel"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const digits = "0123456789"
+
+// 验证码长度的范围，和 web 层校验用户输入的 min=4,max=16 保持一致，超出范围的验证码用户永远输入不进来
+const (
+	codeMinLength = 4
+	codeMaxLength = 16
+)
+
+var ErrCodePolicyInvalid = errors.New("验证码策略不合法")
+
+// DefaultCodePolicy 和历史行为保持一致：6 位数字，10 分钟有效，1 分钟内不能重发，最多验证 3 次
+var DefaultCodePolicy = domain.CodePolicy{
+	TplId:          "1877556",
+	Length:         6,
+	Alphabet:       digits,
+	TTL:            10 * time.Minute,
+	ResendInterval: time.Minute,
+	MaxAttempts:    3,
+	DailyLimit:     10,
+	Channels:       []string{channel.SMS},
+}
+
+// CodePolicyRegistry 按业务（biz）登记验证码策略，没有登记的业务使用默认策略
+type CodePolicyRegistry struct {
+	mu       sync.RWMutex
+	def      domain.CodePolicy
+	policies map[string]domain.CodePolicy
+}
+
+func NewCodePolicyRegistry(def domain.CodePolicy) *CodePolicyRegistry {
+	return &CodePolicyRegistry{
+		def:      fillPolicy(def, DefaultCodePolicy),
+		policies: make(map[string]domain.CodePolicy),
+	}
+}
+
+// Register 登记某个业务的策略，没有设置的字段使用默认策略里的值
+// DailyLimit 例外：0 表示不限制，小于 0 才使用默认值
+func (r *CodePolicyRegistry) Register(biz string, p domain.CodePolicy) *CodePolicyRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies[biz] = fillPolicy(p, r.def)
+	return r
+}
+
+func (r *CodePolicyRegistry) Get(biz string) domain.CodePolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.policies[biz]; ok {
+		return p
+	}
+	return r.def
+}
+
+// Validate 检查所有策略（包括默认策略）的验证码长度在 4 到 16 之间，用到的投递渠道都已经注册，channels 是注册了的渠道名字
+// 策略里写了没有注册的渠道时，投递的时候会被跳过，用户以为能收到验证码实际上收不到
+func (r *CodePolicyRegistry) Validate(channels []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	check := func(biz string, p domain.CodePolicy) error {
+		if p.Length < codeMinLength || p.Length > codeMaxLength {
+			return fmt.Errorf("%w: 验证码策略 %q 的长度 %d 不在 %d 到 %d 之间", ErrCodePolicyInvalid, biz, p.Length, codeMinLength, codeMaxLength)
+		}
+		for _, name := range p.Channels {
+			if !slices.Contains(channels, name) {
+				return fmt.Errorf("%w: 验证码策略 %q 使用了没有注册的渠道 %q", ErrCodeChannelUnsupported, biz, name)
+			}
+		}
+		return nil
+	}
+	if err := check("default", r.def); err != nil {
+		return err
+	}
+	for biz, p := range r.policies {
+		if err := check(biz, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fillPolicy(p, def domain.CodePolicy) domain.CodePolicy {
+	if p.TplId == "" {
+		p.TplId = def.TplId
+	}
+	if p.Length <= 0 {
+		p.Length = def.Length
+	}
+	if p.Alphabet == "" {
+		p.Alphabet = def.Alphabet
+	}
+	if p.TTL <= 0 {
+		p.TTL = def.TTL
+	}
+	if p.ResendInterval <= 0 {
+		p.ResendInterval = def.ResendInterval
+	}
+	if p.ResendInterval > p.TTL {
+		p.ResendInterval = p.TTL
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.DailyLimit < 0 {
+		p.DailyLimit = def.DailyLimit
+	}
+	if len(p.Channels) == 0 {
+		p.Channels = def.Channels
+	}
+	return p
+}
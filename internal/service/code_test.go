@@ -4,11 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"bedrock/internal/domain"
 	"bedrock/internal/repository"
 	repoMocks "bedrock/internal/repository/mocks"
+	"bedrock/internal/service/channel"
+	emailmemory "bedrock/internal/service/email/memory"
 	smsMocks "bedrock/internal/service/sms/mocks"
+	voicememory "bedrock/internal/service/voice/memory"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -29,7 +35,7 @@ func TestCodeService_Send(t *testing.T) {
 				repo := repoMocks.NewMockCodeRepository(ctrl)
 				smsSvc := smsMocks.NewMockService(ctrl)
 
-				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any()).DoAndReturn(func(ctx context.Context, biz, phone, code string) error {
+				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any(), DefaultCodePolicy).DoAndReturn(func(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
 					assert.Len(t, code, 6)
 					smsSvc.EXPECT().Send(gomock.Any(), "1877556", []string{code}, "12345678901").Return(nil)
 					return nil
@@ -46,7 +52,7 @@ func TestCodeService_Send(t *testing.T) {
 				repo := repoMocks.NewMockCodeRepository(ctrl)
 				smsSvc := smsMocks.NewMockService(ctrl)
 
-				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any()).Return(errors.New("redis error"))
+				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any(), DefaultCodePolicy).Return(errors.New("redis error"))
 				return repo, smsSvc
 			},
 			biz:     "login",
@@ -59,7 +65,7 @@ func TestCodeService_Send(t *testing.T) {
 				repo := repoMocks.NewMockCodeRepository(ctrl)
 				smsSvc := smsMocks.NewMockService(ctrl)
 
-				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any()).DoAndReturn(func(ctx context.Context, biz, phone, code string) error {
+				repo.EXPECT().Set(gomock.Any(), "login", "12345678901", gomock.Any(), DefaultCodePolicy).DoAndReturn(func(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
 					smsSvc.EXPECT().Send(gomock.Any(), "1877556", []string{code}, "12345678901").Return(errors.New("sms error"))
 					return nil
 				})
@@ -67,7 +73,51 @@ func TestCodeService_Send(t *testing.T) {
 			},
 			biz:     "login",
 			phone:   "12345678901",
-			wantErr: errors.New("sms error"),
+			wantErr: errors.Join(fmt.Errorf("sms: %w", errors.New("sms error"))),
+		},
+		{
+			name: "lowercase alphabet",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, *smsMocks.MockService) {
+				repo := repoMocks.NewMockCodeRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+
+				repo.EXPECT().Set(gomock.Any(), "bind", "12345678901", gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
+					// 保存的是大写，投递出去的还是原样的
+					assert.Regexp(t, "^[XY]{4}$", code)
+					smsSvc.EXPECT().Send(gomock.Any(), "3000000", gomock.Any(), "12345678901").
+						DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+							assert.Equal(t, strings.ToLower(code), args[0])
+							return nil
+						})
+					return nil
+				})
+				return repo, smsSvc
+			},
+			biz:     "bind",
+			phone:   "12345678901",
+			wantErr: nil,
+		},
+		{
+			name: "custom policy",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, *smsMocks.MockService) {
+				repo := repoMocks.NewMockCodeRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+
+				repo.EXPECT().Set(gomock.Any(), "reset", "12345678901", gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, biz, phone, code string, policy domain.CodePolicy) error {
+					assert.Len(t, code, 8)
+					assert.Regexp(t, "^[AB]{8}$", code)
+					assert.Equal(t, 5*time.Minute, policy.TTL)
+					assert.Equal(t, 5, policy.MaxAttempts)
+					// 没有配置的字段使用默认值
+					assert.Equal(t, DefaultCodePolicy.ResendInterval, policy.ResendInterval)
+					smsSvc.EXPECT().Send(gomock.Any(), "2000000", []string{code}, "12345678901").Return(nil)
+					return nil
+				})
+				return repo, smsSvc
+			},
+			biz:     "reset",
+			phone:   "12345678901",
+			wantErr: nil,
 		},
 	}
 
@@ -79,9 +129,124 @@ func TestCodeService_Send(t *testing.T) {
 			defer ctrl.Finish()
 
 			repo, smsSvc := tc.mock(ctrl)
-			svc := NewCodeService(repo, smsSvc)
-			err := svc.Send(context.Background(), tc.biz, tc.phone)
+			policies := NewCodePolicyRegistry(DefaultCodePolicy).
+				Register("reset", domain.CodePolicy{
+					TplId:       "2000000",
+					Length:      8,
+					Alphabet:    "AB",
+					TTL:         5 * time.Minute,
+					MaxAttempts: 5,
+				}).
+				Register("bind", domain.CodePolicy{
+					TplId:    "3000000",
+					Length:   4,
+					Alphabet: "xy",
+				})
+			svc := NewCodeService(repo, []channel.Channel{channel.NewSMSChannel(smsSvc)}, policies)
+			err := svc.Send(context.Background(), tc.biz, tc.phone, "")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCodeService_SendFallback(t *testing.T) {
+	t.Parallel()
+	policies := NewCodePolicyRegistry(DefaultCodePolicy).
+		Register("login", domain.CodePolicy{
+			Channels: []string{channel.SMS, channel.Voice, channel.Email},
+		})
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder)
+		hint  string
+		phone string
+
+		wantErr   error
+		wantCalls int
+		wantMails int
+	}{
+		{
+			name:  "短信失败，改用语音",
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder) {
+				smsSvc := smsMocks.NewMockService(ctrl)
+				smsSvc.EXPECT().Send(gomock.Any(), "1877556", gomock.Any(), "+8612345678901").Return(errors.New("sms error"))
+				return smsSvc, repoMocks.NewMockUserRepository(ctrl)
+			},
+			wantCalls: 1,
+		},
+		{
+			name:  "指定邮箱优先",
+			hint:  channel.Email,
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder) {
+				users := repoMocks.NewMockUserRepository(ctrl)
+				users.EXPECT().FindByPhone(gomock.Any(), "+8612345678901").Return(domain.User{Email: "a@example.com"}, nil)
+				return smsMocks.NewMockService(ctrl), users
+			},
+			wantMails: 1,
+		},
+		{
+			name:  "没有邮箱，回到短信",
+			hint:  channel.Email,
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder) {
+				users := repoMocks.NewMockUserRepository(ctrl)
+				users.EXPECT().FindByPhone(gomock.Any(), "+8612345678901").Return(domain.User{}, nil)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				smsSvc.EXPECT().Send(gomock.Any(), "1877556", gomock.Any(), "+8612345678901").Return(nil)
+				return smsSvc, users
+			},
+		},
+		{
+			name:  "全部失败",
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder) {
+				voiceSvc.SetErr(errors.New("voice error"))
+				users := repoMocks.NewMockUserRepository(ctrl)
+				users.EXPECT().FindByPhone(gomock.Any(), "+8612345678901").Return(domain.User{}, repository.ErrUserNotFound)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				smsSvc.EXPECT().Send(gomock.Any(), "1877556", gomock.Any(), "+8612345678901").Return(errors.New("sms error"))
+				return smsSvc, users
+			},
+			wantErr: errors.Join(
+				fmt.Errorf("sms: %w", errors.New("sms error")),
+				fmt.Errorf("voice: %w", errors.New("voice error")),
+				fmt.Errorf("email: %w", fmt.Errorf("%w: %w", channel.ErrNoContact, repository.ErrUserNotFound)),
+			),
+		},
+		{
+			name:  "不支持的渠道",
+			hint:  "pigeon",
+			phone: "+8612345678901",
+			mock: func(ctrl *gomock.Controller, voiceSvc *voicememory.Service, emailSvc *emailmemory.Service) (*smsMocks.MockService, channel.UserFinder) {
+				return smsMocks.NewMockService(ctrl), repoMocks.NewMockUserRepository(ctrl)
+			},
+			wantErr: ErrCodeChannelUnsupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			voiceSvc, emailSvc := voicememory.NewService(), emailmemory.NewService()
+			smsSvc, users := tc.mock(ctrl, voiceSvc, emailSvc)
+			repo := repoMocks.NewMockCodeRepository(ctrl)
+			repo.EXPECT().Set(gomock.Any(), "login", tc.phone, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			svc := NewCodeService(repo, []channel.Channel{
+				channel.NewSMSChannel(smsSvc),
+				channel.NewVoiceChannel(voiceSvc),
+				channel.NewEmailChannel(emailSvc, users),
+			}, policies)
+			err := svc.Send(context.Background(), "login", tc.phone, tc.hint)
 			assert.Equal(t, tc.wantErr, err)
+			assert.Len(t, voiceSvc.Calls(), tc.wantCalls)
+			assert.Len(t, emailSvc.Mails(), tc.wantMails)
 		})
 	}
 }
@@ -110,6 +275,20 @@ func TestCodeService_Verify(t *testing.T) {
 			wantOk:    true,
 			wantErr:   nil,
 		},
+		{
+			// 字母验证码生成的是大写，用户输入小写也能通过
+			name: "lowercase input",
+			mock: func(ctrl *gomock.Controller) repository.CodeRepository {
+				repo := repoMocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login", "12345678901", "AB23CD").Return(true, nil)
+				return repo
+			},
+			biz:       "login",
+			phone:     "12345678901",
+			inputCode: "ab23Cd",
+			wantOk:    true,
+			wantErr:   nil,
+		},
 		{
 			name: "verify failed",
 			mock: func(ctrl *gomock.Controller) repository.CodeRepository {
@@ -174,7 +353,7 @@ func TestCodeService_Verify(t *testing.T) {
 			repo := tc.mock(ctrl)
 			// Mock SMS service is not needed for Verify
 			smsSvc := smsMocks.NewMockService(ctrl)
-			svc := NewCodeService(repo, smsSvc)
+			svc := NewCodeService(repo, []channel.Channel{channel.NewSMSChannel(smsSvc)}, NewCodePolicyRegistry(DefaultCodePolicy))
 			ok, err := svc.Verify(context.Background(), tc.biz, tc.phone, tc.inputCode)
 			assert.Equal(t, tc.wantErr, err)
 			assert.Equal(t, tc.wantOk, ok)
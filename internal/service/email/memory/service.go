@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"bedrock/internal/service/email"
+	"context"
+	"sync"
+)
+
+var _ email.Service = &Service{}
+
+// Mail 一封"发出去"的邮件
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Service 内存版的邮件服务，用于开发和测试，可以注入错误来模拟发送失败
+type Service struct {
+	mu    sync.Mutex
+	err   error
+	mails []Mail
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// SetErr 之后的发送都返回 err，传 nil 恢复正常
+func (s *Service) SetErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *Service) Send(ctx context.Context, to, subject, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.mails = append(s.mails, Mail{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// Mails 返回所有发送成功的邮件
+func (s *Service) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mails...)
+}
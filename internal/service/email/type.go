@@ -0,0 +1,10 @@
+package email
+
+import "context"
+
+// Service 邮件发送服务
+//
+//go:generate mockgen -source=./type.go -package=mocks -destination=./mocks/email_mock.go Service
+type Service interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFileNotFound  = repository.ErrFileNotFound
+	ErrQuotaExceeded = repository.ErrQuotaExceeded
+	ErrFileInUse     = repository.ErrFileInUse
+	ErrFileTooLarge  = errors.New("文件太大")
+	ErrFileForbidden = errors.New("没有权限访问文件")
+	ErrFileRejected  = errors.New("文件没有通过审核")
+)
+
+const (
+	// filePrefix 文件按照可见性分成两个目录，方便在 bucket 上只给 files/public/ 配置公共读
+	// files/private/ 下的对象写入时都指定 ACLPrivate，bucket 配置错了也不会公开
+	filePublicPrefix  = "files/public/"
+	filePrivatePrefix = "files/private/"
+	// fileTmpPrefix 上传过程中先写到这里，算出 SHA-256 之后再复制到隔离区
+	fileTmpPrefix = "tmp/files/"
+	// fileQuarantinePrefix 审核通过之前放在这里，通过之后再复制到正式的 key
+	// 临时文件和隔离区里的都是没有审核过的内容，写入时都指定 ACLPrivate，不依赖 bucket 或者目录的配置
+	fileQuarantinePrefix = "quarantine/files/"
+)
+
+// FileConfig 文件服务的配置
+type FileConfig struct {
+	// MaxSize 单个文件的大小上限（字节）
+	MaxSize int64 `mapstructure:"max_size"`
+	// Quota 每个用户的存储配额（字节），< 0 表示不限制
+	Quota int64 `mapstructure:"quota"`
+	// URLExpire 私有文件签名链接的有效期
+	URLExpire time.Duration `mapstructure:"url_expire"`
+}
+
+//go:generate mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go FileService
+type FileService interface {
+	// Upload 保存文件并占用配额，同一个用户重复上传相同的内容（并且可见性相同）时直接返回之前的文件
+	// 不同用户上传相同的内容共用存储里的同一个对象，但是各自占用配额
+	// 文件先放进隔离区提交审核，没有通过时返回 ErrFileRejected，等待审核时返回的文件状态是隔离中
+	// 新上传的文件没有被引用，需要由引用它的实体调用 Attach，否则会被 CleanOrphans 清理
+	Upload(ctx context.Context, ownerID int64, name string, r io.Reader, public bool) (domain.File, error)
+	// Get 查询文件，私有文件和没有审核通过的文件只有所有者可以查询，其他人返回 ErrFileForbidden
+	Get(ctx context.Context, viewerID, id int64) (domain.File, error)
+	// URL 根据可见性返回访问地址：审核通过的公开文件是 Provider.URL，其余是 GetPrivateURL 签名的临时链接
+	// 私有文件和隔离中的文件只有所有者可以访问，其他人返回 ErrFileForbidden，没有通过审核的文件返回 ErrFileRejected
+	URL(ctx context.Context, viewerID int64, f domain.File) (string, error)
+	// SignedURL 不检查权限，直接返回签名的临时链接，没有通过审核的文件返回 ErrFileRejected
+	// 用于引用了文件的实体自己决定访问权限的场景，例如帖子的附件跟随帖子的可见性
+	SignedURL(ctx context.Context, f domain.File) (string, error)
+	// Attach ref 引用文件，只能引用自己的文件，同一个实体重复引用只算一次
+	Attach(ctx context.Context, ownerID, id int64, ref domain.FileRef) (domain.File, error)
+	// Detach ref 取消引用，只能操作自己的文件，引用全部取消之后文件会被 CleanOrphans 清理
+	Detach(ctx context.Context, ownerID, id int64, ref domain.FileRef) error
+	// Delete 删除没有被引用的文件，被引用时返回 ErrFileInUse
+	Delete(ctx context.Context, ownerID, id int64) error
+	Usage(ctx context.Context, ownerID int64) (domain.FileUsage, error)
+	// CleanOrphans 删除 before 之前上传或者取消引用、现在没有被引用的文件，以及上传失败残留的临时文件
+	CleanOrphans(ctx context.Context, before time.Time) (int, error)
+}
+
+type DefaultFileService struct {
+	repo          repository.FileRepository
+	storage       storage.Provider
+	moderationSvc ModerationService
+	l             logger.Logger
+	cfg           FileConfig
+}
+
+var _ ModerationHandler = &DefaultFileService{}
+
+// NewFileService 创建文件服务，同时注册为文件审核的处理器
+func NewFileService(repo repository.FileRepository, storageSvc storage.Provider, moderationSvc ModerationService, l logger.Logger, cfg FileConfig) FileService {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100 << 20
+	}
+	if cfg.Quota == 0 {
+		cfg.Quota = 1 << 30
+	}
+	if cfg.URLExpire <= 0 {
+		cfg.URLExpire = 15 * time.Minute
+	}
+	svc := &DefaultFileService{
+		repo:          repo,
+		storage:       storageSvc,
+		moderationSvc: moderationSvc,
+		l:             l,
+		cfg:           cfg,
+	}
+	moderationSvc.Register(domain.ModerationBizFile, svc)
+	return svc
+}
+
+func (svc *DefaultFileService) Upload(ctx context.Context, ownerID int64, name string, r io.Reader, public bool) (domain.File, error) {
+	// 已经用完配额的就不用上传了，没用完的要等知道大小之后才能判断
+	if svc.cfg.Quota > 0 {
+		used, err := svc.repo.GetUsage(ctx, ownerID)
+		if err != nil {
+			return domain.File{}, err
+		}
+		if used >= svc.cfg.Quota {
+			return domain.File{}, ErrQuotaExceeded
+		}
+	}
+
+	// 边上传边计算 SHA-256，多读一个字节用来判断是否超过了上限
+	h := sha256.New()
+	tmpKey := fileTmpPrefix + uuid.New().String()
+	_, err := svc.storage.Upload(ctx, tmpKey, io.TeeReader(io.LimitReader(r, svc.cfg.MaxSize+1), h), -1, storage.UploadOptions{
+		ACL: storage.ACLPrivate,
+	})
+	if err != nil {
+		return domain.File{}, err
+	}
+	defer svc.discard(ctx, tmpKey)
+	info, err := svc.storage.Stat(ctx, tmpKey)
+	if err != nil {
+		return domain.File{}, err
+	}
+	if info.Size > svc.cfg.MaxSize {
+		return domain.File{}, ErrFileTooLarge
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	existing, err := svc.repo.FindByHash(ctx, ownerID, hash, public)
+	switch {
+	case err == nil:
+		return existing, nil
+	case !errors.Is(err, ErrFileNotFound):
+		return domain.File{}, err
+	}
+	f, err := svc.repo.Create(ctx, domain.File{
+		OwnerID:  ownerID,
+		Name:     fileName(name),
+		Key:      fileQuarantineKey(hash),
+		Size:     info.Size,
+		Hash:     hash,
+		MimeType: info.ContentType,
+		Public:   public,
+		Status:   domain.ModerationStatusQuarantined,
+	}, svc.cfg.Quota)
+	switch {
+	case errors.Is(err, repository.ErrDuplicateFile):
+		// 并发上传了同样的内容
+		return svc.repo.FindByHash(ctx, ownerID, hash, public)
+	case err != nil:
+		return domain.File{}, err
+	}
+
+	// 先插入记录再检查对象是否存在：删除文件时先删记录再检查还有没有人在用，
+	// 这样并发的上传和删除里至少有一方能看到对方
+	ok, err := svc.storage.Exists(ctx, f.Key)
+	if err == nil && !ok {
+		err = svc.storage.Copy(ctx, tmpKey, f.Key, storage.CopyOptions{ACL: storage.ACLPrivate})
+	}
+	if err != nil {
+		if _, derr := svc.repo.Delete(ctx, f.ID); derr != nil {
+			svc.l.Error(ctx, "回滚文件记录失败", logger.Error(derr), logger.Int64("id", f.ID))
+		}
+		return domain.File{}, err
+	}
+
+	m, err := svc.moderationSvc.Submit(ctx, domain.Moderation{
+		Biz:   domain.ModerationBizFile,
+		BizID: f.ID,
+		Uid:   ownerID,
+		Key:   f.Key,
+	})
+	if err != nil {
+		svc.rollback(ctx, f)
+		return domain.File{}, err
+	}
+	switch m.Status {
+	case domain.ModerationStatusApproved:
+		// 审核通过时 OnApproved 已经把文件挪到了正式的 key
+		return svc.repo.FindById(ctx, f.ID)
+	case domain.ModerationStatusRejected:
+		return domain.File{}, ErrFileRejected
+	default:
+		return f, nil
+	}
+}
+
+// OnApproved 把隔离区里的对象复制到正式的 key
+func (svc *DefaultFileService) OnApproved(ctx context.Context, m domain.Moderation) error {
+	f, err := svc.repo.FindById(ctx, m.BizID)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		// 审核期间被删掉了
+		return nil
+	case err != nil:
+		return err
+	case f.Status == domain.ModerationStatusApproved:
+		return nil
+	}
+	key := fileKey(f.Hash, f.Public)
+	// 私有文件显式指定 ACLPrivate，不依赖 files/private/ 在 bucket 或者 private_prefixes 上的配置
+	opts := storage.CopyOptions{}
+	if !f.Public {
+		opts.ACL = storage.ACLPrivate
+	}
+	ok, err := svc.storage.Exists(ctx, key)
+	if err == nil && !ok {
+		err = svc.storage.Copy(ctx, f.Key, key, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if err = svc.repo.UpdateStatus(ctx, f.ID, key, domain.ModerationStatusApproved); err != nil {
+		return err
+	}
+	svc.release(ctx, f.Key)
+	return nil
+}
+
+// OnRejected 删除文件，已经被引用的文件保留记录，但是不能再访问，取消引用之后由 CleanOrphans 删除
+func (svc *DefaultFileService) OnRejected(ctx context.Context, m domain.Moderation) error {
+	f, err := svc.repo.FindById(ctx, m.BizID)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+	if err = svc.repo.UpdateStatus(ctx, f.ID, f.Key, domain.ModerationStatusRejected); err != nil {
+		return err
+	}
+	if _, err = svc.repo.Delete(ctx, f.ID); err != nil {
+		if errors.Is(err, ErrFileInUse) {
+			return nil
+		}
+		return err
+	}
+	svc.release(ctx, f.Key)
+	return nil
+}
+
+func (svc *DefaultFileService) Get(ctx context.Context, viewerID, id int64) (domain.File, error) {
+	f, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.File{}, err
+	}
+	if !svc.visible(f) && f.OwnerID != viewerID {
+		return domain.File{}, ErrFileForbidden
+	}
+	return f, nil
+}
+
+func (svc *DefaultFileService) URL(ctx context.Context, viewerID int64, f domain.File) (string, error) {
+	if f.Status == domain.ModerationStatusRejected {
+		return "", ErrFileRejected
+	}
+	if svc.visible(f) {
+		return svc.storage.URL(f.Key), nil
+	}
+	if f.OwnerID != viewerID {
+		return "", ErrFileForbidden
+	}
+	return svc.SignedURL(ctx, f)
+}
+
+func (svc *DefaultFileService) SignedURL(ctx context.Context, f domain.File) (string, error) {
+	if f.Status == domain.ModerationStatusRejected {
+		return "", ErrFileRejected
+	}
+	return svc.storage.GetPrivateURL(ctx, f.Key, int64(svc.cfg.URLExpire.Seconds()))
+}
+
+func (svc *DefaultFileService) Attach(ctx context.Context, ownerID, id int64, ref domain.FileRef) (domain.File, error) {
+	f, err := svc.owned(ctx, ownerID, id)
+	if err != nil {
+		return domain.File{}, err
+	}
+	if f.Status == domain.ModerationStatusRejected {
+		return domain.File{}, ErrFileRejected
+	}
+	if err = svc.repo.AddRef(ctx, id, ref); err != nil {
+		return domain.File{}, err
+	}
+	return svc.repo.FindById(ctx, id)
+}
+
+func (svc *DefaultFileService) Detach(ctx context.Context, ownerID, id int64, ref domain.FileRef) error {
+	if _, err := svc.owned(ctx, ownerID, id); err != nil {
+		return err
+	}
+	return svc.repo.RemoveRef(ctx, id, ref)
+}
+
+func (svc *DefaultFileService) Delete(ctx context.Context, ownerID, id int64) error {
+	if _, err := svc.owned(ctx, ownerID, id); err != nil {
+		return err
+	}
+	f, err := svc.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	svc.release(ctx, f.Key)
+	return nil
+}
+
+func (svc *DefaultFileService) Usage(ctx context.Context, ownerID int64) (domain.FileUsage, error) {
+	used, err := svc.repo.GetUsage(ctx, ownerID)
+	return domain.FileUsage{Used: used, Quota: max(svc.cfg.Quota, 0)}, err
+}
+
+func (svc *DefaultFileService) CleanOrphans(ctx context.Context, before time.Time) (int, error) {
+	const batch = 100
+	var (
+		deleted int
+		afterID int64
+	)
+	for {
+		files, err := svc.repo.FindUnreferenced(ctx, before, afterID, batch)
+		if err != nil {
+			return deleted, err
+		}
+		for _, f := range files {
+			afterID = f.ID
+			if _, err = svc.repo.Delete(ctx, f.ID); err != nil {
+				// 查出来之后又被引用了
+				if !errors.Is(err, ErrFileInUse) {
+					svc.l.Warn(ctx, "删除孤儿文件失败", logger.Error(err), logger.Int64("id", f.ID))
+				}
+				continue
+			}
+			svc.release(ctx, f.Key)
+			deleted++
+		}
+		if len(files) < batch {
+			break
+		}
+	}
+	// 上传过程中进程退出会留下临时文件
+	err := svc.cleanTmp(ctx, before)
+	return deleted, err
+}
+
+func (svc *DefaultFileService) cleanTmp(ctx context.Context, before time.Time) error {
+	opts := storage.ListOptions{Prefix: fileTmpPrefix}
+	for {
+		res, err := svc.storage.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, obj := range res.Objects {
+			if obj.LastModified.Before(before) {
+				svc.discard(ctx, obj.Key)
+			}
+		}
+		if !res.Truncated {
+			return nil
+		}
+		opts.Marker = res.NextMarker
+	}
+}
+
+// owned 查询 ownerID 自己的文件，别人的文件返回 ErrFileForbidden
+func (svc *DefaultFileService) owned(ctx context.Context, ownerID, id int64) (domain.File, error) {
+	f, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.File{}, err
+	}
+	if f.OwnerID != ownerID {
+		return domain.File{}, ErrFileForbidden
+	}
+	return f, nil
+}
+
+// visible 审核通过的公开文件所有人都可以访问
+func (svc *DefaultFileService) visible(f domain.File) bool {
+	return f.Public && f.Status == domain.ModerationStatusApproved
+}
+
+// rollback 删除刚刚创建的文件记录
+func (svc *DefaultFileService) rollback(ctx context.Context, f domain.File) {
+	if _, err := svc.repo.Delete(ctx, f.ID); err != nil {
+		svc.l.Error(ctx, "回滚文件记录失败", logger.Error(err), logger.Int64("id", f.ID))
+		return
+	}
+	svc.release(ctx, f.Key)
+}
+
+// release 没有文件再使用这个对象时从存储里删除
+func (svc *DefaultFileService) release(ctx context.Context, key string) {
+	cnt, err := svc.repo.CountByKey(ctx, key)
+	if err != nil {
+		svc.l.Warn(ctx, "查询文件引用失败", logger.Error(err), logger.String("key", key))
+		return
+	}
+	if cnt == 0 {
+		svc.discard(ctx, key)
+	}
+}
+
+func (svc *DefaultFileService) discard(ctx context.Context, key string) {
+	if err := svc.storage.Delete(ctx, key); err != nil {
+		svc.l.Warn(ctx, "删除存储文件失败", logger.Error(err), logger.String("key", key))
+	}
+}
+
+// fileKey 内容寻址的 key，用哈希的前两位分散目录，避免单个目录下文件太多
+func fileKey(hash string, public bool) string {
+	prefix := filePrivatePrefix
+	if public {
+		prefix = filePublicPrefix
+	}
+	return prefix + hash[:2] + "/" + hash
+}
+
+// fileQuarantineKey 隔离区里的 key，内容相同的文件同样共用一个对象
+func fileQuarantineKey(hash string) string {
+	return fileQuarantinePrefix + hash[:2] + "/" + hash
+}
+
+// fileName 去掉路径，只保留文件名
+func fileName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
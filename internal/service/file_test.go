@@ -0,0 +1,613 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	repomocks "bedrock/internal/repository/mocks"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/local"
+	"bedrock/pkg/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFileService_Upload(t *testing.T) {
+	t.Parallel()
+	const content = "hello, world"
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	privateKey := "files/private/" + hash[:2] + "/" + hash
+	quarantineKey := "quarantine/files/" + hash[:2] + "/" + hash
+	dbErr := errors.New("db error")
+	// expectCreate 创建记录之后的读写都在 saved 上进行，模拟审核过程中对记录的修改
+	expectCreate := func(repo *repomocks.MockFileRepository, quota any) *domain.File {
+		saved := &domain.File{}
+		repo.EXPECT().Create(gomock.Any(), gomock.Any(), quota).
+			DoAndReturn(func(_ context.Context, f domain.File, _ int64) (domain.File, error) {
+				assert.Equal(t, quarantineKey, f.Key)
+				assert.Equal(t, domain.ModerationStatusQuarantined, f.Status)
+				f.ID = 3
+				*saved = f
+				return f, nil
+			})
+		repo.EXPECT().FindById(gomock.Any(), int64(3)).
+			DoAndReturn(func(context.Context, int64) (domain.File, error) {
+				return *saved, nil
+			}).AnyTimes()
+		repo.EXPECT().UpdateStatus(gomock.Any(), int64(3), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ int64, key string, status domain.ModerationStatus) error {
+				saved.Key = key
+				saved.Status = status
+				return nil
+			}).AnyTimes()
+		// 隔离区的对象没有别人在用
+		repo.EXPECT().CountByKey(gomock.Any(), quarantineKey).Return(int64(0), nil).AnyTimes()
+		return saved
+	}
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.FileRepository
+		// keys 上传之前存储里已经有的对象
+		keys    []string
+		cfg     FileConfig
+		public  bool
+		verdict scanner.Verdict
+
+		wantFile domain.File
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name: "新文件",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, repository.ErrFileNotFound)
+				expectCreate(repo, int64(1024))
+				return repo
+			},
+			cfg: FileConfig{Quota: 1024},
+			wantFile: domain.File{
+				ID:       3,
+				OwnerID:  1,
+				Name:     "hello.txt",
+				Key:      privateKey,
+				Size:     int64(len(content)),
+				Hash:     hash,
+				MimeType: "text/plain; charset=utf-8",
+				Status:   domain.ModerationStatusApproved,
+			},
+			wantKeys: []string{privateKey},
+		},
+		{
+			name: "公开文件",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, true).Return(domain.File{}, repository.ErrFileNotFound)
+				expectCreate(repo, gomock.Any())
+				return repo
+			},
+			public: true,
+			wantFile: domain.File{
+				ID:       3,
+				OwnerID:  1,
+				Name:     "hello.txt",
+				Key:      "files/public/" + hash[:2] + "/" + hash,
+				Size:     int64(len(content)),
+				Hash:     hash,
+				MimeType: "text/plain; charset=utf-8",
+				Public:   true,
+				Status:   domain.ModerationStatusApproved,
+			},
+			wantKeys: []string{"files/public/" + hash[:2] + "/" + hash},
+		},
+		{
+			name: "自己上传过相同的内容",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{ID: 2, Key: privateKey}, nil)
+				return repo
+			},
+			keys:     []string{privateKey},
+			wantFile: domain.File{ID: 2, Key: privateKey},
+			wantKeys: []string{privateKey},
+		},
+		{
+			name: "别人上传过相同的内容",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, repository.ErrFileNotFound)
+				expectCreate(repo, gomock.Any())
+				return repo
+			},
+			keys: []string{privateKey},
+			wantFile: domain.File{
+				ID:       3,
+				OwnerID:  1,
+				Name:     "hello.txt",
+				Key:      privateKey,
+				Size:     int64(len(content)),
+				Hash:     hash,
+				MimeType: "text/plain; charset=utf-8",
+				Status:   domain.ModerationStatusApproved,
+			},
+			wantKeys: []string{privateKey},
+		},
+		{
+			name: "等待人工审核",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, true).Return(domain.File{}, repository.ErrFileNotFound)
+				expectCreate(repo, gomock.Any())
+				return repo
+			},
+			public:  true,
+			verdict: scanner.VerdictReview,
+			wantFile: domain.File{
+				ID:       3,
+				OwnerID:  1,
+				Name:     "hello.txt",
+				Key:      quarantineKey,
+				Size:     int64(len(content)),
+				Hash:     hash,
+				MimeType: "text/plain; charset=utf-8",
+				Public:   true,
+				Status:   domain.ModerationStatusQuarantined,
+			},
+			// 隔离区的对象不能公开访问
+			wantKeys: []string{quarantineKey},
+		},
+		{
+			name: "审核不通过",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, repository.ErrFileNotFound)
+				saved := expectCreate(repo, gomock.Any())
+				repo.EXPECT().Delete(gomock.Any(), int64(3)).
+					DoAndReturn(func(context.Context, int64) (domain.File, error) {
+						assert.Equal(t, domain.ModerationStatusRejected, saved.Status)
+						return *saved, nil
+					})
+				return repo
+			},
+			verdict: scanner.VerdictReject,
+			wantErr: ErrFileRejected,
+		},
+		{
+			name: "并发上传了相同的内容",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, repository.ErrFileNotFound)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.File{}, repository.ErrDuplicateFile)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{ID: 2, Key: privateKey}, nil)
+				return repo
+			},
+			wantFile: domain.File{ID: 2, Key: privateKey},
+		},
+		{
+			name: "配额已经用完",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(1024), nil)
+				return repo
+			},
+			cfg:     FileConfig{Quota: 1024},
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "超过配额",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(1020), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, repository.ErrFileNotFound)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any(), int64(1024)).Return(domain.File{}, repository.ErrQuotaExceeded)
+				return repo
+			},
+			cfg:     FileConfig{Quota: 1024},
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "不限制配额",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{ID: 2, Key: privateKey}, nil)
+				return repo
+			},
+			keys:     []string{privateKey},
+			cfg:      FileConfig{Quota: -1},
+			wantFile: domain.File{ID: 2, Key: privateKey},
+			wantKeys: []string{privateKey},
+		},
+		{
+			name: "文件太大",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				return repo
+			},
+			cfg:     FileConfig{MaxSize: int64(len(content) - 1)},
+			wantErr: ErrFileTooLarge,
+		},
+		{
+			name: "保存记录失败",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+				repo.EXPECT().FindByHash(gomock.Any(), int64(1), hash, false).Return(domain.File{}, dbErr)
+				return repo
+			},
+			wantErr: dbErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			for _, key := range tc.keys {
+				_, err := store.Upload(context.Background(), key, strings.NewReader(content), int64(len(content)), storage.UploadOptions{})
+				require.NoError(t, err)
+			}
+			svc := NewFileService(tc.mock(ctrl), store, newModerationService(ctrl, store, tc.verdict), logger.NewNopLogger(), tc.cfg)
+			f, err := svc.Upload(context.Background(), 1, "../docs/hello.txt", strings.NewReader(content), tc.public)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantFile, f)
+			// 临时文件都要删掉
+			assert.ElementsMatch(t, tc.wantKeys, listKeys(t, store))
+		})
+	}
+}
+
+// TestFileService_UploadACL 没有审核通过的文件和私有文件不依赖 private_prefixes 的配置，没有签名不能下载
+func TestFileService_UploadACL(t *testing.T) {
+	t.Parallel()
+	const content = "hello, world"
+	testCases := []struct {
+		name    string
+		public  bool
+		verdict scanner.Verdict
+
+		wantPrefix string
+	}{
+		{
+			name:       "公开文件等待审核",
+			public:     true,
+			verdict:    scanner.VerdictReview,
+			wantPrefix: "quarantine/files/",
+		},
+		{
+			name:       "私有文件等待审核",
+			verdict:    scanner.VerdictReview,
+			wantPrefix: "quarantine/files/",
+		},
+		{
+			name:       "审核通过的私有文件",
+			verdict:    scanner.VerdictClean,
+			wantPrefix: "files/private/",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := repomocks.NewMockFileRepository(ctrl)
+			repo.EXPECT().GetUsage(gomock.Any(), int64(1)).Return(int64(0), nil)
+			repo.EXPECT().FindByHash(gomock.Any(), int64(1), gomock.Any(), tc.public).Return(domain.File{}, repository.ErrFileNotFound)
+			saved := domain.File{}
+			repo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, f domain.File, _ int64) (domain.File, error) {
+					f.ID = 3
+					saved = f
+					return f, nil
+				})
+			repo.EXPECT().FindById(gomock.Any(), int64(3)).
+				DoAndReturn(func(context.Context, int64) (domain.File, error) {
+					return saved, nil
+				}).AnyTimes()
+			repo.EXPECT().UpdateStatus(gomock.Any(), int64(3), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ int64, key string, status domain.ModerationStatus) error {
+					saved.Key = key
+					saved.Status = status
+					return nil
+				}).AnyTimes()
+			repo.EXPECT().CountByKey(gomock.Any(), gomock.Any()).Return(int64(0), nil).AnyTimes()
+
+			store := local.NewProvider(local.Config{RootPath: t.TempDir(), BaseURL: "http://localhost/static", Secret: "secret"})
+			svc := NewFileService(repo, store, newModerationService(ctrl, store, tc.verdict), logger.NewNopLogger(), FileConfig{})
+			f, err := svc.Upload(context.Background(), 1, "hello.txt", strings.NewReader(content), tc.public)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(f.Key, tc.wantPrefix))
+			// 临时文件已经删掉了，只剩下 f.Key
+			assert.Equal(t, []string{f.Key}, listKeys(t, store))
+
+			verifier := store.(storage.DownloadVerifier)
+			assert.Equal(t, storage.ErrInvalidSignature, verifier.VerifyDownload(f.Key, url.Values{}))
+			signed, err := svc.SignedURL(context.Background(), f)
+			require.NoError(t, err)
+			u, err := url.Parse(signed)
+			require.NoError(t, err)
+			assert.NoError(t, verifier.VerifyDownload(f.Key, u.Query()))
+		})
+	}
+}
+
+func TestFileService_URL(t *testing.T) {
+	t.Parallel()
+	store := memory.NewProvider("https://cdn.example.com")
+	svc := NewFileService(nil, store, NewModerationService(nil, store, nil, logger.NewNopLogger(), ModerationConfig{}), logger.NewNopLogger(), FileConfig{})
+	public := domain.File{OwnerID: 1, Key: "files/public/ab/abc", Public: true, Status: domain.ModerationStatusApproved}
+	private := domain.File{OwnerID: 1, Key: "files/private/ab/abc", Status: domain.ModerationStatusApproved}
+	quarantined := domain.File{OwnerID: 1, Key: "quarantine/files/ab/abc", Public: true, Status: domain.ModerationStatusQuarantined}
+	rejected := domain.File{OwnerID: 1, Key: "quarantine/files/ab/abc", Public: true, Status: domain.ModerationStatusRejected}
+
+	url, err := svc.URL(context.Background(), 2, public)
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/files/public/ab/abc", url)
+
+	url, err = svc.URL(context.Background(), 1, private)
+	require.NoError(t, err)
+	assert.Regexp(t, `^https://cdn\.example\.com/files/private/ab/abc\?expires=\d+$`, url)
+
+	_, err = svc.URL(context.Background(), 2, private)
+	assert.ErrorIs(t, err, ErrFileForbidden)
+
+	// 审核通过之前公开文件也只有所有者能访问
+	url, err = svc.URL(context.Background(), 1, quarantined)
+	require.NoError(t, err)
+	assert.Regexp(t, `^https://cdn\.example\.com/quarantine/files/ab/abc\?expires=\d+$`, url)
+	_, err = svc.URL(context.Background(), 2, quarantined)
+	assert.ErrorIs(t, err, ErrFileForbidden)
+
+	_, err = svc.URL(context.Background(), 1, rejected)
+	assert.ErrorIs(t, err, ErrFileRejected)
+}
+
+func TestFileService_Delete(t *testing.T) {
+	t.Parallel()
+	const key = "files/private/ab/abc"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.FileRepository
+
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name: "删除最后一个使用者",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Key: key}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Key: key}, nil)
+				repo.EXPECT().CountByKey(gomock.Any(), key).Return(int64(0), nil)
+				return repo
+			},
+			wantKeys: []string{},
+		},
+		{
+			name: "还有别人在使用",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Key: key}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Key: key}, nil)
+				repo.EXPECT().CountByKey(gomock.Any(), key).Return(int64(1), nil)
+				return repo
+			},
+			wantKeys: []string{key},
+		},
+		{
+			name: "不是自己的文件",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 2, Key: key}, nil)
+				return repo
+			},
+			wantKeys: []string{key},
+			wantErr:  ErrFileForbidden,
+		},
+		{
+			name: "正在被引用",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Key: key, Refs: 1}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(3)).Return(domain.File{}, repository.ErrFileInUse)
+				return repo
+			},
+			wantKeys: []string{key},
+			wantErr:  ErrFileInUse,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			_, err := store.Upload(context.Background(), key, strings.NewReader("x"), 1, storage.UploadOptions{})
+			require.NoError(t, err)
+			svc := NewFileService(tc.mock(ctrl), store, NewModerationService(nil, store, nil, logger.NewNopLogger(), ModerationConfig{}), logger.NewNopLogger(), FileConfig{})
+			err = svc.Delete(context.Background(), 1, 3)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantKeys, listKeys(t, store))
+		})
+	}
+}
+
+func TestFileService_Attach(t *testing.T) {
+	t.Parallel()
+	ref := domain.FileRef{Biz: "post", BizID: 7}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.FileRepository
+
+		wantFile domain.File
+		wantErr  error
+	}{
+		{
+			name: "引用成功",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1}, nil)
+				repo.EXPECT().AddRef(gomock.Any(), int64(3), ref).Return(nil)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Refs: 1}, nil)
+				return repo
+			},
+			wantFile: domain.File{ID: 3, OwnerID: 1, Refs: 1},
+		},
+		{
+			name: "不是自己的文件",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 2}, nil)
+				return repo
+			},
+			wantErr: ErrFileForbidden,
+		},
+		{
+			name: "没有通过审核",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).
+					Return(domain.File{ID: 3, OwnerID: 1, Status: domain.ModerationStatusRejected}, nil)
+				return repo
+			},
+			wantErr: ErrFileRejected,
+		},
+		{
+			name: "文件不存在",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{}, repository.ErrFileNotFound)
+				return repo
+			},
+			wantErr: ErrFileNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			svc := NewFileService(tc.mock(ctrl), store, NewModerationService(nil, store, nil, logger.NewNopLogger(), ModerationConfig{}), logger.NewNopLogger(), FileConfig{})
+			f, err := svc.Attach(context.Background(), 1, 3, ref)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantFile, f)
+		})
+	}
+}
+
+func TestFileService_Detach(t *testing.T) {
+	t.Parallel()
+	ref := domain.FileRef{Biz: "post", BizID: 7}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.FileRepository
+
+		wantErr error
+	}{
+		{
+			name: "取消引用",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 1, Refs: 1}, nil)
+				repo.EXPECT().RemoveRef(gomock.Any(), int64(3), ref).Return(nil)
+				return repo
+			},
+		},
+		{
+			// 别人不能取消引用，否则可以让文件被 CleanOrphans 删掉
+			name: "不是自己的文件",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{ID: 3, OwnerID: 2, Refs: 1}, nil)
+				return repo
+			},
+			wantErr: ErrFileForbidden,
+		},
+		{
+			name: "文件不存在",
+			mock: func(ctrl *gomock.Controller) repository.FileRepository {
+				repo := repomocks.NewMockFileRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.File{}, repository.ErrFileNotFound)
+				return repo
+			},
+			wantErr: ErrFileNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			svc := NewFileService(tc.mock(ctrl), store, NewModerationService(nil, store, nil, logger.NewNopLogger(), ModerationConfig{}), logger.NewNopLogger(), FileConfig{})
+			err := svc.Detach(context.Background(), 1, 3, ref)
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestFileService_CleanOrphans(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := memory.NewProvider("https://cdn.example.com")
+	for _, key := range []string{"files/private/aa/a", "files/private/bb/b", "tmp/files/c"} {
+		_, err := store.Upload(context.Background(), key, strings.NewReader("x"), 1, storage.UploadOptions{})
+		require.NoError(t, err)
+	}
+	before := time.Now().Add(time.Hour)
+	repo := repomocks.NewMockFileRepository(ctrl)
+	repo.EXPECT().FindUnreferenced(gomock.Any(), before, int64(0), 100).Return([]domain.File{
+		{ID: 1, Key: "files/private/aa/a"},
+		{ID: 2, Key: "files/private/bb/b"},
+	}, nil)
+	repo.EXPECT().Delete(gomock.Any(), int64(1)).Return(domain.File{ID: 1, Key: "files/private/aa/a"}, nil)
+	repo.EXPECT().CountByKey(gomock.Any(), "files/private/aa/a").Return(int64(0), nil)
+	// 查出来之后又被引用了
+	repo.EXPECT().Delete(gomock.Any(), int64(2)).Return(domain.File{}, repository.ErrFileInUse)
+
+	svc := NewFileService(repo, store, NewModerationService(nil, store, nil, logger.NewNopLogger(), ModerationConfig{}), logger.NewNopLogger(), FileConfig{})
+	deleted, err := svc.CleanOrphans(context.Background(), before)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, []string{"files/private/bb/b"}, listKeys(t, store))
+}
@@ -0,0 +1,74 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./avatar.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./avatar.go -package=mocks -destination=./mocks/avatar_mock.go AvatarService
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "bedrock/internal/domain"
+	context "context"
+	io "io"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAvatarService is a mock of AvatarService interface.
+type MockAvatarService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAvatarServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockAvatarServiceMockRecorder is the mock recorder for MockAvatarService.
+type MockAvatarServiceMockRecorder struct {
+	mock *MockAvatarService
+}
+
+// NewMockAvatarService creates a new mock instance.
+func NewMockAvatarService(ctrl *gomock.Controller) *MockAvatarService {
+	mock := &MockAvatarService{ctrl: ctrl}
+	mock.recorder = &MockAvatarServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAvatarService) EXPECT() *MockAvatarServiceMockRecorder {
+	return m.recorder
+}
+
+// CleanOrphans mocks base method.
+func (m *MockAvatarService) CleanOrphans(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanOrphans", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CleanOrphans indicates an expected call of CleanOrphans.
+func (mr *MockAvatarServiceMockRecorder) CleanOrphans(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanOrphans", reflect.TypeOf((*MockAvatarService)(nil).CleanOrphans), ctx, before)
+}
+
+// Upload mocks base method.
+func (m *MockAvatarService) Upload(ctx context.Context, uid int64, r io.Reader) ([]domain.AvatarVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", ctx, uid, r)
+	ret0, _ := ret[0].([]domain.AvatarVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockAvatarServiceMockRecorder) Upload(ctx, uid, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockAvatarService)(nil).Upload), ctx, uid, r)
+}
@@ -0,0 +1,177 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./file.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./file.go -package=mocks -destination=./mocks/file_mock.go
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "bedrock/internal/domain"
+	context "context"
+	io "io"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFileService is a mock of FileService interface.
+type MockFileService struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockFileServiceMockRecorder is the mock recorder for MockFileService.
+type MockFileServiceMockRecorder struct {
+	mock *MockFileService
+}
+
+// NewMockFileService creates a new mock instance.
+func NewMockFileService(ctrl *gomock.Controller) *MockFileService {
+	mock := &MockFileService{ctrl: ctrl}
+	mock.recorder = &MockFileServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileService) EXPECT() *MockFileServiceMockRecorder {
+	return m.recorder
+}
+
+// Attach mocks base method.
+func (m *MockFileService) Attach(ctx context.Context, ownerID, id int64, ref domain.FileRef) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Attach", ctx, ownerID, id, ref)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Attach indicates an expected call of Attach.
+func (mr *MockFileServiceMockRecorder) Attach(ctx, ownerID, id, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attach", reflect.TypeOf((*MockFileService)(nil).Attach), ctx, ownerID, id, ref)
+}
+
+// CleanOrphans mocks base method.
+func (m *MockFileService) CleanOrphans(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanOrphans", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CleanOrphans indicates an expected call of CleanOrphans.
+func (mr *MockFileServiceMockRecorder) CleanOrphans(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanOrphans", reflect.TypeOf((*MockFileService)(nil).CleanOrphans), ctx, before)
+}
+
+// Delete mocks base method.
+func (m *MockFileService) Delete(ctx context.Context, ownerID, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, ownerID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFileServiceMockRecorder) Delete(ctx, ownerID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFileService)(nil).Delete), ctx, ownerID, id)
+}
+
+// Detach mocks base method.
+func (m *MockFileService) Detach(ctx context.Context, ownerID, id int64, ref domain.FileRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Detach", ctx, ownerID, id, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Detach indicates an expected call of Detach.
+func (mr *MockFileServiceMockRecorder) Detach(ctx, ownerID, id, ref any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Detach", reflect.TypeOf((*MockFileService)(nil).Detach), ctx, ownerID, id, ref)
+}
+
+// Get mocks base method.
+func (m *MockFileService) Get(ctx context.Context, viewerID, id int64) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, viewerID, id)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockFileServiceMockRecorder) Get(ctx, viewerID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFileService)(nil).Get), ctx, viewerID, id)
+}
+
+// SignedURL mocks base method.
+func (m *MockFileService) SignedURL(ctx context.Context, f domain.File) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignedURL", ctx, f)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignedURL indicates an expected call of SignedURL.
+func (mr *MockFileServiceMockRecorder) SignedURL(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignedURL", reflect.TypeOf((*MockFileService)(nil).SignedURL), ctx, f)
+}
+
+// URL mocks base method.
+func (m *MockFileService) URL(ctx context.Context, viewerID int64, f domain.File) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "URL", ctx, viewerID, f)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// URL indicates an expected call of URL.
+func (mr *MockFileServiceMockRecorder) URL(ctx, viewerID, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URL", reflect.TypeOf((*MockFileService)(nil).URL), ctx, viewerID, f)
+}
+
+// Upload mocks base method.
+func (m *MockFileService) Upload(ctx context.Context, ownerID int64, name string, r io.Reader, public bool) (domain.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", ctx, ownerID, name, r, public)
+	ret0, _ := ret[0].(domain.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockFileServiceMockRecorder) Upload(ctx, ownerID, name, r, public any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockFileService)(nil).Upload), ctx, ownerID, name, r, public)
+}
+
+// Usage mocks base method.
+func (m *MockFileService) Usage(ctx context.Context, ownerID int64) (domain.FileUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage", ctx, ownerID)
+	ret0, _ := ret[0].(domain.FileUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockFileServiceMockRecorder) Usage(ctx, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockFileService)(nil).Usage), ctx, ownerID)
+}
@@ -116,17 +116,17 @@ func (mr *MockUserServiceMockRecorder) Signup(ctx, user any) *gomock.Call {
 }
 
 // UpdateAvatarPath mocks base method.
-func (m *MockUserService) UpdateAvatarPath(ctx context.Context, uid int64, newPath string) error {
+func (m *MockUserService) UpdateAvatarPath(ctx context.Context, uid int64, newPath string, variants []domain.AvatarVariant) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAvatarPath", ctx, uid, newPath)
+	ret := m.ctrl.Call(m, "UpdateAvatarPath", ctx, uid, newPath, variants)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateAvatarPath indicates an expected call of UpdateAvatarPath.
-func (mr *MockUserServiceMockRecorder) UpdateAvatarPath(ctx, uid, newPath any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) UpdateAvatarPath(ctx, uid, newPath, variants any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatarPath", reflect.TypeOf((*MockUserService)(nil).UpdateAvatarPath), ctx, uid, newPath)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatarPath", reflect.TypeOf((*MockUserService)(nil).UpdateAvatarPath), ctx, uid, newPath, variants)
 }
 
 // UpdateNonSensitiveInfo mocks base method.
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrModerationNotFound = repository.ErrModerationNotFound
+	ErrModerationHandled  = repository.ErrModerationHandled
+)
+
+// ModerationConfig 内容审核的配置
+type ModerationConfig struct {
+	// MaxAttempts 扫描失败多少次之后不再重试，转人工审核
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Timeout 单次扫描的超时时间，上传时同步扫描超时的留给后台任务重新扫描
+	Timeout time.Duration `mapstructure:"timeout"`
+	// URLExpire 管理员查看隔离区内容的签名地址有效期
+	URLExpire time.Duration `mapstructure:"url_expire"`
+}
+
+// ModerationHandler 业务方在审核有了结论之后公开或者删除隔离区里的内容
+// 处理失败时记录会放回隔离区重新扫描，所以可能被重复调用，需要幂等
+type ModerationHandler interface {
+	OnApproved(ctx context.Context, m domain.Moderation) error
+	OnRejected(ctx context.Context, m domain.Moderation) error
+}
+
+// ModerationService 内容审核，Register 的参数引用了本包的类型，生成的 mock 会造成循环引用，所以不生成 mock
+type ModerationService interface {
+	// Register 注册业务方的处理器，在启动时调用
+	Register(biz string, h ModerationHandler)
+	// Submit 把已经放进隔离区的内容提交审核并立即扫描一次，返回扫描之后的记录
+	// 有了结论时会同步调用处理器；扫描失败或者处理器失败都不返回错误，记录留在隔离区由 Rescan 重试
+	Submit(ctx context.Context, m domain.Moderation) (domain.Moderation, error)
+	// Rescan 重新扫描 before 之前进入隔离区、还没有扫描结论的内容，返回得出结论的数量
+	Rescan(ctx context.Context, before time.Time) (int, error)
+	// ListPending 等待人工审核的记录，按照 ID 从小到大排列
+	ListPending(ctx context.Context, afterID int64, limit int) ([]domain.Moderation, error)
+	// URL 隔离区内容的签名地址，给管理员审核用
+	URL(ctx context.Context, m domain.Moderation) (string, error)
+	// Review 人工审核，已经有了结论的记录返回 ErrModerationHandled
+	Review(ctx context.Context, reviewerID, id int64, approve bool, reason string) (domain.Moderation, error)
+}
+
+type DefaultModerationService struct {
+	repo    repository.ModerationRepository
+	storage storage.Provider
+	// scanner 为 nil 时所有内容直接通过
+	scanner  scanner.Scanner
+	l        logger.Logger
+	cfg      ModerationConfig
+	handlers map[string]ModerationHandler
+}
+
+func NewModerationService(repo repository.ModerationRepository, storageSvc storage.Provider, s scanner.Scanner, l logger.Logger, cfg ModerationConfig) ModerationService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.URLExpire <= 0 {
+		cfg.URLExpire = 10 * time.Minute
+	}
+	return &DefaultModerationService{
+		repo:     repo,
+		storage:  storageSvc,
+		scanner:  s,
+		l:        l,
+		cfg:      cfg,
+		handlers: make(map[string]ModerationHandler),
+	}
+}
+
+func (svc *DefaultModerationService) Register(biz string, h ModerationHandler) {
+	svc.handlers[biz] = h
+}
+
+func (svc *DefaultModerationService) Submit(ctx context.Context, m domain.Moderation) (domain.Moderation, error) {
+	m.Status = domain.ModerationStatusQuarantined
+	m.Scanned = false
+	m, err := svc.repo.Create(ctx, m)
+	if err != nil {
+		return domain.Moderation{}, err
+	}
+	res, err := svc.scan(ctx, m)
+	if err != nil {
+		svc.l.Warn(ctx, "处理审核结果失败", logger.Error(err), logger.Int64("id", m.ID))
+	}
+	return res, nil
+}
+
+func (svc *DefaultModerationService) Rescan(ctx context.Context, before time.Time) (int, error) {
+	const batch = 100
+	var (
+		done    int
+		afterID int64
+	)
+	for {
+		ms, err := svc.repo.FindQuarantined(ctx, false, before, afterID, batch)
+		if err != nil {
+			return done, err
+		}
+		for _, m := range ms {
+			afterID = m.ID
+			res, err := svc.scan(ctx, m)
+			switch {
+			case errors.Is(err, ErrModerationHandled):
+				// 扫描期间被管理员审核了
+			case err != nil:
+				svc.l.Warn(ctx, "重新扫描失败", logger.Error(err), logger.Int64("id", m.ID))
+			case res.Scanned:
+				done++
+			}
+		}
+		if len(ms) < batch {
+			return done, nil
+		}
+	}
+}
+
+func (svc *DefaultModerationService) ListPending(ctx context.Context, afterID int64, limit int) ([]domain.Moderation, error) {
+	return svc.repo.FindQuarantined(ctx, true, time.Now(), afterID, limit)
+}
+
+func (svc *DefaultModerationService) URL(ctx context.Context, m domain.Moderation) (string, error) {
+	return svc.storage.GetPrivateURL(ctx, m.Key, int64(svc.cfg.URLExpire.Seconds()))
+}
+
+func (svc *DefaultModerationService) Review(ctx context.Context, reviewerID, id int64, approve bool, reason string) (domain.Moderation, error) {
+	m, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.Moderation{}, err
+	}
+	if m.Status != domain.ModerationStatusQuarantined {
+		return domain.Moderation{}, ErrModerationHandled
+	}
+	prev := m
+	m.Status = domain.ModerationStatusRejected
+	if approve {
+		m.Status = domain.ModerationStatusApproved
+	}
+	m.Scanned = true
+	m.Reason = reason
+	m.ReviewerID = reviewerID
+	return svc.apply(ctx, prev, m)
+}
+
+// scan 扫描一次并保存结果，扫描失败时累加失败次数，超过上限之后转人工审核
+func (svc *DefaultModerationService) scan(ctx context.Context, m domain.Moderation) (domain.Moderation, error) {
+	prev := m
+	res, err := svc.doScan(ctx, m.Key)
+	if err != nil {
+		m.Attempts++
+		m.Reason = truncate(fmt.Sprintf("scan failed: %v", err), 512)
+		m.Scanned = m.Attempts >= svc.cfg.MaxAttempts
+		svc.l.Warn(ctx, "扫描失败", logger.Error(err), logger.Int64("id", m.ID), logger.Int("attempts", m.Attempts))
+		return m, svc.repo.Update(ctx, m, domain.ModerationStatusQuarantined)
+	}
+	m.Scanned = true
+	m.Reason = truncate(res.Reason, 512)
+	switch res.Verdict {
+	case scanner.VerdictClean:
+		m.Status = domain.ModerationStatusApproved
+	case scanner.VerdictReject:
+		m.Status = domain.ModerationStatusRejected
+	}
+	return svc.apply(ctx, prev, m)
+}
+
+func (svc *DefaultModerationService) doScan(ctx context.Context, key string) (scanner.Result, error) {
+	if svc.scanner == nil {
+		return scanner.Result{Verdict: scanner.VerdictClean}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, svc.cfg.Timeout)
+	defer cancel()
+	info, err := svc.storage.Stat(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		// 内容已经被删掉了，没有什么可以公开的
+		return scanner.Result{Verdict: scanner.VerdictReject, Reason: "object not found"}, nil
+	}
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	return svc.scanner.Scan(ctx, scanner.Object{
+		Key:         key,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			r, _, err := svc.storage.Get(ctx, key)
+			return r, err
+		},
+	})
+}
+
+// apply 先保存结论再通知业务方，业务方处理失败时放回 prev 的状态等待重试
+func (svc *DefaultModerationService) apply(ctx context.Context, prev, m domain.Moderation) (domain.Moderation, error) {
+	if err := svc.repo.Update(ctx, m, domain.ModerationStatusQuarantined); err != nil {
+		return prev, err
+	}
+	if m.Status == domain.ModerationStatusQuarantined {
+		return m, nil
+	}
+	h, ok := svc.handlers[m.Biz]
+	if !ok {
+		return m, nil
+	}
+	var err error
+	if m.Status == domain.ModerationStatusApproved {
+		err = h.OnApproved(ctx, m)
+	} else {
+		err = h.OnRejected(ctx, m)
+	}
+	if err == nil {
+		return m, nil
+	}
+	// 自动扫描的放回去重新扫描，人工审核的放回审核队列
+	if rerr := svc.repo.Update(ctx, prev, m.Status); rerr != nil {
+		svc.l.Error(ctx, "回滚审核结果失败", logger.Error(rerr), logger.Int64("id", m.ID))
+	}
+	return prev, err
+}
+
+// truncate 按字符截断，数据库里的 varchar 长度是字符数
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
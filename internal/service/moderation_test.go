@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	repomocks "bedrock/internal/repository/mocks"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// recordingHandler 记录收到的审核结论，err 不为空时处理失败
+type recordingHandler struct {
+	approved []int64
+	rejected []int64
+	err      error
+}
+
+func (h *recordingHandler) OnApproved(ctx context.Context, m domain.Moderation) error {
+	h.approved = append(h.approved, m.ID)
+	return h.err
+}
+
+func (h *recordingHandler) OnRejected(ctx context.Context, m domain.Moderation) error {
+	h.rejected = append(h.rejected, m.ID)
+	return h.err
+}
+
+func TestModerationService_Submit(t *testing.T) {
+	t.Parallel()
+	scanErr := errors.New("clamd unavailable")
+	handlerErr := errors.New("db error")
+
+	testCases := []struct {
+		name   string
+		key    string
+		result scanner.Result
+		err    error
+		// handlerErr 业务方处理失败
+		handlerErr error
+
+		// wantUpdates 依次保存的状态
+		wantUpdates  []domain.Moderation
+		wantStatus   domain.ModerationStatus
+		wantApproved []int64
+		wantRejected []int64
+	}{
+		{
+			name:   "扫描通过",
+			key:    "a.png",
+			result: scanner.Result{Verdict: scanner.VerdictClean},
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusApproved, Scanned: true},
+			},
+			wantStatus:   domain.ModerationStatusApproved,
+			wantApproved: []int64{1},
+		},
+		{
+			name:   "发现病毒",
+			key:    "a.png",
+			result: scanner.Result{Verdict: scanner.VerdictReject, Reason: "virus: Eicar"},
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusRejected, Scanned: true, Reason: "virus: Eicar"},
+			},
+			wantStatus:   domain.ModerationStatusRejected,
+			wantRejected: []int64{1},
+		},
+		{
+			name:   "转人工审核",
+			key:    "a.png",
+			result: scanner.Result{Verdict: scanner.VerdictReview, Reason: "moderation: porn 0.60"},
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusQuarantined, Scanned: true, Reason: "moderation: porn 0.60"},
+			},
+			wantStatus: domain.ModerationStatusQuarantined,
+		},
+		{
+			name: "扫描失败",
+			key:  "a.png",
+			err:  scanErr,
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusQuarantined, Attempts: 1, Reason: "scan failed: clamd unavailable"},
+			},
+			wantStatus: domain.ModerationStatusQuarantined,
+		},
+		{
+			name: "对象不存在",
+			key:  "missing.png",
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusRejected, Scanned: true, Reason: "object not found"},
+			},
+			wantStatus:   domain.ModerationStatusRejected,
+			wantRejected: []int64{1},
+		},
+		{
+			// 放回隔离区等待重新扫描
+			name:       "业务方处理失败",
+			key:        "a.png",
+			result:     scanner.Result{Verdict: scanner.VerdictClean},
+			handlerErr: handlerErr,
+			wantUpdates: []domain.Moderation{
+				{Status: domain.ModerationStatusApproved, Scanned: true},
+				{Status: domain.ModerationStatusQuarantined},
+			},
+			wantStatus:   domain.ModerationStatusQuarantined,
+			wantApproved: []int64{1},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := memory.NewProvider("https://cdn.example.com")
+			_, err := store.Upload(context.Background(), "a.png", strings.NewReader("png"), 3, storage.UploadOptions{})
+			require.NoError(t, err)
+			var updates []domain.Moderation
+			repo := repomocks.NewMockModerationRepository(ctrl)
+			repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, m domain.Moderation) (domain.Moderation, error) {
+					assert.Equal(t, domain.ModerationStatusQuarantined, m.Status)
+					m.ID = 1
+					return m, nil
+				})
+			repo.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, m domain.Moderation, _ domain.ModerationStatus) error {
+					updates = append(updates, domain.Moderation{
+						Status:   m.Status,
+						Scanned:  m.Scanned,
+						Reason:   m.Reason,
+						Attempts: m.Attempts,
+					})
+					return nil
+				}).AnyTimes()
+			s := scanner.Func(func(ctx context.Context, obj scanner.Object) (scanner.Result, error) {
+				assert.Equal(t, "image/png", obj.ContentType)
+				return tc.result, tc.err
+			})
+			h := &recordingHandler{err: tc.handlerErr}
+			svc := NewModerationService(repo, store, s, logger.NewNopLogger(), ModerationConfig{})
+			svc.Register(domain.ModerationBizAvatar, h)
+
+			m, err := svc.Submit(context.Background(), domain.Moderation{Biz: domain.ModerationBizAvatar, Uid: 1, Key: tc.key})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantStatus, m.Status)
+			assert.Equal(t, tc.wantUpdates, updates)
+			assert.Equal(t, tc.wantApproved, h.approved)
+			assert.Equal(t, tc.wantRejected, h.rejected)
+		})
+	}
+}
+
+func TestModerationService_Rescan(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := memory.NewProvider("https://cdn.example.com")
+	for _, key := range []string{"a.png", "b.png"} {
+		_, err := store.Upload(context.Background(), key, strings.NewReader("png"), 3, storage.UploadOptions{})
+		require.NoError(t, err)
+	}
+	before := time.Now()
+	repo := repomocks.NewMockModerationRepository(ctrl)
+	repo.EXPECT().FindQuarantined(gomock.Any(), false, before, int64(0), gomock.Any()).Return([]domain.Moderation{
+		{ID: 1, Biz: domain.ModerationBizFile, Key: "a.png", Status: domain.ModerationStatusQuarantined, Attempts: 1},
+		// 最后一次重试也失败了，转人工审核
+		{ID: 2, Biz: domain.ModerationBizFile, Key: "b.png", Status: domain.ModerationStatusQuarantined, Attempts: 2},
+	}, nil)
+	saved := map[int64]domain.Moderation{}
+	repo.EXPECT().Update(gomock.Any(), gomock.Any(), domain.ModerationStatusQuarantined).
+		DoAndReturn(func(_ context.Context, m domain.Moderation, _ domain.ModerationStatus) error {
+			saved[m.ID] = m
+			return nil
+		}).Times(2)
+	s := scanner.Func(func(ctx context.Context, obj scanner.Object) (scanner.Result, error) {
+		if obj.Key == "b.png" {
+			return scanner.Result{}, errors.New("timeout")
+		}
+		return scanner.Result{Verdict: scanner.VerdictClean}, nil
+	})
+	h := &recordingHandler{}
+	svc := NewModerationService(repo, store, s, logger.NewNopLogger(), ModerationConfig{MaxAttempts: 3})
+	svc.Register(domain.ModerationBizFile, h)
+
+	done, err := svc.Rescan(context.Background(), before)
+	require.NoError(t, err)
+	assert.Equal(t, 2, done)
+	assert.Equal(t, []int64{1}, h.approved)
+	assert.Equal(t, domain.ModerationStatusApproved, saved[1].Status)
+	assert.Equal(t, domain.ModerationStatusQuarantined, saved[2].Status)
+	assert.True(t, saved[2].Scanned)
+	assert.Equal(t, 3, saved[2].Attempts)
+}
+
+func TestModerationService_Review(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) repository.ModerationRepository
+		approve bool
+
+		wantApproved []int64
+		wantRejected []int64
+		wantErr      error
+	}{
+		{
+			name: "通过",
+			mock: func(ctrl *gomock.Controller) repository.ModerationRepository {
+				repo := repomocks.NewMockModerationRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Moderation{
+					ID: 1, Biz: domain.ModerationBizFile, Status: domain.ModerationStatusQuarantined, Scanned: true,
+				}, nil)
+				repo.EXPECT().Update(gomock.Any(), domain.Moderation{
+					ID: 1, Biz: domain.ModerationBizFile, Status: domain.ModerationStatusApproved, Scanned: true,
+					Reason: "没问题", ReviewerID: 9,
+				}, domain.ModerationStatusQuarantined).Return(nil)
+				return repo
+			},
+			approve:      true,
+			wantApproved: []int64{1},
+		},
+		{
+			name: "拒绝",
+			mock: func(ctrl *gomock.Controller) repository.ModerationRepository {
+				repo := repomocks.NewMockModerationRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Moderation{
+					ID: 1, Biz: domain.ModerationBizFile, Status: domain.ModerationStatusQuarantined, Scanned: true,
+				}, nil)
+				repo.EXPECT().Update(gomock.Any(), gomock.Any(), domain.ModerationStatusQuarantined).Return(nil)
+				return repo
+			},
+			wantRejected: []int64{1},
+		},
+		{
+			name: "已经处理过了",
+			mock: func(ctrl *gomock.Controller) repository.ModerationRepository {
+				repo := repomocks.NewMockModerationRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Moderation{
+					ID: 1, Status: domain.ModerationStatusApproved,
+				}, nil)
+				return repo
+			},
+			approve: true,
+			wantErr: ErrModerationHandled,
+		},
+		{
+			// 另一个管理员同时审核了
+			name: "并发审核",
+			mock: func(ctrl *gomock.Controller) repository.ModerationRepository {
+				repo := repomocks.NewMockModerationRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Moderation{
+					ID: 1, Biz: domain.ModerationBizFile, Status: domain.ModerationStatusQuarantined, Scanned: true,
+				}, nil)
+				repo.EXPECT().Update(gomock.Any(), gomock.Any(), domain.ModerationStatusQuarantined).Return(repository.ErrModerationHandled)
+				return repo
+			},
+			approve: true,
+			wantErr: ErrModerationHandled,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			h := &recordingHandler{}
+			svc := NewModerationService(tc.mock(ctrl), nil, nil, logger.NewNopLogger(), ModerationConfig{})
+			svc.Register(domain.ModerationBizFile, h)
+			reason := "没问题"
+			if !tc.approve {
+				reason = "违规"
+			}
+			_, err := svc.Review(context.Background(), 9, 1, tc.approve, reason)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantApproved, h.approved)
+			assert.Equal(t, tc.wantRejected, h.rejected)
+		})
+	}
+}
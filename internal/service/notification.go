@@ -0,0 +1,355 @@
+package service
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	"bedrock/internal/service/sms"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/phone"
+	"context"
+	"errors"
+	"sync"
+	"time"
+	// 部署环境（例如精简过的容器镜像）不一定带时区数据，免打扰时段依赖它
+	_ "time/tzdata"
+)
+
+var (
+	ErrCampaignNotFound   = repository.ErrCampaignNotFound
+	ErrNoValidRecipients  = errors.New("没有合法的接收人")
+	ErrInvalidTimezone    = errors.New("无法识别的时区")
+	ErrInvalidQuietPeriod = errors.New("免打扰时段必须在一天之内")
+)
+
+// releaseTimeout 发送被打断之后释放接收人的超时时间，这时候任务的 ctx 通常已经结束了
+const releaseTimeout = 3 * time.Second
+
+// NotificationConfig 群发短信的配置
+type NotificationConfig struct {
+	// BatchSize 一次调用短信服务商发送的号码数，腾讯云单次最多 200 个
+	BatchSize int `mapstructure:"batch_size"`
+	// QuietStart/QuietEnd 接收人当地时间的免打扰时段，表示距离零点的时长，
+	// 例如 21h 到 8h，允许跨零点，两者相等表示不启用
+	QuietStart time.Duration `mapstructure:"quiet_start"`
+	QuietEnd   time.Duration `mapstructure:"quiet_end"`
+	// DefaultTimezone 既没有设置偏好、也无法从号码推断时区时使用，为空时使用服务器时区
+	DefaultTimezone string `mapstructure:"default_timezone"`
+	// Lease 领取的接收人多久没有处理完就允许重新领取，要比发送一批的耗时长，
+	// 进程在发送过程中退出时这一批会在租约到期之后重发
+	Lease time.Duration `mapstructure:"lease"`
+}
+
+//go:generate mockgen -source=./notification.go -package=mocks -destination=./mocks/notification_mock.go NotificationService
+type NotificationService interface {
+	// CreateCampaign 创建群发活动并保存接收人，由 Dispatch 分批发送，返回活动 ID
+	// 非法号码直接计入失败，重复号码只发一次
+	CreateCampaign(ctx context.Context, c domain.Campaign, phones []string) (int64, error)
+	// Dispatch 分批发送所有到了发送时间的接收人，处于当地免打扰时段的推迟到时段结束，返回处理的人数
+	// 接收人保存在数据库里，进程重启之后接着发送，多个实例同时执行也不会重复领取
+	Dispatch(ctx context.Context) (int, error)
+	FindCampaign(ctx context.Context, id int64) (domain.Campaign, error)
+	// GetPreference 查询偏好，没有设置过时返回默认偏好（不退订）
+	GetPreference(ctx context.Context, phone string) (domain.SMSPreference, error)
+	SetPreference(ctx context.Context, p domain.SMSPreference) error
+}
+
+type DefaultNotificationService struct {
+	repo repository.NotificationRepository
+	// sms 调用方负责装饰限流、监控等功能
+	sms sms.Service
+	l   logger.Logger
+	cfg NotificationConfig
+	def *time.Location
+
+	// now 方便测试替换
+	now func() time.Time
+
+	// locations 缓存加载过的时区
+	locations sync.Map
+}
+
+func NewNotificationService(repo repository.NotificationRepository, smsSvc sms.Service, l logger.Logger, cfg NotificationConfig) (NotificationService, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	if cfg.QuietStart < 0 || cfg.QuietStart >= 24*time.Hour || cfg.QuietEnd < 0 || cfg.QuietEnd >= 24*time.Hour {
+		return nil, ErrInvalidQuietPeriod
+	}
+	def := time.Local
+	if cfg.DefaultTimezone != "" {
+		loc, err := time.LoadLocation(cfg.DefaultTimezone)
+		if err != nil {
+			return nil, err
+		}
+		def = loc
+	}
+	return &DefaultNotificationService{
+		repo: repo,
+		sms:  smsSvc,
+		l:    l,
+		cfg:  cfg,
+		def:  def,
+		now:  time.Now,
+	}, nil
+}
+
+func (svc *DefaultNotificationService) CreateCampaign(ctx context.Context, c domain.Campaign, phones []string) (int64, error) {
+	recipients := make([]string, 0, len(phones))
+	seen := make(map[string]struct{}, len(phones))
+	var invalid int64
+	for _, p := range phones {
+		number, err := phone.Normalize(p, "")
+		if err != nil {
+			invalid++
+			continue
+		}
+		if _, ok := seen[number]; ok {
+			continue
+		}
+		seen[number] = struct{}{}
+		recipients = append(recipients, number)
+	}
+	if len(recipients) == 0 {
+		return 0, ErrNoValidRecipients
+	}
+	c.Status = domain.CampaignStatusRunning
+	c.Stats = domain.CampaignStats{
+		Total:  int64(len(recipients)) + invalid,
+		Failed: invalid,
+	}
+	// 群发可能持续很久（免打扰时段要等到第二天），接收人先保存下来，由定时任务发送
+	return svc.repo.CreateCampaign(ctx, c, recipients, svc.now())
+}
+
+func (svc *DefaultNotificationService) FindCampaign(ctx context.Context, id int64) (domain.Campaign, error) {
+	return svc.repo.FindCampaign(ctx, id)
+}
+
+func (svc *DefaultNotificationService) GetPreference(ctx context.Context, phone string) (domain.SMSPreference, error) {
+	prefs, err := svc.repo.FindPreferences(ctx, []string{phone})
+	if err != nil {
+		return domain.SMSPreference{}, err
+	}
+	if p, ok := prefs[phone]; ok {
+		return p, nil
+	}
+	return domain.SMSPreference{Phone: phone}, nil
+}
+
+func (svc *DefaultNotificationService) SetPreference(ctx context.Context, p domain.SMSPreference) error {
+	if p.Timezone != "" {
+		if _, err := svc.location(p.Timezone); err != nil {
+			return ErrInvalidTimezone
+		}
+	}
+	return svc.repo.SavePreference(ctx, p)
+}
+
+func (svc *DefaultNotificationService) Dispatch(ctx context.Context) (int, error) {
+	var handled int
+	// 同一次执行里的活动不会变，不用每一批都查一次
+	campaigns := make(map[int64]domain.Campaign)
+	for ctx.Err() == nil {
+		now := svc.now()
+		recipients, err := svc.repo.ClaimRecipients(ctx, now, now.Add(svc.cfg.Lease), svc.cfg.BatchSize)
+		if err != nil {
+			return handled, err
+		}
+		// 一次领取的接收人可能属于不同的活动，按照活动分开发送
+		groups := make(map[int64][]domain.CampaignRecipient)
+		var ids []int64
+		for _, r := range recipients {
+			if _, ok := groups[r.CampaignID]; !ok {
+				ids = append(ids, r.CampaignID)
+			}
+			groups[r.CampaignID] = append(groups[r.CampaignID], r)
+		}
+		for i, id := range ids {
+			c, ok := campaigns[id]
+			if !ok {
+				if c, err = svc.repo.FindCampaign(ctx, id); err != nil {
+					if ctx.Err() != nil {
+						svc.release(ctx, ids[i:], groups)
+						return handled, ctx.Err()
+					}
+					// 租约到期之后会被重新领取
+					svc.l.Error(ctx, "查询群发活动失败", logger.Int64("campaign_id", id), logger.Error(err))
+					continue
+				}
+				campaigns[id] = c
+			}
+			settled, err := svc.deliver(ctx, c, groups[id])
+			if err != nil {
+				// 这一批和后面还没处理的马上释放，下一次执行接着发；
+				// 继续领取只会领到刚刚释放的接收人，所以直接结束这一次执行
+				svc.release(ctx, ids[i:], groups)
+				return handled, err
+			}
+			if settled {
+				handled += len(groups[id])
+			}
+		}
+		if len(recipients) < svc.cfg.BatchSize {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return handled, err
+	}
+	// 每次执行都检查一遍，上一次结束活动失败的这次会补上
+	if _, err := svc.repo.FinishCampaigns(ctx); err != nil {
+		return handled, err
+	}
+	return handled, nil
+}
+
+// deliver 发送同一个活动的一批接收人，处理结果和统计在同一个事务里保存
+// 返回 false 表示保存结果失败，等租约到期之后重新领取；
+// 返回 error 表示发送被打断（任务超时、进程退出、等不到发送配额），这一批需要重发
+func (svc *DefaultNotificationService) deliver(ctx context.Context, c domain.Campaign, recipients []domain.CampaignRecipient) (bool, error) {
+	var (
+		stats    domain.CampaignStats
+		done     = make([]int64, 0, len(recipients))
+		deferred []domain.CampaignRecipient
+		phones   = make([]string, 0, len(recipients))
+	)
+	for _, r := range recipients {
+		// 之前推迟过的已经计入了 Deferred，重新分类之前先扣掉
+		if r.Deferred {
+			stats.Deferred--
+		}
+		phones = append(phones, r.Phone)
+	}
+	prefs, err := svc.repo.FindPreferences(ctx, phones)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		// 查不到偏好就没法确认用户是否退订，宁可不发
+		svc.l.Error(ctx, "查询短信偏好失败",
+			logger.Int64("campaign_id", c.ID), logger.Error(err))
+		stats.Failed = int64(len(recipients))
+		for _, r := range recipients {
+			done = append(done, r.ID)
+		}
+		return svc.settle(ctx, c.ID, stats, done, nil), nil
+	}
+	now := svc.now()
+	eligible := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		pref := prefs[r.Phone]
+		if pref.OptOut {
+			stats.OptedOut++
+			done = append(done, r.ID)
+			continue
+		}
+		if resumeAt, quiet := svc.quietUntil(now, svc.recipientLocation(r.Phone, pref)); quiet {
+			stats.Deferred++
+			r.SendAfter = resumeAt
+			deferred = append(deferred, r)
+			continue
+		}
+		eligible = append(eligible, r.Phone)
+		done = append(done, r.ID)
+	}
+	if len(eligible) > 0 {
+		if err = svc.sms.Send(ctx, c.TplId, c.Args, eligible...); err != nil {
+			if interrupted(ctx, err) {
+				// 限速等不到配额时没有发出去；服务商超时的不知道有没有发出去，和租约到期之后重发一样，至少发送一次
+				return false, err
+			}
+			svc.l.Warn(ctx, "群发短信失败",
+				logger.Int64("campaign_id", c.ID),
+				logger.Int("batch_size", len(eligible)),
+				logger.Error(err))
+			stats.Failed += int64(len(eligible))
+		} else {
+			stats.Success += int64(len(eligible))
+		}
+	}
+	return svc.settle(ctx, c.ID, stats, done, deferred), nil
+}
+
+// interrupted 判断发送是不是被超时或者取消打断的，这种错误不能计入失败，否则接收人会被删掉，再也收不到
+// 限速装饰器等到配额会超过截止时间的时候，ctx 还没有结束就会返回 context.DeadlineExceeded
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// release 释放 ids 里这些活动领取到的接收人，ctx 可能已经结束了，用一个新的超时
+func (svc *DefaultNotificationService) release(ctx context.Context, ids []int64, groups map[int64][]domain.CampaignRecipient) {
+	var recipients []int64
+	for _, id := range ids {
+		for _, r := range groups[id] {
+			recipients = append(recipients, r.ID)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	if err := svc.repo.ReleaseRecipients(ctx, recipients, svc.now()); err != nil {
+		// 释放失败也没关系，租约到期之后一样会被重新领取
+		svc.l.Warn(ctx, "释放群发接收人失败", logger.Int("count", len(recipients)), logger.Error(err))
+	}
+}
+
+func (svc *DefaultNotificationService) settle(ctx context.Context, id int64, stats domain.CampaignStats, done []int64, deferred []domain.CampaignRecipient) bool {
+	if err := svc.repo.SettleRecipients(ctx, id, stats, done, deferred); err != nil {
+		// 已经发出去的会在租约到期之后重发，记录下来人工核对
+		svc.l.Error(ctx, "保存群发结果失败",
+			logger.Int64("campaign_id", id), logger.Error(err))
+		return false
+	}
+	return true
+}
+
+// recipientLocation 时区优先级：用户偏好 > 号码所属地区 > 默认时区
+func (svc *DefaultNotificationService) recipientLocation(number string, pref domain.SMSPreference) *time.Location {
+	if pref.Timezone != "" {
+		if loc, err := svc.location(pref.Timezone); err == nil {
+			return loc
+		}
+	}
+	if n, err := phone.Parse(number, ""); err == nil && n.Timezone() != "" {
+		if loc, err := svc.location(n.Timezone()); err == nil {
+			return loc
+		}
+	}
+	return svc.def
+}
+
+func (svc *DefaultNotificationService) location(name string) (*time.Location, error) {
+	if loc, ok := svc.locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	svc.locations.Store(name, loc)
+	return loc, nil
+}
+
+// quietUntil 判断 now 在 loc 当地是否处于免打扰时段，是的话返回时段结束的时间
+func (svc *DefaultNotificationService) quietUntil(now time.Time, loc *time.Location) (time.Time, bool) {
+	start, end := svc.cfg.QuietStart, svc.cfg.QuietEnd
+	if start == end {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	y, m, d := local.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	offset := local.Sub(midnight)
+	switch {
+	case start < end && offset >= start && offset < end:
+		return midnight.Add(end), true
+	case start > end && offset >= start:
+		// 跨零点，要等到第二天
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(end), true
+	case start > end && offset < end:
+		return midnight.Add(end), true
+	}
+	return time.Time{}, false
+}
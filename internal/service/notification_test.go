@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bedrock/internal/domain"
+	"bedrock/internal/repository"
+	repoMocks "bedrock/internal/repository/mocks"
+	"bedrock/internal/service/sms"
+	smsMocks "bedrock/internal/service/sms/mocks"
+	"bedrock/internal/service/sms/throttle"
+	"bedrock/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNotificationService_Dispatch(t *testing.T) {
+	t.Parallel()
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	campaign := domain.Campaign{ID: 1, TplId: "tpl", Args: []string{"双十一"}}
+	const (
+		cn1 = "+8613800138000"
+		cn2 = "+8613800138001"
+		cn3 = "+8613800138002"
+		us  = "+12025550123"
+	)
+	day := time.Date(2025, 11, 11, 10, 0, 0, 0, shanghai)
+	night := time.Date(2025, 11, 11, 22, 0, 0, 0, shanghai)
+	rc := func(id int64, phone string) domain.CampaignRecipient {
+		return domain.CampaignRecipient{ID: id, CampaignID: 1, Phone: phone}
+	}
+	// claim 期望领取一批接收人，批次大小是 2
+	claim := func(repo *repoMocks.MockNotificationRepository, now time.Time, res ...domain.CampaignRecipient) {
+		repo.EXPECT().ClaimRecipients(gomock.Any(), now, now.Add(5*time.Minute), 2).Return(res, nil)
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service)
+		now  time.Time
+
+		wantHandled int
+		wantErr     error
+	}{
+		{
+			name: "按照批次大小切分",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				claim(repo, day, rc(1, cn1), rc(2, cn2))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, cn2}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1, cn2).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 2}, []int64{1, 2}, nil).Return(nil)
+				// 同一次执行里活动只查一次
+				claim(repo, day, rc(3, cn3))
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn3}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn3).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1}, []int64{3}, nil).Return(nil)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsSvc
+			},
+			now:         day,
+			wantHandled: 3,
+		},
+		{
+			name: "跳过退订用户",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				claim(repo, day, rc(1, cn1), rc(2, cn2))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, cn2}).Return(map[string]domain.SMSPreference{
+					cn2: {Phone: cn2, OptOut: true},
+				}, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1, OptedOut: 1}, []int64{1, 2}, nil).Return(nil)
+				claim(repo, day)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsSvc
+			},
+			now:         day,
+			wantHandled: 2,
+		},
+		{
+			name: "全部退订不调用服务商",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				claim(repo, day, rc(1, cn1))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(map[string]domain.SMSPreference{
+					cn1: {Phone: cn1, OptOut: true},
+				}, nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{OptedOut: 1}, []int64{1}, nil).Return(nil)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsMocks.NewMockService(ctrl)
+			},
+			now:         day,
+			wantHandled: 1,
+		},
+		{
+			name: "免打扰时段顺延到当地早上",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				// 北京时间 22:00，纽约是 09:00，可以发
+				claim(repo, night, rc(1, cn1), rc(2, us))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, us}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, us).Return(nil)
+				// 北京的号码推迟到第二天 08:00
+				deferred := rc(1, cn1)
+				deferred.SendAfter = time.Date(2025, 11, 12, 8, 0, 0, 0, shanghai)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1, Deferred: 1}, []int64{2},
+					[]domain.CampaignRecipient{deferred}).Return(nil)
+				claim(repo, night)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(0), nil)
+				return repo, smsSvc
+			},
+			now:         night,
+			wantHandled: 2,
+		},
+		{
+			name: "推迟的接收人到时间之后发送",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				now := time.Date(2025, 11, 12, 8, 0, 0, 0, shanghai)
+				deferred := rc(1, cn1)
+				deferred.Deferred = true
+				claim(repo, now, deferred)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1).Return(nil)
+				// 之前计入了 Deferred，发送之后扣掉
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1, Deferred: -1}, []int64{1}, nil).Return(nil)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsSvc
+			},
+			now:         time.Date(2025, 11, 12, 8, 0, 0, 0, shanghai),
+			wantHandled: 1,
+		},
+		{
+			name: "偏好里的时区优先于号码所属地区",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				// 用户人在伦敦，北京时间 22:00 是伦敦 14:00
+				claim(repo, night, rc(1, cn1))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(map[string]domain.SMSPreference{
+					cn1: {Phone: cn1, Timezone: "Europe/London"},
+				}, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1}, []int64{1}, nil).Return(nil)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsSvc
+			},
+			now:         night,
+			wantHandled: 1,
+		},
+		{
+			name: "不同活动分开发送",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				other := domain.CampaignRecipient{ID: 2, CampaignID: 2, Phone: cn2}
+				claim(repo, day, rc(1, cn1), other)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1}, []int64{1}, nil).Return(nil)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(2)).Return(domain.Campaign{ID: 2, TplId: "tpl2"}, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn2}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl2", nil, cn2).Return(nil)
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(2), domain.CampaignStats{Success: 1}, []int64{2}, nil).Return(nil)
+				claim(repo, day)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(2), nil)
+				return repo, smsSvc
+			},
+			now:         day,
+			wantHandled: 2,
+		},
+		{
+			name: "服务商失败计入失败数",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				claim(repo, day, rc(1, cn1), rc(2, cn2))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, cn2}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1, cn2).Return(errors.New("服务商错误"))
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Failed: 2}, []int64{1, 2}, nil).Return(nil)
+				claim(repo, day)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsSvc
+			},
+			now:         day,
+			wantHandled: 2,
+		},
+		{
+			name: "查询偏好失败时不发送",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				claim(repo, day, rc(1, cn1))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(nil, errors.New("db error"))
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Failed: 1}, []int64{1}, nil).Return(nil)
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(1), nil)
+				return repo, smsMocks.NewMockService(ctrl)
+			},
+			now:         day,
+			wantHandled: 1,
+		},
+		{
+			name: "保存结果失败",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				claim(repo, day, rc(1, cn1))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(campaign, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"双十一"}, cn1).Return(nil)
+				// 接收人还是领取状态，租约到期之后重新领取
+				repo.EXPECT().SettleRecipients(gomock.Any(), int64(1), domain.CampaignStats{Success: 1}, []int64{1}, nil).Return(errors.New("db error"))
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(0), nil)
+				return repo, smsSvc
+			},
+			now: day,
+		},
+		{
+			name: "查询活动失败",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				claim(repo, day, rc(1, cn1))
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(domain.Campaign{}, errors.New("db error"))
+				repo.EXPECT().FinishCampaigns(gomock.Any()).Return(int64(0), nil)
+				return repo, smsMocks.NewMockService(ctrl)
+			},
+			now: day,
+		},
+		{
+			name: "领取失败",
+			mock: func(ctrl *gomock.Controller) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				repo.EXPECT().ClaimRecipients(gomock.Any(), day, day.Add(5*time.Minute), 2).Return(nil, errors.New("db error"))
+				return repo, smsMocks.NewMockService(ctrl)
+			},
+			now:     day,
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo, smsSvc := tc.mock(ctrl)
+			svc := newTestNotificationService(t, repo, smsSvc, tc.now)
+			handled, err := svc.Dispatch(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantHandled, handled)
+		})
+	}
+}
+
+// TestNotificationService_DispatchInterrupted 发送被超时或者取消打断时，接收人不能计入失败，
+// 要马上释放，由下一次执行重新领取
+func TestNotificationService_DispatchInterrupted(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 11, 11, 10, 0, 0, 0, time.UTC)
+	const (
+		cn1 = "+8613800138000"
+		cn2 = "+8613800138001"
+		cn3 = "+8613800138002"
+	)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller, cancel context.CancelFunc) (repository.NotificationRepository, sms.Service)
+		// timeout 为 0 表示 ctx 不设置超时
+		timeout time.Duration
+
+		wantErr error
+	}{
+		{
+			name: "任务超时",
+			mock: func(ctrl *gomock.Controller, cancel context.CancelFunc) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				repo.EXPECT().ClaimRecipients(gomock.Any(), now, now.Add(5*time.Minute), 2).Return([]domain.CampaignRecipient{
+					{ID: 1, CampaignID: 1, Phone: cn1},
+					{ID: 2, CampaignID: 1, Phone: cn2},
+				}, nil)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(domain.Campaign{ID: 1, TplId: "tpl"}, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, cn2}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), cn1, cn2).
+					DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+						cancel()
+						return ctx.Err()
+					})
+				repo.EXPECT().ReleaseRecipients(gomock.Any(), []int64{1, 2}, now).Return(nil)
+				return repo, smsSvc
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			// 每秒只能发 1 个号码，第二个号码要等 1 秒，超过了任务的截止时间，
+			// 这时候 ctx 还没有结束，不能当成服务商失败
+			name: "等不到发送配额",
+			mock: func(ctrl *gomock.Controller, cancel context.CancelFunc) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				repo.EXPECT().ClaimRecipients(gomock.Any(), now, now.Add(5*time.Minute), 2).Return([]domain.CampaignRecipient{
+					{ID: 1, CampaignID: 1, Phone: cn1},
+					{ID: 2, CampaignID: 1, Phone: cn2},
+				}, nil)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(domain.Campaign{ID: 1, TplId: "tpl"}, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1, cn2}).Return(nil, nil)
+				repo.EXPECT().ReleaseRecipients(gomock.Any(), []int64{1, 2}, now).Return(nil)
+				return repo, throttle.NewService(smsMocks.NewMockService(ctrl), 1, 1)
+			},
+			timeout: time.Second / 2,
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "同一批里其他活动的接收人一起释放",
+			mock: func(ctrl *gomock.Controller, cancel context.CancelFunc) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				smsSvc := smsMocks.NewMockService(ctrl)
+				repo.EXPECT().ClaimRecipients(gomock.Any(), now, now.Add(5*time.Minute), 2).Return([]domain.CampaignRecipient{
+					{ID: 1, CampaignID: 1, Phone: cn1},
+					{ID: 3, CampaignID: 2, Phone: cn3},
+				}, nil)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(domain.Campaign{ID: 1, TplId: "tpl"}, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).Return(nil, nil)
+				smsSvc.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), cn1).Return(context.DeadlineExceeded)
+				repo.EXPECT().ReleaseRecipients(gomock.Any(), []int64{1, 3}, now).Return(nil)
+				return repo, smsSvc
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "释放失败等租约到期",
+			mock: func(ctrl *gomock.Controller, cancel context.CancelFunc) (repository.NotificationRepository, sms.Service) {
+				repo := repoMocks.NewMockNotificationRepository(ctrl)
+				repo.EXPECT().ClaimRecipients(gomock.Any(), now, now.Add(5*time.Minute), 2).Return([]domain.CampaignRecipient{
+					{ID: 1, CampaignID: 1, Phone: cn1},
+				}, nil)
+				repo.EXPECT().FindCampaign(gomock.Any(), int64(1)).Return(domain.Campaign{ID: 1, TplId: "tpl"}, nil)
+				repo.EXPECT().FindPreferences(gomock.Any(), []string{cn1}).
+					DoAndReturn(func(ctx context.Context, phones []string) (map[string]domain.SMSPreference, error) {
+						cancel()
+						return nil, ctx.Err()
+					})
+				repo.EXPECT().ReleaseRecipients(gomock.Any(), []int64{1}, now).Return(errors.New("db error"))
+				return repo, smsMocks.NewMockService(ctrl)
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			repo, smsSvc := tc.mock(ctrl, cancel)
+			// 没有 SettleRecipients 和 FinishCampaigns 的调用，接收人还在表里等待发送
+			svc := newTestNotificationService(t, repo, smsSvc, now)
+			handled, err := svc.Dispatch(ctx)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, 0, handled)
+		})
+	}
+}
+
+func TestNotificationService_CreateCampaign(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2025, 11, 11, 10, 0, 0, 0, time.UTC)
+	repo := repoMocks.NewMockNotificationRepository(ctrl)
+	// 一个非法号码，一个重复号码，接收人保存下来由 Dispatch 发送
+	repo.EXPECT().CreateCampaign(gomock.Any(), domain.Campaign{
+		Name:   "双十一",
+		TplId:  "tpl",
+		Status: domain.CampaignStatusRunning,
+		Stats:  domain.CampaignStats{Total: 2, Failed: 1},
+	}, []string{"+8613800138000"}, now).Return(int64(1), nil)
+
+	svc := newTestNotificationService(t, repo, smsMocks.NewMockService(ctrl), now)
+	id, err := svc.CreateCampaign(context.Background(), domain.Campaign{Name: "双十一", TplId: "tpl"},
+		[]string{"13800138000", "+86 138-0013-8000", "123"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	_, err = svc.CreateCampaign(context.Background(), domain.Campaign{TplId: "tpl"}, []string{"123"})
+	assert.Equal(t, ErrNoValidRecipients, err)
+}
+
+// newTestNotificationService 每批 2 个号码，北京时间 21:00 到 08:00 免打扰，当前时间固定为 now
+func newTestNotificationService(t *testing.T, repo repository.NotificationRepository, smsSvc sms.Service, now time.Time) *DefaultNotificationService {
+	res, err := NewNotificationService(repo, smsSvc, logger.NewNopLogger(), NotificationConfig{
+		BatchSize:       2,
+		QuietStart:      21 * time.Hour,
+		QuietEnd:        8 * time.Hour,
+		DefaultTimezone: "Asia/Shanghai",
+	})
+	require.NoError(t, err)
+	svc := res.(*DefaultNotificationService)
+	svc.now = func() time.Time { return now }
+	return svc
+}
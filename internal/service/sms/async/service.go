@@ -1 +1,128 @@
 package async
+
+import (
+	"bedrock/internal/service/sms"
+	"bedrock/pkg/logger"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var _ sms.Service = &Service{}
+
+// Config 异步重试的配置
+type Config struct {
+	// QueueSize 最多排队等待重试的请求数，<= 0 时默认 1000
+	QueueSize int `mapstructure:"queue_size"`
+	// RetryInterval 两次重试之间的间隔，<= 0 时默认 1s
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+	// MaxRetries 最多重试的次数，<= 0 时默认 3
+	MaxRetries int `mapstructure:"max_retries"`
+}
+
+type request struct {
+	ctx     context.Context
+	tplId   string
+	args    []string
+	numbers []string
+}
+
+// Service 被装饰的服务发送失败（例如触发限流、服务商故障）时，把请求放进内存队列由后台重试，调用方直接拿到成功
+// 超时和取消不会重试，因为不知道短信有没有发出去；队列满了返回原来的错误
+// 队列在内存里，进程退出时没有重试完的请求会丢失，不适合必须送达的短信
+type Service struct {
+	// 被装饰的
+	svc   sms.Service
+	l     logger.Logger
+	cfg   Config
+	queue chan request
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewService(svc sms.Service, l logger.Logger, cfg Config) *Service {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		svc:    svc,
+		l:      l,
+		cfg:    cfg,
+		queue:  make(chan request, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 启动后台重试，必须在 Send 之前调用
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop 停止重试并等待正在进行的重试退出，还在排队的请求会被丢弃
+func (s *Service) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	err := s.svc.Send(ctx, tplId, args, numbers...)
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	req := request{
+		// 请求结束之后还要重试，只保留 ctx 里的值（例如 trace）
+		ctx:     context.WithoutCancel(ctx),
+		tplId:   tplId,
+		args:    args,
+		numbers: numbers,
+	}
+	select {
+	case s.queue <- req:
+		s.l.Warn(ctx, "发送短信失败，转为异步重试", logger.Error(err))
+		return nil
+	default:
+		return err
+	}
+}
+
+func (s *Service) loop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case req := <-s.queue:
+			s.retry(req)
+		}
+	}
+}
+
+func (s *Service) retry(req request) {
+	var err error
+	for i := 0; i < s.cfg.MaxRetries; i++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(s.cfg.RetryInterval):
+		}
+		if err = s.svc.Send(req.ctx, req.tplId, req.args, req.numbers...); err == nil {
+			return
+		}
+	}
+	s.l.Error(req.ctx, "异步重试发送短信失败",
+		logger.Int("retries", s.cfg.MaxRetries),
+		logger.Int("batch_size", len(req.numbers)),
+		logger.Error(err))
+}
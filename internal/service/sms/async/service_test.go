@@ -0,0 +1,118 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bedrock/internal/service/sms"
+	smsMocks "bedrock/internal/service/sms/mocks"
+	"bedrock/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Send(t *testing.T) {
+	t.Parallel()
+	errLimited := errors.New("触发限流")
+	testCases := []struct {
+		name string
+		// mock 返回被装饰的服务，sent 在重试成功之后加一
+		mock func(ctrl *gomock.Controller, sent *atomic.Int32) sms.Service
+		cfg  Config
+
+		wantErr error
+		// wantSent 最终发出去的次数
+		wantSent int32
+	}{
+		{
+			name: "直接发送成功",
+			mock: func(ctrl *gomock.Controller, sent *atomic.Int32) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").
+					DoAndReturn(func(context.Context, string, []string, ...string) error {
+						sent.Add(1)
+						return nil
+					})
+				return svc
+			},
+			wantSent: 1,
+		},
+		{
+			name: "失败之后异步重试成功",
+			mock: func(ctrl *gomock.Controller, sent *atomic.Int32) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				gomock.InOrder(
+					svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").Return(errLimited).Times(2),
+					svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").
+						DoAndReturn(func(context.Context, string, []string, ...string) error {
+							sent.Add(1)
+							return nil
+						}),
+				)
+				return svc
+			},
+			cfg:      Config{RetryInterval: time.Millisecond},
+			wantSent: 1,
+		},
+		{
+			name: "重试次数用完",
+			mock: func(ctrl *gomock.Controller, sent *atomic.Int32) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				// 第一次同步发送加上两次重试
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").Return(errLimited).Times(3)
+				return svc
+			},
+			cfg: Config{RetryInterval: time.Millisecond, MaxRetries: 2},
+		},
+		{
+			// 不知道超时的短信有没有发出去，重试可能让用户收到两条
+			name: "超时不重试",
+			mock: func(ctrl *gomock.Controller, sent *atomic.Int32) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").Return(context.DeadlineExceeded)
+				return svc
+			},
+			cfg:     Config{RetryInterval: time.Millisecond},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var sent atomic.Int32
+			svc := NewService(tc.mock(ctrl, &sent), logger.NewNopLogger(), tc.cfg)
+			svc.Start()
+			err := svc.Send(context.Background(), "tpl", []string{"1"}, "a")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Eventually(t, func() bool {
+				return sent.Load() == tc.wantSent
+			}, time.Second, time.Millisecond)
+			// 等重试都结束了再检查调用次数
+			time.Sleep(20 * time.Millisecond)
+			svc.Stop()
+		})
+	}
+}
+
+func TestService_QueueFull(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	errLimited := errors.New("触发限流")
+	inner := smsMocks.NewMockService(ctrl)
+	inner.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errLimited).Times(2)
+	// 没有启动后台重试，排队的请求不会被取走
+	svc := NewService(inner, logger.NewNopLogger(), Config{QueueSize: 1})
+	assert.NoError(t, svc.Send(context.Background(), "tpl", nil, "a"))
+	assert.Equal(t, errLimited, svc.Send(context.Background(), "tpl", nil, "b"))
+}
@@ -0,0 +1,56 @@
+package region
+
+import (
+	"bedrock/internal/service/sms"
+	"bedrock/pkg/phone"
+	"context"
+	"errors"
+)
+
+var _ sms.Service = &Service{}
+
+// Service 按照手机号的国家码把短信路由到不同的服务商
+// 例如 +86 走腾讯云，其余的走国际短信服务商
+// 传入的号码必须是 E.164 格式
+type Service struct {
+	// routes 国家码（不带 +）到服务商的映射
+	routes map[string]sms.Service
+	// fallback 没有命中 routes 的号码都走这个服务商
+	fallback sms.Service
+}
+
+func NewService(routes map[string]sms.Service, fallback sms.Service) sms.Service {
+	return &Service{
+		routes:   routes,
+		fallback: fallback,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	// 同一个服务商的号码合并成一次调用，保持号码原有的先后顺序
+	groups := make(map[sms.Service][]string, 2)
+	order := make([]sms.Service, 0, 2)
+	for _, number := range numbers {
+		n, err := phone.Parse(number, "")
+		if err != nil {
+			return err
+		}
+		svc, ok := s.routes[n.CountryCode]
+		if !ok {
+			svc = s.fallback
+		}
+		if svc == nil {
+			return errors.New("没有可以发送该地区短信的服务商: +" + n.CountryCode)
+		}
+		if _, ok = groups[svc]; !ok {
+			order = append(order, svc)
+		}
+		groups[svc] = append(groups[svc], n.E164())
+	}
+	for _, svc := range order {
+		if err := svc.Send(ctx, tplId, args, groups[svc]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package region
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bedrock/internal/service/sms"
+	smsMocks "bedrock/internal/service/sms/mocks"
+	"bedrock/pkg/phone"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Send(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (domestic, fallback sms.Service)
+		numbers []string
+
+		wantErr error
+	}{
+		{
+			name: "按照国家码路由",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				domestic := smsMocks.NewMockService(ctrl)
+				intl := smsMocks.NewMockService(ctrl)
+				domestic.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+8613800138000", "+8613800138001").Return(nil)
+				intl.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+12025550123", "+447700900123").Return(nil)
+				return domestic, intl
+			},
+			numbers: []string{"+8613800138000", "+12025550123", "+8613800138001", "+447700900123"},
+		},
+		{
+			name: "路由之前规范化号码",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				domestic := smsMocks.NewMockService(ctrl)
+				intl := smsMocks.NewMockService(ctrl)
+				domestic.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+8613800138000").Return(nil)
+				intl.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+447700900123").Return(nil)
+				return domestic, intl
+			},
+			numbers: []string{"13800138000", "0044 07700 900123"},
+		},
+		{
+			name: "没有收录规则的地区走默认服务商",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				intl := smsMocks.NewMockService(ctrl)
+				intl.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+201012345678").Return(nil)
+				return smsMocks.NewMockService(ctrl), intl
+			},
+			numbers: []string{"+201012345678"},
+		},
+		{
+			name: "没有默认服务商",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				return smsMocks.NewMockService(ctrl), nil
+			},
+			numbers: []string{"+8613800138000", "+12025550123"},
+			wantErr: errors.New("没有可以发送该地区短信的服务商: +1"),
+		},
+		{
+			name: "非法号码不发送",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				return smsMocks.NewMockService(ctrl), smsMocks.NewMockService(ctrl)
+			},
+			numbers: []string{"+8613800138000", "123"},
+			wantErr: phone.ErrInvalidNumber,
+		},
+		{
+			name: "不存在的国家码",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				return smsMocks.NewMockService(ctrl), smsMocks.NewMockService(ctrl)
+			},
+			numbers: []string{"+99912345678"},
+			wantErr: phone.ErrUnknownCountryCode,
+		},
+		{
+			name: "服务商失败时不再发送后面的",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				domestic := smsMocks.NewMockService(ctrl)
+				domestic.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "+8613800138000").Return(errors.New("服务商错误"))
+				return domestic, smsMocks.NewMockService(ctrl)
+			},
+			numbers: []string{"+8613800138000", "+12025550123"},
+			wantErr: errors.New("服务商错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			domestic, fallback := tc.mock(ctrl)
+			svc := NewService(map[string]sms.Service{"86": domestic}, fallback)
+			err := svc.Send(context.Background(), "tpl", []string{"1"}, tc.numbers...)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
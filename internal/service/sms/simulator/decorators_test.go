@@ -0,0 +1,142 @@
+package simulator
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bedrock/internal/service/sms"
+	"bedrock/internal/service/sms/failover"
+	"bedrock/internal/service/sms/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecorators 把 failover、timeout、ratelimit 几个装饰器叠在注入了故障的模拟器上，端到端验证它们的行为
+//
+//	ratelimit -> timeout(threshold=1) -> [roundrobin(primary, secondary), backup]
+func TestDecorators(t *testing.T) {
+	t.Parallel()
+	const phone = "+8613800138000"
+	testCases := []struct {
+		name string
+		// primary/secondary/backup 三个服务商的故障配置
+		primary, secondary, backup Config
+		// allow 限流器放行的次数，< 0 表示不限流
+		allow int64
+		run   func(t *testing.T, s *stack)
+	}{
+		{
+			name:    "服务商出错时切换到下一个",
+			primary: Config{ErrorRate: 1},
+			allow:   -1,
+			run: func(t *testing.T, s *stack) {
+				// 轮询的起点每次都会变，不管从哪个开始，都落到正常的服务商
+				for i := 0; i < 4; i++ {
+					require.NoError(t, s.svc.Send(context.Background(), "tpl", []string{"123456"}, phone))
+				}
+				assert.Empty(t, s.primary.Inbox(phone))
+				assert.Len(t, s.secondary.Inbox(phone), 4)
+				assert.Empty(t, s.backup.Inbox(phone))
+			},
+		},
+		{
+			name:      "全部出错",
+			primary:   Config{ErrorRate: 1},
+			secondary: Config{ErrorRate: 1},
+			allow:     -1,
+			run: func(t *testing.T, s *stack) {
+				err := s.svc.Send(context.Background(), "tpl", []string{"123456"}, phone)
+				assert.Error(t, err)
+				// 不是超时，不会切换到备用服务商
+				err = s.svc.Send(context.Background(), "tpl", []string{"123456"}, phone)
+				assert.Error(t, err)
+				assert.Empty(t, s.backup.Inbox(phone))
+			},
+		},
+		{
+			name:      "连续超时切换到备用服务商",
+			primary:   Config{TimeoutRate: 1, Timeout: 10 * time.Millisecond},
+			secondary: Config{TimeoutRate: 1, Timeout: 10 * time.Millisecond},
+			allow:     -1,
+			run: func(t *testing.T, s *stack) {
+				// 超时不在轮询里重试，直接返回
+				err := s.svc.Send(context.Background(), "tpl", []string{"123456"}, phone)
+				assert.Equal(t, context.DeadlineExceeded, err)
+				// 达到阈值之后切换
+				require.NoError(t, s.svc.Send(context.Background(), "tpl", []string{"654321"}, phone))
+				code, ok := s.backup.LastCode(phone)
+				assert.True(t, ok)
+				assert.Equal(t, "654321", code)
+				// 之后一直使用备用服务商
+				require.NoError(t, s.svc.Send(context.Background(), "tpl", []string{"111111"}, phone))
+				assert.Len(t, s.backup.Inbox(phone), 2)
+			},
+		},
+		{
+			name:  "触发限流",
+			allow: 2,
+			run: func(t *testing.T, s *stack) {
+				for i := 0; i < 2; i++ {
+					require.NoError(t, s.svc.Send(context.Background(), "tpl", []string{"123456"}, phone))
+				}
+				err := s.svc.Send(context.Background(), "tpl", []string{"654321"}, phone)
+				assert.Error(t, err)
+				assert.Len(t, append(s.primary.Inbox(phone), s.secondary.Inbox(phone)...), 2)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.run(t, newStack(tc.primary, tc.secondary, tc.backup, tc.allow))
+		})
+	}
+}
+
+type stack struct {
+	primary, secondary, backup *Service
+	limiter                    *countLimiter
+	svc                        sms.Service
+}
+
+func newStack(primary, secondary, backup Config, allow int64) *stack {
+	s := &stack{
+		primary:   NewService(primary),
+		secondary: NewService(secondary),
+		backup:    NewService(backup),
+		limiter:   &countLimiter{},
+	}
+	s.limiter.allow.Store(allow)
+	s.svc = ratelimit.NewService(
+		failover.NewTimeoutService([]sms.Service{
+			failover.NewFailOverSMSService([]sms.Service{s.primary, s.secondary}),
+			s.backup,
+		}, 1),
+		s.limiter,
+	)
+	return s
+}
+
+// countLimiter 放行 allow 次之后一直限流，allow < 0 表示不限流
+type countLimiter struct {
+	allow atomic.Int64
+}
+
+func (c *countLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	for {
+		n := c.allow.Load()
+		switch {
+		case n < 0:
+			return false, nil
+		case n == 0:
+			return true, nil
+		case c.allow.CompareAndSwap(n, n-1):
+			return false, nil
+		}
+	}
+}
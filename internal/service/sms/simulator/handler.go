@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"bedrock/pkg/ginx"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 暴露模拟器收件箱的 HTTP 接口，只应该在开发和测试环境注册
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(e *gin.Engine) {
+	g := e.Group("/dev/sms")
+	g.GET("/inbox", ginx.Wrap(h.Inbox))
+	g.DELETE("/inbox", ginx.Wrap(h.Clear))
+}
+
+// Inbox GET /dev/sms/inbox?phone=13800138000
+func (h *Handler) Inbox(ctx *gin.Context) (ginx.Result, error) {
+	phone := ctx.Query("phone")
+	if phone == "" {
+		return ginx.Result{
+			Code: http.StatusBadRequest,
+			Msg:  "user.phone_required",
+		}, nil
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.query_ok",
+		Data: h.svc.Inbox(phone),
+	}, nil
+}
+
+// Clear DELETE /dev/sms/inbox?phone=13800138000，不传 phone 则清空全部
+func (h *Handler) Clear(ctx *gin.Context) (ginx.Result, error) {
+	h.svc.Clear(ctx.Query("phone"))
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "sms_inbox.cleared",
+	}, nil
+}
@@ -0,0 +1,167 @@
+package simulator
+
+import (
+	"bedrock/internal/service/sms"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInjected        = errors.New("模拟短信服务商发送失败")
+	errInvalidArgument = errors.New("模拟短信服务商参数错误")
+)
+
+var _ sms.Service = &Service{}
+
+// Config 故障注入配置，零值代表一个永远成功、没有延迟的服务商
+type Config struct {
+	// Latency 每次发送的固定延迟
+	Latency time.Duration `mapstructure:"latency"`
+	// ErrorRate 返回 ErrInjected 的概率，取值 [0, 1]
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// TimeoutRate 模拟超时的概率，取值 [0, 1]
+	// 命中之后会一直阻塞到 ctx 结束；如果 ctx 没有设置超时，则阻塞 Timeout（默认 3s）之后返回 context.DeadlineExceeded
+	TimeoutRate float64       `mapstructure:"timeout_rate"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	// Capacity 每个手机号最多保留的短信条数，<= 0 时默认 20
+	Capacity int `mapstructure:"capacity"`
+}
+
+// Message 一条"发送成功"的短信
+type Message struct {
+	TplId string    `json:"tplId"`
+	Args  []string  `json:"args"`
+	Phone string    `json:"phone"`
+	Ctime time.Time `json:"ctime"`
+}
+
+// Service 本地短信模拟器，用于开发环境和集成测试
+// 它不会真的发短信，而是把短信放进按手机号划分的收件箱里，方便查看和断言
+type Service struct {
+	mu    sync.RWMutex
+	cfg   Config
+	inbox map[string][]Message
+}
+
+func NewService(cfg Config) *Service {
+	return &Service{
+		cfg:   cfg,
+		inbox: make(map[string][]Message),
+	}
+}
+
+// SetConfig 在运行时调整故障注入配置，方便测试在不同用例之间切换
+func (s *Service) SetConfig(cfg Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	if len(numbers) == 0 {
+		return errInvalidArgument
+	}
+	s.mu.RLock()
+	cfg := s.cfg
+	s.mu.RUnlock()
+
+	if cfg.Latency > 0 {
+		select {
+		case <-time.After(cfg.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if hit(cfg.TimeoutRate) {
+		return s.timeout(ctx, cfg.Timeout)
+	}
+	if hit(cfg.ErrorRate) {
+		return ErrInjected
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, phone := range numbers {
+		msgs := append(s.inbox[phone], Message{
+			TplId: tplId,
+			Args:  append([]string(nil), args...),
+			Phone: phone,
+			Ctime: now,
+		})
+		if capacity := s.capacity(); len(msgs) > capacity {
+			msgs = msgs[len(msgs)-capacity:]
+		}
+		s.inbox[phone] = msgs
+	}
+	return nil
+}
+
+// Inbox 返回某个手机号收到的全部短信，按发送时间从早到晚排列
+func (s *Service) Inbox(phone string) []Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Message(nil), s.inbox[phone]...)
+}
+
+// Last 返回某个手机号收到的最后一条短信
+func (s *Service) Last(phone string) (Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	msgs := s.inbox[phone]
+	if len(msgs) == 0 {
+		return Message{}, false
+	}
+	return msgs[len(msgs)-1], true
+}
+
+// LastCode 返回某个手机号收到的最后一个验证码
+// 验证码类短信约定第一个参数就是验证码
+func (s *Service) LastCode(phone string) (string, bool) {
+	msg, ok := s.Last(phone)
+	if !ok || len(msg.Args) == 0 {
+		return "", false
+	}
+	return msg.Args[0], true
+}
+
+// Clear 清空某个手机号的收件箱，phone 为空时清空所有
+func (s *Service) Clear(phone string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if phone == "" {
+		s.inbox = make(map[string][]Message)
+		return
+	}
+	delete(s.inbox, phone)
+}
+
+func (s *Service) timeout(ctx context.Context, d time.Duration) error {
+	if _, ok := ctx.Deadline(); ok {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if d <= 0 {
+		d = 3 * time.Second
+	}
+	select {
+	case <-time.After(d):
+		return context.DeadlineExceeded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) capacity() int {
+	if s.cfg.Capacity <= 0 {
+		return 20
+	}
+	return s.cfg.Capacity
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
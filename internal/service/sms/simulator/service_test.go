@@ -0,0 +1,252 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Send(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		cfg     Config
+		numbers []string
+		// timeout 为 0 表示 ctx 不设置超时
+		timeout time.Duration
+
+		wantErr error
+		// wantMin/wantMax 发送耗时的范围，wantMax 为 0 表示不检查
+		wantMin   time.Duration
+		wantMax   time.Duration
+		wantInbox bool
+	}{
+		{
+			name:      "没有故障",
+			numbers:   []string{"+8613800138000", "+8613800138001"},
+			wantInbox: true,
+		},
+		{
+			name:      "注入延迟",
+			cfg:       Config{Latency: 30 * time.Millisecond},
+			numbers:   []string{"+8613800138000"},
+			wantMin:   30 * time.Millisecond,
+			wantInbox: true,
+		},
+		{
+			name:    "延迟超过 ctx 的超时",
+			cfg:     Config{Latency: time.Second},
+			numbers: []string{"+8613800138000"},
+			timeout: 20 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+			wantMax: 500 * time.Millisecond,
+		},
+		{
+			name:    "注入错误",
+			cfg:     Config{ErrorRate: 1},
+			numbers: []string{"+8613800138000"},
+			wantErr: ErrInjected,
+		},
+		{
+			name:    "注入超时，阻塞到 ctx 超时",
+			cfg:     Config{TimeoutRate: 1, Timeout: time.Second},
+			numbers: []string{"+8613800138000"},
+			timeout: 20 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+			wantMin: 20 * time.Millisecond,
+			wantMax: 500 * time.Millisecond,
+		},
+		{
+			name:    "注入超时，ctx 没有超时的时候阻塞 Timeout",
+			cfg:     Config{TimeoutRate: 1, Timeout: 30 * time.Millisecond},
+			numbers: []string{"+8613800138000"},
+			wantErr: context.DeadlineExceeded,
+			wantMin: 30 * time.Millisecond,
+			wantMax: 500 * time.Millisecond,
+		},
+		{
+			name:    "超时优先于错误",
+			cfg:     Config{TimeoutRate: 1, ErrorRate: 1, Timeout: 10 * time.Millisecond},
+			numbers: []string{"+8613800138000"},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:    "没有号码",
+			wantErr: errInvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			svc := NewService(tc.cfg)
+			start := time.Now()
+			err := svc.Send(ctx, "tpl", []string{"123456"}, tc.numbers...)
+			elapsed := time.Since(start)
+			assert.Equal(t, tc.wantErr, err)
+			assert.GreaterOrEqual(t, elapsed, tc.wantMin)
+			if tc.wantMax > 0 {
+				assert.Less(t, elapsed, tc.wantMax)
+			}
+			for _, phone := range tc.numbers {
+				code, ok := svc.LastCode(phone)
+				assert.Equal(t, tc.wantInbox, ok, phone)
+				if tc.wantInbox {
+					assert.Equal(t, "123456", code)
+				}
+			}
+		})
+	}
+}
+
+// TestService_ErrorRate 错误率是概率，只检查大致的比例
+func TestService_ErrorRate(t *testing.T) {
+	t.Parallel()
+	svc := NewService(Config{ErrorRate: 0.5})
+	var failed int
+	for i := 0; i < 1000; i++ {
+		if svc.Send(context.Background(), "tpl", nil, "+8613800138000") != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 500, failed, 100)
+	// 失败的没有进收件箱，成功的超出了默认容量
+	assert.Len(t, svc.Inbox("+8613800138000"), 20)
+}
+
+func TestService_Inbox(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		capacity int
+		sent     int
+
+		wantCodes []string
+	}{
+		{
+			name:      "没有超出容量",
+			capacity:  3,
+			sent:      2,
+			wantCodes: []string{"0", "1"},
+		},
+		{
+			name:      "超出容量时丢弃最早的",
+			capacity:  3,
+			sent:      5,
+			wantCodes: []string{"2", "3", "4"},
+		},
+		{
+			name:      "默认容量",
+			sent:      25,
+			wantCodes: []string{"5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20", "21", "22", "23", "24"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			svc := NewService(Config{Capacity: tc.capacity})
+			for i := 0; i < tc.sent; i++ {
+				assert.NoError(t, svc.Send(context.Background(), "tpl", []string{fmt.Sprint(i)}, "+8613800138000"))
+			}
+			inbox := svc.Inbox("+8613800138000")
+			codes := make([]string, 0, len(inbox))
+			for _, msg := range inbox {
+				assert.Equal(t, "tpl", msg.TplId)
+				assert.Equal(t, "+8613800138000", msg.Phone)
+				codes = append(codes, msg.Args[0])
+			}
+			assert.Equal(t, tc.wantCodes, codes)
+			// 其他号码的收件箱不受影响
+			assert.Empty(t, svc.Inbox("+8613800138001"))
+		})
+	}
+}
+
+func TestService_LastCode(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		send  func(svc *Service)
+		phone string
+
+		wantCode string
+		wantOk   bool
+	}{
+		{
+			name: "最后一条短信的第一个参数",
+			send: func(svc *Service) {
+				_ = svc.Send(context.Background(), "login", []string{"111111", "5"}, "+8613800138000")
+				_ = svc.Send(context.Background(), "login", []string{"222222", "5"}, "+8613800138000")
+			},
+			phone:    "+8613800138000",
+			wantCode: "222222",
+			wantOk:   true,
+		},
+		{
+			name: "没有收到过短信",
+			send: func(svc *Service) {
+				_ = svc.Send(context.Background(), "login", []string{"111111"}, "+8613800138000")
+			},
+			phone: "+8613800138001",
+		},
+		{
+			name: "最后一条短信没有参数",
+			send: func(svc *Service) {
+				_ = svc.Send(context.Background(), "login", []string{"111111"}, "+8613800138000")
+				_ = svc.Send(context.Background(), "notice", nil, "+8613800138000")
+			},
+			phone: "+8613800138000",
+		},
+		{
+			name: "清空之后",
+			send: func(svc *Service) {
+				_ = svc.Send(context.Background(), "login", []string{"111111"}, "+8613800138000")
+				svc.Clear("+8613800138000")
+			},
+			phone: "+8613800138000",
+		},
+		{
+			name: "全部清空之后",
+			send: func(svc *Service) {
+				_ = svc.Send(context.Background(), "login", []string{"111111"}, "+8613800138000")
+				svc.Clear("")
+			},
+			phone: "+8613800138000",
+		},
+		{
+			name: "发送之后修改参数不影响收件箱",
+			send: func(svc *Service) {
+				args := []string{"111111"}
+				_ = svc.Send(context.Background(), "login", args, "+8613800138000")
+				args[0] = "222222"
+			},
+			phone:    "+8613800138000",
+			wantCode: "111111",
+			wantOk:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			svc := NewService(Config{})
+			tc.send(svc)
+			code, ok := svc.LastCode(tc.phone)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantCode, code)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package throttle
+
+import (
+	"bedrock/internal/service/sms"
+	"context"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+var _ sms.Service = &Service{}
+
+// Service 按照号码数量控制发送速率
+// 和 ratelimit 不同，触发限流时不会直接返回错误，而是阻塞等待令牌，
+// 适合群发这种不在乎延迟、但要控制整体吞吐的场景
+type Service struct {
+	// 被装饰的
+	svc     sms.Service
+	limiter *rate.Limiter
+}
+
+// NewService perSecond 每秒最多发送的号码数，burst 允许的突发号码数
+func NewService(svc sms.Service, perSecond float64, burst int) sms.Service {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &Service{
+		svc:     svc,
+		limiter: rate.NewLimiter(rate.Limit(perSecond), burst),
+	}
+}
+
+// Send 等待令牌期间 ctx 结束，或者等到令牌会超过 ctx 的截止时间时，返回的错误都是 context.DeadlineExceeded
+// 或者 context.Canceled，调用方据此判断短信没有发出去
+func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	// WaitN 要求 n 不能超过 burst，号码太多时分几次取令牌
+	for remain := len(numbers); remain > 0; {
+		n := min(remain, s.limiter.Burst())
+		if err := s.limiter.WaitN(ctx, n); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			// 令牌不够、等下去会超过截止时间，WaitN 提前返回，这时候 ctx 还没有结束
+			return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+		}
+		remain -= n
+	}
+	return s.svc.Send(ctx, tplId, args, numbers...)
+}
@@ -0,0 +1,116 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bedrock/internal/service/sms"
+	smsMocks "bedrock/internal/service/sms/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Send(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name      string
+		mock      func(ctrl *gomock.Controller) sms.Service
+		perSecond float64
+		burst     int
+		numbers   []string
+		// timeout 为 0 表示不设置超时
+		timeout time.Duration
+
+		wantErr error
+		// wantMin 发送至少要等待的时间，用来确认确实限速了
+		wantMin time.Duration
+	}{
+		{
+			name: "突发范围内不等待",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a", "b", "c").Return(nil)
+				return svc
+			},
+			perSecond: 1,
+			burst:     3,
+			numbers:   []string{"a", "b", "c"},
+		},
+		{
+			name: "号码数超过突发时分几次取令牌",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a", "b", "c", "d", "e").Return(nil)
+				return svc
+			},
+			// 第一次取 2 个是现成的，剩下的 3 个号码按照每秒 100 个要等 30ms
+			perSecond: 100,
+			burst:     2,
+			numbers:   []string{"a", "b", "c", "d", "e"},
+			wantMin:   20 * time.Millisecond,
+		},
+		{
+			name: "等不到令牌时超时返回",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				return smsMocks.NewMockService(ctrl)
+			},
+			// 第二个号码要等 1 秒，超过了 ctx 的截止时间
+			perSecond: 1,
+			burst:     1,
+			numbers:   []string{"a", "b"},
+			timeout:   50 * time.Millisecond,
+			wantErr:   context.DeadlineExceeded,
+		},
+		{
+			name: "被装饰的服务出错",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a").Return(errors.New("服务商错误"))
+				return svc
+			},
+			perSecond: 1,
+			burst:     1,
+			numbers:   []string{"a"},
+			wantErr:   errors.New("服务商错误"),
+		},
+		{
+			name: "突发小于等于零时按 1 处理",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsMocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "tpl", []string{"1"}, "a", "b").Return(nil)
+				return svc
+			},
+			perSecond: 100,
+			burst:     0,
+			numbers:   []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			svc := NewService(tc.mock(ctrl), tc.perSecond, tc.burst)
+			start := time.Now()
+			err := svc.Send(ctx, "tpl", []string{"1"}, tc.numbers...)
+			if tc.wantErr == context.DeadlineExceeded {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.Equal(t, tc.wantErr, err)
+			}
+			assert.GreaterOrEqual(t, time.Since(start), tc.wantMin)
+		})
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"bedrock/pkg/logger"
 	"context"
 	"errors"
-	"os"
-	"path/filepath"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -21,7 +19,8 @@ var (
 type UserService interface {
 	Signup(ctx context.Context, user domain.User) error
 	Login(ctx context.Context, email string, password string) (domain.User, error)
-	UpdateAvatarPath(ctx context.Context, uid int64, newPath string) error
+	// UpdateAvatarPath 更新头像和各个尺寸的缩略图，newPath 和 variants 里存的都是存储的 key
+	UpdateAvatarPath(ctx context.Context, uid int64, newPath string, variants []domain.AvatarVariant) error
 	UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error
 	FindById(ctx context.Context, uid int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
@@ -68,39 +67,9 @@ func (svc *DefaultUserService) Login(ctx context.Context, email string, password
 	return u, nil
 }
 
-// UpdateAvatarPath 只负责业务逻辑：更新数据库和删除旧文件
-func (svc *DefaultUserService) UpdateAvatarPath(ctx context.Context, uid int64, newPath string) error {
-	// 1. 获取旧头像路径
-	oldUser, err := svc.repo.FindById(ctx, uid)
-	if err != nil {
-		return err // 获取用户信息失败
-	}
-	oldAvatarPath := oldUser.Avatar
-
-	// 2. 更新数据库为新路径
-	err = svc.repo.UpdateAvatar(ctx, uid, newPath)
-	if err != nil {
-		// 数据库更新失败，直接返回错误。
-		// 新文件的清理工作应该由调用方（Handler）负责。
-		return err
-	}
-
-	// 3. 数据库更新成功后，删除旧的头像文件
-	if oldAvatarPath != "" && oldAvatarPath != "path/to/default/avatar.png" { // 避免删除默认头像
-		// 使用绝对路径进行删除，更安全
-		absOldPath, err := filepath.Abs(oldAvatarPath)
-		if err != nil {
-			// 转换路径失败，只记录日志，不影响主流程
-			svc.l.Warn(ctx, "转换旧头像为绝对路径失败", logger.Error(err), logger.String("old_avatar_path", oldAvatarPath))
-		} else {
-			if err := os.Remove(absOldPath); err != nil {
-				// 删除旧文件失败是一个可以容忍的错误，记录日志即可
-				svc.l.Warn(ctx, "数据库更新成功,但删除旧头像失败", logger.Error(err), logger.String("old_avatar_path", absOldPath))
-			}
-		}
-	}
-
-	return nil
+// UpdateAvatarPath 只负责更新数据库，存储里的文件（包括删除旧头像）由 AvatarService 负责
+func (svc *DefaultUserService) UpdateAvatarPath(ctx context.Context, uid int64, newPath string, variants []domain.AvatarVariant) error {
+	return svc.repo.UpdateAvatar(ctx, uid, newPath, variants)
 }
 
 func (svc *DefaultUserService) UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error {
@@ -294,41 +294,22 @@ func TestUserService_UpdateAvatarPath(t *testing.T) {
 			name: "success",
 			mock: func(ctrl *gomock.Controller) repository.UserRepository {
 				repo := mocks.NewMockUserRepository(ctrl)
-				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
-					ID:     1,
-					Avatar: "old/path/avatar.jpg",
-				}, nil)
-				repo.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "new/path/avatar.jpg").Return(nil)
+				repo.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatars/1/a_512.jpg", gomock.Nil()).Return(nil)
 				return repo
 			},
 			uid:     1,
-			newPath: "new/path/avatar.jpg",
+			newPath: "avatars/1/a_512.jpg",
 			wantErr: nil,
 		},
-		{
-			name: "find user error",
-			mock: func(ctrl *gomock.Controller) repository.UserRepository {
-				repo := mocks.NewMockUserRepository(ctrl)
-				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{}, errors.New("db error"))
-				return repo
-			},
-			uid:     1,
-			newPath: "new/path/avatar.jpg",
-			wantErr: errors.New("db error"),
-		},
 		{
 			name: "update avatar error",
 			mock: func(ctrl *gomock.Controller) repository.UserRepository {
 				repo := mocks.NewMockUserRepository(ctrl)
-				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
-					ID:     1,
-					Avatar: "old/path/avatar.jpg",
-				}, nil)
-				repo.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "new/path/avatar.jpg").Return(errors.New("update error"))
+				repo.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatars/1/a_512.jpg", gomock.Nil()).Return(errors.New("update error"))
 				return repo
 			},
 			uid:     1,
-			newPath: "new/path/avatar.jpg",
+			newPath: "avatars/1/a_512.jpg",
 			wantErr: errors.New("update error"),
 		},
 	}
@@ -342,7 +323,7 @@ func TestUserService_UpdateAvatarPath(t *testing.T) {
 
 			repo := tc.mock(ctrl)
 			svc := NewUserService(logger.NewNopLogger(), repo)
-			err := svc.UpdateAvatarPath(context.Background(), tc.uid, tc.newPath)
+			err := svc.UpdateAvatarPath(context.Background(), tc.uid, tc.newPath, nil)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"bedrock/internal/service/voice"
+	"context"
+	"sync"
+)
+
+var _ voice.Service = &Service{}
+
+// Call 一次"拨出"的语音电话
+type Call struct {
+	TplId  string
+	Args   []string
+	Number string
+}
+
+// Service 内存版的语音服务，用于开发和测试，可以注入错误来模拟呼叫失败
+type Service struct {
+	mu    sync.Mutex
+	err   error
+	calls []Call
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// SetErr 之后的呼叫都返回 err，传 nil 恢复正常
+func (s *Service) SetErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *Service) Call(ctx context.Context, tplId string, args []string, number string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.calls = append(s.calls, Call{TplId: tplId, Args: args, Number: number})
+	return nil
+}
+
+// Calls 返回所有成功的呼叫
+func (s *Service) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
@@ -0,0 +1,10 @@
+package voice
+
+import "context"
+
+// Service 语音通知服务，通过电话把验证码念给用户
+//
+//go:generate mockgen -source=./type.go -package=mocks -destination=./mocks/voice_mock.go Service
+type Service interface {
+	Call(ctx context.Context, tplId string, args []string, number string) error
+}
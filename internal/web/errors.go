@@ -0,0 +1,67 @@
+package web
+
+import (
+	"bedrock/internal/service"
+	"bedrock/internal/web/errs"
+	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx"
+	"net/http"
+)
+
+// 业务逻辑返回的错误到响应的映射，handler 直接返回 service 的错误，由 ginx.Wrap* 统一转换
+// 各种 NotFound 都是 gorm.ErrRecordNotFound 的别名，注册了就分不清是哪种记录不存在，所以由 handler 自己返回对应的 *ginx.Error
+var (
+	errUserDuplicateEmail    = ginx.NewError(errs.UserDuplicateEmail, http.StatusConflict, "user.duplicate_email")
+	errUserInvalidOrPassword = ginx.NewError(errs.UserInvalidOrPassword, http.StatusUnauthorized, "user.invalid_credentials")
+	errUserCodeSendTooMany   = ginx.NewError(errs.UserCodeSendTooMany, http.StatusTooManyRequests, "code.send_too_many")
+	errUserCodeDailyLimit    = ginx.NewError(errs.UserCodeSendTooMany, http.StatusTooManyRequests, "code.daily_limit")
+	errUserCodeChannel       = ginx.NewError(errs.UserInvalidInput, http.StatusBadRequest, "code.channel_unsupported")
+	errUserCodeVerifyTooMany = ginx.NewError(errs.UserCodeVerifyTooMany, http.StatusTooManyRequests, "code.verify_too_many")
+	errUserCodeExpired       = ginx.NewError(errs.UserCodeExpired, http.StatusBadRequest, "code.expired")
+	errUserSessionExpired    = ginx.NewError(http.StatusUnauthorized, http.StatusUnauthorized, "user.session_expired")
+	errAvatarTooLarge        = ginx.NewError(errs.UserInvalidInput, http.StatusRequestEntityTooLarge, "avatar.too_large")
+	errAvatarResolution      = ginx.NewError(errs.UserInvalidInput, http.StatusBadRequest, "avatar.resolution")
+	errAvatarInvalid         = ginx.NewError(errs.UserInvalidInput, http.StatusUnsupportedMediaType, "avatar.invalid")
+	errAvatarRejected        = ginx.NewError(errs.UserInvalidInput, http.StatusUnprocessableEntity, "avatar.rejected")
+
+	errNotificationNoRecipients = ginx.NewError(errs.NotificationInvalidInput, http.StatusBadRequest, "notification.no_recipients")
+	errNotificationTimezone     = ginx.NewError(errs.NotificationInvalidInput, http.StatusBadRequest, "notification.invalid_timezone")
+	errCampaignNotFound         = ginx.NewError(errs.NotificationCampaignNotFound, http.StatusNotFound, "notification.campaign_not_found")
+	errNotificationForbidden    = ginx.NewError(errs.NotificationPermissionDenied, http.StatusForbidden, "common.permission_denied")
+
+	errFileNotFound      = ginx.NewError(errs.FileNotFound, http.StatusNotFound, "file.not_found")
+	errFileForbidden     = ginx.NewError(errs.FilePermissionDenied, http.StatusForbidden, "common.permission_denied")
+	errFileTooLarge      = ginx.NewError(errs.FileTooLarge, http.StatusRequestEntityTooLarge, "file.too_large")
+	errFileQuotaExceeded = ginx.NewError(errs.FileQuotaExceeded, http.StatusForbidden, "file.quota_exceeded")
+	errFileRejected      = ginx.NewError(errs.FileRejected, http.StatusUnprocessableEntity, "file.rejected")
+	errFileInUse         = ginx.NewError(errs.FileInUse, http.StatusConflict, "file.in_use")
+
+	errModerationNotFound = ginx.NewError(errs.ModerationNotFound, http.StatusNotFound, "moderation.not_found")
+	errModerationHandled  = ginx.NewError(errs.ModerationHandled, http.StatusConflict, "moderation.handled")
+)
+
+func init() {
+	ginx.RegisterError(service.ErrDuplicateEmail, errUserDuplicateEmail)
+	ginx.RegisterError(service.ErrInvalidUserOrPassword, errUserInvalidOrPassword)
+	ginx.RegisterError(service.ErrCodeSendTooMany, errUserCodeSendTooMany)
+	ginx.RegisterError(service.ErrCodeDailyLimit, errUserCodeDailyLimit)
+	ginx.RegisterError(service.ErrCodeChannelUnsupported, errUserCodeChannel)
+	ginx.RegisterError(service.ErrCodeVerifyTooMany, errUserCodeVerifyTooMany)
+	ginx.RegisterError(service.ErrCodeExpired, errUserCodeExpired)
+	ginx.RegisterError(jwtware.ErrSessionNotFound, errUserSessionExpired)
+	ginx.RegisterError(service.ErrAvatarTooLarge, errAvatarTooLarge)
+	ginx.RegisterError(service.ErrAvatarResolution, errAvatarResolution)
+	ginx.RegisterError(service.ErrAvatarInvalid, errAvatarInvalid)
+	ginx.RegisterError(service.ErrAvatarRejected, errAvatarRejected)
+
+	ginx.RegisterError(service.ErrNoValidRecipients, errNotificationNoRecipients)
+	ginx.RegisterError(service.ErrInvalidTimezone, errNotificationTimezone)
+
+	ginx.RegisterError(service.ErrFileForbidden, errFileForbidden)
+	ginx.RegisterError(service.ErrFileTooLarge, errFileTooLarge)
+	ginx.RegisterError(service.ErrQuotaExceeded, errFileQuotaExceeded)
+	ginx.RegisterError(service.ErrFileRejected, errFileRejected)
+	ginx.RegisterError(service.ErrFileInUse, errFileInUse)
+
+	ginx.RegisterError(service.ErrModerationHandled, errModerationHandled)
+}
@@ -0,0 +1,21 @@
+package errs
+
+// File 部分，模块代码使用 04
+const (
+	// FileInvalidInput 文件相关的 API 参数不对
+	FileInvalidInput = 404001
+	// FileInternalServerError 文件模块系统内部错误
+	FileInternalServerError = 504001
+	// FileNotFound 文件不存在
+	FileNotFound = 404002
+	// FilePermissionDenied 没有权限访问文件
+	FilePermissionDenied = 404003
+	// FileTooLarge 文件太大
+	FileTooLarge = 404004
+	// FileQuotaExceeded 存储空间不足
+	FileQuotaExceeded = 404005
+	// FileInUse 文件正在被引用，不能删除
+	FileInUse = 404006
+	// FileRejected 文件没有通过审核
+	FileRejected = 404007
+)
@@ -0,0 +1,15 @@
+package errs
+
+// Moderation 部分，模块代码使用 05
+const (
+	// ModerationInvalidInput 审核相关的 API 参数不对
+	ModerationInvalidInput = 405001
+	// ModerationInternalServerError 审核模块系统内部错误
+	ModerationInternalServerError = 505001
+	// ModerationPermissionDenied 没有人工审核的权限
+	ModerationPermissionDenied = 405002
+	// ModerationNotFound 审核记录不存在
+	ModerationNotFound = 405003
+	// ModerationHandled 审核记录已经处理过了
+	ModerationHandled = 405004
+)
@@ -0,0 +1,15 @@
+package errs
+
+// Notification 部分，模块代码使用 03
+const (
+	// NotificationInvalidInput 通知相关的 API 参数不对
+	NotificationInvalidInput = 403001
+	// NotificationInternalServerError 通知模块系统内部错误
+	NotificationInternalServerError = 503001
+	// NotificationPermissionDenied 没有创建群发活动的权限
+	NotificationPermissionDenied = 403002
+	// NotificationCampaignNotFound 群发活动不存在
+	NotificationCampaignNotFound = 403003
+	// NotificationPhoneUnbound 用户还没有绑定手机号
+	NotificationPhoneUnbound = 403004
+)
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/service"
+	"bedrock/internal/web/errs"
+	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/openapi"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var _ Handler = (*FileHandler)(nil)
+
+type FileHandler struct {
+	svc service.FileService
+}
+
+func NewFileHandler(svc service.FileService) *FileHandler {
+	return &FileHandler{
+		svc: svc,
+	}
+}
+
+func (h *FileHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/files")
+	openapi.WrapClaims(g, http.MethodPost, "/upload", h.Upload, openapi.Summary("上传文件"),
+		openapi.FormFile("file"), openapi.FormValue[bool]("public"),
+		openapi.FormValue[string]("biz"), openapi.FormValue[int64]("bizId"), openapi.Data[FileVO]())
+	openapi.WrapClaims(g, http.MethodGet, "/usage", h.Usage, openapi.Summary("存储空间用量"), openapi.Data[FileUsageVO]())
+	openapi.WrapClaims(g, http.MethodGet, "/:id", h.Detail, openapi.Summary("文件详情"), openapi.Data[FileVO]())
+	openapi.WrapClaims(g, http.MethodDelete, "/:id", h.Delete, openapi.Summary("删除文件"))
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/:id/attach", h.Attach, openapi.Summary("引用文件"),
+		openapi.Data[FileVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/:id/detach", h.Detach, openapi.Summary("取消引用文件"))
+}
+
+type FileVO struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Public   bool   `json:"public"`
+	// Status 审核状态：quarantined 审核中，只有上传者自己可以访问；approved 审核通过
+	Status string `json:"status"`
+	// URL 公开文件是固定地址，私有文件是有效期很短的签名地址，不要保存下来
+	URL   string `json:"url"`
+	Ctime string `json:"ctime"`
+}
+
+// Upload 上传文件，表单字段 file 是文件，public 为 true 时所有人都可以访问
+// 上传之后需要被其他业务引用，否则过一段时间会被清理；带上 biz 和 bizId 时上传成功直接引用
+func (h *FileHandler) Upload(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInvalidInput,
+			Msg:  "file.required",
+		}, nil
+	}
+	public, _ := strconv.ParseBool(ctx.PostForm("public"))
+	var ref *domain.FileRef
+	if biz := ctx.PostForm("biz"); biz != "" {
+		bizID, err := strconv.ParseInt(ctx.PostForm("bizId"), 10, 64)
+		if err != nil || bizID <= 0 || len(biz) > 64 {
+			return ginx.Result{
+				Code: errs.FileInvalidInput,
+				Msg:  "file.invalid_ref",
+			}, nil
+		}
+		ref = &domain.FileRef{Biz: biz, BizID: bizID}
+	}
+	r, err := file.Open()
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	defer r.Close()
+
+	f, err := h.svc.Upload(ctx.Request.Context(), uc.Uid, file.Filename, r, public)
+	if err != nil {
+		return h.errResult(err)
+	}
+	if ref != nil {
+		// 引用失败时文件没有被引用，过一段时间会被清理，客户端重新上传即可
+		if f, err = h.svc.Attach(ctx.Request.Context(), uc.Uid, f.ID, *ref); err != nil {
+			return h.errResult(err)
+		}
+	}
+	return h.fileResult(ctx.Request.Context(), uc.Uid, f, "file.upload_ok")
+}
+
+type FileUsageVO struct {
+	Used int64 `json:"used"`
+	// Quota 为 0 表示不限制
+	Quota int64 `json:"quota"`
+}
+
+func (h *FileHandler) Usage(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	u, err := h.svc.Usage(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.query_ok",
+		Data: FileUsageVO{
+			Used:  u.Used,
+			Quota: u.Quota,
+		},
+	}, nil
+}
+
+func (h *FileHandler) Detail(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInvalidInput,
+			Msg:  "file.invalid_id",
+		}, nil
+	}
+	f, err := h.svc.Get(ctx.Request.Context(), uc.Uid, id)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return h.fileResult(ctx.Request.Context(), uc.Uid, f, "common.query_ok")
+}
+
+func (h *FileHandler) Delete(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInvalidInput,
+			Msg:  "file.invalid_id",
+		}, nil
+	}
+	if err = h.svc.Delete(ctx.Request.Context(), uc.Uid, id); err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.deleted",
+	}, nil
+}
+
+// FileRefReq 引用文件的实体，例如 {"biz": "post", "bizId": 帖子 ID}
+type FileRefReq struct {
+	Biz   string `json:"biz" binding:"required,max=64"`
+	BizId int64  `json:"bizId" binding:"required,min=1"`
+}
+
+// Attach 引用自己的文件，同一个实体重复引用只算一次
+func (h *FileHandler) Attach(ctx *gin.Context, req FileRefReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInvalidInput,
+			Msg:  "file.invalid_id",
+		}, nil
+	}
+	f, err := h.svc.Attach(ctx.Request.Context(), uc.Uid, id, domain.FileRef{Biz: req.Biz, BizID: req.BizId})
+	if err != nil {
+		return h.errResult(err)
+	}
+	return h.fileResult(ctx.Request.Context(), uc.Uid, f, "file.attached")
+}
+
+// Detach 取消引用，所有引用都取消之后文件过一段时间会被清理
+func (h *FileHandler) Detach(ctx *gin.Context, req FileRefReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.FileInvalidInput,
+			Msg:  "file.invalid_id",
+		}, nil
+	}
+	if err = h.svc.Detach(ctx.Request.Context(), uc.Uid, id, domain.FileRef{Biz: req.Biz, BizID: req.BizId}); err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "file.detached",
+	}, nil
+}
+
+func (h *FileHandler) fileResult(ctx context.Context, uid int64, f domain.File, msg string) (ginx.Result, error) {
+	url, err := h.svc.URL(ctx, uid, f)
+	if err != nil {
+		return h.errResult(err)
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  msg,
+		Data: FileVO{
+			ID:       f.ID,
+			Name:     f.Name,
+			Size:     f.Size,
+			MimeType: f.MimeType,
+			Public:   f.Public,
+			Status:   f.Status.String(),
+			URL:      url,
+			Ctime:    f.Ctime.Format(time.DateTime),
+		},
+	}, nil
+}
+
+// errResult 业务错误由 ginx 按照注册的映射转换，这里只处理不存在和未知错误
+// 没有权限和不存在区分开，方便前端提示，私有文件的 ID 是自增的，本来也猜得到
+func (h *FileHandler) errResult(err error) (ginx.Result, error) {
+	if errors.Is(err, service.ErrFileNotFound) {
+		return ginx.Result{}, errFileNotFound.Wrap(err)
+	}
+	return ginx.Result{
+		Code: errs.FileInternalServerError,
+		Msg:  "common.internal_error",
+	}, err
+}
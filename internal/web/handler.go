@@ -1,9 +1,19 @@
 package web
 
 import (
+	"bedrock/pkg/ginx/openapi"
+
 	"github.com/gin-gonic/gin"
 )
 
+// Handler g 是 API 版本的分组，例如 /api/v1，同一个接口的新旧版本分别实现 Handler 注册到各自的版本
 type Handler interface {
-	RegisterRoutes(e *gin.Engine)
+	RegisterRoutes(g *gin.RouterGroup)
+}
+
+// OpenAPIInfo /openapi.json 里的接口文档信息
+var OpenAPIInfo = openapi.Info{
+	Title:       "bedrock",
+	Version:     "1.0.0",
+	Description: "msg 按照请求头 Accept-Language 翻译，需要登录的接口在 Authorization 头部带上 Bearer token",
 }
@@ -0,0 +1,22 @@
+// Package locales 接口消息的翻译，每个语言一个 <语言>.yaml 文件，第一层是模块，第二层是消息
+package locales
+
+import (
+	"bedrock/pkg/i18n"
+	"embed"
+)
+
+//go:embed *.yaml
+var FS embed.FS
+
+// Default 默认语言，请求的语言都不支持时使用
+const Default = "zh"
+
+// NewBundle 加载内置的翻译
+func NewBundle(fallback string) (*i18n.Bundle, error) {
+	b := i18n.NewBundle(fallback)
+	if err := b.LoadFS(FS); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,75 @@
+package locales
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCatalogsComplete 每个语言都要有所有的 key
+func TestCatalogsComplete(t *testing.T) {
+	t.Parallel()
+	b, err := NewBundle(Default)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"zh", "en"}, b.Langs())
+	assert.Empty(t, b.Missing())
+}
+
+// TestHandlerKeysExist handler 里用到的消息 key 都要在目录里
+func TestHandlerKeysExist(t *testing.T) {
+	t.Parallel()
+	b, err := NewBundle(Default)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob("../*.go")
+	require.NoError(t, err)
+	files = append(files, "../../service/sms/simulator/handler.go")
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+		for _, key := range msgKeys(f) {
+			for _, lang := range b.Langs() {
+				assert.Truef(t, b.Has(lang, key), "%s: %s 缺少 %s", file, lang, key)
+			}
+		}
+	}
+}
+
+// msgKeys 找出 Result{Msg: "..."}、ginx.NewError(code, status, "...") 里的字符串常量
+func msgKeys(f *ast.File) []string {
+	var keys []string
+	add := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		if key, err := strconv.Unquote(lit.Value); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.KeyValueExpr:
+			if ident, ok := node.Key.(*ast.Ident); ok && ident.Name == "Msg" {
+				add(node.Value)
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "NewError" && len(node.Args) == 3 {
+				add(node.Args[2])
+			}
+		}
+		return true
+	})
+	return keys
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx/router"
 	"net/http"
 	"time"
 
@@ -25,6 +26,7 @@ func NewJWTAuth(hdl jwtware.Handler) *JWTAuth {
 	s.Add("/oauth2/wechat/authurl")
 	s.Add("/oauth2/wechat/callback")
 	s.Add("/test/random")
+	s.Add("/dev/sms/inbox")
 	return &JWTAuth{
 		publicPaths: s,
 		hdl:         hdl,
@@ -32,8 +34,8 @@ func NewJWTAuth(hdl jwtware.Handler) *JWTAuth {
 }
 func (j *JWTAuth) Middleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// 不需要校验
-		if j.publicPaths.Exist(ctx.Request.URL.Path) {
+		// 不需要校验，路径不带版本前缀，所有版本共用
+		if j.publicPaths.Exist(router.Path(ctx)) {
 			return
 		}
 		// 如果是空字符串，你可以预期后面 Parse 就会报错
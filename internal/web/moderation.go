@@ -0,0 +1,160 @@
+package web
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/service"
+	"bedrock/internal/web/errs"
+	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/openapi"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var _ Handler = (*ModerationHandler)(nil)
+
+// ModerationHandler 人工审核队列，只有管理员可以访问
+type ModerationHandler struct {
+	svc service.ModerationService
+	// admins 允许人工审核的用户
+	admins map[int64]struct{}
+}
+
+func NewModerationHandler(svc service.ModerationService, admins []int64) *ModerationHandler {
+	m := make(map[int64]struct{}, len(admins))
+	for _, uid := range admins {
+		m[uid] = struct{}{}
+	}
+	return &ModerationHandler{
+		svc:    svc,
+		admins: m,
+	}
+}
+
+func (h *ModerationHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/moderations")
+	openapi.WrapClaims(g, http.MethodGet, "", h.List, openapi.Summary("等待人工审核的记录"),
+		openapi.Query[int64]("after"), openapi.Query[int]("limit"), openapi.Data[[]ModerationVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/:id/review", h.Review, openapi.Summary("人工审核"),
+		openapi.Data[ModerationVO]())
+}
+
+type ModerationVO struct {
+	ID    int64  `json:"id"`
+	Biz   string `json:"biz"`
+	BizID int64  `json:"bizId"`
+	Uid   int64  `json:"uid"`
+	// URL 隔离区里的内容，有效期很短的签名地址
+	URL      string `json:"url"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason"`
+	Attempts int    `json:"attempts"`
+	Ctime    string `json:"ctime"`
+}
+
+// List 等待人工审核的记录，按照 ID 从小到大排列，用上一页最后一条的 ID 作为 after 翻页
+func (h *ModerationHandler) List(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	if !h.isAdmin(uc.Uid) {
+		return ginx.Result{
+			Code: errs.ModerationPermissionDenied,
+			Msg:  "common.permission_denied",
+		}, nil
+	}
+	afterID, err := strconv.ParseInt(ctx.DefaultQuery("after", "0"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.ModerationInvalidInput,
+			Msg:  "moderation.invalid_after",
+		}, nil
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		return ginx.Result{
+			Code: errs.ModerationInvalidInput,
+			Msg:  "moderation.invalid_limit",
+		}, nil
+	}
+	ms, err := h.svc.ListPending(ctx.Request.Context(), afterID, limit)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.ModerationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	vos := make([]ModerationVO, 0, len(ms))
+	for _, m := range ms {
+		url, err := h.svc.URL(ctx.Request.Context(), m)
+		if err != nil {
+			return ginx.Result{
+				Code: errs.ModerationInternalServerError,
+				Msg:  "common.internal_error",
+			}, err
+		}
+		vos = append(vos, h.toVO(m, url))
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.query_ok",
+		Data: vos,
+	}, nil
+}
+
+type ReviewReq struct {
+	Approve bool   `json:"approve"`
+	Reason  string `json:"reason" binding:"max=512"`
+}
+
+func (h *ModerationHandler) Review(ctx *gin.Context, req ReviewReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	if !h.isAdmin(uc.Uid) {
+		return ginx.Result{
+			Code: errs.ModerationPermissionDenied,
+			Msg:  "common.permission_denied",
+		}, nil
+	}
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.ModerationInvalidInput,
+			Msg:  "moderation.invalid_id",
+		}, nil
+	}
+	m, err := h.svc.Review(ctx.Request.Context(), uc.Uid, id, req.Approve, req.Reason)
+	switch {
+	case err == nil:
+		return ginx.Result{
+			Code: http.StatusOK,
+			Msg:  "moderation.review_ok",
+			Data: h.toVO(m, ""),
+		}, nil
+	case errors.Is(err, service.ErrModerationNotFound):
+		return ginx.Result{}, errModerationNotFound.Wrap(err)
+	default:
+		return ginx.Result{
+			Code: errs.ModerationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+}
+
+func (h *ModerationHandler) toVO(m domain.Moderation, url string) ModerationVO {
+	return ModerationVO{
+		ID:       m.ID,
+		Biz:      m.Biz,
+		BizID:    m.BizID,
+		Uid:      m.Uid,
+		URL:      url,
+		Status:   m.Status.String(),
+		Reason:   m.Reason,
+		Attempts: m.Attempts,
+		Ctime:    m.Ctime.Format(time.DateTime),
+	}
+}
+
+func (h *ModerationHandler) isAdmin(uid int64) bool {
+	_, ok := h.admins[uid]
+	return ok
+}
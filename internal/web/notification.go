@@ -0,0 +1,214 @@
+package web
+
+import (
+	"bedrock/internal/domain"
+	"bedrock/internal/service"
+	"bedrock/internal/web/errs"
+	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/openapi"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var _ Handler = (*NotificationHandler)(nil)
+
+type NotificationHandler struct {
+	svc     service.NotificationService
+	userSvc service.UserService
+	// admins 允许创建和查看群发活动的用户
+	admins map[int64]struct{}
+}
+
+func NewNotificationHandler(svc service.NotificationService, userSvc service.UserService, admins []int64) *NotificationHandler {
+	m := make(map[int64]struct{}, len(admins))
+	for _, uid := range admins {
+		m[uid] = struct{}{}
+	}
+	return &NotificationHandler{
+		svc:     svc,
+		userSvc: userSvc,
+		admins:  m,
+	}
+}
+
+func (h *NotificationHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/notifications")
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/campaigns", h.CreateCampaign, openapi.Summary("创建群发活动"),
+		openapi.Data[int64]())
+	openapi.WrapClaims(g, http.MethodGet, "/campaigns/:id", h.Campaign, openapi.Summary("群发活动详情"),
+		openapi.Data[CampaignVO]())
+	openapi.WrapClaims(g, http.MethodGet, "/preference", h.Preference, openapi.Summary("短信偏好"),
+		openapi.Data[PreferenceVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/preference", h.EditPreference, openapi.Summary("修改短信偏好"))
+}
+
+type CreateCampaignReq struct {
+	Name  string   `json:"name" binding:"required,max=256"`
+	TplId string   `json:"tplId" binding:"required"`
+	Args  []string `json:"args"`
+	// Phones 接收人，非法号码会计入失败数，重复号码只发一次
+	Phones []string `json:"phones" binding:"required,min=1,max=100000"`
+}
+
+func (h *NotificationHandler) CreateCampaign(ctx *gin.Context, req CreateCampaignReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	if !h.isAdmin(uc.Uid) {
+		return ginx.Result{}, errNotificationForbidden
+	}
+	id, err := h.svc.CreateCampaign(ctx, domain.Campaign{
+		Name:  req.Name,
+		TplId: req.TplId,
+		Args:  req.Args,
+	}, req.Phones)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.NotificationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	return ginx.Result{
+		Code: http.StatusAccepted,
+		Msg:  "notification.campaign_created",
+		Data: id,
+	}, nil
+}
+
+type CampaignVO struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	TplId    string   `json:"tplId"`
+	Args     []string `json:"args"`
+	Total    int64    `json:"total"`
+	Success  int64    `json:"success"`
+	Failed   int64    `json:"failed"`
+	OptedOut int64    `json:"optedOut"`
+	Deferred int64    `json:"deferred"`
+	Finished bool     `json:"finished"`
+	Ctime    string   `json:"ctime"`
+	Utime    string   `json:"utime"`
+}
+
+func (h *NotificationHandler) Campaign(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	if !h.isAdmin(uc.Uid) {
+		return ginx.Result{}, errNotificationForbidden
+	}
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.NotificationInvalidInput,
+			Msg:  "notification.invalid_campaign_id",
+		}, nil
+	}
+	c, err := h.svc.FindCampaign(ctx, id)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrCampaignNotFound):
+		return ginx.Result{}, errCampaignNotFound.Wrap(err)
+	default:
+		return ginx.Result{
+			Code: errs.NotificationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.query_ok",
+		Data: CampaignVO{
+			ID:       c.ID,
+			Name:     c.Name,
+			TplId:    c.TplId,
+			Args:     c.Args,
+			Total:    c.Stats.Total,
+			Success:  c.Stats.Success,
+			Failed:   c.Stats.Failed,
+			OptedOut: c.Stats.OptedOut,
+			Deferred: c.Stats.Deferred,
+			Finished: c.Status == domain.CampaignStatusFinished,
+			Ctime:    c.Ctime.Format(time.DateTime),
+			Utime:    c.Utime.Format(time.DateTime),
+		},
+	}, nil
+}
+
+type PreferenceVO struct {
+	OptOut   bool   `json:"optOut"`
+	Timezone string `json:"timezone"`
+}
+
+func (h *NotificationHandler) Preference(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
+	number, res, err := h.phoneOf(ctx, uc.Uid)
+	if number == "" {
+		return res, err
+	}
+	p, err := h.svc.GetPreference(ctx, number)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.NotificationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "common.query_ok",
+		Data: PreferenceVO{
+			OptOut:   p.OptOut,
+			Timezone: p.Timezone,
+		},
+	}, nil
+}
+
+type EditPreferenceReq struct {
+	// OptOut 是否退订营销/通知短信，验证码短信不受影响
+	OptOut bool `json:"optOut"`
+	// Timezone IANA 时区，例如 Asia/Shanghai，为空时按手机号所属地区推断
+	Timezone string `json:"timezone"`
+}
+
+func (h *NotificationHandler) EditPreference(ctx *gin.Context, req EditPreferenceReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	number, res, err := h.phoneOf(ctx, uc.Uid)
+	if number == "" {
+		return res, err
+	}
+	err = h.svc.SetPreference(ctx, domain.SMSPreference{
+		Phone:    number,
+		OptOut:   req.OptOut,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		return ginx.Result{
+			Code: errs.NotificationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "notification.preference_saved",
+	}, nil
+}
+
+// phoneOf 查询用户绑定的手机号，没有绑定时返回空串和对应的响应
+func (h *NotificationHandler) phoneOf(ctx *gin.Context, uid int64) (string, ginx.Result, error) {
+	user, err := h.userSvc.FindById(ctx, uid)
+	if err != nil {
+		return "", ginx.Result{
+			Code: errs.NotificationInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
+	if user.Phone == "" {
+		return "", ginx.Result{
+			Code: errs.NotificationPhoneUnbound,
+			Msg:  "notification.phone_unbound",
+		}, nil
+	}
+	return user.Phone, ginx.Result{}, nil
+}
+
+func (h *NotificationHandler) isAdmin(uid int64) bool {
+	_, ok := h.admins[uid]
+	return ok
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"bedrock/pkg/ginx/openapi"
+	"bedrock/pkg/ginx/router"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "重新生成 api/openapi.json")
+
+const openAPIFile = "../../api/openapi.json"
+
+// TestOpenAPI 接口有变化时文档也要跟着更新：
+// go test ./internal/web -run TestOpenAPI -update
+func TestOpenAPI(t *testing.T) {
+	r := router.New(gin.New(), router.Config{Prefix: "/api"}, router.Version{Name: "v1"})
+	// 新增的 Handler 也要加到这里，和 ioc.InitWebEngine 保持一致
+	handlers := []Handler{
+		&UserHandler{},
+		&NotificationHandler{},
+		&FileHandler{},
+		&ModerationHandler{},
+	}
+	for _, hdl := range handlers {
+		hdl.RegisterRoutes(r.Group("v1"))
+	}
+	data, err := json.MarshalIndent(openapi.DefaultSpec().Document(OpenAPIInfo), "", "  ")
+	require.NoError(t, err)
+	data = append(data, '\n')
+
+	if *update {
+		require.NoError(t, os.WriteFile(openAPIFile, data, 0o644))
+		return
+	}
+	want, err := os.ReadFile(openAPIFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(data), "接口文档过期了，用 -update 重新生成")
+}
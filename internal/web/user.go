@@ -6,17 +6,20 @@ import (
 	"bedrock/internal/web/errs"
 	jwtware "bedrock/internal/web/middleware/jwt"
 	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/openapi"
 	"bedrock/pkg/logger"
+	"bedrock/pkg/phone"
 	"bedrock/pkg/storage"
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
-	"path/filepath"
+	"strings"
 	"time"
 
 	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 )
 
 var _ Handler = (*UserHandler)(nil)
@@ -32,37 +35,45 @@ type UserHandler struct {
 	userSvc          service.UserService
 	codeSvc          service.CodeService
 	storageSvc       storage.Provider
+	avatarSvc        service.AvatarService
 	jwtHdl           jwtware.Handler
 	emailRegexExp    *regexp.Regexp
 	passwordRegexExp *regexp.Regexp
 }
 
-func NewUserHandler(log logger.Logger, userSvc service.UserService, codeSvc service.CodeService, storageSvc storage.Provider, jwtHdl jwtware.Handler) *UserHandler {
+func NewUserHandler(log logger.Logger, userSvc service.UserService, codeSvc service.CodeService, storageSvc storage.Provider, avatarSvc service.AvatarService, jwtHdl jwtware.Handler) *UserHandler {
 	return &UserHandler{
 		log:              log,
 		userSvc:          userSvc,
 		codeSvc:          codeSvc,
 		storageSvc:       storageSvc,
+		avatarSvc:        avatarSvc,
 		jwtHdl:           jwtHdl,
 		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
 		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
 	}
 }
 
-func (u *UserHandler) RegisterRoutes(e *gin.Engine) {
-	g := e.Group("/users")
-
-	g.POST("/signup", ginx.WrapBody(u.SignUp))
-	g.POST("/login", ginx.WrapBody(u.LoginJWT))
-	g.POST("/logout", ginx.Wrap(u.LogoutJWT))
-	g.POST("/refresh_token", ginx.Wrap(u.RefreshToken))
-
-	g.POST("/avatar/upload", ginx.WrapClaims(u.UploadAvatar))
-	g.POST("/edit", ginx.WrapBodyAndClaims(u.Edit))
-	g.GET("/profile", ginx.WrapClaims(u.Profile))
-
-	g.POST("/login_sms/code/send", ginx.WrapBody(u.SendSMSLoginCode))
-	g.POST("/login_sms", ginx.WrapBody(u.LoginSMS))
+func (u *UserHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/users")
+
+	openapi.WrapBody(g, http.MethodPost, "/signup", u.SignUp, openapi.Summary("邮箱注册"))
+	openapi.WrapBody(g, http.MethodPost, "/login", u.LoginJWT, openapi.Summary("邮箱密码登录"))
+	openapi.Wrap(g, http.MethodPost, "/logout", u.LogoutJWT, openapi.Summary("退出登录"))
+	openapi.Wrap(g, http.MethodPost, "/refresh_token", u.RefreshToken, openapi.Summary("刷新短 token"),
+		openapi.Header("X-Refresh-Token", true))
+
+	openapi.WrapClaims(g, http.MethodPost, "/avatar/upload", u.UploadAvatar, openapi.Summary("上传头像"),
+		openapi.FormFile("avatar"), openapi.Data[AvatarVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/avatar/ticket", u.AvatarUploadTicket, openapi.Summary("获取头像直传链接"),
+		openapi.Data[AvatarTicketVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/avatar/confirm", u.ConfirmAvatar, openapi.Summary("确认头像直传完成"),
+		openapi.Data[AvatarVO]())
+	openapi.WrapBodyAndClaims(g, http.MethodPost, "/edit", u.Edit, openapi.Summary("修改个人信息"))
+	openapi.WrapClaims(g, http.MethodGet, "/profile", u.Profile, openapi.Summary("个人信息"), openapi.Data[ProfileVO]())
+
+	openapi.WrapBody(g, http.MethodPost, "/login_sms/code/send", u.SendSMSLoginCode, openapi.Summary("发送登录验证码"))
+	openapi.WrapBody(g, http.MethodPost, "/login_sms", u.LoginSMS, openapi.Summary("验证码登录"))
 }
 
 type SignUpReq struct {
@@ -77,55 +88,48 @@ func (u *UserHandler) SignUp(ctx *gin.Context, req SignUpReq) (ginx.Result, erro
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	if !isEmail {
 
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "邮箱格式错误",
+			Msg:  "user.invalid_email",
 		}, nil
 	}
 	if req.Password != req.ConfirmPassword {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "两次输入密码不同",
+			Msg:  "user.password_mismatch",
 		}, nil
 	}
 	isPassword, err := u.passwordRegexExp.MatchString(req.Password)
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	if !isPassword {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "密码必须包含数字、特殊字符、大小字母，并且长度不能小于 8 位",
+			Msg:  "user.weak_password",
 		}, nil
 	}
 
 	// 业务逻辑
 	err = u.userSvc.Signup(ctx.Request.Context(), domain.User{Email: req.Email, Password: req.ConfirmPassword})
-	if errors.Is(err, service.ErrDuplicateEmail) {
-		u.log.Warn(ctx.Request.Context(), "用户邮箱冲突", logger.Error(err))
-		return ginx.Result{
-			Code: errs.UserDuplicateEmail,
-			Msg:  "邮箱冲突",
-		}, err
-	}
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 
 	return ginx.Result{
 		Code: http.StatusCreated,
-		Msg:  "注册成功",
+		Msg:  "user.signup_ok",
 	}, nil
 }
 
@@ -136,44 +140,32 @@ type LoginJWTReq struct {
 
 func (u *UserHandler) LoginJWT(ctx *gin.Context, req LoginJWTReq) (ginx.Result, error) {
 	user, err := u.userSvc.Login(ctx, req.Email, req.Password)
-	switch {
-	case err == nil:
+	if err == nil {
 		err = u.jwtHdl.SetLoginToken(ctx, user.ID)
-		if err != nil {
-			return ginx.Result{
-				Code: errs.UserInternalServerError,
-				Msg:  "系统错误",
-			}, err
-		}
-		return ginx.Result{
-			Code: http.StatusOK,
-			Msg:  "登录成功",
-		}, nil
-	case errors.Is(err, service.ErrInvalidUserOrPassword):
-		return ginx.Result{
-			Code: errs.UserInvalidOrPassword,
-			Msg:  "用户名或者密码错误",
-		}, err
-	default:
+	}
+	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "user.login_ok",
+	}, nil
 }
 
 func (u *UserHandler) LogoutJWT(ctx *gin.Context) (ginx.Result, error) {
 	err := u.jwtHdl.ClearToken(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusOK, ginx.Result{Code: http.StatusInternalServerError, Msg: "系统错误"})
 		return ginx.Result{
 			Code: http.StatusInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "退出登录成功",
+		Msg:  "user.logout_ok",
 	}, nil
 }
 
@@ -189,27 +181,21 @@ func (u *UserHandler) RefreshToken(ctx *gin.Context) (ginx.Result, error) {
 	if err != nil || token == nil || !token.Valid {
 		return ginx.Result{
 			Code: http.StatusUnauthorized,
-			Msg:  "登录已过期，请重新登录",
+			Msg:  "user.token_expired",
 		}, err
 	}
 
 	// 校验 ssid
+	// 会话不存在的业务错误会被转换成 401
 	err = u.jwtHdl.CheckSession(ctx, rc.Ssid)
 	if err != nil {
-		// 如果是会话不存在的业务错误，返回 401
-		if errors.Is(err, jwtware.ErrSessionNotFound) {
-			return ginx.Result{
-				Code: http.StatusUnauthorized,
-				Msg:  "会话已过期，请重新登录",
-			}, err
-		}
 		// 系统错误或者用户已经主动退出登录了
 		// 这里也可以考虑说，如果在 Redis 已经崩溃的时候，
 		// 就不要去校验是不是已经主动退出登录了。
 		//ctx.AbortWithStatus(http.StatusUnauthorized)
 		return ginx.Result{
 			Code: http.StatusInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 
@@ -217,12 +203,12 @@ func (u *UserHandler) RefreshToken(ctx *gin.Context) (ginx.Result, error) {
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统内部错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "刷新成功",
+		Msg:  "user.refresh_ok",
 	}, nil
 }
 
@@ -231,67 +217,188 @@ func (u *UserHandler) UploadAvatar(ctx *gin.Context, uc jwtware.UserClaims) (gin
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "请上传头像文件",
+			Msg:  "avatar.required",
 		}, err
 	}
 
-	// 1. 生成新文件的唯一路径
-	ext := filepath.Ext(file.Filename)
-	key := filepath.Join("avatars", uuid.New().String()+ext)
-
 	f, err := file.Open()
 	if err != nil {
 		u.log.Error(ctx.Request.Context(), "初始化文件失败", logger.Error(err))
 		return ginx.Result{
 			Code: http.StatusInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	defer f.Close()
 
-	// 3. 保存文件
-	url, err := u.storageSvc.Upload(ctx.Request.Context(), key, f, file.Size)
+	// 格式按照文件头识别，扩展名和客户端声明的 Content-Type 都不可信
+	variants, err := u.avatarSvc.Upload(ctx.Request.Context(), uc.Uid, f)
+	return u.avatarResult(ctx.Request.Context(), variants, err)
+}
+
+const (
+	// avatarMaxSize 头像文件的大小上限
+	avatarMaxSize = 5 << 20
+	// avatarTicketExpire 直传链接的有效期
+	avatarTicketExpire = 10 * time.Minute
+)
+
+// avatarTypes 允许上传的头像格式以及对应的扩展名
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+type AvatarTicketReq struct {
+	ContentType string `json:"contentType" binding:"required,oneof=image/png image/jpeg image/webp image/gif"`
+	// Size 文件大小（字节），直传链接只允许上传不超过这个大小的文件
+	Size int64 `json:"size" binding:"required,min=1"`
+}
+
+type AvatarTicketVO struct {
+	// Key 上传完成之后调用 /users/avatar/confirm 时带上
+	Key    string                  `json:"key"`
+	Upload storage.PresignedUpload `json:"upload"`
+}
+
+// AvatarUploadTicket 签发头像直传链接，客户端直接把文件传到存储，不再经过我们的服务
+func (u *UserHandler) AvatarUploadTicket(ctx *gin.Context, req AvatarTicketReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	if req.Size > avatarMaxSize {
+		return ginx.Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "avatar.ticket_too_large",
+		}, nil
+	}
+	// 直传的原图单独放一个目录，key 里带上 uid，确认的时候据此判断是不是本人上传的
+	key := service.NewAvatarUploadKey(uc.Uid, avatarTypes[req.ContentType])
+	upload, err := u.storageSvc.PresignUpload(ctx.Request.Context(), key, storage.UploadPolicy{
+		ContentType: req.ContentType,
+		MaxSize:     req.Size,
+	}, avatarTicketExpire)
 	if err != nil {
-		u.log.Error(ctx.Request.Context(), "保存头像文件失败", logger.Error(err))
 		return ginx.Result{
-			Code: http.StatusInternalServerError,
-			Msg:  "系统错误",
+			Code: errs.UserInternalServerError,
+			Msg:  "common.internal_error",
 		}, err
 	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "avatar.ticket_ok",
+		Data: AvatarTicketVO{
+			Key:    key,
+			Upload: upload,
+		},
+	}, nil
+}
 
-	// 4. 调用 service 层处理业务逻辑
-	err = u.userSvc.UpdateAvatarPath(ctx.Request.Context(), uc.Uid, url)
-	if err != nil {
-		// 如果业务逻辑处理失败（例如数据库更新失败），则删除刚刚保存的文件，进行“回滚”
-		if removeErr := u.storageSvc.Delete(ctx.Request.Context(), key); removeErr != nil {
-			u.log.Warn(ctx.Request.Context(), "数据库更新失败进行回滚操作,但是删除新头像失败",
-				logger.Error(removeErr),
-				logger.String("new_avatar_path", key),
-			)
-		}
+type ConfirmAvatarReq struct {
+	Key string `json:"key" binding:"required"`
+}
 
-		u.log.Error(ctx.Request.Context(), "更新用户头像业务逻辑失败", logger.Error(err))
+// ConfirmAvatar 客户端直传完成之后调用，检查文件无误再更新用户头像
+func (u *UserHandler) ConfirmAvatar(ctx *gin.Context, req ConfirmAvatarReq, uc jwtware.UserClaims) (ginx.Result, error) {
+	// 只接受签发给本人的直传 key，其他 key（包括正在使用的头像）既不读取也不删除
+	if !service.IsAvatarUploadKey(uc.Uid, req.Key) {
 		return ginx.Result{
-			Code: http.StatusInternalServerError,
-			Msg:  "系统错误",
+			Code: errs.UserInvalidInput,
+			Msg:  "avatar.not_found",
+		}, nil
+	}
+	reqCtx := ctx.Request.Context()
+	r, _, err := u.storageSvc.Get(reqCtx, req.Key)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return ginx.Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "avatar.not_found",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: errs.UserInternalServerError,
+			Msg:  "common.internal_error",
 		}, err
 	}
+	// 直传链接不一定能在存储侧限制大小（例如 OSS），ContentType 也是客户端声明的，所以要走一遍完整的校验
+	variants, err := u.avatarSvc.Upload(reqCtx, uc.Uid, r)
+	_ = r.Close()
+	// 原图处理完就用不上了，不合格的也直接删掉，免得占用空间；系统错误时保留，方便客户端重试
+	if err == nil || errors.Is(err, service.ErrAvatarPending) || avatarInvalid(err) {
+		if delErr := u.storageSvc.Delete(reqCtx, req.Key); delErr != nil {
+			u.log.Warn(reqCtx, "删除直传的头像原图失败", logger.Error(delErr), logger.String("key", req.Key))
+		}
+	}
+	return u.avatarResult(reqCtx, variants, err)
+}
+
+// avatarInvalid 头像本身不合格，不是系统错误
+func avatarInvalid(err error) bool {
+	return errors.Is(err, service.ErrAvatarTooLarge) ||
+		errors.Is(err, service.ErrAvatarResolution) ||
+		errors.Is(err, service.ErrAvatarInvalid) ||
+		errors.Is(err, service.ErrAvatarRejected)
+}
+
+type AvatarVO struct {
+	AvatarURL string `json:"avatar_url"`
+	// AvatarSrcset 各个尺寸的头像，前端直接放到 <img srcset> 里
+	AvatarSrcset string `json:"avatar_srcset"`
+}
 
+func (u *UserHandler) avatarResult(ctx context.Context, variants []domain.AvatarVariant, err error) (ginx.Result, error) {
+	if avatarInvalid(err) {
+		return ginx.Result{}, err
+	}
+	if errors.Is(err, service.ErrAvatarPending) {
+		// 需要人工审核，通过之后才会替换掉旧头像
+		return ginx.Result{
+			Code: http.StatusAccepted,
+			Msg:  "avatar.pending",
+		}, nil
+	}
+	if err != nil {
+		u.log.Error(ctx, "更新用户头像失败", logger.Error(err))
+		return ginx.Result{
+			Code: errs.UserInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
+	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "头像上传成功",
-		Data: gin.H{
-			"avatar_url": url, // 返回新的头像路径
+		Msg:  "avatar.upload_ok",
+		Data: AvatarVO{
+			AvatarURL:    u.avatarURL(variants[len(variants)-1].Key),
+			AvatarSrcset: u.avatarSrcset(variants),
 		},
 	}, nil
 }
 
+// avatarSrcset 拼出 <img srcset> 的值，例如 "a_64.webp 64w, a_128.webp 128w"，没有缩略图时返回空串
+func (u *UserHandler) avatarSrcset(variants []domain.AvatarVariant) string {
+	items := make([]string, 0, len(variants))
+	for _, v := range variants {
+		items = append(items, fmt.Sprintf("%s %dw", u.avatarURL(v.Key), v.Size))
+	}
+	return strings.Join(items, ", ")
+}
+
+// avatarURL 把头像的 key 转换成访问地址，早期的数据存的就是 URL，原样返回
+func (u *UserHandler) avatarURL(key string) string {
+	if key == "" || strings.Contains(key, "://") {
+		return key
+	}
+	return u.storageSvc.URL(key)
+}
+
 type ProfileVO struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
 	AboutMe  string `json:"aboutMe"`
 	Birthday string `json:"birthday"`
 	Avatar   string `json:"avatar"`
+	// AvatarSrcset 各个尺寸的头像，前端直接放到 <img srcset> 里
+	AvatarSrcset string `json:"avatarSrcset"`
 }
 
 func (u *UserHandler) Profile(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Result, error) {
@@ -299,18 +406,19 @@ func (u *UserHandler) Profile(ctx *gin.Context, uc jwtware.UserClaims) (ginx.Res
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "获取用户信息成功",
+		Msg:  "user.profile_ok",
 		Data: ProfileVO{
-			Nickname: user.Nickname,
-			Email:    user.Email,
-			AboutMe:  user.AboutMe,
-			Birthday: user.Birthday.Format(time.DateOnly),
-			Avatar:   user.Avatar,
+			Nickname:     user.Nickname,
+			Email:        user.Email,
+			AboutMe:      user.AboutMe,
+			Birthday:     user.Birthday.Format(time.DateOnly),
+			Avatar:       u.avatarURL(user.Avatar),
+			AvatarSrcset: u.avatarSrcset(user.AvatarVariants),
 		},
 	}, nil
 }
@@ -332,7 +440,7 @@ func (u *UserHandler) Edit(ctx *gin.Context, req UserEditReq, uc jwtware.UserCla
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "生日格式不对",
+			Msg:  "user.invalid_birthday",
 		}, err
 	}
 	err = u.userSvc.UpdateNonSensitiveInfo(ctx, domain.User{
@@ -344,17 +452,20 @@ func (u *UserHandler) Edit(ctx *gin.Context, req UserEditReq, uc jwtware.UserCla
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "上传成功",
+		Msg:  "user.edit_ok",
 	}, nil
 }
 
 type SendSMSCodeReq struct {
-	Phone string `json:"phone" binding:"required,len=11,numeric"`
+	// Phone E.164 格式，例如 +8613800138000；不带国家码时按中国大陆号码处理
+	Phone string `json:"phone" binding:"required,phone"`
+	// Channel 可选，期望的验证码发送方式：sms、voice、email，发送失败时会自动换其他方式
+	Channel string `json:"channel" binding:"omitempty,oneof=sms voice email"`
 }
 
 func (u *UserHandler) SendSMSLoginCode(ctx *gin.Context, req SendSMSCodeReq) (ginx.Result, error) {
@@ -362,80 +473,75 @@ func (u *UserHandler) SendSMSLoginCode(ctx *gin.Context, req SendSMSCodeReq) (gi
 	if req.Phone == "" {
 		return ginx.Result{
 			Code: errs.UserInvalidInput,
-			Msg:  "请输入手机号码",
+			Msg:  "user.phone_required",
 		}, nil
 	}
-	err := u.codeSvc.Send(ctx, bizLogin, req.Phone)
-	switch {
-	case err == nil:
-		return ginx.Result{
-			Code: http.StatusOK,
-			Msg:  "发送成功",
-		}, nil
-	case errors.Is(err, service.ErrCodeSendTooMany):
-		// 事实上，防不住有人不知道怎么触发了
-		// 少数这种错误，是可以接受的
-		// 但是频繁出现，就代表有人在搞你的系统
+	number, err := phone.Normalize(req.Phone, "")
+	if err != nil {
 		return ginx.Result{
-			Code: errs.UserCodeSendTooMany,
-			Msg:  "短信发送太频繁，请稍后再试",
+			Code: errs.UserInvalidInput,
+			Msg:  "user.invalid_phone",
 		}, nil
-	default:
+	}
+	// 发送太频繁：事实上，防不住有人不知道怎么触发了
+	// 少数这种错误，是可以接受的
+	// 但是频繁出现，就代表有人在搞你的系统
+	err = u.codeSvc.Send(ctx, bizLogin, number, req.Channel)
+	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
+	return ginx.Result{
+		Code: http.StatusOK,
+		Msg:  "code.send_ok",
+	}, nil
 }
 
 type LoginSMSReq struct {
-	Phone string `json:"phone" binding:"required,len=11,numeric"`
-	Code  string `json:"code" binding:"required,len=6,numeric"`
+	Phone string `json:"phone" binding:"required,phone"`
+	// Code 长度和字符集由验证码策略决定
+	Code string `json:"code" binding:"required,min=4,max=16,alphanum"`
 }
 
 func (u *UserHandler) LoginSMS(ctx *gin.Context, req LoginSMSReq) (ginx.Result, error) {
-	ok, err := u.codeSvc.Verify(ctx, bizLogin, req.Phone, req.Code)
+	number, err := phone.Normalize(req.Phone, "")
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrCodeVerifyTooMany):
-			return ginx.Result{
-				Code: errs.UserCodeVerifyTooMany,
-				Msg:  "验证码验证次数太多，请稍后再试",
-			}, nil
-		case errors.Is(err, service.ErrCodeExpired):
-			return ginx.Result{
-				Code: errs.UserCodeExpired,
-				Msg:  "验证码已过期",
-			}, nil
-		default:
-			return ginx.Result{
-				Code: errs.UserInternalServerError,
-				Msg:  "系统异常",
-			}, err
-		}
+		return ginx.Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "user.invalid_phone",
+		}, nil
+	}
+	ok, err := u.codeSvc.Verify(ctx, bizLogin, number, req.Code)
+	if err != nil {
+		return ginx.Result{
+			Code: errs.UserInternalServerError,
+			Msg:  "common.internal_error",
+		}, err
 	}
 	if !ok {
 		return ginx.Result{
 			Code: errs.UserCodeInvalid,
-			Msg:  "验证码不对，请重新输入",
+			Msg:  "code.invalid",
 		}, nil
 	}
-	user, err := u.userSvc.FindOrCreate(ctx, req.Phone)
+	user, err := u.userSvc.FindOrCreate(ctx, number)
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	err = u.jwtHdl.SetLoginToken(ctx, user.ID)
 	if err != nil {
 		return ginx.Result{
 			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "登录成功",
+		Msg:  "user.login_ok",
 	}, nil
 }
@@ -10,12 +10,17 @@ import (
 	"bedrock/pkg/ginx"
 	"bedrock/pkg/logger"
 	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
 	storagemocks "bedrock/pkg/storage/mocks"
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,7 +57,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusCreated,
-				Msg:  "注册成功",
+				Msg:  "user.signup_ok",
 			},
 			wantErr: nil,
 		},
@@ -69,7 +74,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "两次输入密码不同",
+				Msg:  "user.password_mismatch",
 			},
 			wantErr: nil,
 		},
@@ -86,7 +91,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "邮箱格式错误",
+				Msg:  "user.invalid_email",
 			},
 			wantErr: nil,
 		},
@@ -103,7 +108,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "密码必须包含数字、特殊字符、大小字母，并且长度不能小于 8 位",
+				Msg:  "user.weak_password",
 			},
 			wantErr: nil,
 		},
@@ -124,7 +129,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserDuplicateEmail,
-				Msg:  "邮箱冲突",
+				Msg:  "user.duplicate_email",
 			},
 			wantErr: service.ErrDuplicateEmail,
 		},
@@ -145,7 +150,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInternalServerError,
-				Msg:  "系统错误",
+				Msg:  "common.internal_error",
 			},
 			wantErr: errors.New("service error"),
 		},
@@ -160,7 +165,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 
 			svc := tc.mock(ctrl)
 			// 使用 NewUserHandler 初始化，确保正则表达式等字段被正确初始化
-			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil)
+			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil, nil)
 
 			// 构造 gin.Context
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
@@ -171,7 +176,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			res, err := h.SignUp(ctx, tc.req)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -193,7 +198,7 @@ func TestUserHandler_LogoutJWT(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "退出登录成功",
+				Msg:  "user.logout_ok",
 			},
 			wantErr: nil,
 		},
@@ -206,7 +211,7 @@ func TestUserHandler_LogoutJWT(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusInternalServerError,
-				Msg:  "系统错误",
+				Msg:  "common.internal_error",
 			},
 			wantErr: errors.New("redis error"),
 		},
@@ -220,7 +225,7 @@ func TestUserHandler_LogoutJWT(t *testing.T) {
 			defer ctrl.Finish()
 
 			jwtHdl := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), nil, nil, nil, jwtHdl)
+			h := NewUserHandler(logger.NewNopLogger(), nil, nil, nil, nil, jwtHdl)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/logout", nil)
@@ -228,7 +233,7 @@ func TestUserHandler_LogoutJWT(t *testing.T) {
 			res, err := h.LogoutJWT(ctx)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -267,7 +272,7 @@ func TestUserHandler_RefreshToken(t *testing.T) {
 			token: genToken(123, "ssid-123"),
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "刷新成功",
+				Msg:  "user.refresh_ok",
 			},
 			wantErr: nil,
 		},
@@ -281,7 +286,7 @@ func TestUserHandler_RefreshToken(t *testing.T) {
 			token: genToken(123, "ssid-123"),
 			wantResult: ginx.Result{
 				Code: http.StatusUnauthorized,
-				Msg:  "会话已过期，请重新登录",
+				Msg:  "user.session_expired",
 			},
 			wantErr: jwtware.ErrSessionNotFound,
 		},
@@ -295,7 +300,7 @@ func TestUserHandler_RefreshToken(t *testing.T) {
 			defer ctrl.Finish()
 
 			jwtHdl := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), nil, nil, nil, jwtHdl)
+			h := NewUserHandler(logger.NewNopLogger(), nil, nil, nil, nil, jwtHdl)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/refresh_token", nil)
@@ -304,7 +309,7 @@ func TestUserHandler_RefreshToken(t *testing.T) {
 			res, err := h.RefreshToken(ctx)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -336,7 +341,7 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "登录成功",
+				Msg:  "user.login_ok",
 			},
 			wantErr: nil,
 		},
@@ -354,7 +359,7 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidOrPassword,
-				Msg:  "用户名或者密码错误",
+				Msg:  "user.invalid_credentials",
 			},
 			wantErr: service.ErrInvalidUserOrPassword,
 		},
@@ -374,7 +379,7 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInternalServerError,
-				Msg:  "系统错误",
+				Msg:  "common.internal_error",
 			},
 			wantErr: errors.New("service error"),
 		},
@@ -388,7 +393,7 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc, jwtHdl := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, jwtHdl)
+			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil, jwtHdl)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/login", nil)
@@ -396,7 +401,7 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 			res, err := h.LoginJWT(ctx, tc.req)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -432,7 +437,7 @@ func TestUserHandler_Edit(t *testing.T) {
 			uc: jwtware.UserClaims{Uid: 123},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "上传成功",
+				Msg:  "user.edit_ok",
 			},
 			wantErr: nil,
 		},
@@ -448,7 +453,7 @@ func TestUserHandler_Edit(t *testing.T) {
 			uc: jwtware.UserClaims{Uid: 123},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "生日格式不对",
+				Msg:  "user.invalid_birthday",
 			},
 			wantErr: func() error {
 				_, err := time.Parse(time.DateOnly, "invalid-date")
@@ -465,7 +470,7 @@ func TestUserHandler_Edit(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil)
+			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil, nil)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/edit", nil)
@@ -473,7 +478,7 @@ func TestUserHandler_Edit(t *testing.T) {
 			res, err := h.Edit(ctx, tc.req, tc.uc)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -492,7 +497,7 @@ func TestUserHandler_SendSMSLoginCode(t *testing.T) {
 			name: "发送成功",
 			mock: func(ctrl *gomock.Controller) service.CodeService {
 				svc := svcmocks.NewMockCodeService(ctrl)
-				svc.EXPECT().Send(gomock.Any(), "login", "12345678901").Return(nil)
+				svc.EXPECT().Send(gomock.Any(), "login", "+8612345678901", "").Return(nil)
 				return svc
 			},
 			req: SendSMSCodeReq{
@@ -500,7 +505,88 @@ func TestUserHandler_SendSMSLoginCode(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "发送成功",
+				Msg:  "code.send_ok",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "国际号码发送成功",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				svc := svcmocks.NewMockCodeService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", "+447911123456", "").Return(nil)
+				return svc
+			},
+			req: SendSMSCodeReq{
+				Phone: "+44 07911 123456",
+			},
+			wantResult: ginx.Result{
+				Code: http.StatusOK,
+				Msg:  "code.send_ok",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "超过每日发送上限",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				svc := svcmocks.NewMockCodeService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", "+8612345678901", "").Return(service.ErrCodeDailyLimit)
+				return svc
+			},
+			req: SendSMSCodeReq{
+				Phone: "12345678901",
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserCodeSendTooMany,
+				Msg:  "code.daily_limit",
+			},
+			wantErr: service.ErrCodeDailyLimit,
+		},
+		{
+			name: "指定语音验证码",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				svc := svcmocks.NewMockCodeService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", "+8612345678901", "voice").Return(nil)
+				return svc
+			},
+			req: SendSMSCodeReq{
+				Phone:   "12345678901",
+				Channel: "voice",
+			},
+			wantResult: ginx.Result{
+				Code: http.StatusOK,
+				Msg:  "code.send_ok",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "不支持的发送方式",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				svc := svcmocks.NewMockCodeService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login", "+8612345678901", "email").Return(service.ErrCodeChannelUnsupported)
+				return svc
+			},
+			req: SendSMSCodeReq{
+				Phone:   "12345678901",
+				Channel: "email",
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "code.channel_unsupported",
+			},
+			wantErr: service.ErrCodeChannelUnsupported,
+		},
+		{
+			name: "手机号码格式错误",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				svc := svcmocks.NewMockCodeService(ctrl)
+				return svc
+			},
+			req: SendSMSCodeReq{
+				Phone: "+8623456789012",
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "user.invalid_phone",
 			},
 			wantErr: nil,
 		},
@@ -515,7 +601,7 @@ func TestUserHandler_SendSMSLoginCode(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "请输入手机号码",
+				Msg:  "user.phone_required",
 			},
 			wantErr: nil,
 		},
@@ -529,7 +615,7 @@ func TestUserHandler_SendSMSLoginCode(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), nil, svc, nil, nil)
+			h := NewUserHandler(logger.NewNopLogger(), nil, svc, nil, nil, nil)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/login_sms/code/send", nil)
@@ -537,7 +623,7 @@ func TestUserHandler_SendSMSLoginCode(t *testing.T) {
 			res, err := h.SendSMSLoginCode(ctx, tc.req)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -559,8 +645,8 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				jwtHdl := jwtmocks.NewMockHandler(ctrl)
 
-				codeSvc.EXPECT().Verify(gomock.Any(), "login", "12345678901", "123456").Return(true, nil)
-				userSvc.EXPECT().FindOrCreate(gomock.Any(), "12345678901").Return(domain.User{ID: 123}, nil)
+				codeSvc.EXPECT().Verify(gomock.Any(), "login", "+8612345678901", "123456").Return(true, nil)
+				userSvc.EXPECT().FindOrCreate(gomock.Any(), "+8612345678901").Return(domain.User{ID: 123}, nil)
 				jwtHdl.EXPECT().SetLoginToken(gomock.Any(), int64(123)).Return(nil)
 
 				return codeSvc, userSvc, jwtHdl
@@ -571,7 +657,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "登录成功",
+				Msg:  "user.login_ok",
 			},
 			wantErr: nil,
 		},
@@ -582,7 +668,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				jwtHdl := jwtmocks.NewMockHandler(ctrl)
 
-				codeSvc.EXPECT().Verify(gomock.Any(), "login", "12345678901", "123456").Return(false, nil)
+				codeSvc.EXPECT().Verify(gomock.Any(), "login", "+8612345678901", "123456").Return(false, nil)
 
 				return codeSvc, userSvc, jwtHdl
 			},
@@ -592,7 +678,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserCodeInvalid,
-				Msg:  "验证码不对，请重新输入",
+				Msg:  "code.invalid",
 			},
 			wantErr: nil,
 		},
@@ -606,7 +692,7 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 			defer ctrl.Finish()
 
 			codeSvc, userSvc, jwtHdl := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), userSvc, codeSvc, nil, jwtHdl)
+			h := NewUserHandler(logger.NewNopLogger(), userSvc, codeSvc, nil, nil, jwtHdl)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("POST", "/users/login_sms", nil)
@@ -614,16 +700,20 @@ func TestUserHandler_LoginSMS(t *testing.T) {
 			res, err := h.LoginSMS(ctx, tc.req)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
 
 func TestUserHandler_UploadAvatar(t *testing.T) {
 	t.Parallel()
+	variants := []domain.AvatarVariant{
+		{Size: 64, Key: "avatars/123/a_64.jpg"},
+		{Size: 512, Key: "avatars/123/a_512.jpg"},
+	}
 	testCases := []struct {
 		name     string
-		mock     func(ctrl *gomock.Controller) (service.UserService, storage.Provider)
+		mock     func(ctrl *gomock.Controller) service.AvatarService
 		uc       jwtware.UserClaims
 		setupReq func(w *multipart.Writer)
 
@@ -632,14 +722,16 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 	}{
 		{
 			name: "上传成功",
-			mock: func(ctrl *gomock.Controller) (service.UserService, storage.Provider) {
-				userSvc := svcmocks.NewMockUserService(ctrl)
-				storageSvc := storagemocks.NewMockProvider(ctrl)
-
-				storageSvc.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("https://example.com/avatar.jpg", nil)
-				userSvc.EXPECT().UpdateAvatarPath(gomock.Any(), int64(123), "https://example.com/avatar.jpg").Return(nil)
-
-				return userSvc, storageSvc
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, r io.Reader) ([]domain.AvatarVariant, error) {
+						content, err := io.ReadAll(r)
+						assert.NoError(t, err)
+						assert.Equal(t, "image content", string(content))
+						return variants, nil
+					})
+				return avatarSvc
 			},
 			uc: jwtware.UserClaims{Uid: 123},
 			setupReq: func(w *multipart.Writer) {
@@ -648,19 +740,18 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "头像上传成功",
-				Data: gin.H{
-					"avatar_url": "https://example.com/avatar.jpg",
+				Msg:  "avatar.upload_ok",
+				Data: AvatarVO{
+					AvatarURL:    "https://example.com/avatars/123/a_512.jpg",
+					AvatarSrcset: "https://example.com/avatars/123/a_64.jpg 64w, https://example.com/avatars/123/a_512.jpg 512w",
 				},
 			},
 			wantErr: nil,
 		},
 		{
 			name: "未上传文件",
-			mock: func(ctrl *gomock.Controller) (service.UserService, storage.Provider) {
-				userSvc := svcmocks.NewMockUserService(ctrl)
-				storageSvc := storagemocks.NewMockProvider(ctrl)
-				return userSvc, storageSvc
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				return svcmocks.NewMockAvatarService(ctrl)
 			},
 			uc: jwtware.UserClaims{Uid: 123},
 			setupReq: func(w *multipart.Writer) {
@@ -668,19 +759,53 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 			},
 			wantResult: ginx.Result{
 				Code: errs.UserInvalidInput,
-				Msg:  "请上传头像文件",
+				Msg:  "avatar.required",
 			},
 			wantErr: http.ErrMissingFile,
 		},
 		{
-			name: "存储服务错误",
-			mock: func(ctrl *gomock.Controller) (service.UserService, storage.Provider) {
-				userSvc := svcmocks.NewMockUserService(ctrl)
-				storageSvc := storagemocks.NewMockProvider(ctrl)
-
-				storageSvc.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("", errors.New("storage error"))
-
-				return userSvc, storageSvc
+			name: "格式不支持",
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).
+					Return(nil, fmt.Errorf("%w: %w", service.ErrAvatarInvalid, errors.New("unknown format")))
+				return avatarSvc
+			},
+			uc: jwtware.UserClaims{Uid: 123},
+			setupReq: func(w *multipart.Writer) {
+				part, _ := w.CreateFormFile("avatar", "avatar.svg")
+				part.Write([]byte("<svg></svg>"))
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.invalid",
+			},
+			wantErr: fmt.Errorf("%w: %w", service.ErrAvatarInvalid, errors.New("unknown format")),
+		},
+		{
+			name: "分辨率太大",
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarResolution)
+				return avatarSvc
+			},
+			uc: jwtware.UserClaims{Uid: 123},
+			setupReq: func(w *multipart.Writer) {
+				part, _ := w.CreateFormFile("avatar", "avatar.png")
+				part.Write([]byte("image content"))
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.resolution",
+			},
+			wantErr: service.ErrAvatarResolution,
+		},
+		{
+			name: "审核不通过",
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarRejected)
+				return avatarSvc
 			},
 			uc: jwtware.UserClaims{Uid: 123},
 			setupReq: func(w *multipart.Writer) {
@@ -688,23 +813,17 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 				part.Write([]byte("image content"))
 			},
 			wantResult: ginx.Result{
-				Code: http.StatusInternalServerError,
-				Msg:  "系统错误",
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.rejected",
 			},
-			wantErr: errors.New("storage error"),
+			wantErr: service.ErrAvatarRejected,
 		},
 		{
-			name: "更新用户头像路径失败",
-			mock: func(ctrl *gomock.Controller) (service.UserService, storage.Provider) {
-				userSvc := svcmocks.NewMockUserService(ctrl)
-				storageSvc := storagemocks.NewMockProvider(ctrl)
-
-				storageSvc.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("https://example.com/avatar.jpg", nil)
-				userSvc.EXPECT().UpdateAvatarPath(gomock.Any(), int64(123), "https://example.com/avatar.jpg").Return(errors.New("db error"))
-				// 回滚删除
-				storageSvc.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil)
-
-				return userSvc, storageSvc
+			name: "等待人工审核",
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarPending)
+				return avatarSvc
 			},
 			uc: jwtware.UserClaims{Uid: 123},
 			setupReq: func(w *multipart.Writer) {
@@ -712,10 +831,27 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 				part.Write([]byte("image content"))
 			},
 			wantResult: ginx.Result{
-				Code: http.StatusInternalServerError,
-				Msg:  "系统错误",
+				Code: http.StatusAccepted,
+				Msg:  "avatar.pending",
 			},
-			wantErr: errors.New("db error"),
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) service.AvatarService {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, errors.New("storage error"))
+				return avatarSvc
+			},
+			uc: jwtware.UserClaims{Uid: 123},
+			setupReq: func(w *multipart.Writer) {
+				part, _ := w.CreateFormFile("avatar", "avatar.jpg")
+				part.Write([]byte("image content"))
+			},
+			wantResult: ginx.Result{
+				Code: errs.UserInternalServerError,
+				Msg:  "common.internal_error",
+			},
+			wantErr: errors.New("storage error"),
 		},
 	}
 
@@ -726,8 +862,7 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
-			userSvc, storageSvc := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), userSvc, nil, storageSvc, nil)
+			h := NewUserHandler(logger.NewNopLogger(), nil, nil, memory.NewProvider("https://example.com"), tc.mock(ctrl), nil)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 
@@ -742,7 +877,7 @@ func TestUserHandler_UploadAvatar(t *testing.T) {
 			res, err := h.UploadAvatar(ctx, tc.uc)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -766,24 +901,50 @@ func TestUserHandler_Profile(t *testing.T) {
 					Email:    "test@example.com",
 					AboutMe:  "I am a tester",
 					Birthday: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
-					Avatar:   "avatar.jpg",
+					Avatar:   "avatars/123/a_128.jpg",
+					AvatarVariants: []domain.AvatarVariant{
+						{Size: 64, Key: "avatars/123/a_64.jpg"},
+						{Size: 128, Key: "avatars/123/a_128.jpg"},
+					},
 				}, nil)
 				return svc
 			},
 			uc: jwtware.UserClaims{Uid: 123},
 			wantResult: ginx.Result{
 				Code: http.StatusOK,
-				Msg:  "获取用户信息成功",
+				Msg:  "user.profile_ok",
 				Data: ProfileVO{
-					Nickname: "test_user",
-					Email:    "test@example.com",
-					AboutMe:  "I am a tester",
-					Birthday: "2000-01-01",
-					Avatar:   "avatar.jpg",
+					Nickname:     "test_user",
+					Email:        "test@example.com",
+					AboutMe:      "I am a tester",
+					Birthday:     "2000-01-01",
+					Avatar:       "https://example.com/avatars/123/a_128.jpg",
+					AvatarSrcset: "https://example.com/avatars/123/a_64.jpg 64w, https://example.com/avatars/123/a_128.jpg 128w",
 				},
 			},
 			wantErr: nil,
 		},
+		{
+			name: "早期存的是 URL",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{
+					Nickname: "test_user",
+					Avatar:   "http://localhost:8080/uploads/avatars/a.jpg",
+				}, nil)
+				return svc
+			},
+			uc: jwtware.UserClaims{Uid: 123},
+			wantResult: ginx.Result{
+				Code: http.StatusOK,
+				Msg:  "user.profile_ok",
+				Data: ProfileVO{
+					Nickname: "test_user",
+					Birthday: "0001-01-01",
+					Avatar:   "http://localhost:8080/uploads/avatars/a.jpg",
+				},
+			},
+		},
 		{
 			name: "查询失败",
 			mock: func(ctrl *gomock.Controller) service.UserService {
@@ -794,7 +955,7 @@ func TestUserHandler_Profile(t *testing.T) {
 			uc: jwtware.UserClaims{Uid: 123},
 			wantResult: ginx.Result{
 				Code: errs.UserInternalServerError,
-				Msg:  "系统错误",
+				Msg:  "common.internal_error",
 			},
 			wantErr: errors.New("db error"),
 		},
@@ -808,7 +969,7 @@ func TestUserHandler_Profile(t *testing.T) {
 			defer ctrl.Finish()
 
 			svc := tc.mock(ctrl)
-			h := NewUserHandler(logger.NewNopLogger(), svc, nil, nil, nil)
+			h := NewUserHandler(logger.NewNopLogger(), svc, nil, memory.NewProvider("https://example.com"), nil, nil)
 
 			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
 			ctx.Request = httptest.NewRequest("GET", "/users/profile", nil)
@@ -816,7 +977,246 @@ func TestUserHandler_Profile(t *testing.T) {
 			res, err := h.Profile(ctx, tc.uc)
 
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantResult, res)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
+		})
+	}
+}
+
+func TestUserHandler_AvatarUploadTicket(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) storage.Provider
+		req  AvatarTicketReq
+
+		wantCode int
+		wantErr  error
+	}{
+		{
+			name: "签发成功",
+			mock: func(ctrl *gomock.Controller) storage.Provider {
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().PresignUpload(gomock.Any(),
+					gomock.Cond(func(key string) bool {
+						return service.IsAvatarUploadKey(123, key) && strings.HasSuffix(key, ".webp")
+					}),
+					storage.UploadPolicy{ContentType: "image/webp", MaxSize: 1024},
+					avatarTicketExpire,
+				).Return(storage.PresignedUpload{Method: http.MethodPut, URL: "https://example.com/upload"}, nil)
+				return storageSvc
+			},
+			req:      AvatarTicketReq{ContentType: "image/webp", Size: 1024},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "文件太大",
+			mock: func(ctrl *gomock.Controller) storage.Provider {
+				return storagemocks.NewMockProvider(ctrl)
+			},
+			req:      AvatarTicketReq{ContentType: "image/png", Size: avatarMaxSize + 1},
+			wantCode: errs.UserInvalidInput,
+		},
+		{
+			name: "存储不支持直传",
+			mock: func(ctrl *gomock.Controller) storage.Provider {
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().PresignUpload(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(storage.PresignedUpload{}, storage.ErrPresignUnsupported)
+				return storageSvc
+			},
+			req:      AvatarTicketReq{ContentType: "image/png", Size: 1024},
+			wantCode: errs.UserInternalServerError,
+			wantErr:  storage.ErrPresignUnsupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			h := NewUserHandler(logger.NewNopLogger(), nil, nil, tc.mock(ctrl), nil, nil)
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/avatar/ticket", nil)
+
+			res, err := h.AvatarUploadTicket(ctx, tc.req, jwtware.UserClaims{Uid: 123})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCode, ginx.Resolve(res, err).Code)
+		})
+	}
+}
+
+func TestUserHandler_ConfirmAvatar(t *testing.T) {
+	t.Parallel()
+	key := service.NewAvatarUploadKey(123, ".png")
+	object := func() (io.ReadCloser, storage.ObjectInfo, error) {
+		return io.NopCloser(strings.NewReader("image content")), storage.ObjectInfo{Key: key, Size: 13}, nil
+	}
+	variants := []domain.AvatarVariant{{Size: 128, Key: "avatars/123/a_128.jpg"}}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider)
+		key  string
+
+		wantResult ginx.Result
+		wantErr    error
+	}{
+		{
+			name: "确认成功",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(object())
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(variants, nil)
+				// 原图处理完就删掉
+				storageSvc.EXPECT().Delete(gomock.Any(), key).Return(nil)
+				storageSvc.EXPECT().URL("avatars/123/a_128.jpg").Return("https://example.com/avatars/123/a_128.jpg").Times(2)
+				return avatarSvc, storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: http.StatusOK,
+				Msg:  "avatar.upload_ok",
+				Data: AvatarVO{
+					AvatarURL:    "https://example.com/avatars/123/a_128.jpg",
+					AvatarSrcset: "https://example.com/avatars/123/a_128.jpg 128w",
+				},
+			},
+		},
+		{
+			name: "不是自己的文件",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				return svcmocks.NewMockAvatarService(ctrl), storagemocks.NewMockProvider(ctrl)
+			},
+			key: service.NewAvatarUploadKey(456, ".png"),
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.not_found",
+			},
+		},
+		{
+			// 正在使用的头像和直传的原图都在用户自己名下，不能当成原图读取，更不能删除
+			name: "正在使用的头像",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				return svcmocks.NewMockAvatarService(ctrl), storagemocks.NewMockProvider(ctrl)
+			},
+			key: "avatars/123/a_128.jpg",
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.not_found",
+			},
+		},
+		{
+			name: "不是签发的 key",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				return svcmocks.NewMockAvatarService(ctrl), storagemocks.NewMockProvider(ctrl)
+			},
+			key: "uploads/avatars/123/../../avatars/123/a_128.jpg",
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.not_found",
+			},
+		},
+		{
+			name: "还没有上传",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(nil, storage.ObjectInfo{}, storage.ErrNotFound)
+				return svcmocks.NewMockAvatarService(ctrl), storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.not_found",
+			},
+		},
+		{
+			name: "文件太大",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(object())
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarTooLarge)
+				storageSvc.EXPECT().Delete(gomock.Any(), key).Return(nil)
+				return avatarSvc, storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.too_large",
+			},
+			wantErr: service.ErrAvatarTooLarge,
+		},
+		{
+			name: "内容不是图片",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(object())
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarInvalid)
+				storageSvc.EXPECT().Delete(gomock.Any(), key).Return(nil)
+				return avatarSvc, storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: errs.UserInvalidInput,
+				Msg:  "avatar.invalid",
+			},
+			wantErr: service.ErrAvatarInvalid,
+		},
+		{
+			name: "等待人工审核",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(object())
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, service.ErrAvatarPending)
+				// 缩略图已经放进隔离区，原图可以删掉
+				storageSvc.EXPECT().Delete(gomock.Any(), key).Return(nil)
+				return avatarSvc, storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: http.StatusAccepted,
+				Msg:  "avatar.pending",
+			},
+		},
+		{
+			name: "更新用户头像失败",
+			mock: func(ctrl *gomock.Controller) (service.AvatarService, storage.Provider) {
+				avatarSvc := svcmocks.NewMockAvatarService(ctrl)
+				storageSvc := storagemocks.NewMockProvider(ctrl)
+				storageSvc.EXPECT().Get(gomock.Any(), key).Return(object())
+				// 系统错误时保留原图，客户端可以重试
+				avatarSvc.EXPECT().Upload(gomock.Any(), int64(123), gomock.Any()).Return(nil, errors.New("db error"))
+				return avatarSvc, storageSvc
+			},
+			key: key,
+			wantResult: ginx.Result{
+				Code: errs.UserInternalServerError,
+				Msg:  "common.internal_error",
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			avatarSvc, storageSvc := tc.mock(ctrl)
+			h := NewUserHandler(logger.NewNopLogger(), nil, nil, storageSvc, avatarSvc, nil)
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/avatar/confirm", nil)
+
+			res, err := h.ConfirmAvatar(ctx, ConfirmAvatarReq{Key: tc.key}, jwtware.UserClaims{Uid: 123})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantResult, ginx.Resolve(res, err))
 		})
 	}
 }
@@ -40,8 +40,8 @@ func NewOAuth2WechatHandler(svc wechat.Service, hdl jwtware.Handler, userSvc ser
 	}
 }
 
-func (o *OAuth2WechatHandler) RegisterRoutes(server *gin.Engine) {
-	g := server.Group("/oauth2/wechat")
+func (o *OAuth2WechatHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/oauth2/wechat")
 	g.GET("/authurl", ginx.Wrap(o.Auth2URL))
 	g.Any("/callback", ginx.Wrap(o.Callback))
 }
@@ -53,7 +53,7 @@ func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) (ginx.Result, error) {
 		o.l.Error(ctx.Request.Context(), "获取微信授权码失败", logger.Error(err))
 		return ginx.Result{
 			Code: errs.WechatCodeGetDefeated,
-			Msg:  "获取微信授权码失败",
+			Msg:  "wechat.authurl_failed",
 		}, err
 	}
 	err = o.setStateCookie(ctx, state)
@@ -61,12 +61,12 @@ func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) (ginx.Result, error) {
 		o.l.Error(ctx.Request.Context(), "设置 state cookie 失败", logger.Error(err))
 		return ginx.Result{
 			Code: errs.WechatInternalServerError,
-			Msg:  "服务器异常",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "OK",
+		Msg:  "wechat.ok",
 		Data: val,
 	}, nil
 
@@ -77,7 +77,7 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) (ginx.Result, error) {
 	if err != nil {
 		return ginx.Result{
 			Code: errs.WechatInvalidRequest,
-			Msg:  "非法请求",
+			Msg:  "wechat.invalid_state",
 		}, err
 	}
 	// 你校验不校验都可以
@@ -87,27 +87,27 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) (ginx.Result, error) {
 	if err != nil {
 		return ginx.Result{
 			Code: errs.WechatInvalidCode,
-			Msg:  "授权码有误",
+			Msg:  "wechat.invalid_code",
 		}, err
 	}
 	u, err := o.userSvc.FindOrCreateByWechat(ctx, wechatInfo)
 	if err != nil {
 		return ginx.Result{
 			Code: errs.WechatInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 	err = o.jwtHdl.SetLoginToken(ctx, u.ID)
 	if err != nil {
 		return ginx.Result{
 			Code: errs.WechatInternalServerError,
-			Msg:  "系统错误",
+			Msg:  "common.internal_error",
 		}, err
 	}
 
 	return ginx.Result{
 		Code: http.StatusOK,
-		Msg:  "微信授权成功",
+		Msg:  "wechat.auth_ok",
 	}, nil
 }
 
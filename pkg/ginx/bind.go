@@ -0,0 +1,76 @@
+package ginx
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// BindAll 把路径参数、查询参数、请求头和请求体绑定到 obj 上，最后统一校验一次
+// gin 的 ShouldBindUri 之类的方法每次都会校验整个结构体，必填的字段分散在不同的来源时没法单独使用
+// 请求体按照 Content-Type 绑定，和请求体里同名的字段以请求体为准
+func BindAll(ctx *gin.Context, obj any) error {
+	params := make(map[string][]string, len(ctx.Params))
+	for _, p := range ctx.Params {
+		params[p.Key] = []string{p.Value}
+	}
+	if err := binding.MapFormWithTag(obj, params, "uri"); err != nil {
+		return err
+	}
+	if err := binding.MapFormWithTag(obj, ctx.Request.URL.Query(), "form"); err != nil {
+		return err
+	}
+	if err := binding.MapFormWithTag(obj, headerForm(reflect.TypeOf(obj), ctx.Request.Header), "header"); err != nil {
+		return err
+	}
+	if hasBody(ctx.Request) {
+		// 绑定请求体的时候会顺带校验
+		return ctx.ShouldBindWith(obj, binding.Default(ctx.Request.Method, ctx.ContentType()))
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+func hasBody(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return false
+	}
+	return req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+}
+
+// headerForm 按照结构体里 header tag 的写法取出请求头，这样 X-Request-ID 和 x-request-id 都能绑定上
+func headerForm(t reflect.Type, h http.Header) map[string][]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	res := make(map[string][]string)
+	if t.Kind() != reflect.Struct {
+		return res
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("header"), ",")
+		if name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range headerForm(ft, h) {
+				res[k] = v
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		if vals := h.Values(name); len(vals) > 0 {
+			res[name] = vals
+		}
+	}
+	return res
+}
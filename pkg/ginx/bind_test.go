@@ -0,0 +1,209 @@
+package ginx
+
+import (
+	"bedrock/pkg/validate"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindClaims struct {
+	jwt.RegisteredClaims
+	Uid int64
+}
+
+type bindPage struct {
+	Offset int `form:"offset" json:"offset"`
+	Limit  int `form:"limit" json:"limit" binding:"required,max=100"`
+}
+
+type bindID struct {
+	ID int64 `uri:"id" json:"id" binding:"required,min=1"`
+}
+
+type bindReq struct {
+	bindID
+	Version   string `form:"version" json:"-"`
+	RequestID string `header:"X-Request-ID" json:"-" binding:"required"`
+	Name      string `json:"name" binding:"required,max=8"`
+}
+
+func TestWrapQueryAndURI(t *testing.T) {
+	require.NoError(t, validate.InitTrans("zh"))
+	t.Parallel()
+	server := gin.New()
+	server.GET("/items", WrapQuery(func(ctx *gin.Context, req bindPage) (Result, error) {
+		return Result{Code: http.StatusOK, Data: req}, nil
+	}))
+	server.GET("/items/:id", WrapURI(func(ctx *gin.Context, req bindID) (Result, error) {
+		return Result{Code: http.StatusOK, Data: req}, nil
+	}))
+
+	testCases := []struct {
+		name string
+		url  string
+
+		wantCode int
+		wantData any
+	}{
+		{
+			name:     "查询参数",
+			url:      "/items?offset=20&limit=10",
+			wantCode: http.StatusOK,
+			wantData: map[string]any{"offset": float64(20), "limit": float64(10)},
+		},
+		{
+			name:     "查询参数校验失败",
+			url:      "/items?limit=1000",
+			wantCode: http.StatusBadRequest,
+			wantData: "limit:limit必须小于或等于100; ",
+		},
+		{
+			name:     "查询参数格式错误",
+			url:      "/items?limit=abc",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "路径参数",
+			url:      "/items/123",
+			wantCode: http.StatusOK,
+			wantData: map[string]any{"id": float64(123)},
+		},
+		{
+			name:     "路径参数校验失败",
+			url:      "/items/0",
+			wantCode: http.StatusBadRequest,
+			wantData: "id:id为必填字段; ",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCode, res.Code)
+			assert.Equal(t, tc.wantData, res.Data)
+		})
+	}
+}
+
+func TestWrapReq(t *testing.T) {
+	require.NoError(t, validate.InitTrans("zh"))
+	t.Parallel()
+	server := gin.New()
+	server.Use(func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") != "" {
+			ctx.Set("user", bindClaims{Uid: 123})
+		}
+	})
+	server.PUT("/items/:id", WrapReq(func(ctx *gin.Context, req bindReq, uc bindClaims) (Result, error) {
+		return Result{Code: http.StatusOK, Data: map[string]any{
+			"id":        req.ID,
+			"version":   req.Version,
+			"requestId": req.RequestID,
+			"name":      req.Name,
+			"uid":       uc.Uid,
+		}}, nil
+	}))
+
+	testCases := []struct {
+		name    string
+		url     string
+		body    string
+		headers map[string]string
+
+		wantCode int
+		wantData any
+	}{
+		{
+			name:    "所有来源",
+			url:     "/items/7?version=v2",
+			body:    `{"name":"abc"}`,
+			headers: map[string]string{"Authorization": "Bearer x", "x-request-id": "r1"},
+
+			wantCode: http.StatusOK,
+			wantData: map[string]any{"id": float64(7), "version": "v2", "requestId": "r1", "name": "abc", "uid": float64(123)},
+		},
+		{
+			name:    "请求头缺失",
+			url:     "/items/7",
+			body:    `{"name":"abc"}`,
+			headers: map[string]string{"Authorization": "Bearer x"},
+
+			wantCode: http.StatusBadRequest,
+			wantData: "RequestID:RequestID为必填字段; ",
+		},
+		{
+			name:    "请求体校验失败",
+			url:     "/items/7",
+			body:    `{"name":"abcdefghijk"}`,
+			headers: map[string]string{"Authorization": "Bearer x", "X-Request-ID": "r1"},
+
+			wantCode: http.StatusBadRequest,
+			wantData: "name:name长度不能超过8个字符; ",
+		},
+		{
+			name:    "没有请求体也要校验",
+			url:     "/items/7",
+			headers: map[string]string{"Authorization": "Bearer x", "X-Request-ID": "r1"},
+
+			wantCode: http.StatusBadRequest,
+			wantData: "name:name为必填字段; ",
+		},
+		{
+			name:    "请求体格式错误",
+			url:     "/items/7",
+			body:    `{"name":`,
+			headers: map[string]string{"Authorization": "Bearer x", "X-Request-ID": "r1"},
+
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "没有登录",
+			url:      "/items/7",
+			body:     `{"name":"abc"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var body io.Reader = http.NoBody
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(http.MethodPut, tc.url, body)
+			req.Header.Set("Content-Type", "application/json")
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantCode == http.StatusUnauthorized {
+				return
+			}
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCode, res.Code)
+			assert.Equal(t, tc.wantData, res.Data)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package ginx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Error 带业务码的错误，业务逻辑直接返回它（或者注册过的哨兵错误），由 Wrap* 统一转换成响应
+type Error struct {
+	// Code 业务码，写进 Result.Code
+	Code int
+	// Status HTTP 状态码
+	Status int
+	// Msg 返回给用户的消息 key，见 SetI18n
+	Msg string
+	// Cause 内部原因，只记录日志，不返回给用户
+	Cause error
+}
+
+// ErrInternal 没有注册过的错误一律按照系统错误处理，不把内部原因暴露给用户
+var ErrInternal = NewError(http.StatusInternalServerError, http.StatusInternalServerError, "common.internal_error")
+
+func NewError(code, status int, msg string) *Error {
+	return &Error{Code: code, Status: status, Msg: msg}
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("%d: %s", e.Code, e.Msg)
+	}
+	return fmt.Sprintf("%d: %s: %v", e.Code, e.Msg, e.Cause)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is 业务码和消息相同就认为是同一种错误，这样 Wrap 出来的副本也能用 errors.Is 判断
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	return e.Code == t.Code && e.Msg == t.Msg
+}
+
+// Wrap 返回带上内部原因的副本，e 一般是包级变量，不能直接修改
+func (e *Error) Wrap(cause error) *Error {
+	res := *e
+	res.Cause = cause
+	return &res
+}
+
+func (e *Error) Result() Result {
+	return Result{Code: e.Code, Msg: e.Msg}
+}
+
+// ErrorRegistry 哨兵错误到 *Error 的映射，按照注册顺序匹配，先注册的优先
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	entries []registryEntry
+}
+
+type registryEntry struct {
+	target error
+	err    *Error
+}
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{}
+}
+
+// Register 注册 target 对应的错误，一般在 init 里调用
+func (r *ErrorRegistry) Register(target error, e *Error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, registryEntry{target: target, err: e})
+}
+
+// Lookup 查找 err 对应的 *Error，err 本身就是 *Error 时直接返回，结果里带上 err 作为内部原因
+func (r *ErrorRegistry) Lookup(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, entry := range r.entries {
+		if errors.Is(err, entry.target) {
+			return entry.err.Wrap(err), true
+		}
+	}
+	return nil, false
+}
+
+var registry = NewErrorRegistry()
+
+// RegisterError 注册到 Wrap* 使用的全局映射
+func RegisterError(target error, e *Error) {
+	registry.Register(target, e)
+}
+
+// LookupError 在全局映射里查找 err 对应的 *Error
+func LookupError(err error) (*Error, bool) {
+	return registry.Lookup(err)
+}
+
+// Resolve 按照 Wrap* 的规则得到最终的响应：
+// 返回的错误是 *Error 或者注册过的哨兵错误时，用它生成响应；
+// 其余错误沿用业务逻辑返回的 res，res 是零值时按照系统错误处理
+func Resolve(res Result, err error) Result {
+	if err == nil {
+		return res
+	}
+	if e, ok := LookupError(err); ok {
+		return e.Result()
+	}
+	if res.Code == 0 {
+		return ErrInternal.Result()
+	}
+	return res
+}
@@ -0,0 +1,148 @@
+package ginx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRegistry_Lookup(t *testing.T) {
+	t.Parallel()
+	errDuplicate := errors.New("duplicate")
+	errNotFound := errors.New("not found")
+	duplicate := NewError(401003, http.StatusConflict, "邮箱冲突")
+	notFound := NewError(404002, http.StatusNotFound, "文件不存在")
+	r := NewErrorRegistry()
+	r.Register(errDuplicate, duplicate)
+	r.Register(errNotFound, notFound)
+
+	testCases := []struct {
+		name string
+		err  error
+
+		wantOK   bool
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name:     "注册过的错误",
+			err:      errDuplicate,
+			wantOK:   true,
+			wantCode: 401003,
+			wantMsg:  "邮箱冲突",
+		},
+		{
+			name:     "包装过的错误",
+			err:      fmt.Errorf("signup: %w", errNotFound),
+			wantOK:   true,
+			wantCode: 404002,
+			wantMsg:  "文件不存在",
+		},
+		{
+			name:     "直接返回 *Error",
+			err:      fmt.Errorf("detail: %w", notFound.Wrap(errors.New("db"))),
+			wantOK:   true,
+			wantCode: 404002,
+			wantMsg:  "文件不存在",
+		},
+		{
+			name: "没有注册过",
+			err:  errors.New("db error"),
+		},
+		{
+			name: "nil",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, ok := r.Lookup(tc.err)
+			require.Equal(t, tc.wantOK, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, tc.wantCode, e.Code)
+			assert.Equal(t, tc.wantMsg, e.Msg)
+			// 内部原因要保留下来，方便记录日志
+			assert.ErrorIs(t, e, tc.err)
+		})
+	}
+}
+
+func TestError_Wrap(t *testing.T) {
+	t.Parallel()
+	cause := errors.New("db error")
+	tmpl := NewError(404002, http.StatusNotFound, "文件不存在")
+	e := tmpl.Wrap(cause)
+
+	assert.Nil(t, tmpl.Cause)
+	assert.ErrorIs(t, e, cause)
+	assert.ErrorIs(t, e, tmpl)
+	assert.NotErrorIs(t, e, NewError(404003, http.StatusForbidden, "没有权限"))
+	assert.Equal(t, "404002: 文件不存在: db error", e.Error())
+}
+
+func TestWrap_TranslatesError(t *testing.T) {
+	t.Parallel()
+	errSentinel := errors.New("wrap test sentinel")
+	RegisterError(errSentinel, NewError(401005, http.StatusTooManyRequests, "发送太频繁"))
+
+	testCases := []struct {
+		name string
+		res  Result
+		err  error
+
+		wantResult Result
+	}{
+		{
+			name:       "成功",
+			res:        Result{Code: http.StatusOK, Msg: "OK"},
+			wantResult: Result{Code: http.StatusOK, Msg: "OK"},
+		},
+		{
+			// 注册过的错误优先于业务逻辑返回的结果
+			name:       "注册过的错误",
+			res:        Result{Code: 501001, Msg: "common.internal_error"},
+			err:        fmt.Errorf("send: %w", errSentinel),
+			wantResult: Result{Code: 401005, Msg: "发送太频繁"},
+		},
+		{
+			name:       "未知错误沿用业务逻辑的结果",
+			res:        Result{Code: 501001, Msg: "common.internal_error"},
+			err:        errors.New("db error"),
+			wantResult: Result{Code: 501001, Msg: "common.internal_error"},
+		},
+		{
+			name:       "未知错误并且没有结果",
+			err:        errors.New("db error"),
+			wantResult: Result{Code: http.StatusInternalServerError, Msg: "common.internal_error"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+			Wrap(func(ctx *gin.Context) (Result, error) {
+				return tc.res, tc.err
+			})(ctx)
+
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package ginx
+
+import (
+	"bedrock/pkg/i18n"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// langKey 请求的语言缓存在 gin.Context 里，避免重复解析 Accept-Language
+const langKey = "ginx_lang"
+
+var bundle atomic.Pointer[i18n.Bundle]
+
+// SetI18n 设置之后 Result.Msg 当做消息 key，按照请求的 Accept-Language 翻译；没有设置时原样返回
+func SetI18n(b *i18n.Bundle) {
+	bundle.Store(b)
+}
+
+// Lang 请求使用的语言，没有设置 SetI18n 时返回空串
+func Lang(ctx *gin.Context) string {
+	if lang := ctx.GetString(langKey); lang != "" {
+		return lang
+	}
+	b := bundle.Load()
+	if b == nil {
+		return ""
+	}
+	lang := b.Match(ctx.GetHeader("Accept-Language"))
+	ctx.Set(langKey, lang)
+	return lang
+}
+
+// T 按照请求的语言翻译消息，handler 需要自己拼接消息的时候用
+func T(ctx *gin.Context, key string, args ...any) string {
+	b := bundle.Load()
+	if b == nil {
+		return key
+	}
+	return b.T(Lang(ctx), key, args...)
+}
@@ -0,0 +1,105 @@
+package ginx
+
+import (
+	"bedrock/pkg/i18n"
+	"bedrock/pkg/validate"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWrap_I18n SetI18n 是全局的，不能并行
+func TestWrap_I18n(t *testing.T) {
+	require.NoError(t, validate.InitTrans("zh"))
+	b := i18n.NewBundle("zh")
+	b.Add("zh", map[string]string{"user.login_ok": "登录成功", "common.invalid_params": "输入参数有误，请检查"})
+	b.Add("en", map[string]string{"user.login_ok": "Logged in", "common.invalid_params": "Invalid parameters"})
+
+	type signUpReq struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	testCases := []struct {
+		name   string
+		bundle *i18n.Bundle
+		path   string
+		header string
+
+		wantMsg  string
+		wantData any
+	}{
+		{
+			name:    "没有设置翻译",
+			path:    "/login",
+			header:  "en",
+			wantMsg: "user.login_ok",
+		},
+		{
+			name:    "默认语言",
+			bundle:  b,
+			path:    "/login",
+			wantMsg: "登录成功",
+		},
+		{
+			name:    "英文",
+			bundle:  b,
+			path:    "/login",
+			header:  "en-US,en;q=0.9,zh;q=0.5",
+			wantMsg: "Logged in",
+		},
+		{
+			name:     "校验错误按照请求的语言翻译",
+			bundle:   b,
+			path:     "/signup",
+			header:   "en",
+			wantMsg:  "Invalid parameters",
+			wantData: "email:email is a required field; ",
+		},
+		{
+			name:     "校验错误中文",
+			bundle:   b,
+			path:     "/signup",
+			header:   "zh-CN",
+			wantMsg:  "输入参数有误，请检查",
+			wantData: "email:email为必填字段; ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetI18n(tc.bundle)
+			defer SetI18n(nil)
+
+			server := gin.New()
+			server.GET("/login", Wrap(func(ctx *gin.Context) (Result, error) {
+				return Result{Code: http.StatusOK, Msg: "user.login_ok"}, nil
+			}))
+			server.POST("/signup", WrapBody(func(ctx *gin.Context, req signUpReq) (Result, error) {
+				return Result{Code: http.StatusOK}, nil
+			}))
+			var req *http.Request
+			if tc.path == "/signup" {
+				req = httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(`{}`))
+				req.Header.Set("Content-Type", "application/json")
+			} else {
+				req = httptest.NewRequest(http.MethodGet, tc.path, nil)
+			}
+			req.Header.Set("Accept-Language", tc.header)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantMsg, res.Msg)
+			if tc.wantData != nil {
+				assert.Equal(t, tc.wantData, res.Data)
+			}
+		})
+	}
+}
@@ -1,23 +1,47 @@
 package middleware
 
 import (
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/router"
 	"bytes"
 	"context"
 	"io"
+	"math/rand/v2"
+	"path"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxLogBody 日志里请求体、响应体最多保留的长度
+	maxLogBody = 2048
+	// maxCaptureBody 请求体、响应体超过这个大小就不再缓存，没办法脱敏，只记录长度
+	maxCaptureBody = 64 << 10
 )
 
 type AccessLogBuilder struct {
 	logFn         func(ctx context.Context, l AccessLog)
 	allowReqBody  bool
 	allowRespBody bool
+	allowHeaders  bool
+	redactor      *redactor
+	// allow 不为空时只记录这些路由的请求体、响应体，deny 里的路由总是不记录
+	allow []string
+	deny  []string
+	// samples 按照状态码分类（2 表示 2xx）的采样率，没有配置的分类全部记录
+	samples map[int]float64
+	userID  func(ctx *gin.Context) string
+	random  func() float64
 }
 
 func NewAccessLogBuilder(logFn func(ctx context.Context, l AccessLog)) *AccessLogBuilder {
 	return &AccessLogBuilder{
-		logFn: logFn,
+		logFn:    logFn,
+		redactor: newRedactor(),
+		samples:  map[int]float64{},
+		random:   rand.Float64,
 	}
 }
 
@@ -31,41 +55,125 @@ func (l *AccessLogBuilder) AllowRespBody() *AccessLogBuilder {
 	return l
 }
 
+// AllowHeaders 记录请求头和响应头，RedactHeaders 里的头只记录 ***
+func (l *AccessLogBuilder) AllowHeaders() *AccessLogBuilder {
+	l.allowHeaders = true
+	return l
+}
+
+// RedactFields 在 DefaultRedactFields 之外还需要脱敏的 JSON 字段，规则见 DefaultRedactFields
+func (l *AccessLogBuilder) RedactFields(fields ...string) *AccessLogBuilder {
+	l.redactor.fields.add(fields...)
+	return l
+}
+
+// RedactReqFields 只在请求体里脱敏的字段，见 DefaultRedactReqFields
+func (l *AccessLogBuilder) RedactReqFields(fields ...string) *AccessLogBuilder {
+	l.redactor.reqFields.add(fields...)
+	return l
+}
+
+// RedactHeaders 在 DefaultRedactHeaders 之外还需要脱敏的头
+func (l *AccessLogBuilder) RedactHeaders(headers ...string) *AccessLogBuilder {
+	l.redactor.addHeaders(headers...)
+	return l
+}
+
+// Allow 只记录这些路由的请求体、响应体，路径不带版本前缀，例如 /users/profile，规则和 path.Match 一样
+func (l *AccessLogBuilder) Allow(routes ...string) *AccessLogBuilder {
+	l.allow = append(l.allow, routes...)
+	return l
+}
+
+// Deny 不记录这些路由的请求体、响应体，优先于 Allow
+func (l *AccessLogBuilder) Deny(routes ...string) *AccessLogBuilder {
+	l.deny = append(l.deny, routes...)
+	return l
+}
+
+// Sample class 是状态码的分类，例如 2 表示 2xx，rate 是 0 到 1 之间的采样率
+func (l *AccessLogBuilder) Sample(class int, rate float64) *AccessLogBuilder {
+	l.samples[class] = rate
+	return l
+}
+
+// UserID 从请求里取出登录用户，鉴权中间件在后面执行，这里拿到的是业务逻辑结束之后的状态
+func (l *AccessLogBuilder) UserID(fn func(ctx *gin.Context) string) *AccessLogBuilder {
+	l.userID = fn
+	return l
+}
+
 func (l *AccessLogBuilder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		path := ctx.Request.URL.Path
-		if len(path) > 1024 {
-			path = path[:1024]
+		urlPath := ctx.Request.URL.Path
+		if len(urlPath) > 1024 {
+			urlPath = urlPath[:1024]
 		}
 		al := AccessLog{
-			Path:   path,
-			Method: ctx.Request.Method,
+			Path:      urlPath,
+			Method:    ctx.Request.Method,
+			ClientIP:  ctx.ClientIP(),
+			UserAgent: ctx.Request.UserAgent(),
+			ReqSize:   ctx.Request.ContentLength,
 		}
-		if l.allowReqBody {
-			// Request.Body 是一个 Stream 对象，只能读一次
-			body, _ := ctx.GetRawData()
-			if len(body) > 2048 {
-				al.ReqBody = string(body[:2048])
-			} else {
-				al.ReqBody = string(body)
-			}
+		// 路由要等版本中间件执行之后才知道，所以先把请求体读出来，最后再决定要不要记录
+		reqType := ctx.ContentType()
+		var reqBody []byte
+		reqOverflow := false
+		if l.allowReqBody && textual(reqType) && ctx.Request.Body != nil {
+			// Request.Body 是一个 Stream 对象，只能读一次，最多读 maxCaptureBody，剩下的留给后面的 handler
+			body := ctx.Request.Body
+			reqBody, _ = io.ReadAll(io.LimitReader(body, maxCaptureBody+1))
+			reqOverflow = len(reqBody) > maxCaptureBody
 			// 放回去
-			ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
-			//ctx.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+			ctx.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(reqBody), body),
+				Closer: body,
+			}
+			if al.ReqSize < 0 && !reqOverflow {
+				al.ReqSize = int64(len(reqBody))
+			}
+		}
+		if l.allowHeaders {
+			al.ReqHeaders = l.redactor.header(ctx.Request.Header)
 		}
 
 		start := time.Now()
 
+		var w *responseWriter
 		if l.allowRespBody {
-			ctx.Writer = &responseWriter{
-				ResponseWriter: ctx.Writer,
-				al:             &al,
-			}
+			w = &responseWriter{ResponseWriter: ctx.Writer}
+			ctx.Writer = w
 		}
 
 		defer func() {
 			al.Duration = time.Since(start)
-			//duration := time.Now().Sub(start)
+			// 没有显式调用 WriteHeader 的响应也要记录状态码，兼容模式下写出去的总是 200，要用真实的状态码
+			al.Status = ginx.Status(ctx)
+			if !l.sampled(al.Status) {
+				return
+			}
+			if l.userID != nil {
+				al.UserID = l.userID(ctx)
+			}
+			if sc := trace.SpanContextFromContext(ctx.Request.Context()); sc.HasTraceID() {
+				al.TraceID = sc.TraceID().String()
+			}
+			if l.allowHeaders {
+				al.RespHeaders = l.redactor.header(ctx.Writer.Header())
+			}
+			if l.bodyAllowed(router.Path(ctx)) {
+				if l.allowReqBody {
+					if textual(reqType) && !reqOverflow {
+						al.ReqBody = truncate(l.redactor.body(reqType, reqBody, true))
+					} else {
+						al.ReqBody = omitted(reqType, int(al.ReqSize))
+					}
+				}
+				if w != nil {
+					al.RespBody = w.body(l.redactor)
+				}
+			}
 			l.logFn(ctx.Request.Context(), al)
 		}()
 
@@ -75,6 +183,37 @@ func (l *AccessLogBuilder) Build() gin.HandlerFunc {
 	}
 }
 
+func (l *AccessLogBuilder) sampled(status int) bool {
+	rate, ok := l.samples[status/100]
+	if !ok {
+		return true
+	}
+	return l.random() < rate
+}
+
+func (l *AccessLogBuilder) bodyAllowed(p string) bool {
+	if matchRoute(l.deny, p) {
+		return false
+	}
+	return len(l.allow) == 0 || matchRoute(l.allow, p)
+}
+
+func matchRoute(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string) string {
+	if len(s) > maxLogBody {
+		return s[:maxLogBody]
+	}
+	return s
+}
+
 type AccessLog struct {
 	Path     string        `json:"path"`
 	Method   string        `json:"method"`
@@ -82,24 +221,49 @@ type AccessLog struct {
 	Status   int           `json:"status"`
 	RespBody string        `json:"resp_body"`
 	Duration time.Duration `json:"duration"`
+	ClientIP string        `json:"client_ip"`
+	// UserID 没有登录时为空
+	UserID  string `json:"user_id"`
+	TraceID string `json:"trace_id"`
+	// ReqSize 请求体的大小，客户端没有带 Content-Length 时是读到的长度
+	ReqSize     int64             `json:"req_size"`
+	UserAgent   string            `json:"user_agent"`
+	ReqHeaders  map[string]string `json:"req_headers,omitempty"`
+	RespHeaders map[string]string `json:"resp_headers,omitempty"`
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 type responseWriter struct {
 	gin.ResponseWriter
-	al *AccessLog
+	buf  bytes.Buffer
+	size int
 }
 
 func (w *responseWriter) Write(data []byte) (int, error) {
-	//w.al.RespBody = string(data)
-	if len(data) > 1024 {
-		w.al.RespBody = string(data[:1024])
-	} else {
-		w.al.RespBody = string(data)
-	}
+	w.capture(data)
 	return w.ResponseWriter.Write(data)
 }
 
-func (w *responseWriter) WriteHeader(statusCode int) {
-	w.al.Status = statusCode
-	w.ResponseWriter.WriteHeader(statusCode)
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *responseWriter) capture(data []byte) {
+	w.size += len(data)
+	if w.size <= maxCaptureBody {
+		w.buf.Write(data)
+	}
+}
+
+func (w *responseWriter) body(r *redactor) string {
+	contentType := w.Header().Get("Content-Type")
+	if w.size > maxCaptureBody || !textual(contentType) {
+		return omitted(contentType, w.size)
+	}
+	return truncate(r.body(contentType, w.buf.Bytes(), false))
 }
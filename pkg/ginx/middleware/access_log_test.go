@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bedrock/pkg/ginx"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogBuilder_Build(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		builder     func(b *AccessLogBuilder) *AccessLogBuilder
+		target      string
+		contentType string
+		reqBody     string
+		header      map[string]string
+		// handler 业务逻辑写的响应
+		handler func(ctx *gin.Context)
+
+		wantLogged   bool
+		wantReqBody  string
+		wantRespBody string
+		wantHeaders  map[string]string
+		wantUserID   string
+	}{
+		{
+			name: "redact default fields",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody().AllowRespBody()
+			},
+			target:      "/users/signup",
+			contentType: "application/json",
+			reqBody:     `{"email":"a@b.com","password":"hello#world123","confirmPassword":"hello#world123"}`,
+			handler: func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{"data": []gin.H{{"accessToken": "t1", "id": 1}}})
+			},
+			wantLogged:   true,
+			wantReqBody:  `{"confirmPassword":"***","email":"a@b.com","password":"***"}`,
+			wantRespBody: `{"data":[{"accessToken":"***","id":1}]}`,
+		},
+		{
+			name: "code only redacted in request",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody().AllowRespBody()
+			},
+			target:      "/users/login_sms",
+			contentType: "application/json",
+			reqBody:     `{"phone":"138","code":"123456"}`,
+			handler: func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, ginx.Result{Code: 401002, Msg: "验证码错误", Data: gin.H{"accessToken": "t1"}})
+			},
+			wantLogged:   true,
+			wantReqBody:  `{"code":"***","phone":"138"}`,
+			wantRespBody: `{"code":401002,"data":{"accessToken":"***"},"msg":"验证码错误"}`,
+		},
+		{
+			name: "large body not buffered",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody()
+			},
+			target:      "/users/edit",
+			contentType: "application/json",
+			reqBody:     `{"about":"` + strings.Repeat("a", maxCaptureBody) + `"}`,
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+			wantLogged:  true,
+			wantReqBody: fmt.Sprintf("[application/json, %d bytes]", maxCaptureBody+12),
+		},
+		{
+			name: "redact path",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody().RedactFields("profile.phone")
+			},
+			target:      "/users/edit",
+			contentType: "application/json",
+			reqBody:     `{"phone":"138","profile":{"phone":"139"}}`,
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+			wantLogged:  true,
+			wantReqBody: `{"phone":"138","profile":{"phone":"***"}}`,
+		},
+		{
+			name: "redact form",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody()
+			},
+			target:      "/users/login_sms",
+			contentType: "application/x-www-form-urlencoded",
+			reqBody:     "phone=138&code=123456",
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+			wantLogged:  true,
+			wantReqBody: "code=%2A%2A%2A&phone=138",
+		},
+		{
+			name: "invalid json not logged",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody()
+			},
+			target:      "/users/login",
+			contentType: "application/json",
+			reqBody:     `{"password":"abc"`,
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusBadRequest) },
+			wantLogged:  true,
+			wantReqBody: "[invalid json, 17 bytes]",
+		},
+		{
+			name: "binary body not read",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody()
+			},
+			target:      "/files/upload",
+			contentType: "application/octet-stream",
+			reqBody:     "abcd",
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+			wantLogged:  true,
+			wantReqBody: "[application/octet-stream, 4 bytes]",
+		},
+		{
+			name: "redact headers",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowHeaders()
+			},
+			target: "/users/profile",
+			header: map[string]string{"Authorization": "Bearer abc", "X-Request-Id": "r1"},
+			handler: func(ctx *gin.Context) {
+				ctx.Header("X-Jwt-Token", "abc")
+				ctx.Status(http.StatusOK)
+			},
+			wantLogged: true,
+			wantHeaders: map[string]string{
+				"req:Authorization": "***",
+				"req:X-Request-Id":  "r1",
+				"resp:X-Jwt-Token":  "***",
+			},
+		},
+		{
+			name: "deny route",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody().AllowRespBody().Deny("/users/*")
+			},
+			target:      "/users/login",
+			contentType: "application/json",
+			reqBody:     `{"email":"a@b.com"}`,
+			handler:     func(ctx *gin.Context) { ctx.JSON(http.StatusOK, gin.H{"id": 1}) },
+			wantLogged:  true,
+		},
+		{
+			name: "not in allow",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.AllowReqBody().Allow("/files/*")
+			},
+			target:      "/users/login",
+			contentType: "application/json",
+			reqBody:     `{"email":"a@b.com"}`,
+			handler:     func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+			wantLogged:  true,
+		},
+		{
+			name: "sampled out",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.Sample(2, 0.5)
+			},
+			target:  "/users/profile",
+			handler: func(ctx *gin.Context) { ctx.Status(http.StatusOK) },
+		},
+		{
+			name: "other class not sampled",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.Sample(2, 0)
+			},
+			target:     "/users/profile",
+			handler:    func(ctx *gin.Context) { ctx.Status(http.StatusInternalServerError) },
+			wantLogged: true,
+		},
+		{
+			name: "user id",
+			builder: func(b *AccessLogBuilder) *AccessLogBuilder {
+				return b.UserID(func(ctx *gin.Context) string { return ctx.GetString("uid") })
+			},
+			target: "/users/profile",
+			handler: func(ctx *gin.Context) {
+				ctx.Set("uid", "123")
+				ctx.Status(http.StatusOK)
+			},
+			wantLogged: true,
+			wantUserID: "123",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var logs []AccessLog
+			b := tc.builder(NewAccessLogBuilder(func(ctx context.Context, al AccessLog) {
+				logs = append(logs, al)
+			}))
+			b.random = func() float64 { return 0.7 }
+
+			server := gin.New()
+			server.Any(tc.target, b.Build(), func(ctx *gin.Context) {
+				// 请求体读过之后要放回去
+				raw, _ := ctx.GetRawData()
+				assert.Equal(t, tc.reqBody, string(raw))
+				tc.handler(ctx)
+			})
+			req := httptest.NewRequest(http.MethodPost, tc.target, bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("User-Agent", "bedrock-test")
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			server.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !tc.wantLogged {
+				assert.Empty(t, logs)
+				return
+			}
+			require.Len(t, logs, 1)
+			al := logs[0]
+			assert.Equal(t, tc.target, al.Path)
+			assert.Equal(t, "bedrock-test", al.UserAgent)
+			assert.Equal(t, "192.0.2.1", al.ClientIP)
+			assert.Equal(t, int64(len(tc.reqBody)), al.ReqSize)
+			assert.Equal(t, tc.wantReqBody, al.ReqBody)
+			assert.Equal(t, tc.wantRespBody, al.RespBody)
+			assert.Equal(t, tc.wantUserID, al.UserID)
+			for k, v := range tc.wantHeaders {
+				if name, ok := strings.CutPrefix(k, "req:"); ok {
+					assert.Equal(t, v, al.ReqHeaders[name])
+				} else if name, ok = strings.CutPrefix(k, "resp:"); ok {
+					assert.Equal(t, v, al.RespHeaders[name])
+				}
+			}
+		})
+	}
+}
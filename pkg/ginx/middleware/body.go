@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bedrock/pkg/ginx"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMemBody 请求体在内存里最多保留的大小，超过的部分写到临时文件
+const maxMemBody = 32 << 10
+
+var errBodyTooLarge = errors.New("请求体太大")
+
+// spooledBody 读过一遍的请求体，可以重新读。小的请求体放在内存里，大的写到临时文件，
+// 上传文件这类请求也不会整个读进内存
+type spooledBody struct {
+	mem  []byte
+	file *os.File
+	// sum 请求体 SHA-256 的十六进制
+	sum  string
+	size int64
+	r    io.Reader
+}
+
+func (b *spooledBody) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// Close 交给后面 handler 的请求体关闭时不删除临时文件，由 spoolBody 返回的 cleanup 删除
+func (b *spooledBody) Close() error {
+	return nil
+}
+
+func (b *spooledBody) rewind() error {
+	if b.file == nil {
+		b.r = bytes.NewReader(b.mem)
+		return nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	b.r = io.MultiReader(bytes.NewReader(b.mem), b.file)
+	return nil
+}
+
+// spoolBody 读出请求体，计算 SHA-256，然后把可以重新读的请求体放回去，返回请求体摘要的十六进制。
+// 前面的中间件已经读过的直接复用；超过 limit 返回 errBodyTooLarge。
+// cleanup 在后面的 handler 执行完之后调用，删除临时文件
+func spoolBody(ctx *gin.Context, limit int64) (sum string, cleanup func(), err error) {
+	cleanup = func() {}
+	if b, ok := ctx.Request.Body.(*spooledBody); ok {
+		if b.size > limit {
+			return "", cleanup, errBodyTooLarge
+		}
+		return b.sum, cleanup, nil
+	}
+	if ctx.Request.ContentLength > limit {
+		return "", cleanup, errBodyTooLarge
+	}
+	body := &spooledBody{}
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		empty := sha256.Sum256(nil)
+		body.sum = hex.EncodeToString(empty[:])
+		ctx.Request.Body = body
+		return body.sum, cleanup, body.rewind()
+	}
+
+	h := sha256.New()
+	// 多读一个字节，用来判断有没有超过 limit
+	src := io.TeeReader(io.LimitReader(ctx.Request.Body, limit+1), h)
+	body.mem, err = io.ReadAll(io.LimitReader(src, maxMemBody))
+	if err != nil {
+		return "", cleanup, err
+	}
+	body.size = int64(len(body.mem))
+	if body.size > limit {
+		return "", cleanup, errBodyTooLarge
+	}
+	if len(body.mem) == maxMemBody {
+		body.file, err = os.CreateTemp("", "bedrock-body-*")
+		if err != nil {
+			return "", cleanup, err
+		}
+		cleanup = func() {
+			_ = body.file.Close()
+			_ = os.Remove(body.file.Name())
+		}
+		n, err := io.Copy(body.file, src)
+		if err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+		body.size += n
+		if body.size > limit {
+			cleanup()
+			return "", func() {}, errBodyTooLarge
+		}
+	}
+	body.sum = hex.EncodeToString(h.Sum(nil))
+	if err = body.rewind(); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	ctx.Request.Body = body
+	return body.sum, cleanup, nil
+}
+
+// abortBody 读取请求体失败时的响应
+func abortBody(ctx *gin.Context, err error) {
+	if errors.Is(err, errBodyTooLarge) {
+		ginx.Abort(ctx, ginx.Result{
+			Code: http.StatusRequestEntityTooLarge,
+			Msg:  "common.body_too_large",
+		}, nil)
+		return
+	}
+	ginx.Abort(ctx, ginx.Result{
+		Code: http.StatusBadRequest,
+		Msg:  "common.bad_body",
+	}, nil)
+}
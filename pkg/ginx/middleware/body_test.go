@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolBody(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		body  string
+		limit int64
+		// chunked 为 true 时不带 Content-Length，只能读到超过上限才知道
+		chunked bool
+
+		wantErr  error
+		wantFile bool
+	}{
+		{
+			name:  "small body in memory",
+			body:  `{"email":"a@b.com"}`,
+			limit: 1 << 20,
+		},
+		{
+			name:     "large body spooled to file",
+			body:     strings.Repeat("a", maxMemBody*3+7),
+			limit:    1 << 20,
+			wantFile: true,
+		},
+		{
+			name:    "content length over limit",
+			body:    strings.Repeat("a", 100),
+			limit:   10,
+			wantErr: errBodyTooLarge,
+		},
+		{
+			name:    "chunked body over limit in memory",
+			body:    strings.Repeat("a", 100),
+			limit:   10,
+			chunked: true,
+			wantErr: errBodyTooLarge,
+		},
+		{
+			name:    "chunked body over limit in file",
+			body:    strings.Repeat("a", maxMemBody*2),
+			limit:   maxMemBody + 1,
+			chunked: true,
+			wantErr: errBodyTooLarge,
+		},
+		{
+			name:  "empty body",
+			limit: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPost, "/files/upload", bytes.NewBufferString(tc.body))
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = req
+
+			sum, cleanup, err := spoolBody(ctx, tc.limit)
+			defer cleanup()
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			want := sha256.Sum256([]byte(tc.body))
+			assert.Equal(t, hex.EncodeToString(want[:]), sum)
+
+			body, ok := ctx.Request.Body.(*spooledBody)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantFile, body.file != nil)
+			assert.LessOrEqual(t, len(body.mem), maxMemBody)
+
+			// 后面的 handler 读到的是完整的请求体
+			raw, err := io.ReadAll(ctx.Request.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.body, string(raw))
+
+			// 后面的中间件直接复用，不会再读一遍
+			again, cleanupAgain, err := spoolBody(ctx, tc.limit)
+			require.NoError(t, err)
+			cleanupAgain()
+			assert.Equal(t, sum, again)
+
+			if body.file != nil {
+				name := body.file.Name()
+				cleanup()
+				_, err = os.Stat(name)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
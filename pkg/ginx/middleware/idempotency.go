@@ -0,0 +1,316 @@
+package middleware
+
+import (
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/logger"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+//go:embed idempotency_acquire.lua
+var luaIdempotencyAcquire string
+
+//go:embed idempotency_save.lua
+var luaIdempotencySave string
+
+const (
+	// IdempotencyKeyHeader 客户端为每一次操作生成的唯一 key，重试的时候带上同一个 key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 响应是之前保存下来的，不是这一次请求执行的结果
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyBuilder 按照 Idempotency-Key 请求头保证写操作只执行一次：
+// 第一次请求执行完之后把响应保存在 Redis 里，带着同一个 key 的重试直接返回保存的响应；
+// 同一个 key 换了请求内容返回 422，上一次请求还没有结束返回 409。
+// 5xx 的响应不保存，客户端可以用同一个 key 重试
+type IdempotencyBuilder struct {
+	cmd     redis.Cmdable
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+	methods map[string]struct{}
+	// required 为 true 时没有带 key 的请求直接拒绝
+	required bool
+	// maxBody 超过这个大小的响应不保存，同一个 key 的重试会重新执行
+	maxBody int
+	// maxReqBody 计算指纹时请求体的上限，超过返回 413，大的请求体写到临时文件，不会整个读进内存
+	maxReqBody int64
+	// scope 区分不同的调用方，避免不同用户的 key 撞在一起
+	scope func(ctx *gin.Context) string
+	// newToken 标记持有锁的请求，测试里替换成固定值
+	newToken func() string
+}
+
+func NewIdempotencyBuilder(cmd redis.Cmdable) *IdempotencyBuilder {
+	return &IdempotencyBuilder{
+		cmd:     cmd,
+		prefix:  "idempotency",
+		ttl:     24 * time.Hour,
+		lockTTL: 30 * time.Second,
+		methods: map[string]struct{}{
+			http.MethodPost:  {},
+			http.MethodPatch: {},
+		},
+		maxBody:    1 << 20,
+		maxReqBody: 128 << 20,
+		scope:      defaultIdempotencyScope,
+		newToken:   randomToken,
+	}
+}
+
+func (b *IdempotencyBuilder) Prefix(prefix string) *IdempotencyBuilder {
+	b.prefix = prefix
+	return b
+}
+
+// TTL 响应保存多久，超过之后同一个 key 会重新执行
+func (b *IdempotencyBuilder) TTL(ttl time.Duration) *IdempotencyBuilder {
+	b.ttl = ttl
+	return b
+}
+
+// LockTTL 请求执行的最长时间，超过之后锁自动释放，避免进程挂掉之后 key 一直处于处理中
+func (b *IdempotencyBuilder) LockTTL(ttl time.Duration) *IdempotencyBuilder {
+	b.lockTTL = ttl
+	return b
+}
+
+// Methods 需要检查的请求方法，默认 POST 和 PATCH
+func (b *IdempotencyBuilder) Methods(methods ...string) *IdempotencyBuilder {
+	b.methods = make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		b.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	return b
+}
+
+// Required 没有带 Idempotency-Key 的请求返回 400，默认不带 key 的请求直接放行
+func (b *IdempotencyBuilder) Required() *IdempotencyBuilder {
+	b.required = true
+	return b
+}
+
+func (b *IdempotencyBuilder) MaxBody(size int) *IdempotencyBuilder {
+	b.maxBody = size
+	return b
+}
+
+// MaxReqBody 请求体的上限，要比上传文件这类接口自己的上限大
+func (b *IdempotencyBuilder) MaxReqBody(size int64) *IdempotencyBuilder {
+	b.maxReqBody = size
+	return b
+}
+
+// Scope 调用方的标识，默认是 Authorization 请求头，没有登录的请求用客户端 IP
+func (b *IdempotencyBuilder) Scope(fn func(ctx *gin.Context) string) *IdempotencyBuilder {
+	b.scope = fn
+	return b
+}
+
+func (b *IdempotencyBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := b.methods[ctx.Request.Method]; !ok {
+			ctx.Next()
+			return
+		}
+		idemKey := ctx.GetHeader(IdempotencyKeyHeader)
+		if idemKey == "" {
+			if b.required {
+				ginx.Abort(ctx, ginx.Result{
+					Code: http.StatusBadRequest,
+					Msg:  "idempotency.key_required",
+				}, nil)
+				return
+			}
+			ctx.Next()
+			return
+		}
+		if len(idemKey) > 255 {
+			ginx.Abort(ctx, ginx.Result{
+				Code: http.StatusBadRequest,
+				Msg:  "idempotency.key_too_long",
+			}, nil)
+			return
+		}
+
+		bodySum, cleanup, err := spoolBody(ctx, b.maxReqBody)
+		if err != nil {
+			abortBody(ctx, err)
+			return
+		}
+		defer cleanup()
+
+		key := b.key(ctx, idemKey)
+		token := b.newToken()
+		res, err := b.cmd.Eval(ctx, luaIdempotencyAcquire, []string{key},
+			token, fingerprint(ctx.Request, bodySum), b.lockTTL.Milliseconds()).StringSlice()
+		if err != nil || len(res) == 0 {
+			log.Error(ctx.Request.Context(), "检查幂等 key 失败", logger.Error(err))
+			ginx.Abort(ctx, ginx.Result{
+				Code: http.StatusInternalServerError,
+				Msg:  "common.internal_error",
+			}, nil)
+			return
+		}
+
+		switch res[0] {
+		case "conflict":
+			ginx.Abort(ctx, ginx.Result{
+				Code: http.StatusUnprocessableEntity,
+				Msg:  "idempotency.conflict",
+			}, nil)
+		case "in_flight":
+			ctx.Header("Retry-After", strconv.Itoa(int(b.lockTTL.Seconds())))
+			ginx.Abort(ctx, ginx.Result{
+				Code: http.StatusConflict,
+				Msg:  "idempotency.in_flight",
+			}, nil)
+		case "done":
+			b.replay(ctx, res)
+		default:
+			b.handle(ctx, key, token)
+		}
+	}
+}
+
+// handle 拿到锁之后执行后面的 handler，结束之后保存响应或者释放锁
+func (b *IdempotencyBuilder) handle(ctx *gin.Context, key, token string) {
+	w := &idempotencyWriter{ResponseWriter: ctx.Writer, limit: b.maxBody}
+	ctx.Writer = w
+	// handler panic 的时候也要释放锁，否则在 LockTTL 之内重试都会返回 409
+	saved := false
+	defer func() {
+		ctx.Writer = w.ResponseWriter
+		if !saved {
+			b.save(ctx, key, token, "")
+		}
+	}()
+
+	ctx.Next()
+
+	if ginx.Status(ctx) >= http.StatusInternalServerError || w.overflow {
+		return
+	}
+	resp, err := json.Marshal(storedResponse{
+		Status: w.Status(),
+		Header: storedHeader(w.Header()),
+		Body:   w.buf.Bytes(),
+	})
+	if err != nil {
+		log.Error(ctx.Request.Context(), "序列化幂等响应失败", logger.Error(err))
+		return
+	}
+	saved = true
+	b.save(ctx, key, token, string(resp))
+}
+
+// save resp 为空时释放锁
+func (b *IdempotencyBuilder) save(ctx *gin.Context, key, token, resp string) {
+	err := b.cmd.Eval(ctx, luaIdempotencySave, []string{key}, token, resp, b.ttl.Milliseconds()).Err()
+	if err != nil {
+		log.Error(ctx.Request.Context(), "保存幂等响应失败", logger.Error(err))
+	}
+}
+
+func (b *IdempotencyBuilder) replay(ctx *gin.Context, res []string) {
+	var resp storedResponse
+	if len(res) < 2 || json.Unmarshal([]byte(res[1]), &resp) != nil {
+		log.Error(ctx.Request.Context(), "幂等响应格式错误")
+		ginx.Abort(ctx, ginx.Result{
+			Code: http.StatusInternalServerError,
+			Msg:  "common.internal_error",
+		}, nil)
+		return
+	}
+	for k, vals := range resp.Header {
+		ctx.Writer.Header()[k] = vals
+	}
+	ctx.Header(IdempotentReplayedHeader, "true")
+	ctx.Status(resp.Status)
+	_, _ = ctx.Writer.Write(resp.Body)
+	ctx.Abort()
+}
+
+// key 调用方和客户端的 key 一起哈希，Authorization 这类敏感信息不会直接出现在 Redis 里
+func (b *IdempotencyBuilder) key(ctx *gin.Context, idemKey string) string {
+	sum := sha256.Sum256([]byte(b.scope(ctx) + "\n" + idemKey))
+	return b.prefix + ":" + hex.EncodeToString(sum[:])
+}
+
+func defaultIdempotencyScope(ctx *gin.Context) string {
+	if auth := ctx.GetHeader("Authorization"); auth != "" {
+		return auth
+	}
+	return ctx.ClientIP()
+}
+
+// fingerprint 同一个 key 的请求方法、路径、查询参数和请求体必须完全一致，bodySum 是请求体 SHA-256 的十六进制
+func fingerprint(r *http.Request, bodySum string) string {
+	sum := sha256.Sum256([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n" + bodySum))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type storedResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+// storedHeader 跨域相关的响应头取决于每一次请求的 Origin，长度和日期重放的时候重新计算，都不保存
+func storedHeader(h http.Header) map[string][]string {
+	res := make(map[string][]string, len(h))
+	for k, vals := range h {
+		if strings.HasPrefix(k, "Access-Control-") || k == "Content-Length" || k == "Date" || k == "Vary" {
+			continue
+		}
+		res[k] = vals
+	}
+	return res
+}
+
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
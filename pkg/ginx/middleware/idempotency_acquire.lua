@@ -0,0 +1,21 @@
+-- 第一次请求：记录指纹，加上处理中的锁
+-- 重复请求：指纹不一致说明复用了 key，处理中说明上一次请求还没有结束，处理完了就返回保存的响应
+local key = KEYS[1]
+local token = ARGV[1]
+local fp = ARGV[2]
+local lockTTL = tonumber(ARGV[3])
+
+if redis.call("EXISTS", key) == 0 then
+    redis.call("HSET", key, "fp", fp, "state", "processing", "owner", token)
+    redis.call("PEXPIRE", key, lockTTL)
+    return {"acquired"}
+end
+
+local vals = redis.call("HMGET", key, "fp", "state", "resp")
+if vals[1] ~= fp then
+    return {"conflict"}
+end
+if vals[2] ~= "done" then
+    return {"in_flight"}
+end
+return {"done", vals[3]}
@@ -0,0 +1,17 @@
+-- 只有持有锁的请求才能保存响应，锁过期之后被其他请求拿走了就什么都不做
+-- 响应为空表示这次请求失败了，删掉 key，让客户端可以重试
+local key = KEYS[1]
+local token = ARGV[1]
+local resp = ARGV[2]
+local ttl = tonumber(ARGV[3])
+
+if redis.call("HGET", key, "owner") ~= token then
+    return 0
+end
+if resp == "" then
+    redis.call("DEL", key)
+    return 1
+end
+redis.call("HSET", key, "state", "done", "resp", resp)
+redis.call("PEXPIRE", key, ttl)
+return 1
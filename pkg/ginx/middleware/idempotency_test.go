@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyBuilder_Build(t *testing.T) {
+	t.Parallel()
+	sum := sha256.Sum256([]byte("scope\nk1"))
+	key := "idempotency:" + hex.EncodeToString(sum[:])
+	body := `{"email":"a@b.com"}`
+	bodySum := sha256.Sum256([]byte(body))
+	fp := fingerprint(httptest.NewRequest(http.MethodPost, "/users/signup", nil), hex.EncodeToString(bodySum[:]))
+	okResp, err := json.Marshal(storedResponse{
+		Status: http.StatusOK,
+		Header: map[string][]string{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:   []byte(`{"id":1}`),
+	})
+	require.NoError(t, err)
+	replayResp, err := json.Marshal(storedResponse{
+		Status: http.StatusCreated,
+		Header: map[string][]string{"X-Foo": {"bar"}},
+		Body:   []byte(`{"id":1}`),
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		mock     func(mock redismock.ClientMock)
+		method   string
+		idemKey  string
+		required bool
+		// maxReqBody 不为 0 时限制请求体大小
+		maxReqBody int64
+		// status 业务逻辑返回的状态码
+		status int
+
+		wantStatus   int
+		wantBody     string
+		wantCalled   bool
+		wantReplayed bool
+		wantHeader   map[string]string
+	}{
+		{
+			name:       "not checked method",
+			mock:       func(mock redismock.ClientMock) {},
+			method:     http.MethodGet,
+			idemKey:    "k1",
+			status:     http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+			wantCalled: true,
+		},
+		{
+			name:       "no key",
+			mock:       func(mock redismock.ClientMock) {},
+			method:     http.MethodPost,
+			status:     http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+			wantCalled: true,
+		},
+		{
+			name:       "key required",
+			mock:       func(mock redismock.ClientMock) {},
+			method:     http.MethodPost,
+			required:   true,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			mock:       func(mock redismock.ClientMock) {},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			maxReqBody: 8,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "first request saves response",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetVal([]interface{}{"acquired"})
+				mock.ExpectEval(luaIdempotencySave, []string{key}, "tok", string(okResp), int64(86400000)).SetVal(int64(1))
+			},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			status:     http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+			wantCalled: true,
+		},
+		{
+			name: "server error releases lock",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetVal([]interface{}{"acquired"})
+				mock.ExpectEval(luaIdempotencySave, []string{key}, "tok", "", int64(86400000)).SetVal(int64(1))
+			},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			status:     http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"id":1}`,
+			wantCalled: true,
+		},
+		{
+			name: "replay",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetVal([]interface{}{"done", string(replayResp)})
+			},
+			method:       http.MethodPost,
+			idemKey:      "k1",
+			wantStatus:   http.StatusCreated,
+			wantBody:     `{"id":1}`,
+			wantReplayed: true,
+			wantHeader:   map[string]string{"X-Foo": "bar"},
+		},
+		{
+			name: "conflict",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetVal([]interface{}{"conflict"})
+			},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "in flight",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetVal([]interface{}{"in_flight"})
+			},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			wantStatus: http.StatusConflict,
+			wantHeader: map[string]string{"Retry-After": "30"},
+		},
+		{
+			name: "redis error",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectEval(luaIdempotencyAcquire, []string{key}, "tok", fp, int64(30000)).SetErr(errors.New("redis error"))
+			},
+			method:     http.MethodPost,
+			idemKey:    "k1",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cmd, mock := redismock.NewClientMock()
+			tc.mock(mock)
+
+			b := NewIdempotencyBuilder(cmd).Scope(func(ctx *gin.Context) string { return "scope" })
+			b.newToken = func() string { return "tok" }
+			if tc.required {
+				b = b.Required()
+			}
+			if tc.maxReqBody > 0 {
+				b = b.MaxReqBody(tc.maxReqBody)
+			}
+			called := false
+			server := gin.New()
+			server.Handle(tc.method, "/users/signup", b.Build(), func(ctx *gin.Context) {
+				called = true
+				// 请求体读过之后要放回去
+				raw, _ := ctx.GetRawData()
+				if tc.method == http.MethodPost {
+					assert.Equal(t, body, string(raw))
+				}
+				ctx.JSON(tc.status, gin.H{"id": 1})
+			})
+
+			req := httptest.NewRequest(tc.method, "/users/signup", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.idemKey != "" {
+				req.Header.Set(IdempotencyKeyHeader, tc.idemKey)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantCalled, called)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+			assert.Equal(t, tc.wantReplayed, recorder.Header().Get(IdempotentReplayedHeader) == "true")
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, recorder.Header().Get(k))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package middleware
+
+import "bedrock/pkg/logger"
+
+var log = logger.NewNopLogger()
+
+func SetLogger(l logger.Logger) {
+	log = l
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"bedrock/pkg/ginx"
 	"strconv"
 	"time"
 
@@ -55,9 +56,9 @@ func (p *PrometheusBuilder) BuildResponseTime() gin.HandlerFunc {
 		method := ctx.Request.Method
 		start := time.Now()
 		defer func() {
-			// 最后我们再来统计一下
+			// 最后我们再来统计一下，兼容模式下写出去的总是 200，要用真实的状态码
 			vector.WithLabelValues(method, ctx.FullPath(),
-				strconv.Itoa(ctx.Writer.Status())).
+				strconv.Itoa(ginx.Status(ctx))).
 				Observe(float64(time.Since(start).Milliseconds())) // 执行时间
 		}()
 		ctx.Next()
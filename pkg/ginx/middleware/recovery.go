@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/logger"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecoveryBuilder 替换 gin 自带的 Recovery：panic 通过 logger.Logger 记录，带上调用栈、请求和 trace，
+// 当前的 span 标记为失败，返回和业务逻辑一样的 ginx.Result。
+// 要放在 otelgin 后面，否则拿不到 span；放在访问日志、监控后面，它们才能记录到 500
+type RecoveryBuilder struct {
+	l        logger.Logger
+	counter  *prometheus.CounterVec
+	redactor *redactor
+}
+
+func NewRecoveryBuilder(l logger.Logger) *RecoveryBuilder {
+	return &RecoveryBuilder{
+		l:        l,
+		redactor: newRedactor(),
+	}
+}
+
+// Counter panic 的次数，标签是 method 和 pattern，见 NewPanicCounter
+func (b *RecoveryBuilder) Counter(c *prometheus.CounterVec) *RecoveryBuilder {
+	b.counter = c
+	return b
+}
+
+// NewPanicCounter 创建 panic 计数器，需要调用方自己注册
+func NewPanicCounter(namespace, subsystem string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "panics_total",
+		Help:      "HTTP 接口 panic 的次数",
+	}, []string{"method", "pattern"})
+}
+
+func (b *RecoveryBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			err, ok := rec.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rec)
+			}
+			reqCtx := ctx.Request.Context()
+			fields := []logger.Field{
+				logger.Error(err),
+				logger.String("request", b.dump(ctx.Request)),
+			}
+			if sc := trace.SpanContextFromContext(reqCtx); sc.IsValid() {
+				fields = append(fields,
+					logger.String("trace_id", sc.TraceID().String()),
+					logger.String("span_id", sc.SpanID().String()))
+			}
+
+			// 客户端断开了连接，不是我们的问题，也没办法再写响应
+			if brokenPipe(err) {
+				b.l.Warn(reqCtx, "客户端断开连接", fields...)
+				_ = ctx.Error(err)
+				ctx.Abort()
+				return
+			}
+
+			stack := debug.Stack()
+			b.l.Error(reqCtx, "panic", append(fields, logger.String("stack", string(stack)))...)
+			span := trace.SpanFromContext(reqCtx)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic: "+err.Error())
+			if b.counter != nil {
+				b.counter.WithLabelValues(ctx.Request.Method, ctx.FullPath()).Inc()
+			}
+
+			// 已经写过响应头的没办法再改状态码
+			if ctx.Writer.Written() {
+				ctx.Abort()
+				return
+			}
+			ginx.Abort(ctx, ginx.Result{
+				Code: http.StatusInternalServerError,
+				Msg:  "common.internal_error",
+			}, nil)
+		}()
+		ctx.Next()
+	}
+}
+
+// dump 请求行和请求头，敏感的头脱敏，请求体不记录
+func (b *RecoveryBuilder) dump(r *http.Request) string {
+	clone := r.Clone(r.Context())
+	clone.Header = make(http.Header, len(r.Header))
+	for k, v := range b.redactor.header(r.Header) {
+		clone.Header.Set(k, v)
+	}
+	res, err := httputil.DumpRequest(clone, false)
+	if err != nil {
+		return ""
+	}
+	return string(res)
+}
+
+func brokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, http.ErrAbortHandler)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bedrock/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRecoveryBuilder_Build(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		handler func(ctx *gin.Context)
+
+		wantStatus   int
+		wantBody     string
+		wantLevel    string
+		wantPanics   float64
+		wantSpanFail bool
+	}{
+		{
+			name:         "panic",
+			handler:      func(ctx *gin.Context) { panic("boom") },
+			wantStatus:   http.StatusInternalServerError,
+			wantBody:     `{"code":500,"msg":"common.internal_error"}`,
+			wantLevel:    "error",
+			wantPanics:   1,
+			wantSpanFail: true,
+		},
+		{
+			name: "panic after written",
+			handler: func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, "partial")
+				panic(errors.New("boom"))
+			},
+			wantStatus:   http.StatusOK,
+			wantBody:     "partial",
+			wantLevel:    "error",
+			wantPanics:   1,
+			wantSpanFail: true,
+		},
+		{
+			name: "broken pipe",
+			handler: func(ctx *gin.Context) {
+				panic(fmt.Errorf("write: %w", syscall.EPIPE))
+			},
+			// 没有写响应，httptest 默认是 200
+			wantStatus: http.StatusOK,
+			wantLevel:  "warn",
+		},
+		{
+			name:       "no panic",
+			handler:    func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") },
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			l := &recordLogger{}
+			panics := NewPanicCounter("bedrock", "test")
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				spanCtx, span := tp.Tracer("test").Start(ctx.Request.Context(), "request")
+				defer span.End()
+				ctx.Request = ctx.Request.WithContext(spanCtx)
+				ctx.Next()
+			})
+			server.Use(NewRecoveryBuilder(l).Counter(panics).Build())
+			server.GET("/users/profile", tc.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			var m dto.Metric
+			require.NoError(t, panics.WithLabelValues(http.MethodGet, "/users/profile").Write(&m))
+			assert.Equal(t, tc.wantPanics, m.GetCounter().GetValue())
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tc.wantSpanFail, spans[0].Status.Code == codes.Error)
+
+			if tc.wantLevel == "" {
+				assert.Empty(t, l.entries)
+				return
+			}
+			require.Len(t, l.entries, 1)
+			e := l.entries[0]
+			assert.Equal(t, tc.wantLevel, e.level)
+			assert.Equal(t, spans[0].SpanContext.TraceID().String(), e.fields["trace_id"])
+			// 请求头脱敏之后再记录
+			assert.Contains(t, e.fields["request"], "Authorization: ***")
+			assert.NotContains(t, e.fields["request"], "secret")
+			_, hasStack := e.fields["stack"]
+			assert.Equal(t, tc.wantLevel == "error", hasStack)
+		})
+	}
+}
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]any
+}
+
+// recordLogger 记录下所有日志，方便检查字段
+type recordLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (r *recordLogger) record(level, msg string, args []logger.Field) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fields := make(map[string]any, len(args))
+	for _, arg := range args {
+		fields[arg.Key] = arg.Val
+	}
+	r.entries = append(r.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (r *recordLogger) Debug(ctx context.Context, msg string, args ...logger.Field) {
+	r.record("debug", msg, args)
+}
+
+func (r *recordLogger) Info(ctx context.Context, msg string, args ...logger.Field) {
+	r.record("info", msg, args)
+}
+
+func (r *recordLogger) Warn(ctx context.Context, msg string, args ...logger.Field) {
+	r.record("warn", msg, args)
+}
+
+func (r *recordLogger) Error(ctx context.Context, msg string, args ...logger.Field) {
+	r.record("error", msg, args)
+}
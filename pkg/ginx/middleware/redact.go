@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "***"
+
+// DefaultRedactFields 默认脱敏的 JSON 字段，请求体和响应体都生效，不区分大小写
+// 不带 . 的名字匹配任意层级的同名字段，带 . 的是从根开始的路径，数组是透明的，例如 data.token 匹配 {"data":[{"token":""}]}
+var DefaultRedactFields = []string{
+	"password", "confirmPassword", "oldPassword", "newPassword",
+	"token", "accessToken", "refreshToken",
+}
+
+// DefaultRedactReqFields 只在请求体里脱敏的字段，规则和 DefaultRedactFields 一样
+// 请求里的 code 是验证码，响应里的 code 是 ginx.Result 的业务码，排查问题要用，不能脱敏
+var DefaultRedactReqFields = []string{"code"}
+
+// DefaultRedactHeaders 默认脱敏的请求头和响应头
+var DefaultRedactHeaders = []string{
+	"Authorization", "Cookie", "Set-Cookie",
+	"X-Jwt-Token", "X-Refresh-Token", SignatureHeader,
+}
+
+type redactor struct {
+	fields *fieldSet
+	// reqFields 只在请求体里生效
+	reqFields *fieldSet
+	headers   map[string]struct{}
+}
+
+func newRedactor() *redactor {
+	r := &redactor{
+		fields:    newFieldSet(),
+		reqFields: newFieldSet(),
+		headers:   map[string]struct{}{},
+	}
+	r.fields.add(DefaultRedactFields...)
+	r.reqFields.add(DefaultRedactReqFields...)
+	r.addHeaders(DefaultRedactHeaders...)
+	return r
+}
+
+func (r *redactor) addHeaders(headers ...string) {
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+}
+
+func (r *redactor) field(path, name string, req bool) bool {
+	return r.fields.match(path, name) || (req && r.reqFields.match(path, name))
+}
+
+type fieldSet struct {
+	// names 任意层级匹配的字段名
+	names map[string]struct{}
+	// paths 从根开始匹配的路径
+	paths map[string]struct{}
+}
+
+func newFieldSet() *fieldSet {
+	return &fieldSet{
+		names: map[string]struct{}{},
+		paths: map[string]struct{}{},
+	}
+}
+
+func (s *fieldSet) add(fields ...string) {
+	for _, f := range fields {
+		f = strings.ToLower(f)
+		if strings.Contains(f, ".") {
+			s.paths[f] = struct{}{}
+		} else {
+			s.names[f] = struct{}{}
+		}
+	}
+}
+
+func (s *fieldSet) match(path, name string) bool {
+	if _, ok := s.names[strings.ToLower(name)]; ok {
+		return true
+	}
+	_, ok := s.paths[strings.ToLower(path)]
+	return ok
+}
+
+// body 按照 Content-Type 脱敏，JSON 和表单按照字段脱敏，解析不了的内容只记录长度，避免敏感信息原样写进日志
+// req 为 true 表示请求体，DefaultRedactReqFields 这类只针对请求的规则也要生效
+func (r *redactor) body(contentType string, body []byte, req bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var val any
+		if err := dec.Decode(&val); err != nil {
+			return omitted("invalid json", len(body))
+		}
+		res, err := json.Marshal(r.json("", val, req))
+		if err != nil {
+			return omitted("invalid json", len(body))
+		}
+		return string(res)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		vals, err := url.ParseQuery(string(body))
+		if err != nil {
+			return omitted("invalid form", len(body))
+		}
+		for k := range vals {
+			if r.field(k, k, req) {
+				vals[k] = []string{redacted}
+			}
+		}
+		return vals.Encode()
+	case textual(contentType):
+		return string(body)
+	}
+	return omitted(contentType, len(body))
+}
+
+func (r *redactor) json(path string, val any, req bool) any {
+	switch v := val.(type) {
+	case map[string]any:
+		for k, child := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if r.field(p, k, req) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = r.json(p, child, req)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = r.json(path, child, req)
+		}
+	}
+	return val
+}
+
+// header 多个值用逗号拼起来
+func (r *redactor) header(h http.Header) map[string]string {
+	res := make(map[string]string, len(h))
+	for k, vals := range h {
+		if _, ok := r.headers[http.CanonicalHeaderKey(k)]; ok {
+			res[k] = redacted
+			continue
+		}
+		res[k] = strings.Join(vals, ", ")
+	}
+	return res
+}
+
+// textual 可以直接记录的内容，上传的文件这类内容不读进内存
+func textual(contentType string) bool {
+	return contentType == "" ||
+		strings.Contains(contentType, "json") ||
+		strings.HasPrefix(contentType, "application/x-www-form-urlencoded") ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "xml")
+}
+
+func omitted(kind string, size int) string {
+	return fmt.Sprintf("[%s, %d bytes]", kind, size)
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayProtectBuilder_Build(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	secret := []byte("ios-secret")
+	body := `{"phone":"13800138000"}`
+	// 超过 maxMemBody 的请求体写到临时文件，签名一样要能校验
+	largeBody := strings.Repeat("a", maxMemBody*2)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sign := func(path, query, ts, nonce, body string) string {
+		return SignRequest(secret, http.MethodPost, path, query, ts, nonce, []byte(body))
+	}
+
+	testCases := []struct {
+		name     string
+		mock     func(mock redismock.ClientMock)
+		required bool
+		// maxBody 不为 0 时限制请求体大小
+		maxBody int64
+		target  string
+		body    string
+		header  map[string]string
+
+		wantStatus int
+		wantCalled bool
+	}{
+		{
+			name:       "unsigned request passes",
+			mock:       func(mock redismock.ClientMock) {},
+			target:     "/users/login_sms/code/send",
+			body:       body,
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:       "unsigned request required",
+			mock:       func(mock redismock.ClientMock) {},
+			required:   true,
+			target:     "/users/login_sms/code/send",
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "valid",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX("nonce:ios:n1", ts, 10*time.Minute).SetVal(true)
+			},
+			target: "/users/login_sms/code/send?b=2&a=1",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				// 查询参数排序之后签名
+				SignatureHeader: sign("/users/login_sms/code/send", "a=1&b=2", ts, "n1", body),
+			},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:   "missing signature",
+			mock:   func(mock redismock.ClientMock) {},
+			target: "/users/login_sms/code/send",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown app",
+			mock:   func(mock redismock.ClientMock) {},
+			target: "/users/login_sms/code/send",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "web",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", body),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "timestamp out of skew",
+			mock:   func(mock redismock.ClientMock) {},
+			target: "/users/login_sms/code/send",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10),
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10), "n1", body),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "tampered body",
+			mock:   func(mock redismock.ClientMock) {},
+			target: "/users/login_sms/code/send",
+			body:   `{"phone":"13900139000"}`,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", body),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "nonce used",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX("nonce:ios:n1", ts, 10*time.Minute).SetVal(false)
+			},
+			target: "/users/login_sms/code/send",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", body),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "body too large",
+			mock:    func(mock redismock.ClientMock) {},
+			maxBody: 8,
+			target:  "/users/login_sms/code/send",
+			body:    body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", body),
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "large body",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX("nonce:ios:n1", ts, 10*time.Minute).SetVal(true)
+			},
+			target: "/users/login_sms/code/send",
+			body:   largeBody,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", largeBody),
+			},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name: "redis error",
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX("nonce:ios:n1", ts, 10*time.Minute).SetErr(errors.New("redis error"))
+			},
+			target: "/users/login_sms/code/send",
+			body:   body,
+			header: map[string]string{
+				AppIDHeader:     "ios",
+				TimestampHeader: ts,
+				NonceHeader:     "n1",
+				SignatureHeader: sign("/users/login_sms/code/send", "", ts, "n1", body),
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cmd, mock := redismock.NewClientMock()
+			tc.mock(mock)
+
+			b := NewReplayProtectBuilder(cmd, map[string][]byte{"ios": secret})
+			b.now = func() time.Time { return now }
+			if tc.required {
+				b = b.Required()
+			}
+			if tc.maxBody > 0 {
+				b = b.MaxBody(tc.maxBody)
+			}
+			called := false
+			server := gin.New()
+			server.POST("/users/login_sms/code/send", b.Build(), func(ctx *gin.Context) {
+				called = true
+				raw, _ := ctx.GetRawData()
+				assert.Equal(t, tc.body, string(raw))
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tc.target, bytes.NewBufferString(tc.body))
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantCalled, called)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestRequestPath 版本协商改写路径之后，签名仍然按照客户端请求的路径计算
+func TestRequestPath(t *testing.T) {
+	t.Parallel()
+	req := httptest.NewRequest(http.MethodGet, "/users/profile?a=1", nil)
+	req.URL.Path = "/api/v1/users/profile"
+	assert.Equal(t, "/users/profile", requestPath(req))
+}
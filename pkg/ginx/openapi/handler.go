@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 返回 DefaultSpec 生成的文档，挂在 /openapi.json
+func Handler(info Info) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, defaultSpec.Document(info))
+	}
+}
+
+// UIHandler Swagger UI 页面，specURL 是文档的地址，静态资源从 CDN 加载
+func UIHandler(specURL string) gin.HandlerFunc {
+	page := fmt.Sprintf(uiTemplate, specURL)
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+const uiTemplate = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+  <meta charset="utf-8">
+  <title>API 文档</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+  window.ui = SwaggerUIBundle({url: %q, dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"bedrock/pkg/ginx"
+	"path"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	defaultSpec = NewSpec()
+	// versionSeg 路径里 API 前缀和版本号这样的段不能当做默认的标签，例如 /api/v1/users 的标签是 users
+	versionSeg = regexp.MustCompile(`^(api|v[0-9]+)$`)
+)
+
+// DefaultSpec 下面的 Wrap* 注册路由时使用的全局 Spec
+func DefaultSpec() *Spec {
+	return defaultSpec
+}
+
+// Option 补充 Wrap* 推断不出来的信息
+type Option func(r *Route)
+
+func Summary(summary string) Option {
+	return func(r *Route) {
+		r.Summary = summary
+	}
+}
+
+// Tags 默认是路径里除了 API 前缀和版本号的第一段，例如 /api/v1/users/login 是 users
+func Tags(tags ...string) Option {
+	return func(r *Route) {
+		r.Tags = tags
+	}
+}
+
+func Deprecated() Option {
+	return func(r *Route) {
+		r.Deprecated = true
+	}
+}
+
+// Data 成功时 Result.Data 的类型
+func Data[T any]() Option {
+	return func(r *Route) {
+		r.Data = reflect.TypeFor[T]()
+	}
+}
+
+// Query 业务逻辑自己用 ctx.Query 读取的参数，T 是参数的类型
+func Query[T any](name string) Option {
+	return func(r *Route) {
+		r.Params = append(r.Params, Param{Name: name, In: InQuery, Type: reflect.TypeFor[T]()})
+	}
+}
+
+// Header 业务逻辑自己用 ctx.GetHeader 读取的请求头
+func Header(name string, required bool) Option {
+	return func(r *Route) {
+		r.Params = append(r.Params, Param{Name: name, In: InHeader, Type: reflect.TypeFor[string](), Required: required})
+	}
+}
+
+// FormFile 业务逻辑自己用 ctx.FormFile 读取的文件，请求体变成 multipart/form-data
+func FormFile(name string) Option {
+	return func(r *Route) {
+		r.FormFields = append(r.FormFields, Param{Name: name, Type: fileHeaderType, Required: true})
+	}
+}
+
+// FormValue 和 FormFile 一起上传的普通字段
+func FormValue[T any](name string) Option {
+	return func(r *Route) {
+		r.FormFields = append(r.FormFields, Param{Name: name, Type: reflect.TypeFor[T]()})
+	}
+}
+
+// 下面的 Wrap* 和 ginx 里的同名函数一样，额外把接口记录到 DefaultSpec 里
+
+func Wrap(g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.Wrap(bizFn))
+	register(g, method, relativePath, bizFn, nil, "", false, opts)
+}
+
+func WrapBody[Req any](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, req Req) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapBody(bizFn))
+	register(g, method, relativePath, bizFn, reflect.TypeFor[Req](), reqIn(method), false, opts)
+}
+
+func WrapQuery[Req any](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, req Req) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapQuery(bizFn))
+	register(g, method, relativePath, bizFn, reflect.TypeFor[Req](), InQuery, false, opts)
+}
+
+func WrapURI[Req any](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, req Req) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapURI(bizFn))
+	register(g, method, relativePath, bizFn, reflect.TypeFor[Req](), InPath, false, opts)
+}
+
+func WrapReq[Req any, Claims jwt.Claims](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, req Req, uc Claims) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapReq(bizFn))
+	register(g, method, relativePath, bizFn, reflect.TypeFor[Req](), InAll, true, opts)
+}
+
+func WrapClaims[Claims any](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, uc Claims) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapClaims(bizFn))
+	register(g, method, relativePath, bizFn, nil, "", true, opts)
+}
+
+func WrapBodyAndClaims[Req any, Claims jwt.Claims](g *gin.RouterGroup, method, relativePath string,
+	bizFn func(ctx *gin.Context, req Req, uc Claims) (ginx.Result, error), opts ...Option) {
+	g.Handle(method, relativePath, ginx.WrapBodyAndClaims(bizFn))
+	register(g, method, relativePath, bizFn, reflect.TypeFor[Req](), reqIn(method), true, opts)
+}
+
+func register(g *gin.RouterGroup, method, relativePath string, bizFn any, req reflect.Type, in string, auth bool, opts []Option) {
+	full := joinPath(g.BasePath(), relativePath)
+	r := Route{
+		Method:      method,
+		Path:        full,
+		OperationID: operationID(bizFn),
+		Auth:        auth,
+		Req:         req,
+		ReqIn:       in,
+	}
+	for _, seg := range strings.Split(strings.Trim(full, "/"), "/") {
+		if seg != "" && !versionSeg.MatchString(seg) {
+			r.Tags = []string{seg}
+			break
+		}
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	defaultSpec.Add(r)
+}
+
+func joinPath(base, relativePath string) string {
+	if relativePath == "" {
+		return base
+	}
+	return path.Join(base, relativePath)
+}
+
+// operationID 用业务逻辑的方法名，例如 bedrock/internal/web.(*UserHandler).SignUp-fm -> UserHandler.SignUp
+func operationID(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	name = name[strings.LastIndex(name, "/")+1:]
+	if _, after, ok := strings.Cut(name, "."); ok {
+		name = after
+	}
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	fileHeaderType = reflect.TypeFor[multipart.FileHeader]()
+	invalidName    = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// schemaGen 把 Go 类型转换成 Schema，具名结构体放到 components 里，用 $ref 引用
+type schemaGen struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	taken   map[string]reflect.Type
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+		taken:   make(map[string]reflect.Type),
+	}
+}
+
+// field 结构体里参与绑定的字段，匿名嵌入的结构体会被展开
+type field struct {
+	name    string
+	typ     reflect.Type
+	binding string
+	// tagged 字段名是 tag 里写的，不是字段名
+	tagged bool
+	tag    reflect.StructTag
+}
+
+// fieldsOf tag 是字段名使用的 tag，请求体用 json，查询参数用 form
+func fieldsOf(t reflect.Type, tag string) []field {
+	var res []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			res = append(res, fieldsOf(ft, tag)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = f.Name
+		}
+		res = append(res, field{name: name, typ: f.Type, binding: f.Tag.Get("binding"), tagged: tagged, tag: f.Tag})
+	}
+	return res
+}
+
+func (g *schemaGen) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.register(t)}
+	default:
+		// interface{} 之类的类型，什么都可以
+		return &Schema{}
+	}
+}
+
+// register 具名结构体只生成一次，名字冲突时带上包名
+func (g *schemaGen) register(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := invalidName.ReplaceAllString(t.Name(), "_")
+	if other, ok := g.taken[name]; ok && other != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	g.taken[name] = t
+	// 先占位，结构体引用自己的时候不会死循环
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fieldsOf(t, "json") {
+		fs := g.schemaOf(f.typ)
+		if applyBinding(fs, f.typ, f.binding) {
+			s.Required = append(s.Required, f.name)
+		}
+		s.Properties[f.name] = fs
+	}
+	return s
+}
+
+// applyBinding 把 binding tag 里的校验规则转换成 Schema 的约束，返回字段是否必填
+// 只支持常用的规则，其余的规则忽略；dive 之后的规则是给元素用的，也忽略
+func applyBinding(s *Schema, t reflect.Type, binding string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "phone":
+			s.Format = "phone"
+		case "alphanum":
+			s.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			s.Pattern = "^[0-9]+$"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(t, v))
+			}
+		case "len":
+			setLength(s, t, param, true, true)
+		case "min":
+			setLength(s, t, param, true, false)
+		case "max":
+			setLength(s, t, param, false, true)
+		case "gt", "gte", "lt", "lte":
+			v, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "gt":
+				s.ExclusiveMinimum = &v
+			case "gte":
+				s.Minimum = &v
+			case "lt":
+				s.ExclusiveMaximum = &v
+			case "lte":
+				s.Maximum = &v
+			}
+		}
+	}
+	return required
+}
+
+// setLength min、max、len 对字符串是长度，对切片是元素个数，对数字是取值范围
+func setLength(s *Schema, t reflect.Type, param string, isMin, isMax bool) {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return
+		}
+		str := t.Kind() == reflect.String
+		if isMin {
+			if str {
+				s.MinLength = &n
+			} else {
+				s.MinItems = &n
+			}
+		}
+		if isMax {
+			if str {
+				s.MaxLength = &n
+			} else {
+				s.MaxItems = &n
+			}
+		}
+	default:
+		v, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if isMin {
+			s.Minimum = &v
+		}
+		if isMax {
+			s.Maximum = &v
+		}
+	}
+}
+
+func enumValue(t reflect.Type, v string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
@@ -0,0 +1,279 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 请求参数的位置
+const (
+	InBody   = "body"
+	InQuery  = "query"
+	InPath   = "path"
+	InHeader = "header"
+	// InAll 路径参数、查询参数、请求头和请求体都有，见 ginx.WrapReq
+	InAll = "all"
+)
+
+// paramTags 各个位置的参数在结构体里用的 tag，和 gin 的绑定规则一致
+var paramTags = map[string]string{
+	InPath:   "uri",
+	InQuery:  "form",
+	InHeader: "header",
+}
+
+// Route 一个接口的描述，注册路由的时候记录下来，生成文档的时候再转换成 Operation
+type Route struct {
+	Method string
+	// Path gin 格式的完整路径，例如 /files/:id
+	Path        string
+	OperationID string
+	Summary     string
+	Tags        []string
+	Deprecated  bool
+	// Auth 需要登录，也就是使用了带 Claims 的 Wrap*
+	Auth bool
+	// Req 请求参数的结构体，ReqIn 是它的位置
+	Req   reflect.Type
+	ReqIn string
+	// Data 成功时 Result.Data 的类型，nil 表示没有数据
+	Data reflect.Type
+	// Params 业务逻辑自己读取的参数，例如 ctx.Query、ctx.GetHeader
+	Params []Param
+	// FormFields 不为空时请求体是 multipart/form-data
+	FormFields []Param
+}
+
+// Param 单个参数，Type 是它在 Go 里的类型
+type Param struct {
+	Name     string
+	In       string
+	Type     reflect.Type
+	Required bool
+}
+
+// Spec 记录所有注册过的接口，同一个方法和路径重复注册时以后注册的为准
+type Spec struct {
+	mu     sync.RWMutex
+	routes map[string]Route
+}
+
+func NewSpec() *Spec {
+	return &Spec{routes: make(map[string]Route)}
+}
+
+func (s *Spec) Add(r Route) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[r.Method+" "+r.Path] = r
+}
+
+// Routes 按照路径和方法排序
+func (s *Spec) Routes() []Route {
+	s.mu.RLock()
+	res := make([]Route, 0, len(s.routes))
+	for _, r := range s.routes {
+		res = append(res, r)
+	}
+	s.mu.RUnlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// Document 生成 OpenAPI 文档，每次调用都重新生成
+func (s *Spec) Document(info Info) *Document {
+	g := newSchemaGen()
+	g.schemas["Result"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer", Description: "业务码，成功是 200"},
+			"msg":  {Type: "string", Description: "按照 Accept-Language 翻译过的消息"},
+			"data": {},
+		},
+		Required: []string{"code", "msg"},
+	}
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	for _, r := range s.Routes() {
+		p := openAPIPath(r.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]*Operation)
+		}
+		doc.Paths[p][strings.ToLower(r.Method)] = g.operation(r)
+	}
+	return doc
+}
+
+func (g *schemaGen) operation(r Route) *Operation {
+	op := &Operation{
+		OperationID: r.OperationID,
+		Summary:     r.Summary,
+		Tags:        r.Tags,
+		Deprecated:  r.Deprecated,
+		Responses: map[string]*Response{
+			"200": {
+				Description: "成功",
+				Content:     jsonContent(g.resultSchema(r.Data)),
+			},
+			"default": {
+				Description: "失败，code 是业务码",
+				Content:     jsonContent(&Schema{Ref: "#/components/schemas/Result"}),
+			},
+		},
+	}
+	if r.Auth {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+	switch r.ReqIn {
+	case InQuery:
+		// 没有写 form tag 的字段 gin 按照字段名绑定
+		op.Parameters = append(op.Parameters, g.parameters(r.Req, InQuery, false)...)
+	case InPath, InHeader:
+		op.Parameters = append(op.Parameters, g.parameters(r.Req, r.ReqIn, true)...)
+	case InAll:
+		for _, in := range []string{InPath, InQuery, InHeader} {
+			op.Parameters = append(op.Parameters, g.parameters(r.Req, in, true)...)
+		}
+	}
+	for _, p := range r.Params {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name: p.Name, In: p.In, Required: p.Required, Schema: g.schemaOf(p.Type),
+		})
+	}
+	// 结构体里没有描述的路径参数按照字符串处理
+	for _, seg := range strings.Split(r.Path, "/") {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') && !hasParam(op.Parameters, InPath, seg[1:]) {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name: seg[1:], In: InPath, Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	switch {
+	case len(r.FormFields) > 0:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, f := range r.FormFields {
+			s.Properties[f.Name] = g.schemaOf(f.Type)
+			if f.Required {
+				s.Required = append(s.Required, f.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"multipart/form-data": {Schema: s}},
+		}
+	case r.ReqIn == InBody:
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schemaOf(r.Req))}
+	case r.ReqIn == InAll && reqIn(r.Method) == InBody:
+		if s := g.bodySchema(r.Req); len(s.Properties) > 0 {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(s)}
+		}
+	}
+	return op
+}
+
+// parameters 结构体里位置是 in 的参数，explicit 为 true 时只要 tag 里写了名字的字段
+func (g *schemaGen) parameters(t reflect.Type, in string, explicit bool) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var res []*Parameter
+	for _, f := range fieldsOf(t, paramTags[in]) {
+		if explicit && !f.tagged {
+			continue
+		}
+		s := g.schemaOf(f.typ)
+		required := applyBinding(s, f.typ, f.binding)
+		res = append(res, &Parameter{Name: f.name, In: in, Required: required || in == InPath, Schema: s})
+	}
+	return res
+}
+
+// bodySchema 去掉路径参数、查询参数、请求头之后剩下的字段，同一个结构体不能放到 components 里
+func (g *schemaGen) bodySchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if t.Kind() != reflect.Struct {
+		return s
+	}
+	for _, f := range fieldsOf(t, "json") {
+		if f.tag.Get("uri") != "" || f.tag.Get("form") != "" || f.tag.Get("header") != "" {
+			continue
+		}
+		fs := g.schemaOf(f.typ)
+		if applyBinding(fs, f.typ, f.binding) {
+			s.Required = append(s.Required, f.name)
+		}
+		s.Properties[f.name] = fs
+	}
+	return s
+}
+
+func hasParam(params []*Parameter, in, name string) bool {
+	for _, p := range params {
+		if p.In == in && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resultSchema 成功时的响应，data 换成具体的类型
+func (g *schemaGen) resultSchema(data reflect.Type) *Schema {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer"},
+			"msg":  {Type: "string"},
+		},
+		Required: []string{"code", "msg"},
+	}
+	if data != nil {
+		s.Properties["data"] = g.schemaOf(data)
+	}
+	return s
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// openAPIPath /files/:id -> /files/{id}
+func openAPIPath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// reqIn ShouldBind 对 GET 请求绑定的是查询参数，其余请求绑定的是请求体
+func reqIn(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return InQuery
+	}
+	return InBody
+}
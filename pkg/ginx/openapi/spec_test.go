@@ -0,0 +1,165 @@
+package openapi
+
+import (
+	"bedrock/pkg/ginx"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClaims struct {
+	jwt.RegisteredClaims
+	Uid int64
+}
+
+type testPage struct {
+	Offset int `form:"offset" binding:"min=0"`
+	Limit  int `form:"limit" binding:"required,min=1,max=100"`
+}
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testReq struct {
+	testBase
+	Email   string   `json:"email" binding:"required,email"`
+	Channel string   `json:"channel" binding:"omitempty,oneof=sms voice"`
+	Tags    []string `json:"tags" binding:"max=3,dive,min=1"`
+	Secret  string   `json:"-"`
+	Child   *testReq `json:"child"`
+}
+
+type testHandler struct{}
+
+func (h *testHandler) Create(ctx *gin.Context, req testReq, uc testClaims) (ginx.Result, error) {
+	return ginx.Result{}, nil
+}
+
+func (h *testHandler) List(ctx *gin.Context, req testPage) (ginx.Result, error) {
+	return ginx.Result{}, nil
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+	g := newSchemaGen()
+	s := g.schemaOf(reflect.TypeFor[testReq]())
+	assert.Equal(t, "#/components/schemas/testReq", s.Ref)
+
+	req := g.schemas["testReq"]
+	require.NotNil(t, req)
+	assert.Equal(t, []string{"email"}, req.Required)
+	// 匿名嵌入的结构体展开，json:"-" 忽略
+	assert.Contains(t, req.Properties, "id")
+	assert.NotContains(t, req.Properties, "Secret")
+	assert.Equal(t, "email", req.Properties["email"].Format)
+	assert.Equal(t, []any{"sms", "voice"}, req.Properties["channel"].Enum)
+	assert.Equal(t, int64(3), *req.Properties["tags"].MaxItems)
+	assert.Nil(t, req.Properties["tags"].MinLength)
+	// 引用自己
+	assert.Equal(t, "#/components/schemas/testReq", req.Properties["child"].Ref)
+}
+
+func TestWrap_RecordsRoute(t *testing.T) {
+	t.Parallel()
+	server := gin.New()
+	h := &testHandler{}
+	g := server.Group("/openapi_test")
+	WrapBodyAndClaims(g, http.MethodPost, "/items/:id", h.Create, Summary("创建"), Data[testBase]())
+	WrapBody(g, http.MethodGet, "/items", h.List)
+
+	doc := DefaultSpec().Document(Info{Title: "test", Version: "1.0.0"})
+
+	create := doc.Paths["/openapi_test/items/{id}"]["post"]
+	require.NotNil(t, create)
+	assert.Equal(t, "testHandler.Create", create.OperationID)
+	assert.Equal(t, "创建", create.Summary)
+	assert.Equal(t, []string{"openapi_test"}, create.Tags)
+	assert.Equal(t, []map[string][]string{{"bearerAuth": {}}}, create.Security)
+	assert.Equal(t, []*Parameter{{Name: "id", In: InPath, Required: true, Schema: &Schema{Type: "string"}}}, create.Parameters)
+	assert.Equal(t, "#/components/schemas/testReq", create.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/testBase",
+		create.Responses["200"].Content["application/json"].Schema.Properties["data"].Ref)
+
+	// GET 请求绑定的是查询参数
+	list := doc.Paths["/openapi_test/items"]["get"]
+	require.NotNil(t, list)
+	assert.Nil(t, list.RequestBody)
+	assert.Nil(t, list.Security)
+	require.Len(t, list.Parameters, 2)
+	assert.Equal(t, "offset", list.Parameters[0].Name)
+	assert.False(t, list.Parameters[0].Required)
+	assert.Equal(t, "limit", list.Parameters[1].Name)
+	assert.True(t, list.Parameters[1].Required)
+	assert.Equal(t, float64(100), *list.Parameters[1].Schema.Maximum)
+
+	// 路由本身也注册上了
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi_test/items?limit=10", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	server := gin.New()
+	server.GET("/openapi.json", Handler(Info{Title: "test", Version: "1.0.0"}))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Contains(t, doc.Components.Schemas, "Result")
+}
+
+type testUpdateReq struct {
+	ID        int64  `uri:"id" json:"-" binding:"required"`
+	DryRun    bool   `form:"dryRun" json:"-"`
+	RequestID string `header:"X-Request-ID" json:"-"`
+	Name      string `json:"name" binding:"required"`
+}
+
+func (h *testHandler) Update(ctx *gin.Context, req testUpdateReq, uc testClaims) (ginx.Result, error) {
+	return ginx.Result{}, nil
+}
+
+func (h *testHandler) Detail(ctx *gin.Context, req testUpdateReq) (ginx.Result, error) {
+	return ginx.Result{}, nil
+}
+
+func TestWrapReq_RecordsRoute(t *testing.T) {
+	t.Parallel()
+	server := gin.New()
+	h := &testHandler{}
+	g := server.Group("/openapi_req_test")
+	WrapReq(g, http.MethodPut, "/items/:id", h.Update)
+	WrapURI(g, http.MethodGet, "/items/:id", h.Detail)
+
+	doc := DefaultSpec().Document(Info{Title: "test", Version: "1.0.0"})
+
+	update := doc.Paths["/openapi_req_test/items/{id}"]["put"]
+	require.NotNil(t, update)
+	assert.Equal(t, []*Parameter{
+		{Name: "id", In: InPath, Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "dryRun", In: InQuery, Schema: &Schema{Type: "boolean"}},
+		{Name: "X-Request-ID", In: InHeader, Schema: &Schema{Type: "string"}},
+	}, update.Parameters)
+	// 请求体里只剩下没有写 uri、form、header 的字段
+	body := update.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"name"}, body.Required)
+	assert.Len(t, body.Properties, 1)
+
+	detail := doc.Paths["/openapi_req_test/items/{id}"]["get"]
+	require.NotNil(t, detail)
+	assert.Nil(t, detail.RequestBody)
+	require.Len(t, detail.Parameters, 1)
+	assert.Equal(t, "id", detail.Parameters[0].Name)
+}
@@ -0,0 +1,218 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	versionKey = "router_version"
+	pathKey    = "router_path"
+)
+
+type Config struct {
+	// Prefix 所有版本的公共前缀，例如 /api，版本的路径是 /api/v1
+	Prefix string `mapstructure:"prefix"`
+	// Header 协商版本的请求头，例如 X-API-Version: v2，也接受不带 v 的写法
+	Header string `mapstructure:"header"`
+	// Default 请求路径和请求头都没有指定版本时使用
+	Default string `mapstructure:"default"`
+	// Legacy 为 true 时不带前缀的老路径也按照协商出来的版本处理，例如 /users/login -> /api/v1/users/login
+	// 只改写这个版本确实注册过的接口，/metrics 之类直接注册在 engine 上的路由不受影响
+	Legacy bool `mapstructure:"legacy"`
+}
+
+// Version 一个 API 版本，同一个 Handler 的新旧版本分别注册到各自的分组，迁移期间同时提供服务
+type Version struct {
+	Name string
+	// Deprecation 不为零时响应里带上 Deprecation 头，告诉客户端这个版本已经废弃（RFC 9745）
+	Deprecation time.Time
+	// Sunset 不为零时响应里带上 Sunset 头，这个时间之后会下线（RFC 8594）
+	Sunset time.Time
+	// Link 迁移文档的地址，放在 Link 头里
+	Link string
+	// Middlewares 只对这个版本生效的中间件
+	Middlewares []gin.HandlerFunc
+}
+
+// Router 按照版本给路由分组，同时负责把没有带版本号的请求转发到协商出来的版本
+// 需要用 Router 代替 gin.Engine 作为 http.Server 的 Handler，版本协商要在 gin 匹配路由之前完成
+type Router struct {
+	engine   *gin.Engine
+	cfg      Config
+	versions map[string]*gin.RouterGroup
+
+	once sync.Once
+	// routes 每个版本注册过的路由（去掉了版本前缀），第一次处理请求的时候从 engine 里取出来
+	routes map[string][]route
+}
+
+type route struct {
+	method string
+	segs   []string
+}
+
+func New(engine *gin.Engine, cfg Config, versions ...Version) *Router {
+	cfg.Prefix = "/" + strings.Trim(cfg.Prefix, "/")
+	if cfg.Prefix == "/" {
+		cfg.Prefix = ""
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Version"
+	}
+	if cfg.Default == "" && len(versions) > 0 {
+		cfg.Default = versions[0].Name
+	}
+	r := &Router{
+		engine:   engine,
+		cfg:      cfg,
+		versions: make(map[string]*gin.RouterGroup, len(versions)),
+	}
+	for _, v := range versions {
+		handlers := append([]gin.HandlerFunc{r.versionMiddleware(v)}, v.Middlewares...)
+		r.versions[v.Name] = engine.Group(cfg.Prefix+"/"+v.Name, handlers...)
+	}
+	return r
+}
+
+// Group 版本对应的分组，Handler 在这上面注册路由；版本不存在时 panic，属于配置错误
+func (r *Router) Group(version string) *gin.RouterGroup {
+	g, ok := r.versions[version]
+	if !ok {
+		panic("router: 没有配置 API 版本 " + version)
+	}
+	return g
+}
+
+// Engine 不需要版本的路由（/metrics、静态文件）直接注册在 engine 上
+func (r *Router) Engine() *gin.Engine {
+	return r.engine
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if p, ok := r.rewrite(req); ok {
+		req.URL.Path = p
+		req.URL.RawPath = ""
+	}
+	r.engine.ServeHTTP(w, req)
+}
+
+// rewrite 没有带版本号的请求改写成协商出来的版本
+func (r *Router) rewrite(req *http.Request) (string, bool) {
+	p := req.URL.Path
+	switch {
+	case r.cfg.Prefix != "" && strings.HasPrefix(p, r.cfg.Prefix+"/"):
+		rest := strings.TrimPrefix(p, r.cfg.Prefix)
+		first, _, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+		if _, ok := r.versions[first]; ok {
+			return "", false
+		}
+		return r.cfg.Prefix + "/" + r.negotiate(req) + rest, true
+	case r.cfg.Legacy:
+		version := r.negotiate(req)
+		if !r.match(version, req.Method, p) {
+			return "", false
+		}
+		return r.cfg.Prefix + "/" + version + p, true
+	}
+	return "", false
+}
+
+// negotiate 请求头里的版本不存在时使用默认版本，实际使用的版本在响应头里返回
+func (r *Router) negotiate(req *http.Request) string {
+	v := strings.TrimSpace(req.Header.Get(r.cfg.Header))
+	if v == "" {
+		return r.cfg.Default
+	}
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if _, ok := r.versions[v]; ok {
+		return v
+	}
+	return r.cfg.Default
+}
+
+func (r *Router) match(version, method, p string) bool {
+	r.once.Do(r.loadRoutes)
+	segs := split(p)
+	for _, rt := range r.routes[version] {
+		if (rt.method == method || (method == http.MethodHead && rt.method == http.MethodGet)) && matchSegs(rt.segs, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) loadRoutes() {
+	r.routes = make(map[string][]route)
+	for _, info := range r.engine.Routes() {
+		rest, ok := strings.CutPrefix(info.Path, r.cfg.Prefix+"/")
+		if !ok {
+			continue
+		}
+		version, p, _ := strings.Cut(rest, "/")
+		if _, ok = r.versions[version]; !ok {
+			continue
+		}
+		r.routes[version] = append(r.routes[version], route{method: info.Method, segs: split("/" + p)})
+	}
+}
+
+func split(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// matchSegs 和 gin 的路由规则一致：:name 匹配一段，*name 匹配剩下的所有段
+func matchSegs(pattern, segs []string) bool {
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, "*") {
+			return true
+		}
+		if i >= len(segs) {
+			return false
+		}
+		if !strings.HasPrefix(seg, ":") && seg != segs[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segs)
+}
+
+func (r *Router) versionMiddleware(v Version) gin.HandlerFunc {
+	base := r.cfg.Prefix + "/" + v.Name
+	return func(ctx *gin.Context) {
+		ctx.Set(versionKey, v.Name)
+		ctx.Set(pathKey, strings.TrimPrefix(ctx.Request.URL.Path, base))
+		h := ctx.Writer.Header()
+		h.Set(r.cfg.Header, v.Name)
+		if !v.Deprecation.IsZero() {
+			h.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+		}
+		if !v.Sunset.IsZero() {
+			h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.Link != "" {
+			h.Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+		}
+	}
+}
+
+// VersionOf 请求使用的 API 版本，不是版本分组里的路由返回空串
+func VersionOf(ctx *gin.Context) string {
+	return ctx.GetString(versionKey)
+}
+
+// Path 去掉版本前缀之后的请求路径，例如 /api/v2/users/login -> /users/login，
+// 不是版本分组里的路由返回原始路径，中间件按照路径做判断时用它，这样不用关心版本
+func Path(ctx *gin.Context) string {
+	if p, ok := ctx.Get(pathKey); ok {
+		return p.(string)
+	}
+	return ctx.Request.URL.Path
+}
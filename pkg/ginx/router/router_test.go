@@ -0,0 +1,162 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter() *Router {
+	engine := gin.New()
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	r := New(engine, Config{Prefix: "/api", Default: "v1", Legacy: true},
+		Version{
+			Name:        "v1",
+			Deprecation: time.Unix(1700000000, 0),
+			Sunset:      sunset,
+			Link:        "https://example.com/migrate",
+		},
+		Version{
+			Name: "v2",
+			// 只对 v2 生效的中间件
+			Middlewares: []gin.HandlerFunc{func(ctx *gin.Context) {
+				ctx.Header("X-V2", "1")
+			}},
+		},
+	)
+	// 同一个接口的两个版本同时提供服务
+	r.Group("v1").GET("/users/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "v1 "+ctx.Param("id")+" "+Path(ctx))
+	})
+	r.Group("v2").GET("/users/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "v2 "+ctx.Param("id")+" "+Path(ctx))
+	})
+	r.Group("v2").GET("/files/*key", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "v2 files "+VersionOf(ctx))
+	})
+	engine.GET("/metrics", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "metrics "+VersionOf(ctx))
+	})
+	return r
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+	r := newTestRouter()
+
+	testCases := []struct {
+		name   string
+		path   string
+		header string
+
+		wantCode    int
+		wantBody    string
+		wantVersion string
+	}{
+		{
+			name:        "带版本号",
+			path:        "/api/v2/users/7",
+			wantCode:    http.StatusOK,
+			wantBody:    "v2 7 /users/7",
+			wantVersion: "v2",
+		},
+		{
+			name:        "没有版本号用默认版本",
+			path:        "/api/users/7",
+			wantCode:    http.StatusOK,
+			wantBody:    "v1 7 /users/7",
+			wantVersion: "v1",
+		},
+		{
+			name:        "请求头协商",
+			path:        "/api/users/7",
+			header:      "2",
+			wantCode:    http.StatusOK,
+			wantBody:    "v2 7 /users/7",
+			wantVersion: "v2",
+		},
+		{
+			name:        "路径里的版本号优先",
+			path:        "/api/v1/users/7",
+			header:      "v2",
+			wantCode:    http.StatusOK,
+			wantBody:    "v1 7 /users/7",
+			wantVersion: "v1",
+		},
+		{
+			name:        "不支持的版本用默认版本",
+			path:        "/api/users/7",
+			header:      "v9",
+			wantCode:    http.StatusOK,
+			wantBody:    "v1 7 /users/7",
+			wantVersion: "v1",
+		},
+		{
+			name:        "老路径",
+			path:        "/users/7",
+			wantCode:    http.StatusOK,
+			wantBody:    "v1 7 /users/7",
+			wantVersion: "v1",
+		},
+		{
+			name:        "老路径也可以协商",
+			path:        "/files/a/b.png",
+			header:      "v2",
+			wantCode:    http.StatusOK,
+			wantBody:    "v2 files v2",
+			wantVersion: "v2",
+		},
+		{
+			name:     "老路径在协商出来的版本里不存在",
+			path:     "/files/a/b.png",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "不属于任何版本的路由不受影响",
+			path:     "/metrics",
+			wantCode: http.StatusOK,
+			wantBody: "metrics ",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("X-API-Version", tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+			assert.Equal(t, tc.wantVersion, recorder.Header().Get("X-API-Version"))
+		})
+	}
+}
+
+func TestRouter_DeprecationHeaders(t *testing.T) {
+	t.Parallel()
+	r := newTestRouter()
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/users/7", nil))
+	assert.Equal(t, "@1700000000", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/migrate>; rel="deprecation"`, recorder.Header().Get("Link"))
+	assert.Empty(t, recorder.Header().Get("X-V2"))
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v2/users/7", nil))
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+	assert.Empty(t, recorder.Header().Get("Sunset"))
+	assert.Equal(t, "1", recorder.Header().Get("X-V2"))
+}
@@ -0,0 +1,53 @@
+package ginx
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusKey Wrap* 把真实的状态码放在 gin.Context 里，兼容模式下中间件也能拿到
+const statusKey = "ginx_status"
+
+var legacyStatus atomic.Bool
+
+// SetLegacyStatus 为 true 时和以前一样总是返回 200，真实的状态只体现在 Result.Code 里，给还没有升级的老客户端用
+func SetLegacyStatus(legacy bool) {
+	legacyStatus.Store(legacy)
+}
+
+// StatusOf 业务逻辑的返回值对应的 HTTP 状态码：
+// 注册过的错误用它的 Status；Result.Code 本身是 HTTP 状态码时直接使用；
+// 业务码 4XXNNN、5XXNNN 分别对应 400、500；没有结果的未知错误是 500
+func StatusOf(res Result, err error) int {
+	if e, ok := LookupError(err); ok {
+		return e.Status
+	}
+	if err != nil && res.Code == 0 {
+		return http.StatusInternalServerError
+	}
+	return statusOfCode(res.Code)
+}
+
+func statusOfCode(code int) int {
+	switch {
+	case code >= 100 && code < 600:
+		return code
+	case code >= 400000 && code < 500000:
+		return http.StatusBadRequest
+	case code >= 500000 && code < 600000:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
+
+// Status 请求真实的状态码，兼容模式下 Wrap* 写的是 200，这里返回的是 StatusOf 的结果
+// 监控、访问日志之类的中间件应该用它而不是 ctx.Writer.Status()
+func Status(ctx *gin.Context) int {
+	if status := ctx.GetInt(statusKey); status != 0 {
+		return status
+	}
+	return ctx.Writer.Status()
+}
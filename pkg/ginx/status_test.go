@@ -0,0 +1,116 @@
+package ginx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusOf(t *testing.T) {
+	t.Parallel()
+	errSentinel := errors.New("status test sentinel")
+	RegisterError(errSentinel, NewError(401003, http.StatusConflict, "邮箱冲突"))
+
+	testCases := []struct {
+		name string
+		res  Result
+		err  error
+
+		wantStatus int
+	}{
+		{
+			name:       "成功",
+			res:        Result{Code: http.StatusOK},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "创建成功",
+			res:        Result{Code: http.StatusCreated},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "业务码 4XXNNN",
+			res:        Result{Code: 401001},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "业务码 5XXNNN",
+			res:        Result{Code: 501001},
+			err:        errors.New("db error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "注册过的错误",
+			res:        Result{Code: 501001},
+			err:        errSentinel,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "没有结果的未知错误",
+			err:        errors.New("db error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "没有结果也没有错误",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.wantStatus, StatusOf(tc.res, tc.err))
+		})
+	}
+}
+
+// 修改了全局的兼容模式，不能和其他测试并行
+func TestWrap_LegacyStatus(t *testing.T) {
+	testCases := []struct {
+		name   string
+		legacy bool
+
+		wantWritten int
+		wantStatus  int
+	}{
+		{
+			name:        "真实状态码",
+			wantWritten: http.StatusBadRequest,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			// 写出去的是 200，中间件拿到的还是真实的状态码
+			name:        "兼容模式",
+			legacy:      true,
+			wantWritten: http.StatusOK,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetLegacyStatus(tc.legacy)
+			defer SetLegacyStatus(false)
+
+			var status int
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Next()
+				status = Status(ctx)
+			})
+			server.GET("/", Wrap(func(ctx *gin.Context) (Result, error) {
+				return Result{Code: 401001, Msg: "参数错误"}, nil
+			}))
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.wantWritten, recorder.Code)
+			assert.Equal(t, tc.wantStatus, status)
+		})
+	}
+}
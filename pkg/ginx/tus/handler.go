@@ -0,0 +1,464 @@
+// Package tus 实现 tus 1.0.0 断点续传协议（https://tus.io/protocols/resumable-upload），
+// 支持 creation、creation-with-upload、expiration、termination 扩展。
+// 文件通过 storage.Provider 的分片上传写入存储，不经过本地磁盘，任何需要上传大文件的功能都可以挂载使用
+package tus
+
+import (
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Version    = "1.0.0"
+	Extensions = "creation,creation-with-upload,expiration,termination"
+	// ContentType PATCH 请求体必须使用的类型
+	ContentType = "application/offset+octet-stream"
+)
+
+// errInterrupted 读取请求体失败，一般是客户端断开了，已经收到的数据都保存下来了
+var errInterrupted = errors.New("tus: upload interrupted")
+
+var (
+	// AllowHeaders 跨域时客户端需要发送的请求头
+	AllowHeaders = []string{"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"}
+	// ExposeHeaders 跨域时客户端需要读取的响应头
+	ExposeHeaders = []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size",
+		"Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Expires"}
+)
+
+// Config 断点续传的配置，除了 Storage 都有默认值
+type Config struct {
+	Storage storage.Provider
+	// Store 保存上传进度，默认 NewMemoryStore
+	Store Store
+	// BasePath 挂载的完整路径，用来生成 Location，例如 "/files"
+	BasePath string
+	// MaxSize 允许上传的最大字节数，<= 0 表示不限制
+	MaxSize int64
+	// PartSize 每个分片的大小，不能小于 storage.MinPartSize，每个 PATCH 请求最多占用这么多内存
+	PartSize int64
+	// Expiration 创建之后多久没有完成就失效，默认 24 小时
+	// 注意：失效之后存储里没有完成的分片需要依靠存储自己的生命周期规则清理
+	Expiration time.Duration
+	// TempPrefix 暂存不够一个分片的数据，默认 "tmp/tus/"
+	TempPrefix string
+	// KeyFunc 根据请求和 Upload-Metadata 决定文件保存的 key，默认 "uploads/<id>"
+	// 返回错误时拒绝创建（400），可以在这里做鉴权、校验文件名和类型
+	KeyFunc func(ctx *gin.Context, id string, meta map[string]string) (string, error)
+	// OnComplete 文件完整写入存储之后调用，返回错误时响应 500，但是文件已经保存了
+	OnComplete func(ctx *gin.Context, u Upload) error
+	Logger     logger.Logger
+}
+
+type Handler struct {
+	cfg Config
+	// locks 同一个上传同一时间只允许一个请求写入，多实例部署时需要在负载均衡上按照路径做会话保持
+	locks sync.Map
+}
+
+func NewHandler(cfg Config) *Handler {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.PartSize < storage.MinPartSize {
+		cfg.PartSize = storage.MinPartSize
+	}
+	if cfg.Expiration <= 0 {
+		cfg.Expiration = 24 * time.Hour
+	}
+	if cfg.TempPrefix == "" {
+		cfg.TempPrefix = "tmp/tus/"
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(ctx *gin.Context, id string, meta map[string]string) (string, error) {
+			return "uploads/" + id, nil
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.NewNopLogger()
+	}
+	cfg.BasePath = strings.TrimRight(cfg.BasePath, "/")
+	return &Handler{cfg: cfg}
+}
+
+// RegisterRoutes 在 g 上注册 tus 的全部路由，g 应该是 Config.BasePath 对应的专用分组，
+// 因为协议版本检查是通过 g.Use 注册的
+func (h *Handler) RegisterRoutes(g gin.IRoutes) {
+	g.Use(h.version)
+	g.OPTIONS("", h.Options)
+	g.POST("", h.Create)
+	g.HEAD("/:id", h.Head)
+	g.PATCH("/:id", h.Patch)
+	g.DELETE("/:id", h.Terminate)
+}
+
+// version 每个响应都带上协议版本，除了 OPTIONS 之外的请求版本不对就拒绝
+func (h *Handler) version(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", Version)
+	if ctx.Request.Method != http.MethodOptions && ctx.GetHeader("Tus-Resumable") != Version {
+		ctx.Header("Tus-Version", Version)
+		ctx.AbortWithStatus(http.StatusPreconditionFailed)
+		return
+	}
+	ctx.Next()
+}
+
+// Options 告诉客户端服务端支持的版本和扩展
+func (h *Handler) Options(ctx *gin.Context) {
+	ctx.Header("Tus-Version", Version)
+	ctx.Header("Tus-Extension", Extensions)
+	if h.cfg.MaxSize > 0 {
+		ctx.Header("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxSize, 10))
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Create 创建上传，请求体里带了数据时（creation-with-upload）直接写入
+func (h *Handler) Create(ctx *gin.Context) {
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		// 不支持 Upload-Defer-Length
+		ctx.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if h.cfg.MaxSize > 0 && length > h.cfg.MaxSize {
+		ctx.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+	meta, err := parseMetadata(ctx.GetHeader("Upload-Metadata"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		h.fail(ctx, "生成上传 ID 失败", err)
+		return
+	}
+	key, err := h.cfg.KeyFunc(ctx, id, meta)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	u := Upload{
+		ID:        id,
+		Key:       key,
+		Length:    length,
+		Metadata:  meta,
+		ExpiresAt: time.Now().Add(h.cfg.Expiration),
+	}
+	if length > 0 {
+		if u.UploadID, err = h.cfg.Storage.InitiateMultipart(reqCtx, key, storage.UploadOptions{}); err != nil {
+			h.fail(ctx, "发起分片上传失败", err)
+			return
+		}
+	}
+	if err = h.cfg.Store.Save(reqCtx, u); err != nil {
+		h.fail(ctx, "保存上传进度失败", err)
+		return
+	}
+	ctx.Header("Location", h.cfg.BasePath+"/"+id)
+	ctx.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	if length == 0 {
+		// 空文件没法分片上传，直接上传一个空对象
+		if _, err = h.cfg.Storage.Upload(reqCtx, key, bytes.NewReader(nil), 0, storage.UploadOptions{}); err != nil {
+			h.fail(ctx, "上传空文件失败", err)
+			return
+		}
+		if !h.complete(ctx, u) {
+			return
+		}
+	} else if ctx.ContentType() == ContentType {
+		unlock, _ := h.lock(id)
+		defer unlock()
+		if u, err = h.write(reqCtx, u, ctx.Request.Body); !h.handleWriteErr(ctx, u, err) {
+			return
+		}
+		ctx.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		if u.Done() && !h.finish(ctx, u) {
+			return
+		}
+	}
+	ctx.Status(http.StatusCreated)
+}
+
+// Head 查询上传进度，客户端断线重连之后根据 Upload-Offset 继续上传
+func (h *Handler) Head(ctx *gin.Context) {
+	u, ok := h.get(ctx)
+	if !ok {
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	ctx.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	if len(u.Metadata) > 0 {
+		ctx.Header("Upload-Metadata", formatMetadata(u.Metadata))
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// Patch 从 Upload-Offset 开始追加数据
+func (h *Handler) Patch(ctx *gin.Context) {
+	if ctx.ContentType() != ContentType {
+		ctx.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.String(http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+	unlock, ok := h.lock(ctx.Param("id"))
+	defer unlock()
+	if !ok {
+		ctx.Status(http.StatusLocked)
+		return
+	}
+	u, ok := h.get(ctx)
+	if !ok {
+		return
+	}
+	if offset != u.Offset {
+		ctx.Status(http.StatusConflict)
+		return
+	}
+	if ctx.Request.ContentLength > u.Length-u.Offset {
+		ctx.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	if !u.Done() {
+		if u, err = h.write(reqCtx, u, ctx.Request.Body); !h.handleWriteErr(ctx, u, err) {
+			return
+		}
+		if u.Done() && !h.finish(ctx, u) {
+			return
+		}
+	}
+	ctx.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	ctx.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.Status(http.StatusNoContent)
+}
+
+// Terminate 取消上传并释放存储里的分片，已经完成的上传只删除进度，文件交给业务处理
+func (h *Handler) Terminate(ctx *gin.Context) {
+	unlock, ok := h.lock(ctx.Param("id"))
+	defer unlock()
+	if !ok {
+		ctx.Status(http.StatusLocked)
+		return
+	}
+	u, ok := h.get(ctx)
+	if !ok {
+		return
+	}
+	reqCtx := ctx.Request.Context()
+	if u.UploadID != "" {
+		if err := h.cfg.Storage.AbortMultipart(reqCtx, u.Key, u.UploadID); err != nil {
+			h.fail(ctx, "取消分片上传失败", err)
+			return
+		}
+	}
+	h.discardPending(reqCtx, u)
+	if err := h.cfg.Store.Delete(reqCtx, u.ID); err != nil {
+		h.fail(ctx, "删除上传进度失败", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// write 把 body 按照 PartSize 切成分片上传，每传完一个分片就保存一次进度，
+// 最后不够一个分片的数据（并且不是文件末尾）暂存到 TempPrefix 下面
+// 读取 body 失败时已经收到的数据也会保存下来，返回的 Upload 始终和存储里的数据一致
+func (h *Handler) write(ctx context.Context, u Upload, body io.Reader) (Upload, error) {
+	var r io.Reader = io.LimitReader(body, u.Length-u.Offset)
+	if u.Pending > 0 {
+		pending, _, err := h.cfg.Storage.Get(ctx, h.pendingKey(u.ID))
+		if err != nil {
+			return u, fmt.Errorf("读取暂存数据失败: %w", err)
+		}
+		defer pending.Close()
+		r = io.MultiReader(io.LimitReader(pending, u.Pending), r)
+	}
+	// committed 已经上传成分片的字节数
+	committed := u.Offset - u.Pending
+	buf := make([]byte, h.cfg.PartSize)
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n == 0 {
+			return u, readErr(rerr)
+		}
+		if n == len(buf) || committed+int64(n) == u.Length {
+			part, err := h.cfg.Storage.UploadPart(ctx, u.Key, u.UploadID, len(u.Parts)+1, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				return u, err
+			}
+			committed += int64(n)
+			u.Parts = append(u.Parts, part)
+			u.Offset, u.Pending = committed, 0
+		} else {
+			// 暂存的数据已经读出来拼在 buf 的前面了，直接覆盖
+			_, err := h.cfg.Storage.Upload(ctx, h.pendingKey(u.ID), bytes.NewReader(buf[:n]), int64(n),
+				storage.UploadOptions{ContentType: "application/octet-stream"})
+			if err != nil {
+				return u, err
+			}
+			u.Offset, u.Pending = committed+int64(n), int64(n)
+		}
+		if err := h.cfg.Store.Save(ctx, u); err != nil {
+			return u, err
+		}
+		if rerr != nil || u.Done() {
+			return u, readErr(rerr)
+		}
+	}
+}
+
+// handleWriteErr 客户端断开时已经收到的数据保存下来了，客户端 HEAD 之后可以从断开的地方继续，
+// 照常响应；其他错误（存储失败）响应 500，返回 false
+func (h *Handler) handleWriteErr(ctx *gin.Context, u Upload, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errInterrupted):
+		h.cfg.Logger.Warn(ctx.Request.Context(), "上传中断", logger.Error(err), logger.String("id", u.ID))
+		return true
+	default:
+		h.fail(ctx, "写入上传数据失败", err)
+		return false
+	}
+}
+
+// finish 合并分片，失败时已经响应了错误
+func (h *Handler) finish(ctx *gin.Context, u Upload) bool {
+	reqCtx := ctx.Request.Context()
+	if _, err := h.cfg.Storage.CompleteMultipart(reqCtx, u.Key, u.UploadID, u.Parts); err != nil {
+		h.fail(ctx, "合并分片失败", err)
+		return false
+	}
+	h.discardPending(reqCtx, u)
+	u.UploadID, u.Parts = "", nil
+	if err := h.cfg.Store.Save(reqCtx, u); err != nil {
+		h.fail(ctx, "保存上传进度失败", err)
+		return false
+	}
+	return h.complete(ctx, u)
+}
+
+func (h *Handler) complete(ctx *gin.Context, u Upload) bool {
+	if h.cfg.OnComplete == nil {
+		return true
+	}
+	if err := h.cfg.OnComplete(ctx, u); err != nil {
+		h.fail(ctx, "上传完成回调失败", err)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) get(ctx *gin.Context) (Upload, bool) {
+	u, err := h.cfg.Store.Get(ctx.Request.Context(), ctx.Param("id"))
+	switch {
+	case errors.Is(err, ErrUploadNotFound):
+		ctx.Status(http.StatusNotFound)
+		return Upload{}, false
+	case err != nil:
+		h.fail(ctx, "查询上传进度失败", err)
+		return Upload{}, false
+	}
+	return u, true
+}
+
+// lock 返回的 unlock 无论是否加锁成功都可以调用
+func (h *Handler) lock(id string) (func(), bool) {
+	if _, loaded := h.locks.LoadOrStore(id, struct{}{}); loaded {
+		return func() {}, false
+	}
+	return func() { h.locks.Delete(id) }, true
+}
+
+// discardPending 暂存的数据拼进分片之后不会马上删除，所以不管 Pending 是多少都要删一次
+func (h *Handler) discardPending(ctx context.Context, u Upload) {
+	if err := h.cfg.Storage.Delete(ctx, h.pendingKey(u.ID)); err != nil {
+		h.cfg.Logger.Warn(ctx, "删除暂存数据失败", logger.Error(err), logger.String("id", u.ID))
+	}
+}
+
+func (h *Handler) pendingKey(id string) string {
+	return h.cfg.TempPrefix + id
+}
+
+func (h *Handler) fail(ctx *gin.Context, msg string, err error) {
+	h.cfg.Logger.Error(ctx.Request.Context(), msg, logger.Error(err))
+	ctx.Status(http.StatusInternalServerError)
+}
+
+// readErr 读完了不算错误
+func readErr(err error) error {
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", errInterrupted, err)
+}
+
+func newID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
+
+// parseMetadata 解析 Upload-Metadata："key1 base64(value1),key2"，值可以省略
+func parseMetadata(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+func formatMetadata(meta map[string]string) string {
+	pairs := make([]string, 0, len(meta))
+	for k, v := range meta {
+		if v == "" {
+			pairs = append(pairs, k)
+			continue
+		}
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,305 @@
+package tus
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tusServer struct {
+	t         *testing.T
+	engine    *gin.Engine
+	store     storage.Provider
+	completed []Upload
+}
+
+func newTusServer(t *testing.T, cfg Config) *tusServer {
+	s := &tusServer{t: t, engine: gin.New(), store: memory.NewProvider("https://cdn.example.com")}
+	cfg.Storage = s.store
+	cfg.BasePath = "/files/"
+	cfg.OnComplete = func(ctx *gin.Context, u Upload) error {
+		s.completed = append(s.completed, u)
+		return nil
+	}
+	NewHandler(cfg).RegisterRoutes(s.engine.Group("/files"))
+	return s
+}
+
+func (s *tusServer) do(method, path string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Tus-Resumable", Version)
+	for k, v := range headers {
+		if v == "" {
+			req.Header.Del(k)
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	recorder := httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (s *tusServer) create(length int, metadata string) string {
+	resp := s.do(http.MethodPost, "/files", nil, map[string]string{
+		"Upload-Length":   strconv.Itoa(length),
+		"Upload-Metadata": metadata,
+	})
+	require.Equal(s.t, http.StatusCreated, resp.Code, resp.Body.String())
+	return resp.Header().Get("Location")
+}
+
+func (s *tusServer) patch(location string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	return s.do(http.MethodPatch, location, body, map[string]string{
+		"Content-Type":  ContentType,
+		"Upload-Offset": strconv.Itoa(offset),
+	})
+}
+
+func (s *tusServer) offset(location string) int {
+	resp := s.do(http.MethodHead, location, nil, nil)
+	require.Equal(s.t, http.StatusOK, resp.Code)
+	assert.Equal(s.t, "no-store", resp.Header().Get("Cache-Control"))
+	offset, err := strconv.Atoi(resp.Header().Get("Upload-Offset"))
+	require.NoError(s.t, err)
+	return offset
+}
+
+func (s *tusServer) read(key string) []byte {
+	r, _, err := s.store.Get(context.Background(), key)
+	require.NoError(s.t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(s.t, err)
+	return data
+}
+
+func randomBytes(n int) []byte {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(rnd.IntN(256))
+	}
+	return data
+}
+
+// brokenReader 读出 n 个字节之后模拟客户端断开
+type brokenReader struct {
+	r io.Reader
+	n int
+}
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	if len(p) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.r.Read(p)
+	b.n -= n
+	return n, err
+}
+
+func TestHandler_Options(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{MaxSize: 1 << 30})
+	resp := s.do(http.MethodOptions, "/files", nil, map[string]string{"Tus-Resumable": ""})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, Version, resp.Header().Get("Tus-Resumable"))
+	assert.Equal(t, Version, resp.Header().Get("Tus-Version"))
+	assert.Equal(t, Extensions, resp.Header().Get("Tus-Extension"))
+	assert.Equal(t, "1073741824", resp.Header().Get("Tus-Max-Size"))
+}
+
+func TestHandler_Create(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		cfg     Config
+		headers map[string]string
+		want    int
+	}{
+		{
+			name:    "版本不对",
+			headers: map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "10"},
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name: "没有 Upload-Length",
+			want: http.StatusBadRequest,
+		},
+		{
+			name:    "超过大小限制",
+			cfg:     Config{MaxSize: 9},
+			headers: map[string]string{"Upload-Length": "10"},
+			want:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "元数据格式不对",
+			headers: map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename !!!"},
+			want:    http.StatusBadRequest,
+		},
+		{
+			name: "业务拒绝",
+			cfg: Config{KeyFunc: func(ctx *gin.Context, id string, meta map[string]string) (string, error) {
+				return "", errors.New("不支持的文件类型")
+			}},
+			headers: map[string]string{"Upload-Length": "10"},
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:    "成功",
+			headers: map[string]string{"Upload-Length": "10"},
+			want:    http.StatusCreated,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := newTusServer(t, tc.cfg)
+			resp := s.do(http.MethodPost, "/files", nil, tc.headers)
+			assert.Equal(t, tc.want, resp.Code)
+			assert.Equal(t, Version, resp.Header().Get("Tus-Resumable"))
+			if tc.want == http.StatusCreated {
+				assert.Regexp(t, `^/files/[0-9a-f]{32}$`, resp.Header().Get("Location"))
+				assert.NotEmpty(t, resp.Header().Get("Upload-Expires"))
+			}
+		})
+	}
+}
+
+func TestHandler_Upload(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{
+		KeyFunc: func(ctx *gin.Context, id string, meta map[string]string) (string, error) {
+			return "videos/" + meta["filename"], nil
+		},
+	})
+	// 两个完整的分片加上一个不完整的分片
+	data := randomBytes(2*storage.MinPartSize + 100)
+	location := s.create(len(data), "filename ZGVtby5tcDQ=,public")
+
+	resp := s.do(http.MethodHead, location, nil, nil)
+	assert.Equal(t, strconv.Itoa(len(data)), resp.Header().Get("Upload-Length"))
+	assert.Equal(t, "filename ZGVtby5tcDQ=,public", resp.Header().Get("Upload-Metadata"))
+
+	// 每次 3MB，比分片小，需要暂存
+	const chunk = 3 << 20
+	offset := 0
+	for offset < len(data) {
+		end := min(offset+chunk, len(data))
+		resp = s.patch(location, offset, bytes.NewReader(data[offset:end]))
+		require.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, strconv.Itoa(end), resp.Header().Get("Upload-Offset"))
+		offset = end
+		assert.Equal(t, offset, s.offset(location))
+		if offset < len(data) {
+			assert.Empty(t, s.completed)
+		}
+	}
+
+	assert.Equal(t, data, s.read("videos/demo.mp4"))
+	require.Len(t, s.completed, 1)
+	assert.Equal(t, "videos/demo.mp4", s.completed[0].Key)
+	assert.Equal(t, map[string]string{"filename": "demo.mp4", "public": ""}, s.completed[0].Metadata)
+	// 暂存的数据都清理掉了
+	res, err := s.store.List(context.Background(), storage.ListOptions{Prefix: "tmp/"})
+	require.NoError(t, err)
+	assert.Empty(t, res.Objects)
+
+	// 完成之后再 PATCH 一个空的请求体，进度不变
+	resp = s.patch(location, len(data), bytes.NewReader(nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Len(t, s.completed, 1)
+}
+
+func TestHandler_Patch(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{})
+	location := s.create(10, "")
+
+	resp := s.do(http.MethodPatch, location, bytes.NewReader([]byte("12345")), map[string]string{
+		"Content-Type":  "application/octet-stream",
+		"Upload-Offset": "0",
+	})
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+
+	resp = s.patch(location, 3, bytes.NewReader([]byte("12345")))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = s.patch(location, 0, bytes.NewReader([]byte("12345678901")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+	resp = s.patch("/files/not-exists", 0, bytes.NewReader([]byte("12345")))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, 0, s.offset(location))
+}
+
+func TestHandler_Resume(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{})
+	data := randomBytes(storage.MinPartSize + 1000)
+	location := s.create(len(data), "")
+
+	// 传到一半断开，已经收到的数据都要保存下来，包括超过一个分片的部分
+	broken := storage.MinPartSize + 300
+	resp := s.patch(location, 0, &brokenReader{r: bytes.NewReader(data), n: broken})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	offset := s.offset(location)
+	assert.Equal(t, broken, offset)
+
+	resp = s.patch(location, offset, bytes.NewReader(data[offset:]))
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, data, s.read(s.completed[0].Key))
+}
+
+func TestHandler_CreationWithUpload(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{})
+	resp := s.do(http.MethodPost, "/files", bytes.NewReader([]byte("hello")), map[string]string{
+		"Upload-Length": "5",
+		"Content-Type":  ContentType,
+	})
+	require.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "5", resp.Header().Get("Upload-Offset"))
+	require.Len(t, s.completed, 1)
+	assert.Equal(t, []byte("hello"), s.read(s.completed[0].Key))
+
+	// 空文件创建之后就完成了
+	location := s.create(0, "")
+	assert.Equal(t, 0, s.offset(location))
+	require.Len(t, s.completed, 2)
+	assert.Empty(t, s.read(s.completed[1].Key))
+}
+
+func TestHandler_Terminate(t *testing.T) {
+	t.Parallel()
+	s := newTusServer(t, Config{})
+	location := s.create(10, "")
+	resp := s.patch(location, 0, bytes.NewReader([]byte("12345")))
+	require.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = s.do(http.MethodDelete, location, nil, nil)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	resp = s.do(http.MethodHead, location, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	res, err := s.store.List(context.Background(), storage.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Objects)
+
+	resp = s.do(http.MethodDelete, location, nil, nil)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
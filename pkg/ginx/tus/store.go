@@ -0,0 +1,135 @@
+package tus
+
+import (
+	"bedrock/pkg/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUploadNotFound 上传不存在或者已经过期
+var ErrUploadNotFound = errors.New("tus: upload not found")
+
+// Upload 一个断点续传的上传进度
+type Upload struct {
+	ID string `json:"id"`
+	// Key 文件在存储里的 key
+	Key string `json:"key"`
+	// UploadID 存储的分片上传 ID，上传完成或者长度为 0 时为空
+	UploadID string `json:"uploadId,omitempty"`
+	// Length 文件的总长度，创建时由客户端通过 Upload-Length 声明
+	Length int64 `json:"length"`
+	// Offset 已经收到的字节数，包括 Pending
+	Offset int64 `json:"offset"`
+	// Parts 已经上传到存储的分片
+	Parts []storage.Part `json:"parts,omitempty"`
+	// Pending 不够一个分片的数据暂存在存储里，下一次 PATCH 的时候拼到前面一起上传
+	Pending int64 `json:"pending,omitempty"`
+	// Metadata 客户端通过 Upload-Metadata 传上来的元数据，例如 filename、filetype
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// Done 是否已经上传完成
+func (u Upload) Done() bool {
+	return u.Offset == u.Length
+}
+
+// Store 保存上传进度，多实例部署时需要使用共享的实现（例如 Redis）
+//
+//go:generate mockgen -source=./store.go -package=mocks -destination=./mocks/store_mock.go Store
+type Store interface {
+	// Get 上传不存在或者过期时返回 ErrUploadNotFound
+	Get(ctx context.Context, id string) (Upload, error)
+	// Save 保存上传进度，到 ExpiresAt 之后自动失效
+	Save(ctx context.Context, u Upload) error
+	Delete(ctx context.Context, id string) error
+}
+
+type MemoryStore struct {
+	mu      sync.RWMutex
+	uploads map[string]Upload
+}
+
+// NewMemoryStore 基于内存的 Store，只适合单实例部署和测试
+func NewMemoryStore() Store {
+	return &MemoryStore{uploads: make(map[string]Upload)}
+}
+
+var _ Store = &MemoryStore{}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.uploads[id]
+	if !ok || !time.Now().Before(u.ExpiresAt) {
+		return Upload{}, ErrUploadNotFound
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, u Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 顺便清理过期的数据
+	now := time.Now()
+	for id, old := range s.uploads {
+		if !now.Before(old.ExpiresAt) {
+			delete(s.uploads, id)
+		}
+	}
+	s.uploads[u.ID] = u
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, id)
+	return nil
+}
+
+type RedisStore struct {
+	cmd    redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore 把上传进度以 JSON 保存在 Redis 里，过期时间和 Upload.ExpiresAt 一致
+func NewRedisStore(cmd redis.Cmdable) Store {
+	return &RedisStore{cmd: cmd, prefix: "tus:upload:"}
+}
+
+var _ Store = &RedisStore{}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (Upload, error) {
+	data, err := s.cmd.Get(ctx, s.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Upload{}, ErrUploadNotFound
+	}
+	if err != nil {
+		return Upload{}, err
+	}
+	var u Upload
+	err = json.Unmarshal(data, &u)
+	return u, err
+}
+
+func (s *RedisStore) Save(ctx context.Context, u Upload) error {
+	ttl := time.Until(u.ExpiresAt)
+	if ttl <= 0 {
+		return s.Delete(ctx, u.ID)
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return s.cmd.Set(ctx, s.prefix+u.ID, data, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.cmd.Del(ctx, s.prefix+id).Err()
+}
@@ -1,7 +1,8 @@
 package ginx
 
 type Result struct {
-	Code int    `json:"code"`
+	Code int `json:"code"`
+	// Msg 消息 key，Wrap* 按照请求的语言翻译之后返回，见 SetI18n
 	Msg  string `json:"msg"`
 	Data any    `json:"data,omitempty"`
 }
@@ -22,26 +22,9 @@ func WrapBodyAndClaims[Req any, Claims jwt.Claims](bizFn func(ctx *gin.Context,
 	return func(ctx *gin.Context) {
 
 		var req Req
-		if err := ctx.ShouldBind(&req); err != nil {
-			log.Error(ctx.Request.Context(), "输入错误", logger.Error(err))
-			var verr validator.ValidationErrors
-			if errors.As(err, &verr) {
-				ctx.JSON(http.StatusOK, Result{
-					Code: http.StatusBadRequest,
-					Msg:  "输入参数有误，请检查",
-					Data: validate.RemoveTopStruct(verr.Translate(validate.Trans)),
-					//Data: verr.Translate(validate.Trans),
-					//Data: verr,
-				})
-			} else {
-				ctx.JSON(http.StatusOK, Result{
-					Code: http.StatusBadRequest,
-					Msg:  "请求体格式错误",
-				})
-			}
+		if !bind(ctx, &req, ctx.ShouldBind) {
 			return
 		}
-		log.Debug(ctx.Request.Context(), "输入参数", logger.Field{Key: "req:=", Val: req})
 
 		val, ok := ctx.Get("user")
 		if !ok {
@@ -56,24 +39,14 @@ func WrapBodyAndClaims[Req any, Claims jwt.Claims](bizFn func(ctx *gin.Context,
 		}
 
 		res, err := bizFn(ctx, req, uc)
-		if err != nil {
-			log.Error(ctx.Request.Context(), "执行业务逻辑失败", logger.Error(err))
-		}
-		log.Debug(ctx.Request.Context(), "返回响应", logger.Field{Key: "res:=", Val: res})
-
-		ctx.JSON(http.StatusOK, res)
+		render(ctx, res, err)
 	}
 }
 
 func Wrap(bizFn func(ctx *gin.Context) (Result, error)) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		res, err := bizFn(ctx)
-		if err != nil {
-			log.Error(ctx.Request.Context(), "执行业务逻辑失败", logger.Error(err))
-		}
-		log.Debug(ctx.Request.Context(), "返回响应", logger.Field{Key: "res:=", Val: res})
-
-		ctx.JSON(http.StatusOK, res)
+		render(ctx, res, err)
 	}
 }
 
@@ -81,34 +54,12 @@ func WrapBody[Req any](bizFn func(ctx *gin.Context, req Req) (Result, error)) gi
 	return func(ctx *gin.Context) {
 
 		var req Req
-		if err := ctx.ShouldBind(&req); err != nil {
-			log.Error(ctx.Request.Context(), "输入错误", logger.Error(err))
-			var verr validator.ValidationErrors
-			if errors.As(err, &verr) {
-				ctx.JSON(http.StatusOK, Result{
-					Code: http.StatusBadRequest,
-					Msg:  "输入参数有误，请检查",
-					Data: validate.RemoveTopStruct(verr.Translate(validate.Trans)),
-					//Data: verr.Translate(validate.Trans),
-					//Data: verr,
-				})
-			} else {
-				ctx.JSON(http.StatusOK, Result{
-					Code: http.StatusBadRequest,
-					Msg:  "请求体格式错误",
-				})
-			}
+		if !bind(ctx, &req, ctx.ShouldBind) {
 			return
 		}
-		log.Debug(ctx.Request.Context(), "输入参数", logger.Field{Key: "req:=", Val: req})
 
 		res, err := bizFn(ctx, req)
-		if err != nil {
-			log.Error(ctx.Request.Context(), "执行业务逻辑失败", logger.Error(err))
-		}
-		log.Debug(ctx.Request.Context(), "返回响应", logger.Field{Key: "res:=", Val: res})
-
-		ctx.JSON(http.StatusOK, res)
+		render(ctx, res, err)
 	}
 }
 
@@ -128,11 +79,106 @@ func WrapClaims[Claims any](bizFn func(ctx *gin.Context, uc Claims) (Result, err
 		}
 
 		res, err := bizFn(ctx, uc)
-		if err != nil {
+		render(ctx, res, err)
+	}
+}
+
+// bind 用 bindFn 绑定请求参数，失败时直接写响应并且返回 false
+// 校验错误按照请求的语言翻译之后放在 Data 里，其余错误统一提示请求体格式错误
+func bind(ctx *gin.Context, req any, bindFn func(obj any) error) bool {
+	if err := bindFn(req); err != nil {
+		log.Error(ctx.Request.Context(), "输入错误", logger.Error(err))
+		var verr validator.ValidationErrors
+		if errors.As(err, &verr) {
+			render(ctx, Result{
+				Code: http.StatusBadRequest,
+				Msg:  "common.invalid_params",
+				Data: validate.RemoveTopStruct(verr.Translate(validate.TransFor(Lang(ctx)))),
+			}, nil)
+		} else {
+			render(ctx, Result{
+				Code: http.StatusBadRequest,
+				Msg:  "common.bad_body",
+			}, nil)
+		}
+		return false
+	}
+	log.Debug(ctx.Request.Context(), "输入参数", logger.Field{Key: "req:=", Val: req})
+	return true
+}
+
+// render 把业务逻辑的返回值写成响应，错误按照 Resolve 的规则转换，状态码按照 StatusOf 的规则计算，Msg 按照请求的语言翻译
+// 用户造成的错误（4xx）只记录 Warn，系统错误和没有注册过的错误记录 Error
+func render(ctx *gin.Context, res Result, err error) {
+	status := StatusOf(res, err)
+	ctx.Set(statusKey, status)
+	if err != nil {
+		if e, ok := LookupError(err); ok && e.Status < http.StatusInternalServerError {
+			log.Warn(ctx.Request.Context(), "业务错误", logger.Int("code", e.Code), logger.Error(err))
+		} else {
 			log.Error(ctx.Request.Context(), "执行业务逻辑失败", logger.Error(err))
 		}
-		log.Debug(ctx.Request.Context(), "返回响应", logger.Field{Key: "res:=", Val: res})
+	}
+	res = Resolve(res, err)
+	res.Msg = T(ctx, res.Msg)
+	log.Debug(ctx.Request.Context(), "返回响应", logger.Field{Key: "res:=", Val: res})
+
+	if legacyStatus.Load() {
+		status = http.StatusOK
+	}
+	ctx.JSON(status, res)
+}
+
+// WrapQuery 只绑定查询参数，字段用 form tag，例如 `form:"limit" binding:"max=100"`
+func WrapQuery[Req any](bizFn func(ctx *gin.Context, req Req) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req Req
+		if !bind(ctx, &req, ctx.ShouldBindQuery) {
+			return
+		}
+		res, err := bizFn(ctx, req)
+		render(ctx, res, err)
+	}
+}
+
+// WrapURI 只绑定路径参数，字段用 uri tag，例如 /files/:id 对应 `uri:"id" binding:"required,min=1"`
+func WrapURI[Req any](bizFn func(ctx *gin.Context, req Req) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req Req
+		if !bind(ctx, &req, ctx.ShouldBindUri) {
+			return
+		}
+		res, err := bizFn(ctx, req)
+		render(ctx, res, err)
+	}
+}
 
-		ctx.JSON(http.StatusOK, res)
+// WrapReq 同时绑定路径参数（uri tag）、查询参数（form tag）、请求头（header tag）和请求体，
+// 所有来源都绑定完之后才校验一次，见 BindAll
+func WrapReq[Req any, Claims jwt.Claims](bizFn func(ctx *gin.Context, req Req, uc Claims) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, ok := ctx.Get("user")
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		uc, ok := val.(Claims)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		var req Req
+		if !bind(ctx, &req, func(obj any) error { return BindAll(ctx, obj) }) {
+			return
+		}
+		res, err := bizFn(ctx, req, uc)
+		render(ctx, res, err)
 	}
 }
+
+// Abort 中间件拒绝请求时使用，和 Wrap* 一样写响应（状态码、翻译、兼容模式），然后中止后面的 handler
+func Abort(ctx *gin.Context, res Result, err error) {
+	render(ctx, res, err)
+	ctx.Abort()
+}
@@ -0,0 +1,189 @@
+package i18n
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle 各个语言的消息目录，消息 key 按照 "模块.消息" 的形式组织，例如 user.login_ok
+// 查找顺序：请求的语言 -> 语言的主标签（zh-TW -> zh）-> 默认语言 -> key 本身
+type Bundle struct {
+	mu       sync.RWMutex
+	fallback string
+	// langs 支持的语言，第一个是默认语言，和 matcher 的下标一一对应
+	langs    []string
+	catalogs map[string]map[string]string
+	matcher  language.Matcher
+}
+
+// NewBundle fallback 是默认语言，请求的语言都不支持时使用
+func NewBundle(fallback string) *Bundle {
+	b := &Bundle{
+		fallback: fallback,
+		catalogs: make(map[string]map[string]string),
+	}
+	b.Add(fallback, nil)
+	return b
+}
+
+// Add 添加消息，重复的 key 以后添加的为准
+func (b *Bundle) Add(lang string, msgs map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog, ok := b.catalogs[lang]
+	if !ok {
+		catalog = make(map[string]string, len(msgs))
+		b.catalogs[lang] = catalog
+		b.langs = append(b.langs, lang)
+		tags := make([]language.Tag, 0, len(b.langs))
+		for _, l := range b.langs {
+			tags = append(tags, language.Make(l))
+		}
+		b.matcher = language.NewMatcher(tags)
+	}
+	for k, v := range msgs {
+		catalog[k] = v
+	}
+}
+
+// LoadFS 加载目录下所有的 <语言>.yaml（或者 .yml）文件，例如 zh.yaml、en.yaml
+// 文件里可以嵌套，加载的时候用 . 拼接成完整的 key
+func (b *Bundle) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return err
+		}
+		var raw map[string]any
+		if err = yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("i18n: 解析 %s 失败: %w", entry.Name(), err)
+		}
+		msgs := make(map[string]string)
+		if err = flatten("", raw, msgs); err != nil {
+			return fmt.Errorf("i18n: 解析 %s 失败: %w", entry.Name(), err)
+		}
+		b.Add(strings.TrimSuffix(entry.Name(), ext), msgs)
+	}
+	return nil
+}
+
+// LoadDir 从本地目录加载，见 LoadFS
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir))
+}
+
+func flatten(prefix string, raw map[string]any, msgs map[string]string) error {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case string:
+			msgs[key] = val
+		case map[string]any:
+			if err := flatten(key, val, msgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s 的值必须是字符串", key)
+		}
+	}
+	return nil
+}
+
+// Match 按照 Accept-Language 选出支持的语言，例如 "en-US,en;q=0.9" -> en，都不支持时返回默认语言
+func (b *Bundle) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return b.fallback
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, idx, conf := b.matcher.Match(tags...)
+	if conf == language.No {
+		return b.fallback
+	}
+	return b.langs[idx]
+}
+
+// T 翻译消息，有 args 时当做格式化字符串，所有语言都没有这个 key 时原样返回 key
+func (b *Bundle) T(lang, key string, args ...any) string {
+	msg, ok := b.lookup(lang, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (b *Bundle) lookup(lang, key string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	chain := []string{lang}
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		chain = append(chain, base)
+	}
+	chain = append(chain, b.fallback)
+	for _, l := range chain {
+		if msg, ok := b.catalogs[l][key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Langs 支持的语言，第一个是默认语言
+func (b *Bundle) Langs() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]string(nil), b.langs...)
+}
+
+// Has 语言 lang 自己的目录里有没有 key，不考虑回退
+func (b *Bundle) Has(lang, key string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.catalogs[lang][key]
+	return ok
+}
+
+// Missing 每个语言缺少的 key（其他语言有、自己没有的），用来检查翻译是否完整
+func (b *Bundle) Missing() map[string][]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	all := make(map[string]struct{})
+	for _, catalog := range b.catalogs {
+		for k := range catalog {
+			all[k] = struct{}{}
+		}
+	}
+	res := make(map[string][]string)
+	for lang, catalog := range b.catalogs {
+		for k := range all {
+			if _, ok := catalog[k]; !ok {
+				res[lang] = append(res[lang], k)
+			}
+		}
+		sort.Strings(res[lang])
+	}
+	return res
+}
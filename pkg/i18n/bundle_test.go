@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBundle(t *testing.T) *Bundle {
+	b := NewBundle("zh")
+	err := b.LoadFS(fstest.MapFS{
+		"zh.yaml": {Data: []byte("user:\n  login_ok: 登录成功\n  hello: 你好，%s\ncommon:\n  internal_error: 系统错误\n")},
+		"en.yml":  {Data: []byte("user:\n  login_ok: Logged in\n  hello: Hello, %s\n")},
+		"README":  {Data: []byte("not a catalog")},
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func TestBundle_Match(t *testing.T) {
+	t.Parallel()
+	b := newTestBundle(t)
+
+	testCases := []struct {
+		name   string
+		header string
+
+		wantLang string
+	}{
+		{name: "没有请求头", header: "", wantLang: "zh"},
+		{name: "英文", header: "en-US,en;q=0.9", wantLang: "en"},
+		{name: "中文地区", header: "zh-CN", wantLang: "zh"},
+		{name: "按照权重", header: "fr;q=1, en;q=0.8, zh;q=0.5", wantLang: "en"},
+		{name: "都不支持", header: "fr-FR", wantLang: "zh"},
+		{name: "格式错误", header: ";;;", wantLang: "zh"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.wantLang, b.Match(tc.header))
+		})
+	}
+}
+
+func TestBundle_T(t *testing.T) {
+	t.Parallel()
+	b := newTestBundle(t)
+
+	testCases := []struct {
+		name string
+		lang string
+		key  string
+		args []any
+
+		wantMsg string
+	}{
+		{name: "中文", lang: "zh", key: "user.login_ok", wantMsg: "登录成功"},
+		{name: "英文", lang: "en", key: "user.login_ok", wantMsg: "Logged in"},
+		{name: "地区回退到主标签", lang: "en-GB", key: "user.login_ok", wantMsg: "Logged in"},
+		{name: "缺少翻译回退到默认语言", lang: "en", key: "common.internal_error", wantMsg: "系统错误"},
+		{name: "没有这个 key", lang: "en", key: "user.unknown", wantMsg: "user.unknown"},
+		{name: "格式化", lang: "en", key: "user.hello", args: []any{"Tom"}, wantMsg: "Hello, Tom"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.wantMsg, b.T(tc.lang, tc.key, tc.args...))
+		})
+	}
+}
+
+func TestBundle_Missing(t *testing.T) {
+	t.Parallel()
+	b := newTestBundle(t)
+	assert.Equal(t, []string{"zh", "en"}, b.Langs())
+	assert.Equal(t, map[string][]string{"en": {"common.internal_error"}}, b.Missing())
+}
+
+func TestBundle_LoadFSInvalid(t *testing.T) {
+	t.Parallel()
+	b := NewBundle("zh")
+	err := b.LoadFS(fstest.MapFS{
+		"zh.yaml": {Data: []byte("user:\n  codes: [1, 2]\n")},
+	})
+	assert.ErrorContains(t, err, "user.codes")
+}
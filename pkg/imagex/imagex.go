@@ -0,0 +1,137 @@
+// Package imagex 提供用户上传图片的校验与处理：
+// 按文件头识别格式、限制尺寸、去掉 EXIF 等元数据、居中裁剪缩放以及重新编码
+package imagex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("不支持的图片格式")
+	ErrTooLarge          = errors.New("图片尺寸超出限制")
+)
+
+// Format 图片格式
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Ext 返回格式对应的扩展名
+func (f Format) Ext() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// Detect 按照文件头（magic bytes）识别图片格式，不认识的返回 false
+// 不信任扩展名和客户端声明的 Content-Type
+func Detect(head []byte) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, true
+	case bytes.HasPrefix(head, []byte{0xff, 0xd8, 0xff}):
+		return FormatJPEG, true
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return FormatGIF, true
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return FormatWebP, true
+	}
+	return "", false
+}
+
+// Limits 解码时的限制，零值代表不限制
+type Limits struct {
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Decode 校验并解码图片
+// 先只解析头部拿到宽高，超出限制的直接拒绝，避免解压炸弹把内存打满；
+// JPEG 会按照 EXIF 里的方向信息把图片转正，之后 EXIF 就不再需要了
+func Decode(data []byte, limits Limits) (image.Image, Format, error) {
+	format, ok := Detect(data)
+	if !ok {
+		return nil, "", ErrUnsupportedFormat
+	}
+	cfg, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+	if Format(name) != format {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 ||
+		(limits.MaxWidth > 0 && cfg.Width > limits.MaxWidth) ||
+		(limits.MaxHeight > 0 && cfg.Height > limits.MaxHeight) {
+		return nil, "", ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// Thumbnail 从图片中间裁剪出最大的正方形，再缩放成 size x size
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, image.Rect(x0, y0, x0+side, y0+side), xdraw.Src, nil)
+	return dst
+}
+
+// Encode 把图片编码成指定格式，quality 只对 JPEG 生效
+// 编码只写像素数据，原图里的 EXIF、ICC 等元数据都不会带过去
+func Encode(w io.Writer, img image.Image, format Format, quality int) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	case FormatWebP:
+		return encodeWebP(w, img)
+	}
+	return ErrUnsupportedFormat
+}
+
+// flatten JPEG 不支持透明通道，透明的部分铺成白色，否则会变成黑色
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
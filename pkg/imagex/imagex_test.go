@@ -0,0 +1,220 @@
+package imagex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name   string
+		head   []byte
+		want   Format
+		wantOk bool
+	}{
+		{name: "png", head: []byte("\x89PNG\r\n\x1a\n...."), want: FormatPNG, wantOk: true},
+		{name: "jpeg", head: []byte{0xff, 0xd8, 0xff, 0xe0}, want: FormatJPEG, wantOk: true},
+		{name: "gif", head: []byte("GIF89a...."), want: FormatGIF, wantOk: true},
+		{name: "webp", head: []byte("RIFF\x00\x00\x00\x00WEBPVP8L"), want: FormatWebP, wantOk: true},
+		{name: "其他 RIFF", head: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), wantOk: false},
+		{name: "svg", head: []byte("<svg xmlns="), wantOk: false},
+		{name: "空", head: nil, wantOk: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			f, ok := Detect(tc.head)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.want, f)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	data := encodePNG(t, gradient(40, 20))
+	testCases := []struct {
+		name    string
+		data    []byte
+		limits  Limits
+		wantErr error
+	}{
+		{name: "成功", data: data, limits: Limits{MaxWidth: 40, MaxHeight: 20}},
+		{name: "宽度超限", data: data, limits: Limits{MaxWidth: 39}, wantErr: ErrTooLarge},
+		{name: "高度超限", data: data, limits: Limits{MaxHeight: 19}, wantErr: ErrTooLarge},
+		{name: "伪装成 PNG 的文本", data: []byte("hello world"), wantErr: ErrUnsupportedFormat},
+		{name: "文件头正确但是内容损坏", data: data[:30], wantErr: ErrUnsupportedFormat},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, f, err := Decode(tc.data, tc.limits)
+			assert.ErrorIs(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, FormatPNG, f)
+			assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+		})
+	}
+}
+
+func TestDecode_Orientation(t *testing.T) {
+	t.Parallel()
+	// 左半边红色，右半边蓝色，EXIF 方向 6 表示要顺时针转 90 度才是正的
+	src := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.NRGBA{R: 0xff, A: 0xff}
+			if x >= 16 {
+				c = color.NRGBA{B: 0xff, A: 0xff}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}))
+	data := withOrientation(buf.Bytes(), 6)
+
+	img, f, err := Decode(data, Limits{})
+	require.NoError(t, err)
+	assert.Equal(t, FormatJPEG, f)
+	require.Equal(t, image.Rect(0, 0, 16, 32), img.Bounds())
+	r, _, b, _ := img.At(8, 4).RGBA()
+	assert.Greater(t, r, b, "原来左边的红色应该转到上面")
+	r, _, b, _ = img.At(8, 28).RGBA()
+	assert.Greater(t, b, r, "原来右边的蓝色应该转到下面")
+
+	// 重新编码之后 EXIF 就没有了
+	var out bytes.Buffer
+	require.NoError(t, Encode(&out, img, FormatJPEG, 90))
+	assert.Equal(t, 1, jpegOrientation(out.Bytes()))
+	assert.False(t, bytes.Contains(out.Bytes(), []byte("Exif")))
+}
+
+func TestThumbnail(t *testing.T) {
+	t.Parallel()
+	// 横图只保留中间的正方形：两边是黑色，中间是白色
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+	img := Thumbnail(src, 64)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), img.Bounds())
+	for _, p := range []image.Point{{0, 0}, {63, 0}, {32, 32}, {0, 63}, {63, 63}} {
+		r, g, b, _ := img.At(p.X, p.Y).RGBA()
+		assert.Equal(t, []uint32{0xffff, 0xffff, 0xffff}, []uint32{r, g, b}, p)
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	t.Parallel()
+	noise := image.NewNRGBA(image.Rect(0, 0, 67, 45))
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(rnd.IntN(256))
+	}
+	transparent := gradient(30, 30)
+	for i := 3; i < len(transparent.Pix); i += 4 {
+		transparent.Pix[i] = uint8(i / 4 % 256)
+	}
+	solid := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range solid.Pix {
+		solid.Pix[i] = 0x80
+	}
+	testCases := []struct {
+		name string
+		img  *image.NRGBA
+	}{
+		{name: "1x1", img: gradient(1, 1)},
+		{name: "渐变", img: gradient(128, 96)},
+		{name: "跨多个预测块", img: gradient(600, 3)},
+		{name: "随机噪声", img: noise},
+		{name: "半透明", img: transparent},
+		{name: "纯色", img: solid},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			require.NoError(t, Encode(&buf, tc.img, FormatWebP, 0))
+			f, ok := Detect(buf.Bytes())
+			require.True(t, ok)
+			assert.Equal(t, FormatWebP, f)
+
+			got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, tc.img.Bounds(), got.Bounds())
+			// 无损编码，每个像素都必须一致
+			b := tc.img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := tc.img.NRGBAAt(x, y)
+					if want.A == 0 {
+						continue
+					}
+					require.Equal(t, want, color.NRGBAModel.Convert(got.At(x, y)), "(%d, %d)", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeJPEG_Transparent(t *testing.T) {
+	t.Parallel()
+	// 透明的部分铺成白色，而不是黑色
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 8, 8)), FormatJPEG, 90))
+	img, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	r, g, b, _ := img.At(4, 4).RGBA()
+	assert.Greater(t, min(r, g, b), uint32(0xf000))
+}
+
+func gradient(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 2), G: uint8(y * 3), B: uint8(x + y), A: 0xff})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withOrientation 在 SOI 之后插入一个只有方向字段的 EXIF 段
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(seg)+2))
+	res := append([]byte{}, jpg[:2]...)
+	res = append(res, app1...)
+	res = append(res, seg...)
+	return append(res, jpg[2:]...)
+}
@@ -0,0 +1,392 @@
+package imagex
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// 这里实现了一个最小的 WebP 无损（VP8L）编码器，标准库和 x/image 只有解码器
+// 只用了减绿和预测两种变换，不做 LZ77 和颜色缓存，压缩率不如 libwebp，
+// 但头像缩略图尺寸很小，换来的是不依赖 cgo
+// 格式说明见 RFC 9649
+
+const (
+	vp8lSignature = 0x2f
+	vp8lMaxSize   = 1 << 14
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+	// predictorBits 预测模式按 2^9 = 512 像素的块划分，整张图用同一种模式
+	predictorBits = 9
+	// predictorSelect 第 11 种预测模式 Select，在左边和上边的像素里挑更接近的一个
+	predictorSelect = 11
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+)
+
+// codeLengthCodeOrder 编码长度码表的写入顺序
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return errors.New("webp: 图片尺寸超出范围")
+	}
+	// VP8L 存的是非预乘的 ARGB
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	pix := make([][4]uint8, width*height)
+	alpha := false
+	for i := range pix {
+		r, g, bl, a := src.Pix[4*i], src.Pix[4*i+1], src.Pix[4*i+2], src.Pix[4*i+3]
+		// 减绿变换：红蓝通道减去绿色，去掉通道之间的相关性
+		pix[i] = [4]uint8{r - g, g, bl - g, a}
+		alpha = alpha || a != 0xff
+	}
+	residuals := predict(pix, width, height)
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	bw.writeBits(boolBit(alpha), 1)
+	bw.writeBits(0, 3) // version
+	// 变换按编码时的顺序写入，解码时倒过来逆变换
+	bw.writeBits(1, 1)
+	bw.writeBits(transformSubtractGreen, 2)
+	bw.writeBits(1, 1)
+	bw.writeBits(transformPredictor, 2)
+	bw.writeBits(predictorBits-2, 3)
+	tiles := nTiles(width, predictorBits) * nTiles(height, predictorBits)
+	modes := make([][4]uint8, tiles)
+	for i := range modes {
+		modes[i] = [4]uint8{0, predictorSelect, 0, 0xff}
+	}
+	writeEntropyImage(bw, modes, false)
+	bw.writeBits(0, 1) // 没有更多变换
+	writeEntropyImage(bw, residuals, true)
+
+	data := bw.bytes()
+	chunk := len(data) + len(data)&1
+	header := make([]byte, 20)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+chunk))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// predict 计算预测变换之后的残差，像素按 R、G、B、A 存放
+// 第一个像素用不透明黑色预测，第一行用左边的像素，第一列用上边的像素，其余用 Select
+func predict(pix [][4]uint8, width, height int) [][4]uint8 {
+	res := make([][4]uint8, len(pix))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var pred [4]uint8
+			switch {
+			case x == 0 && y == 0:
+				pred = [4]uint8{0, 0, 0, 0xff}
+			case y == 0:
+				pred = pix[i-1]
+			case x == 0:
+				pred = pix[i-width]
+			default:
+				pred = selectPredictor(pix[i-1], pix[i-width], pix[i-width-1])
+			}
+			for c := range 4 {
+				res[i][c] = pix[i][c] - pred[c]
+			}
+		}
+	}
+	return res
+}
+
+func selectPredictor(l, t, tl [4]uint8) [4]uint8 {
+	// 估计值 L + T - TL 到 L 的距离就是 |T - TL|，到 T 的距离就是 |L - TL|
+	pl, pt := 0, 0
+	for c := range 4 {
+		pl += absDiff(t[c], tl[c])
+		pt += absDiff(l[c], tl[c])
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+// writeEntropyImage 写入一张熵编码的图像，不用颜色缓存，也不用 LZ77 反向引用
+// 顶层图像多一个 meta 前缀码的标记位，这里只用一组前缀码
+func writeEntropyImage(bw *bitWriter, pix [][4]uint8, topLevel bool) {
+	bw.writeBits(0, 1) // color cache
+	if topLevel {
+		bw.writeBits(0, 1) // meta prefix codes
+	}
+	// 前缀码的顺序是 绿、红、蓝、透明度、距离，绿色的字母表还包含 24 个长度码
+	var histograms [4][]int
+	histograms[0] = make([]int, 256+24)
+	for c := 1; c < 4; c++ {
+		histograms[c] = make([]int, 256)
+	}
+	for _, p := range pix {
+		histograms[0][p[1]]++
+		histograms[1][p[0]]++
+		histograms[2][p[2]]++
+		histograms[3][p[3]]++
+	}
+	var codes [4]prefixCode
+	for c := range codes {
+		codes[c] = writePrefixCode(bw, histograms[c])
+	}
+	// 没有反向引用，距离码只放一个符号
+	writePrefixCode(bw, make([]int, 40))
+	for _, p := range pix {
+		codes[0].write(bw, int(p[1]))
+		codes[1].write(bw, int(p[0]))
+		codes[2].write(bw, int(p[2]))
+		codes[3].write(bw, int(p[3]))
+	}
+}
+
+// prefixCode 规范哈夫曼码，codes 已经按位反转，可以直接按低位在前写入
+type prefixCode struct {
+	lengths []int
+	codes   []uint32
+}
+
+func (p prefixCode) write(bw *bitWriter, symbol int) {
+	bw.writeBits(p.codes[symbol], uint(p.lengths[symbol]))
+}
+
+// writePrefixCode 写入前缀码并返回它，只用到不超过两个 256 以内的符号时用简单编码
+func writePrefixCode(bw *bitWriter, histogram []int) prefixCode {
+	var used []int
+	for s, n := range histogram {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(used[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(used[0]), 8)
+		}
+		lengths := make([]int, len(histogram))
+		codes := make([]uint32, len(histogram))
+		if len(used) == 2 {
+			bw.writeBits(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+			codes[used[1]] = 1
+		}
+		return prefixCode{lengths: lengths, codes: codes}
+	}
+
+	lengths := huffmanLengths(histogram, maxCodeLength)
+	// 每个符号的码长本身也用一个前缀码编码，这里只用 0~15 的字面值，不用重复码
+	clHistogram := make([]int, 19)
+	for _, l := range lengths {
+		clHistogram[l]++
+	}
+	clLengths := huffmanLengths(clHistogram, maxCodeLengthCodeLength)
+	n := len(codeLengthCodeOrder)
+	for n > 4 && clLengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	bw.writeBits(0, 1)
+	bw.writeBits(uint32(n-4), 4)
+	for _, s := range codeLengthCodeOrder[:n] {
+		bw.writeBits(uint32(clLengths[s]), 3)
+	}
+	bw.writeBits(0, 1) // max_symbol 等于字母表大小
+	clCode := canonicalCode(clLengths)
+	for _, l := range lengths {
+		clCode.write(bw, l)
+	}
+	return canonicalCode(lengths)
+}
+
+// canonicalCode 由码长生成规范哈夫曼码
+// 只有一个符号的码不占任何比特，和解码器的约定一致
+func canonicalCode(lengths []int) prefixCode {
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	res := prefixCode{lengths: make([]int, len(lengths)), codes: make([]uint32, len(lengths))}
+	if used <= 1 {
+		return res
+	}
+	var count [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		res.lengths[s] = l
+		res.codes[s] = reverse(next[l], l)
+		next[l]++
+	}
+	return res
+}
+
+// huffmanLengths 计算不超过 limit 的哈夫曼码长
+// 超出时把小的频次抬高重新计算，频次越平均树越矮，最终一定能满足限制
+func huffmanLengths(histogram []int, limit int) []int {
+	for floor := 1; ; floor *= 2 {
+		lengths := buildHuffman(histogram, floor)
+		if maxOf(lengths) <= limit {
+			return lengths
+		}
+	}
+}
+
+type huffmanNode struct {
+	weight      int
+	symbol      int
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x any)   { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+func buildHuffman(histogram []int, floor int) []int {
+	lengths := make([]int, len(histogram))
+	h := &huffmanHeap{}
+	for s, n := range histogram {
+		if n > 0 {
+			*h = append(*h, &huffmanNode{weight: max(n, floor), symbol: s})
+		}
+	}
+	switch h.Len() {
+	case 0:
+		return lengths
+	case 1:
+		lengths[(*h)[0].symbol] = 1
+		return lengths
+	}
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{weight: a.weight + b.weight, symbol: min(a.symbol, b.symbol), left: a, right: b})
+	}
+	var walk func(n *huffmanNode, depth int)
+	walk = func(n *huffmanNode, depth int) {
+		if n.left == nil {
+			lengths[n.symbol] = depth
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	walk(heap.Pop(h).(*huffmanNode), 0)
+	return lengths
+}
+
+// bitWriter VP8L 的比特流按低位在前写入
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
+
+func reverse(code uint32, length int) uint32 {
+	var res uint32
+	for range length {
+		res = res<<1 | code&1
+		code >>= 1
+	}
+	return res
+}
+
+func nTiles(size, bits int) int {
+	return (size + 1<<bits - 1) >> bits
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func maxOf(s []int) int {
+	res := 0
+	for _, v := range s {
+		res = max(res, v)
+	}
+	return res
+}
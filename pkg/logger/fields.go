@@ -29,14 +29,32 @@ func SafeString(key, val string) Field {
 }
 
 // SafePhoneZH 安全地返回中国手机号，eg: 135****1234
+//
+// Deprecated: 手机号已经统一为 E.164 格式，请使用 SafePhone
 func SafePhoneZH(phone string) Field {
 	if DEBUG {
 		return Field{Key: "phone_zh", Val: phone}
 	} else {
-		return Field{Key: "phone_zh", Val: phone[:3] + "****" + phone[len(phone)-4:]}
+		return Field{Key: "phone_zh", Val: maskPhone(phone)}
 	}
 }
 
+// SafePhone 安全地返回手机号，保留国家码、号段和末四位，eg: +86135****1234
+func SafePhone(phone string) Field {
+	if DEBUG {
+		return Field{Key: "phone", Val: phone}
+	} else {
+		return Field{Key: "phone", Val: maskPhone(phone)}
+	}
+}
+
+func maskPhone(phone string) string {
+	if len(phone) < 8 {
+		return "****"
+	}
+	return phone[:len(phone)-8] + "****" + phone[len(phone)-4:]
+}
+
 // SafeEmail 安全地返回邮箱，eg: ***@example.com
 func SafeEmail(email string) Field {
 	if DEBUG {
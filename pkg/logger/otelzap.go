@@ -49,6 +49,12 @@ func (o *OtelZapLogger) toZapFields(args []Field) []zap.Field {
 	return res
 }
 func (o *OtelZapLogger) injectTraceContext(ctx context.Context, args []Field) []Field {
+	// 调用方自己带上了 trace_id 的不重复添加
+	for _, arg := range args {
+		if arg.Key == "trace_id" {
+			return args
+		}
+	}
 	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
 		// 将 trace_id 和 span_id 拼接到 args 切片中
 		args = append(args,
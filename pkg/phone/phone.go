@@ -0,0 +1,172 @@
+// Package phone 提供手机号的 E.164 解析、校验与规范化
+//
+// E.164 格式: +<国家码><国内号码>，总长度不超过 15 位数字，例如 +8613800138000
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidNumber      = errors.New("手机号格式错误")
+	ErrUnknownCountryCode = errors.New("不支持的国家码")
+)
+
+// DefaultCountryCode 没有携带国家码的号码，默认按照中国大陆处理
+const DefaultCountryCode = "86"
+
+// Number 解析之后的手机号
+type Number struct {
+	// CountryCode 国家码，不带 +，例如 "86"
+	CountryCode string
+	// National 国内号码，不带前导 0
+	National string
+}
+
+// E164 返回规范化之后的号码，例如 +8613800138000
+func (n Number) E164() string {
+	return "+" + n.CountryCode + n.National
+}
+
+// Region 返回号码所属的地区，例如 "CN"，未知时返回空串
+func (n Number) Region() string {
+	if r, ok := regions[n.CountryCode]; ok {
+		return r.region
+	}
+	return ""
+}
+
+// Timezone 返回号码所属地区的代表时区（IANA 名字），未知时返回空串
+func (n Number) Timezone() string {
+	if r, ok := regions[n.CountryCode]; ok {
+		return r.timezone
+	}
+	return ""
+}
+
+// Parse 解析并校验手机号
+// 支持的输入格式：
+//   - E.164: +8613800138000
+//   - 国际冠字: 008613800138000
+//   - 国内号码: 13800138000，按 defaultCC 补全国家码，defaultCC 为空时使用 DefaultCountryCode
+//
+// 号码中的空格、横线、括号会被忽略
+func Parse(raw string, defaultCC string) (Number, error) {
+	s := strip(raw)
+	if s == "" {
+		return Number{}, ErrInvalidNumber
+	}
+	if defaultCC == "" {
+		defaultCC = DefaultCountryCode
+	}
+	var n Number
+	switch {
+	case strings.HasPrefix(s, "+"):
+		cc, national, err := splitCountryCode(s[1:])
+		if err != nil {
+			return Number{}, err
+		}
+		n = Number{CountryCode: cc, National: national}
+	case strings.HasPrefix(s, "00"):
+		cc, national, err := splitCountryCode(s[2:])
+		if err != nil {
+			return Number{}, err
+		}
+		n = Number{CountryCode: cc, National: national}
+	default:
+		n = Number{CountryCode: defaultCC, National: s}
+	}
+	if !isDigits(n.National) {
+		return Number{}, ErrInvalidNumber
+	}
+	// 部分国家的国内拨号会带一个前导 0（例如英国 07xxx），E.164 中需要去掉
+	if r, ok := regions[n.CountryCode]; ok && r.trunkZero {
+		n.National = strings.TrimPrefix(n.National, "0")
+	}
+	if err := validate(n); err != nil {
+		return Number{}, err
+	}
+	return n, nil
+}
+
+// Normalize 解析并返回 E.164 格式的号码
+func Normalize(raw string, defaultCC string) (string, error) {
+	n, err := Parse(raw, defaultCC)
+	if err != nil {
+		return "", err
+	}
+	return n.E164(), nil
+}
+
+// Valid 判断号码是否合法
+func Valid(raw string, defaultCC string) bool {
+	_, err := Parse(raw, defaultCC)
+	return err == nil
+}
+
+// Legacy 返回历史数据中的存储格式
+// 早期只支持中国大陆号码，数据库中直接存储了 11 位国内号码，
+// 对于 +86 的号码返回国内号码，其他号码没有历史格式，返回空串
+func Legacy(e164 string) string {
+	if after, ok := strings.CutPrefix(e164, "+"+DefaultCountryCode); ok {
+		return after
+	}
+	return ""
+}
+
+func validate(n Number) error {
+	r, ok := regions[n.CountryCode]
+	if !ok {
+		// 未收录的国家码只做 E.164 的通用校验
+		if len(n.National) < 4 || len(n.CountryCode)+len(n.National) > 15 {
+			return ErrInvalidNumber
+		}
+		return nil
+	}
+	if len(n.National) < r.minLen || len(n.National) > r.maxLen {
+		return ErrInvalidNumber
+	}
+	if r.prefixes != "" && !strings.ContainsRune(r.prefixes, rune(n.National[0])) {
+		return ErrInvalidNumber
+	}
+	return nil
+}
+
+// splitCountryCode 国家码是前缀码，长度 1~3 位，不存在一个国家码是另一个的前缀
+func splitCountryCode(s string) (string, string, error) {
+	if !isDigits(s) {
+		return "", "", ErrInvalidNumber
+	}
+	for l := 1; l <= 3 && l < len(s); l++ {
+		if _, ok := countryCodes[s[:l]]; ok {
+			return s[:l], s[l:], nil
+		}
+	}
+	return "", "", ErrUnknownCountryCode
+}
+
+func strip(raw string) string {
+	var sb strings.Builder
+	sb.Grow(len(raw))
+	for _, c := range strings.TrimSpace(raw) {
+		switch c {
+		case ' ', '-', '(', ')', '.':
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name      string
+		raw       string
+		defaultCC string
+
+		wantNumber Number
+		wantE164   string
+		wantRegion string
+		wantErr    error
+	}{
+		{
+			name:       "E.164",
+			raw:        "+8613800138000",
+			wantNumber: Number{CountryCode: "86", National: "13800138000"},
+			wantE164:   "+8613800138000",
+			wantRegion: "CN",
+		},
+		{
+			name:       "国际冠字",
+			raw:        "008613800138000",
+			wantNumber: Number{CountryCode: "86", National: "13800138000"},
+			wantE164:   "+8613800138000",
+			wantRegion: "CN",
+		},
+		{
+			name:       "国内号码默认按中国大陆处理",
+			raw:        "13800138000",
+			wantNumber: Number{CountryCode: "86", National: "13800138000"},
+			wantE164:   "+8613800138000",
+			wantRegion: "CN",
+		},
+		{
+			name:       "国内号码按指定的国家码补全",
+			raw:        "2025550123",
+			defaultCC:  "1",
+			wantNumber: Number{CountryCode: "1", National: "2025550123"},
+			wantE164:   "+12025550123",
+			wantRegion: "US",
+		},
+		{
+			name:       "忽略空格横线括号和点",
+			raw:        " +1 (202) 555-0123. ",
+			wantNumber: Number{CountryCode: "1", National: "2025550123"},
+			wantE164:   "+12025550123",
+			wantRegion: "US",
+		},
+		{
+			name:       "E.164 里多余的前导 0",
+			raw:        "+44 07700 900123",
+			wantNumber: Number{CountryCode: "44", National: "7700900123"},
+			wantE164:   "+447700900123",
+			wantRegion: "GB",
+		},
+		{
+			name:       "国内拨号的前导 0",
+			raw:        "07700 900123",
+			defaultCC:  "44",
+			wantNumber: Number{CountryCode: "44", National: "7700900123"},
+			wantE164:   "+447700900123",
+			wantRegion: "GB",
+		},
+		{
+			name:       "日本国内拨号的前导 0",
+			raw:        "090-1234-5678",
+			defaultCC:  "81",
+			wantNumber: Number{CountryCode: "81", National: "9012345678"},
+			wantE164:   "+819012345678",
+			wantRegion: "JP",
+		},
+		{
+			name:      "不带前导 0 的地区不去掉 0",
+			raw:       "013800138000",
+			defaultCC: "86",
+			wantErr:   ErrInvalidNumber,
+		},
+		{
+			name:       "未收录的国家码只做通用校验",
+			raw:        "+201012345678",
+			wantNumber: Number{CountryCode: "20", National: "1012345678"},
+			wantE164:   "+201012345678",
+		},
+		{
+			name:    "未收录的国家码号码太短",
+			raw:     "+20123",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "未收录的国家码总长度超过 15 位",
+			raw:     "+2012345678901234",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "不存在的国家码",
+			raw:     "+99912345678",
+			wantErr: ErrUnknownCountryCode,
+		},
+		{
+			name:    "只有国家码",
+			raw:     "+1",
+			wantErr: ErrUnknownCountryCode,
+		},
+		{
+			name:    "空号码",
+			raw:     " - ",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "包含字母",
+			raw:     "+86abc",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "国内号码包含字母",
+			raw:     "1380013800a",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "长度不对",
+			raw:     "1380013800",
+			wantErr: ErrInvalidNumber,
+		},
+		{
+			name:    "首位数字不对",
+			raw:     "23800138000",
+			wantErr: ErrInvalidNumber,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			n, err := Parse(tc.raw, tc.defaultCC)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantNumber, n)
+			assert.Equal(t, tc.wantErr == nil, Valid(tc.raw, tc.defaultCC))
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantRegion, n.Region())
+
+			e164, err := Normalize(tc.raw, tc.defaultCC)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantE164, e164)
+			// 规范化之后的号码再解析一次结果不变
+			again, err := Normalize(e164, "")
+			assert.NoError(t, err)
+			assert.Equal(t, e164, again)
+		})
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name string
+		e164 string
+
+		want string
+	}{
+		{
+			name: "中国大陆号码",
+			e164: "+8613800138000",
+			want: "13800138000",
+		},
+		{
+			name: "其他地区没有历史格式",
+			e164: "+12025550123",
+			want: "",
+		},
+		{
+			name: "不是 E.164",
+			e164: "13800138000",
+			want: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, Legacy(tc.e164))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package phone
+
+import "strings"
+
+// rule 某个国家码下手机号的校验规则
+type rule struct {
+	region string
+	// minLen/maxLen 国内号码（不含前导 0）的长度范围
+	minLen int
+	maxLen int
+	// prefixes 国内手机号允许的首位数字，为空表示不限制
+	prefixes string
+	// trunkZero 国内拨号是否带前导 0
+	trunkZero bool
+	// timezone 该地区的代表时区，跨多个时区的国家取人口最多的那个
+	timezone string
+}
+
+// regions 常用国家/地区的手机号规则，未收录的国家码只做 E.164 通用校验
+var regions = map[string]rule{
+	"86":  {region: "CN", minLen: 11, maxLen: 11, prefixes: "1", timezone: "Asia/Shanghai"},
+	"852": {region: "HK", minLen: 8, maxLen: 8, prefixes: "456789", timezone: "Asia/Hong_Kong"},
+	"853": {region: "MO", minLen: 8, maxLen: 8, prefixes: "6", timezone: "Asia/Macau"},
+	"886": {region: "TW", minLen: 9, maxLen: 9, prefixes: "9", trunkZero: true, timezone: "Asia/Taipei"},
+	"1":   {region: "US", minLen: 10, maxLen: 10, prefixes: "23456789", timezone: "America/New_York"},
+	"44":  {region: "GB", minLen: 10, maxLen: 10, prefixes: "7", trunkZero: true, timezone: "Europe/London"},
+	"81":  {region: "JP", minLen: 10, maxLen: 10, prefixes: "789", trunkZero: true, timezone: "Asia/Tokyo"},
+	"82":  {region: "KR", minLen: 9, maxLen: 10, prefixes: "1", trunkZero: true, timezone: "Asia/Seoul"},
+	"65":  {region: "SG", minLen: 8, maxLen: 8, prefixes: "89", timezone: "Asia/Singapore"},
+	"60":  {region: "MY", minLen: 9, maxLen: 10, prefixes: "1", trunkZero: true, timezone: "Asia/Kuala_Lumpur"},
+	"66":  {region: "TH", minLen: 9, maxLen: 9, prefixes: "689", trunkZero: true, timezone: "Asia/Bangkok"},
+	"84":  {region: "VN", minLen: 9, maxLen: 9, prefixes: "35789", trunkZero: true, timezone: "Asia/Ho_Chi_Minh"},
+	"63":  {region: "PH", minLen: 10, maxLen: 10, prefixes: "9", trunkZero: true, timezone: "Asia/Manila"},
+	"62":  {region: "ID", minLen: 9, maxLen: 12, prefixes: "8", trunkZero: true, timezone: "Asia/Jakarta"},
+	"61":  {region: "AU", minLen: 9, maxLen: 9, prefixes: "4", trunkZero: true, timezone: "Australia/Sydney"},
+	"49":  {region: "DE", minLen: 10, maxLen: 11, prefixes: "1", trunkZero: true, timezone: "Europe/Berlin"},
+	"33":  {region: "FR", minLen: 9, maxLen: 9, prefixes: "67", trunkZero: true, timezone: "Europe/Paris"},
+	"91":  {region: "IN", minLen: 10, maxLen: 10, prefixes: "6789", timezone: "Asia/Kolkata"},
+	"7":   {region: "RU", minLen: 10, maxLen: 10, prefixes: "9", timezone: "Europe/Moscow"},
+}
+
+// countryCodes ITU-T E.164 分配的全部国家码
+var countryCodes = func() map[string]struct{} {
+	const all = `1 7
+20 27 30 31 32 33 34 36 39 40 41 43 44 45 46 47 48 49 51 52 53 54 55 56 57 58
+60 61 62 63 64 65 66 81 82 84 86 90 91 92 93 94 95 98
+211 212 213 216 218 220 221 222 223 224 225 226 227 228 229 230 231 232 233 234
+235 236 237 238 239 240 241 242 243 244 245 246 247 248 249 250 251 252 253 254
+255 256 257 258 260 261 262 263 264 265 266 267 268 269 290 291 297 298 299
+350 351 352 353 354 355 356 357 358 359 370 371 372 373 374 375 376 377 378 379
+380 381 382 383 385 386 387 389 420 421 423
+500 501 502 503 504 505 506 507 508 509 590 591 592 593 594 595 596 597 598 599
+670 672 673 674 675 676 677 678 679 680 681 682 683 685 686 687 688 689 690 691 692
+800 808 850 852 853 855 856 870 878 880 881 882 883 886 888
+960 961 962 963 964 965 966 967 968 970 971 972 973 974 975 976 977 979
+992 993 994 995 996 998`
+	res := make(map[string]struct{}, 256)
+	for _, cc := range strings.Fields(all) {
+		res[cc] = struct{}{}
+	}
+	return res
+}()
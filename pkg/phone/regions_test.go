@@ -0,0 +1,184 @@
+package phone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegions 每个收录的地区都能正确识别合法号码，并拒绝长度或者首位不对的号码
+func TestRegions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		region string
+		// valid 该地区合法的号码，E.164 格式
+		valid []string
+		// invalid 该地区不合法的号码
+		invalid []string
+
+		wantTimezone string
+	}{
+		{
+			region:       "CN",
+			valid:        []string{"+8613800138000", "+8619912345678"},
+			invalid:      []string{"+861380013800", "+86138001380001", "+8623800138000"},
+			wantTimezone: "Asia/Shanghai",
+		},
+		{
+			region:       "HK",
+			valid:        []string{"+85251234567", "+85291234567"},
+			invalid:      []string{"+85221234567", "+8525123456"},
+			wantTimezone: "Asia/Hong_Kong",
+		},
+		{
+			region:       "MO",
+			valid:        []string{"+85366123456"},
+			invalid:      []string{"+85328123456", "+8536612345"},
+			wantTimezone: "Asia/Macau",
+		},
+		{
+			region:       "TW",
+			valid:        []string{"+886912345678", "+8860912345678"},
+			invalid:      []string{"+886212345678", "+88691234567"},
+			wantTimezone: "Asia/Taipei",
+		},
+		{
+			region:       "US",
+			valid:        []string{"+12025550123", "+19175550123"},
+			invalid:      []string{"+11025550123", "+1202555012"},
+			wantTimezone: "America/New_York",
+		},
+		{
+			region:       "GB",
+			valid:        []string{"+447700900123", "+4407700900123"},
+			invalid:      []string{"+442071234567", "+44770090012"},
+			wantTimezone: "Europe/London",
+		},
+		{
+			region:       "JP",
+			valid:        []string{"+819012345678", "+817012345678"},
+			invalid:      []string{"+813123456789", "+81901234567"},
+			wantTimezone: "Asia/Tokyo",
+		},
+		{
+			region:       "KR",
+			valid:        []string{"+821012345678", "+82112345678"},
+			invalid:      []string{"+82212345678", "+8210123456789"},
+			wantTimezone: "Asia/Seoul",
+		},
+		{
+			region:       "SG",
+			valid:        []string{"+6581234567", "+6591234567"},
+			invalid:      []string{"+6561234567", "+658123456"},
+			wantTimezone: "Asia/Singapore",
+		},
+		{
+			region:       "MY",
+			valid:        []string{"+60123456789", "+601123456789"},
+			invalid:      []string{"+60312345678", "+6012345678"},
+			wantTimezone: "Asia/Kuala_Lumpur",
+		},
+		{
+			region:       "TH",
+			valid:        []string{"+66812345678"},
+			invalid:      []string{"+66212345678", "+6681234567"},
+			wantTimezone: "Asia/Bangkok",
+		},
+		{
+			region:       "VN",
+			valid:        []string{"+84912345678", "+84312345678"},
+			invalid:      []string{"+84212345678", "+8491234567"},
+			wantTimezone: "Asia/Ho_Chi_Minh",
+		},
+		{
+			region:       "PH",
+			valid:        []string{"+639171234567"},
+			invalid:      []string{"+632171234567", "+63917123456"},
+			wantTimezone: "Asia/Manila",
+		},
+		{
+			region:       "ID",
+			valid:        []string{"+62812345678", "+62812345678901"},
+			invalid:      []string{"+62212345678", "+6281234567"},
+			wantTimezone: "Asia/Jakarta",
+		},
+		{
+			region:       "AU",
+			valid:        []string{"+61412345678"},
+			invalid:      []string{"+61212345678", "+6141234567"},
+			wantTimezone: "Australia/Sydney",
+		},
+		{
+			region:       "DE",
+			valid:        []string{"+4915123456789", "+491512345678"},
+			invalid:      []string{"+493012345678", "+49151234567"},
+			wantTimezone: "Europe/Berlin",
+		},
+		{
+			region:       "FR",
+			valid:        []string{"+33612345678", "+33712345678"},
+			invalid:      []string{"+33112345678", "+3361234567"},
+			wantTimezone: "Europe/Paris",
+		},
+		{
+			region:       "IN",
+			valid:        []string{"+919812345678"},
+			invalid:      []string{"+915812345678", "+91981234567"},
+			wantTimezone: "Asia/Kolkata",
+		},
+		{
+			region:       "RU",
+			valid:        []string{"+79123456789"},
+			invalid:      []string{"+74951234567", "+7912345678"},
+			wantTimezone: "Europe/Moscow",
+		},
+	}
+
+	covered := make(map[string]struct{}, len(testCases))
+	for _, tc := range testCases {
+		covered[tc.region] = struct{}{}
+	}
+	for cc, r := range regions {
+		_, ok := covered[r.region]
+		assert.True(t, ok, "+%s 没有测试用例", cc)
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.region, func(t *testing.T) {
+			t.Parallel()
+			for _, raw := range tc.valid {
+				n, err := Parse(raw, "")
+				require.NoError(t, err, raw)
+				assert.Equal(t, tc.region, n.Region(), raw)
+				assert.Equal(t, tc.wantTimezone, n.Timezone(), raw)
+			}
+			for _, raw := range tc.invalid {
+				_, err := Parse(raw, "")
+				assert.Equal(t, ErrInvalidNumber, err, raw)
+			}
+		})
+	}
+}
+
+// TestRegionRules 检查规则表本身的一致性
+func TestRegionRules(t *testing.T) {
+	t.Parallel()
+	for cc, r := range regions {
+		_, ok := countryCodes[cc]
+		assert.True(t, ok, "+%s 不是 ITU 分配的国家码", cc)
+		assert.LessOrEqual(t, r.minLen, r.maxLen, cc)
+		assert.LessOrEqual(t, len(cc)+r.maxLen, 15, cc)
+		_, err := time.LoadLocation(r.timezone)
+		assert.NoError(t, err, cc)
+	}
+	// splitCountryCode 依赖国家码是前缀码
+	for cc := range countryCodes {
+		for l := 1; l < len(cc); l++ {
+			_, ok := countryCodes[cc[:l]]
+			assert.False(t, ok, "%s 是 %s 的前缀", cc[:l], cc)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package clamav 通过 clamd 的 INSTREAM 协议扫描病毒，不依赖本地的 clamscan 命令
+//
+// 协议说明见 https://docs.clamav.net/manual/Usage/Scanning.html#clamd
+package clamav
+
+import (
+	"bedrock/pkg/scanner"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSizeLimit 超过了 clamd 的 StreamMaxLength 配置
+	ErrSizeLimit = errors.New("clamav: stream size limit exceeded")
+)
+
+// Config clamd 的连接配置
+type Config struct {
+	// Network tcp 或者 unix，默认 tcp
+	Network string `mapstructure:"network"`
+	// Address 例如 127.0.0.1:3310 或者 /var/run/clamav/clamd.ctl
+	Address string `mapstructure:"address"`
+	// Timeout 单次扫描的超时时间，默认 1 分钟
+	Timeout time.Duration `mapstructure:"timeout"`
+	// ChunkSize 每次发送的数据块大小，默认 64KB
+	ChunkSize int `mapstructure:"chunk_size"`
+}
+
+type Client struct {
+	cfg    Config
+	dialer net.Dialer
+}
+
+var _ scanner.Scanner = &Client{}
+
+func NewClient(c Config) *Client {
+	if c.Network == "" {
+		c.Network = "tcp"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = time.Minute
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 64 << 10
+	}
+	return &Client{cfg: c}
+}
+
+// Ping 检查 clamd 是否可用
+func (c *Client) Ping(ctx context.Context) error {
+	reply, err := c.do(ctx, "PING", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamav: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+// Scan 把对象内容发给 clamd 扫描，发现病毒时返回 VerdictReject，Reason 是病毒名
+func (c *Client) Scan(ctx context.Context, obj scanner.Object) (scanner.Result, error) {
+	r, err := obj.Open(ctx)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	defer r.Close()
+	reply, err := c.do(ctx, "INSTREAM", r)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	return parseReply(reply)
+}
+
+// do 发送一条 z 开头（以 \0 结尾）的命令，body 不为空时按照 INSTREAM 的格式分块发送
+func (c *Client) do(ctx context.Context, cmd string, body io.Reader) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	conn, err := c.dialer.DialContext(ctx, c.cfg.Network, c.cfg.Address)
+	if err != nil {
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	// 超时或者调用方取消时立刻中断读写
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	w := bufio.NewWriterSize(conn, c.cfg.ChunkSize+4)
+	_, err = w.WriteString("z" + cmd + "\x00")
+	if err == nil && body != nil {
+		err = c.stream(w, body)
+		// 读取对象失败时 clamd 还在等待数据，不用再等回复
+		var re readError
+		if errors.As(err, &re) {
+			return "", re.err
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	reply, rerr := bufio.NewReader(conn).ReadString(0)
+	// 超过大小限制时 clamd 会先回复再关闭连接，这时写入失败了也以回复为准
+	if rerr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("clamav: %w", ctxErr)
+		}
+		if err == nil {
+			err = rerr
+		}
+		return "", fmt.Errorf("clamav: %w", err)
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// stream 每一块前面是 4 字节大端序的长度，最后用长度为 0 的块结束
+func (c *Client) stream(w *bufio.Writer, body io.Reader) error {
+	buf := make([]byte, c.cfg.ChunkSize)
+	var size [4]byte
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, werr := w.Write(size[:]); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return readError{err: err}
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	_, err := w.Write(size[:])
+	return err
+}
+
+// readError 读取待扫描的对象失败，和网络错误区分开
+type readError struct {
+	err error
+}
+
+func (e readError) Error() string {
+	return e.err.Error()
+}
+
+// parseReply 解析 INSTREAM 的回复，格式是 "stream: OK"、"stream: <病毒名> FOUND" 或者 "<原因> ERROR"
+func parseReply(reply string) (scanner.Result, error) {
+	msg := reply
+	if _, rest, ok := strings.Cut(reply, ": "); ok {
+		msg = rest
+	}
+	switch {
+	case msg == "OK":
+		return scanner.Result{Verdict: scanner.VerdictClean}, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return scanner.Result{
+			Verdict: scanner.VerdictReject,
+			Reason:  "virus: " + strings.TrimSuffix(msg, " FOUND"),
+		}, nil
+	case strings.Contains(msg, "size limit exceeded"):
+		return scanner.Result{}, ErrSizeLimit
+	default:
+		return scanner.Result{}, fmt.Errorf("clamav: %s", reply)
+	}
+}
@@ -0,0 +1,183 @@
+package clamav
+
+import (
+	"bedrock/pkg/scanner"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd 实现 clamd 协议的一个子集：PING 和 INSTREAM，内容里有 EICAR 测试串时报毒
+type fakeClamd struct {
+	ln net.Listener
+	// maxStream 对应 clamd 的 StreamMaxLength
+	maxStream int
+	// hang 为 true 时收到命令之后不回复
+	hang bool
+}
+
+func newFakeClamd(t *testing.T) *fakeClamd {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	d := &fakeClamd{ln: ln, maxStream: 1 << 20}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	return d
+}
+
+func (d *fakeClamd) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || d.hang {
+		_, _ = io.Copy(io.Discard, r)
+		return
+	}
+	switch cmd {
+	case "zPING\x00":
+		_, _ = conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var data []byte
+		for {
+			var size uint32
+			if err = binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if len(data)+int(size) > d.maxStream {
+				_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+			chunk := make([]byte, size)
+			if _, err = io.ReadFull(r, chunk); err != nil {
+				return
+			}
+			data = append(data, chunk...)
+		}
+		if bytes.Contains(data, []byte(eicar)) {
+			_, _ = conn.Write([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\x00"))
+			return
+		}
+		_, _ = conn.Write([]byte("stream: OK\x00"))
+	default:
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func object(content string) scanner.Object {
+	return scanner.Object{
+		Key:  "a.bin",
+		Size: int64(len(content)),
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func TestClient_Scan(t *testing.T) {
+	t.Parallel()
+	d := newFakeClamd(t)
+	d.maxStream = 100
+	c := NewClient(Config{Address: d.ln.Addr().String(), ChunkSize: 16})
+
+	testCases := []struct {
+		name    string
+		obj     scanner.Object
+		want    scanner.Result
+		wantErr error
+	}{
+		{
+			name: "没有病毒",
+			obj:  object("hello world"),
+			want: scanner.Result{Verdict: scanner.VerdictClean},
+		},
+		{
+			name: "空文件",
+			obj:  object(""),
+			want: scanner.Result{Verdict: scanner.VerdictClean},
+		},
+		{
+			// 病毒串比 ChunkSize 长，跨越了多个数据块
+			name: "发现病毒",
+			obj:  object("prefix " + eicar),
+			want: scanner.Result{Verdict: scanner.VerdictReject, Reason: "virus: Win.Test.EICAR_HDB-1"},
+		},
+		{
+			name:    "超过大小限制",
+			obj:     object(strings.Repeat("a", 200)),
+			wantErr: ErrSizeLimit,
+		},
+		{
+			name: "读取对象失败",
+			obj: scanner.Object{Open: func(ctx context.Context) (io.ReadCloser, error) {
+				return io.NopCloser(io.MultiReader(strings.NewReader("abc"), brokenReader{})), nil
+			}},
+			wantErr: errBroken,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := c.Scan(context.Background(), tc.obj)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+var errBroken = errors.New("broken")
+
+// brokenReader 读取时总是失败
+type brokenReader struct{}
+
+func (brokenReader) Read([]byte) (int, error) {
+	return 0, errBroken
+}
+
+func TestClient_Ping(t *testing.T) {
+	t.Parallel()
+	d := newFakeClamd(t)
+	assert.NoError(t, NewClient(Config{Address: d.ln.Addr().String()}).Ping(context.Background()))
+
+	// 连不上
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	assert.Error(t, NewClient(Config{Address: addr}).Ping(context.Background()))
+}
+
+func TestClient_Timeout(t *testing.T) {
+	t.Parallel()
+	d := newFakeClamd(t)
+	d.hang = true
+	c := NewClient(Config{Address: d.ln.Addr().String(), Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := c.Scan(context.Background(), object("hello"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,67 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig 通用 HTTP 审核服务的配置
+type HTTPConfig struct {
+	// Endpoint 图片以请求体的形式 POST 到这个地址
+	Endpoint string `mapstructure:"endpoint"`
+	// Token 放在 Authorization: Bearer 里，为空时不设置
+	Token   string        `mapstructure:"token"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// HTTPReviewer 对接自建或者网关包装过的审核服务，约定的响应格式是
+//
+//	{"labels": [{"name": "porn", "score": 0.98}]}
+type HTTPReviewer struct {
+	cfg    HTTPConfig
+	client *http.Client
+}
+
+var _ Reviewer = &HTTPReviewer{}
+
+func NewHTTPReviewer(c HTTPConfig) *HTTPReviewer {
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return &HTTPReviewer{
+		cfg:    c,
+		client: &http.Client{Timeout: c.Timeout},
+	}
+}
+
+func (h *HTTPReviewer) Review(ctx context.Context, image []byte, contentType string) ([]Label, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.Endpoint, bytes.NewReader(image))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if h.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.cfg.Token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	var res struct {
+		Labels []Label `json:"labels"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res.Labels, nil
+}
@@ -0,0 +1,99 @@
+// Package moderation 把图片审核服务适配成 scanner.Scanner
+//
+// 各家云厂商的审核接口大同小异：提交图片，返回若干标签和置信度。
+// 实现 Reviewer 接入具体的服务，阈值和结论的换算由这里统一处理
+package moderation
+
+import (
+	"bedrock/pkg/scanner"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Label 审核服务返回的标签，例如 porn、violence，Score 是 0~1 的置信度
+type Label struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+//go:generate mockgen -source=./scanner.go -package=mocks -destination=./mocks/reviewer_mock.go Reviewer
+type Reviewer interface {
+	// Review 审核一张图片，只需要返回命中的标签，正常的图片返回空
+	Review(ctx context.Context, image []byte, contentType string) ([]Label, error)
+}
+
+// Config 审核的阈值
+type Config struct {
+	// RejectScore 任何一个标签达到这个置信度就直接拒绝，默认 0.9
+	RejectScore float64 `mapstructure:"reject_score"`
+	// ReviewScore 达到这个置信度转人工审核，默认 0.5
+	ReviewScore float64 `mapstructure:"review_score"`
+	// MaxSize 提交审核的图片大小上限，超过的转人工审核，默认 10MB
+	MaxSize int64 `mapstructure:"max_size"`
+}
+
+type Scanner struct {
+	reviewer Reviewer
+	cfg      Config
+}
+
+var _ scanner.Scanner = &Scanner{}
+
+func NewScanner(reviewer Reviewer, c Config) *Scanner {
+	if c.RejectScore <= 0 {
+		c.RejectScore = 0.9
+	}
+	if c.ReviewScore <= 0 {
+		c.ReviewScore = 0.5
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 10 << 20
+	}
+	return &Scanner{reviewer: reviewer, cfg: c}
+}
+
+// Scan 只审核图片，其他类型直接放行
+func (s *Scanner) Scan(ctx context.Context, obj scanner.Object) (scanner.Result, error) {
+	if !strings.HasPrefix(obj.ContentType, "image/") {
+		return scanner.Result{Verdict: scanner.VerdictClean}, nil
+	}
+	if obj.Size > s.cfg.MaxSize {
+		return scanner.Result{Verdict: scanner.VerdictReview, Reason: "moderation: image too large"}, nil
+	}
+	r, err := obj.Open(ctx)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxSize+1))
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	if int64(len(data)) > s.cfg.MaxSize {
+		return scanner.Result{Verdict: scanner.VerdictReview, Reason: "moderation: image too large"}, nil
+	}
+	labels, err := s.reviewer.Review(ctx, data, obj.ContentType)
+	if err != nil {
+		return scanner.Result{}, fmt.Errorf("moderation: %w", err)
+	}
+
+	res := scanner.Result{Verdict: scanner.VerdictClean}
+	var top Label
+	for _, l := range labels {
+		if l.Score > top.Score {
+			top = l
+		}
+	}
+	switch {
+	case top.Score >= s.cfg.RejectScore:
+		res.Verdict = scanner.VerdictReject
+	case top.Score >= s.cfg.ReviewScore:
+		res.Verdict = scanner.VerdictReview
+	default:
+		return res, nil
+	}
+	res.Reason = fmt.Sprintf("moderation: %s %.2f", top.Name, top.Score)
+	return res, nil
+}
@@ -0,0 +1,144 @@
+package moderation_test
+
+import (
+	"bedrock/pkg/scanner"
+	"bedrock/pkg/scanner/moderation"
+	"bedrock/pkg/scanner/moderation/mocks"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func image(contentType, content string) scanner.Object {
+	return scanner.Object{
+		Key:         "a.png",
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) moderation.Reviewer
+		obj  scanner.Object
+
+		want    scanner.Result
+		wantErr string
+	}{
+		{
+			name: "正常图片",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				r := mocks.NewMockReviewer(ctrl)
+				r.EXPECT().Review(gomock.Any(), []byte("png"), "image/png").
+					Return([]moderation.Label{{Name: "porn", Score: 0.1}}, nil)
+				return r
+			},
+			obj:  image("image/png", "png"),
+			want: scanner.Result{Verdict: scanner.VerdictClean},
+		},
+		{
+			name: "疑似违规",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				r := mocks.NewMockReviewer(ctrl)
+				r.EXPECT().Review(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return([]moderation.Label{{Name: "porn", Score: 0.2}, {Name: "violence", Score: 0.6}}, nil)
+				return r
+			},
+			obj:  image("image/png", "png"),
+			want: scanner.Result{Verdict: scanner.VerdictReview, Reason: "moderation: violence 0.60"},
+		},
+		{
+			name: "确认违规",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				r := mocks.NewMockReviewer(ctrl)
+				r.EXPECT().Review(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return([]moderation.Label{{Name: "porn", Score: 0.95}}, nil)
+				return r
+			},
+			obj:  image("image/png", "png"),
+			want: scanner.Result{Verdict: scanner.VerdictReject, Reason: "moderation: porn 0.95"},
+		},
+		{
+			name: "不是图片",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				return mocks.NewMockReviewer(ctrl)
+			},
+			obj:  image("application/pdf", "pdf"),
+			want: scanner.Result{Verdict: scanner.VerdictClean},
+		},
+		{
+			name: "图片太大",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				return mocks.NewMockReviewer(ctrl)
+			},
+			obj:  image("image/png", strings.Repeat("a", 11)),
+			want: scanner.Result{Verdict: scanner.VerdictReview, Reason: "moderation: image too large"},
+		},
+		{
+			name: "审核服务出错",
+			mock: func(ctrl *gomock.Controller) moderation.Reviewer {
+				r := mocks.NewMockReviewer(ctrl)
+				r.EXPECT().Review(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("rate limited"))
+				return r
+			},
+			obj:     image("image/png", "png"),
+			wantErr: "moderation: rate limited",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			s := moderation.NewScanner(tc.mock(ctrl), moderation.Config{MaxSize: 10})
+
+			got, err := s.Scan(context.Background(), tc.obj)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHTTPReviewer(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "png", string(body))
+		assert.Equal(t, "image/png", r.Header.Get("Content-Type"))
+		_, _ = w.Write([]byte(`{"labels":[{"name":"porn","score":0.98}]}`))
+	}))
+	defer server.Close()
+
+	labels, err := moderation.NewHTTPReviewer(moderation.HTTPConfig{Endpoint: server.URL, Token: "token"}).
+		Review(context.Background(), []byte("png"), "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, []moderation.Label{{Name: "porn", Score: 0.98}}, labels)
+
+	_, err = moderation.NewHTTPReviewer(moderation.HTTPConfig{Endpoint: server.URL}).
+		Review(context.Background(), []byte("png"), "image/png")
+	assert.EqualError(t, err, "http 401: unauthorized")
+}
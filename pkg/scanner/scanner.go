@@ -0,0 +1,82 @@
+// Package scanner 定义上传文件的内容检查：病毒扫描、图片审核等
+// 文件在对外可见之前先交给 Scanner 检查，根据结果决定放行、拒绝还是转人工审核
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Verdict 扫描结论，多个结论合并时取最严重的一个
+type Verdict uint8
+
+const (
+	// VerdictClean 没有发现问题，可以放行
+	VerdictClean Verdict = iota
+	// VerdictReview 疑似违规或者无法自动判断，需要人工审核
+	VerdictReview
+	// VerdictReject 确认违规（例如病毒），直接拒绝
+	VerdictReject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictClean:
+		return "clean"
+	case VerdictReview:
+		return "review"
+	case VerdictReject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
+// Result 扫描结果
+type Result struct {
+	Verdict Verdict
+	// Reason 给审核人员看的原因，例如病毒名、命中的审核标签
+	Reason string
+}
+
+// Object 待扫描的对象，Open 每次都返回一个新的 reader，多个 Scanner 可以各自从头读取
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	Open        func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Scanner 检查一个对象，返回 error 表示没有得出结论（例如扫描服务不可用），调用方应该稍后重试
+//
+//go:generate mockgen -source=./scanner.go -package=mocks -destination=./mocks/scanner_mock.go Scanner
+type Scanner interface {
+	Scan(ctx context.Context, obj Object) (Result, error)
+}
+
+// Func 把函数转换成 Scanner
+type Func func(ctx context.Context, obj Object) (Result, error)
+
+func (f Func) Scan(ctx context.Context, obj Object) (Result, error) {
+	return f(ctx, obj)
+}
+
+// Chain 依次执行多个 Scanner，返回最严重的结论，遇到 VerdictReject 或者错误时提前返回
+func Chain(scanners ...Scanner) Scanner {
+	return Func(func(ctx context.Context, obj Object) (Result, error) {
+		var res Result
+		for _, s := range scanners {
+			r, err := s.Scan(ctx, obj)
+			if err != nil {
+				return Result{}, err
+			}
+			if r.Verdict > res.Verdict {
+				res = r
+			}
+			if res.Verdict == VerdictReject {
+				break
+			}
+		}
+		return res, nil
+	})
+}
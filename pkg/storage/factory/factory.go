@@ -0,0 +1,159 @@
+// Package factory 根据配置创建 storage.Provider，配置一般来自 viper
+//
+//	type: mirror
+//	mirror:
+//	  backends:
+//	    - type: s3
+//	      s3: {endpoint: ..., bucket: ...}
+//	    - type: local
+//	      local: {root_path: ./uploads, base_url: http://localhost:8080/uploads}
+package factory
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/local"
+	"bedrock/pkg/storage/mirror"
+	"bedrock/pkg/storage/oss"
+	"bedrock/pkg/storage/s3"
+	"bedrock/pkg/storage/tiered"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	TypeLocal  = "local"
+	TypeS3     = "s3"
+	TypeOSS    = "oss"
+	TypeMirror = "mirror"
+	TypeTiered = "tiered"
+)
+
+// Config 存储的配置，Type 决定使用哪一段配置
+type Config struct {
+	Type   string       `mapstructure:"type"`
+	Local  LocalConfig  `mapstructure:"local"`
+	S3     S3Config     `mapstructure:"s3"`
+	OSS    OSSConfig    `mapstructure:"oss"`
+	Mirror MirrorConfig `mapstructure:"mirror"`
+	Tiered TieredConfig `mapstructure:"tiered"`
+}
+
+type LocalConfig struct {
+	RootPath string `mapstructure:"root_path"`
+	BaseURL  string `mapstructure:"base_url"`
+	// Secret 直传链接和私有下载链接的签名密钥，为空时不支持直传和 GetPrivateURL
+	Secret string `mapstructure:"secret"`
+	// Private 没有设置 ACL 的对象也要签名才能下载
+	Private bool `mapstructure:"private"`
+	// PrivatePrefixes 这些前缀下没有设置 ACL 的对象要签名才能下载
+	PrivatePrefixes []string `mapstructure:"private_prefixes"`
+}
+
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UseSSL          bool   `mapstructure:"use_ssl"`
+	Bucket          string `mapstructure:"bucket"`
+	Region          string `mapstructure:"region"`
+	Domain          string `mapstructure:"domain"`
+}
+
+type OSSConfig struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	AccessKeySecret string `mapstructure:"access_key_secret"`
+	Bucket          string `mapstructure:"bucket"`
+	Domain          string `mapstructure:"domain"`
+}
+
+type MirrorConfig struct {
+	// Backends 第一个是主存储，其余的是副本
+	Backends []Config `mapstructure:"backends"`
+	// Cooldown 读取失败之后多久不再优先读取这个存储
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+type TieredConfig struct {
+	Hot  *Config `mapstructure:"hot"`
+	Cold *Config `mapstructure:"cold"`
+	// PromoteMaxSize 从冷存储读取的对象不超过这个大小时挪回热存储
+	PromoteMaxSize int64 `mapstructure:"promote_max_size"`
+}
+
+// New 根据配置创建 Provider，mirror 和 tiered 可以嵌套
+func New(c Config) (storage.Provider, error) {
+	switch c.Type {
+	case TypeLocal:
+		if c.Local.RootPath == "" {
+			return nil, errors.New("storage: local.root_path is required")
+		}
+		p := local.NewProvider(local.Config{
+			RootPath: c.Local.RootPath,
+			BaseURL:  c.Local.BaseURL,
+			Secret:   c.Local.Secret,
+			Private:  c.Local.Private,
+
+			PrivatePrefixes: c.Local.PrivatePrefixes,
+		})
+		if p == nil {
+			return nil, fmt.Errorf("storage: cannot create local root %q", c.Local.RootPath)
+		}
+		return p, nil
+	case TypeS3:
+		p := s3.NewProvider(s3.Config{
+			Endpoint:        c.S3.Endpoint,
+			AccessKeyID:     c.S3.AccessKeyID,
+			SecretAccessKey: c.S3.SecretAccessKey,
+			UseSSL:          c.S3.UseSSL,
+			BucketName:      c.S3.Bucket,
+			Region:          c.S3.Region,
+			Domain:          c.S3.Domain,
+		})
+		if p == nil {
+			return nil, fmt.Errorf("storage: invalid s3 endpoint %q", c.S3.Endpoint)
+		}
+		return p, nil
+	case TypeOSS:
+		p, err := oss.NewProvider(oss.Config{
+			Endpoint:        c.OSS.Endpoint,
+			AccessKeyID:     c.OSS.AccessKeyID,
+			AccessKeySecret: c.OSS.AccessKeySecret,
+			BucketName:      c.OSS.Bucket,
+			Domain:          c.OSS.Domain,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case TypeMirror:
+		if len(c.Mirror.Backends) == 0 {
+			return nil, errors.New("storage: mirror.backends is empty")
+		}
+		backends := make([]storage.Provider, 0, len(c.Mirror.Backends))
+		for i, bc := range c.Mirror.Backends {
+			b, err := New(bc)
+			if err != nil {
+				return nil, fmt.Errorf("mirror.backends[%d]: %w", i, err)
+			}
+			backends = append(backends, b)
+		}
+		return mirror.NewProvider(mirror.Config{Cooldown: c.Mirror.Cooldown}, backends[0], backends[1:]...), nil
+	case TypeTiered:
+		if c.Tiered.Hot == nil || c.Tiered.Cold == nil {
+			return nil, errors.New("storage: tiered.hot and tiered.cold are required")
+		}
+		hot, err := New(*c.Tiered.Hot)
+		if err != nil {
+			return nil, fmt.Errorf("tiered.hot: %w", err)
+		}
+		cold, err := New(*c.Tiered.Cold)
+		if err != nil {
+			return nil, fmt.Errorf("tiered.cold: %w", err)
+		}
+		return tiered.NewProvider(tiered.Config{PromoteMaxSize: c.Tiered.PromoteMaxSize}, hot, cold), nil
+	default:
+		return nil, fmt.Errorf("storage: unknown type %q", c.Type)
+	}
+}
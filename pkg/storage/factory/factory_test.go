@@ -0,0 +1,99 @@
+package factory
+
+import (
+	"bedrock/pkg/storage/local"
+	"bedrock/pkg/storage/mirror"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	dir := filepath.ToSlash(t.TempDir())
+	testCases := []struct {
+		name    string
+		yaml    string
+		check   func(t *testing.T, c Config)
+		wantErr string
+	}{
+		{
+			name: "local",
+			yaml: `
+type: local
+local:
+  root_path: ` + dir + `/a
+  base_url: http://localhost:8080/uploads
+`,
+			check: func(t *testing.T, c Config) {
+				p, err := New(c)
+				require.NoError(t, err)
+				assert.IsType(t, &local.Provider{}, p)
+				assert.Equal(t, "http://localhost:8080/uploads/x.txt", p.URL("x.txt"))
+			},
+		},
+		{
+			name: "mirror 嵌套 tiered",
+			yaml: `
+type: mirror
+mirror:
+  cooldown: 1m
+  backends:
+    - type: tiered
+      tiered:
+        promote_max_size: 1024
+        hot: {type: local, local: {root_path: ` + dir + `/hot, base_url: "http://localhost:8080/uploads"}}
+        cold: {type: local, local: {root_path: ` + dir + `/cold}}
+    - type: local
+      local: {root_path: ` + dir + `/backup}
+`,
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, int64(1024), c.Mirror.Backends[0].Tiered.PromoteMaxSize)
+				p, err := New(c)
+				require.NoError(t, err)
+				assert.IsType(t, &mirror.Provider{}, p)
+				assert.Equal(t, "http://localhost:8080/uploads/x.txt", p.URL("x.txt"))
+			},
+		},
+		{
+			name: "tiered 缺少冷存储",
+			yaml: `
+type: tiered
+tiered:
+  hot: {type: local, local: {root_path: ` + dir + `/hot}}
+`,
+			wantErr: "storage: tiered.hot and tiered.cold are required",
+		},
+		{
+			name: "嵌套的配置错误",
+			yaml: `
+type: mirror
+mirror:
+  backends:
+    - type: ftp
+`,
+			wantErr: `mirror.backends[0]: storage: unknown type "ftp"`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			v := viper.New()
+			v.SetConfigType("yaml")
+			require.NoError(t, v.ReadConfig(strings.NewReader(tc.yaml)))
+			var c Config
+			require.NoError(t, v.Unmarshal(&c))
+			if tc.wantErr != "" {
+				_, err := New(c)
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			tc.check(t, c)
+		})
+	}
+}
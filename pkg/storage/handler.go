@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NewHTTPHandler 通过 Provider 对外提供文件下载
+// 响应会带上上传时设置的 ContentType、ContentDisposition、CacheControl，
+// 并且支持 ETag / Last-Modified 协商缓存；如果 Provider 返回的 reader 支持 Seek（例如本地文件），还支持 Range 请求
+//
+// 如果 Provider 实现了 DownloadVerifier，下载之前会先检查权限，私有对象必须使用 GetPrivateURL 签发的链接
+// 如果 Provider 实现了 UploadVerifier，还会接收 PresignUpload 签发的 PUT 直传请求
+//
+// 请求路径去掉开头的 "/" 就是 key，挂载在子路径下时配合 http.StripPrefix 使用
+func NewHTTPHandler(p Provider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if v, ok := p.(UploadVerifier); ok && r.Method == http.MethodPut {
+			serveUpload(w, r, p, v, key)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if v, ok := p.(DownloadVerifier); ok {
+			if err := v.VerifyDownload(key, r.URL.Query()); err != nil {
+				if errors.Is(err, ErrInvalidKey) {
+					http.NotFound(w, r)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+		body, info, err := p.Get(r.Context(), key)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrInvalidKey):
+			http.NotFound(w, r)
+			return
+		default:
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+
+		h := w.Header()
+		h.Set("Content-Type", info.ContentType)
+		if info.ContentDisposition != "" {
+			h.Set("Content-Disposition", info.ContentDisposition)
+		}
+		if info.CacheControl != "" {
+			h.Set("Cache-Control", info.CacheControl)
+		}
+		if info.ETag != "" {
+			h.Set("ETag", info.ETag)
+		}
+		// 用户上传的内容不允许浏览器再猜测类型，避免把图片当成 HTML 执行
+		h.Set("X-Content-Type-Options", "nosniff")
+
+		if rs, ok := body.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, "", info.LastModified, rs)
+			return
+		}
+		if match := r.Header.Get("If-None-Match"); match != "" && match == info.ETag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if !info.LastModified.IsZero() {
+			h.Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+		}
+		h.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = io.Copy(w, body)
+		}
+	})
+}
+
+// serveUpload 校验签名和限制条件之后写入存储
+func serveUpload(w http.ResponseWriter, r *http.Request, p Provider, v UploadVerifier, key string) {
+	policy, err := v.VerifyUpload(key, r.URL.Query())
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if policy.ContentType != "" && r.Header.Get("Content-Type") != policy.ContentType {
+		http.Error(w, "content type mismatch", http.StatusForbidden)
+		return
+	}
+	body := io.Reader(r.Body)
+	if policy.MaxSize > 0 {
+		if r.ContentLength > policy.MaxSize {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		// 分块传输时没有 Content-Length，只能边读边限制
+		body = http.MaxBytesReader(w, r.Body, policy.MaxSize)
+	}
+	_, err = p.Upload(r.Context(), key, body, r.ContentLength, UploadOptions{ContentType: policy.ContentType})
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusOK)
+	case errors.As(err, &tooLarge):
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrInvalidKey):
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,180 @@
+package local
+
+import (
+	"bedrock/pkg/storage"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// multipartDir 分片上传的临时目录，以 "." 开头，不会被 List 列出来，也不能通过 key 访问
+// 每个上传一个子目录：.multipart/<uploadID>/upload.json 记录 key 和元数据，
+// 分片保存为 <编号>-<md5> 文件，ETag 就在文件名里，完成的时候不用重新计算
+const multipartDir = ".multipart"
+
+type multipartMeta struct {
+	Key     string  `json:"key"`
+	Sidecar sidecar `json:"sidecar"`
+}
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	if _, err := p.path(key); err != nil {
+		return "", err
+	}
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(buf[:])
+	dir := p.uploadDir(uploadID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("local mkdir failed: %w", err)
+	}
+	data, err := json.Marshal(multipartMeta{Key: key, Sidecar: newSidecar(storage.ResolveMultipartOptions(key, opts))})
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "upload.json"), data, 0644); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("local write upload failed: %w", err)
+	}
+	return uploadID, nil
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	if err := storage.ValidatePartNumber(number); err != nil {
+		return storage.Part{}, err
+	}
+	dir, _, err := p.openUpload(key, uploadID)
+	if err != nil {
+		return storage.Part{}, err
+	}
+	tmp, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return storage.Part{}, fmt.Errorf("local create part failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), reader)
+	if err != nil {
+		tmp.Close()
+		return storage.Part{}, fmt.Errorf("local write part failed: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return storage.Part{}, fmt.Errorf("local write part failed: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	// 同一个编号重复上传时，删掉之前的分片
+	old, _ := filepath.Glob(filepath.Join(dir, strconv.Itoa(number)+"-*"))
+	for _, f := range old {
+		_ = os.Remove(f)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(dir, partName(number, sum))); err != nil {
+		return storage.Part{}, fmt.Errorf("local rename part failed: %w", err)
+	}
+	return storage.Part{Number: number, ETag: `"` + sum + `"`, Size: n}, nil
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	if err := storage.ValidateParts(parts); err != nil {
+		return "", err
+	}
+	dir, meta, err := p.openUpload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	var size int64
+	for _, part := range parts {
+		f, err := os.Open(filepath.Join(dir, partName(part.Number, strings.Trim(part.ETag, `"`))))
+		if err != nil {
+			closeAll(readers)
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("%w: part %d", storage.ErrInvalidPart, part.Number)
+			}
+			return "", fmt.Errorf("local open part failed: %w", err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			closeAll(readers)
+			return "", fmt.Errorf("local stat part failed: %w", err)
+		}
+		size += fi.Size()
+		readers = append(readers, f)
+	}
+	// 复用 Upload 的临时文件 + 重命名，合并过程中不会读到一半的文件
+	res, err := p.Upload(ctx, key, io.MultiReader(readers...), size, meta.Sidecar.options())
+	closeAll(readers)
+	if err != nil {
+		return "", err
+	}
+	_ = os.RemoveAll(dir)
+	return res, nil
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	dir, _, err := p.openUpload(key, uploadID)
+	if errors.Is(err, storage.ErrUploadNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("local abort upload failed: %w", err)
+	}
+	return nil
+}
+
+// openUpload 校验 uploadID 并读出上传的信息，key 必须和发起时一致
+func (p *Provider) openUpload(key, uploadID string) (string, multipartMeta, error) {
+	var meta multipartMeta
+	// uploadID 是我们生成的 hex 字符串，其他的都不合法，顺便防止目录遍历
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", meta, storage.ErrUploadNotFound
+	}
+	dir := p.uploadDir(uploadID)
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", meta, p.wrapUploadErr(err)
+	}
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return "", meta, fmt.Errorf("local read upload failed: %w", err)
+	}
+	if meta.Key != key {
+		return "", meta, storage.ErrUploadNotFound
+	}
+	return dir, meta, nil
+}
+
+func (p *Provider) uploadDir(uploadID string) string {
+	return filepath.Join(p.config.RootPath, multipartDir, uploadID)
+}
+
+func (p *Provider) wrapUploadErr(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrUploadNotFound
+	}
+	return fmt.Errorf("local read upload failed: %w", err)
+}
+
+func partName(number int, sum string) string {
+	return strconv.Itoa(number) + "-" + sum
+}
+
+func closeAll(readers []io.Reader) {
+	for _, r := range readers {
+		_ = r.(io.Closer).Close()
+	}
+}
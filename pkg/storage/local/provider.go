@@ -3,17 +3,34 @@ package local
 import (
 	"bedrock/pkg/storage"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Config 本地存储配置
 type Config struct {
 	RootPath string // 文件存储的物理根目录，例如: "./uploads"
 	BaseURL  string // 外网访问的基础URL，例如: "http://localhost:8080/static"
+	// Secret 直传链接和私有下载链接的签名密钥，为空时不支持 PresignUpload 和 GetPrivateURL
+	// 直传和下载的请求由 storage.NewHTTPHandler 接收，需要挂载在 BaseURL 对应的路径上
+	Secret string
+	// Private 为 true 时没有设置 ACL 的对象也要签名才能下载，相当于把 bucket 设置成私有读
+	// 不管它是什么值，上传时指定了 ACLPrivate 的对象都要签名，ACLPublicRead 的都不需要
+	Private bool
+	// PrivatePrefixes 这些前缀下没有设置 ACL 的对象要签名才能下载，相当于 bucket 策略里只对这些目录关闭公共读
+	PrivatePrefixes []string
 }
 
 type Provider struct {
@@ -38,63 +55,394 @@ func NewProvider(c Config) storage.Provider {
 }
 
 var _ storage.Provider = (*Provider)(nil)
+var _ storage.UploadVerifier = (*Provider)(nil)
+var _ storage.DownloadVerifier = (*Provider)(nil)
 
-func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64) (string, error) {
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
 	// 1. 安全检查：防止 key 包含 "../" 进行目录遍历攻击
-	if strings.Contains(key, "..") {
-		return "", fmt.Errorf("invalid key: path traversal attempt")
+	fullPath, err := p.path(key)
+	if err != nil {
+		return "", err
+	}
+	reader, opts, err = storage.ResolveOptions(key, reader, opts)
+	if err != nil {
+		return "", fmt.Errorf("local detect content type failed: %w", err)
 	}
 
-	// 2. 拼接完整的物理路径
-	fullPath := filepath.Join(p.config.RootPath, key)
-
-	// 3. 确保该文件所在的父目录存在 (例如 key="avatars/user1.jpg"，需确保 "avatars" 目录存在)
+	// 2. 确保该文件所在的父目录存在 (例如 key="avatars/user1.jpg"，需确保 "avatars" 目录存在)
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return "", fmt.Errorf("local mkdir failed: %w", err)
 	}
 
-	// 4. 创建文件
-	// 注意：os.Create 不支持 Context 取消。如果需要支持 Cancel，逻辑会很复杂（需自行处理 goroutine）
+	// 3. 先写到同目录下的临时文件，写完再重命名，避免并发的 Get 读到写了一半的文件
+	// 注意：os.CreateTemp 不支持 Context 取消。如果需要支持 Cancel，逻辑会很复杂（需自行处理 goroutine）
 	// 对于本地文件写入，通常速度很快，暂不实现复杂的 Context 控制
-	dst, err := os.Create(fullPath)
+	dst, err := os.CreateTemp(dir, ".upload-*")
 	if err != nil {
 		return "", fmt.Errorf("local create file failed: %w", err)
 	}
-	defer dst.Close()
+	defer os.Remove(dst.Name())
 
-	// 5. 写入内容
+	// 4. 写入内容
 	if _, err := io.Copy(dst, reader); err != nil {
+		dst.Close()
 		return "", fmt.Errorf("local write failed: %w", err)
 	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("local write failed: %w", err)
+	}
+	// CreateTemp 创建的文件权限是 0600，和 os.Create 保持一致
+	if err := os.Chmod(dst.Name(), 0644); err != nil {
+		return "", fmt.Errorf("local chmod failed: %w", err)
+	}
+	// 5. 元数据写到同目录下的隐藏文件里，读取和对外提供下载时使用
+	if err := p.writeSidecar(fullPath, newSidecar(opts)); err != nil {
+		return "", err
+	}
+	if err := os.Rename(dst.Name(), fullPath); err != nil {
+		return "", fmt.Errorf("local rename failed: %w", err)
+	}
 
 	// 6. 拼接返回 URL
-	// 使用 path.Join 而不是 filepath.Join，因为 URL 必须使用 "/" 分隔符
-	// 假设 BaseURL 没有尾部斜杠，key 也不带头部斜杠
-	urlPath := strings.TrimRight(p.config.BaseURL, "/") + "/" + strings.TrimLeft(key, "/")
-	return urlPath, nil
+	return p.URL(key), nil
+}
+
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	fullPath, err := p.path(key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, p.wrapErr("local open file failed", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, storage.ObjectInfo{}, p.wrapErr("local stat failed", err)
+	}
+	if fi.IsDir() {
+		f.Close()
+		return nil, storage.ObjectInfo{}, storage.ErrNotFound
+	}
+	return f, p.info(key, fullPath, fi), nil
+}
+
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	fullPath, err := p.path(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	fi, err := os.Stat(fullPath)
+	if err != nil {
+		return storage.ObjectInfo{}, p.wrapErr("local stat failed", err)
+	}
+	if fi.IsDir() {
+		return storage.ObjectInfo{}, storage.ErrNotFound
+	}
+	return p.info(key, fullPath, fi), nil
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := p.Stat(ctx, key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	if strings.Contains(opts.Prefix, "..") {
+		return storage.ListResult{}, storage.ErrInvalidKey
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	// 只需要遍历前缀所在的目录，例如 prefix="avatar/10" 只遍历 avatar 目录
+	base := p.config.RootPath
+	if i := strings.LastIndex(opts.Prefix, "/"); i >= 0 {
+		base = filepath.Join(base, filepath.FromSlash(opts.Prefix[:i]))
+	}
+	var objects []storage.ObjectInfo
+	err := filepath.WalkDir(base, func(fullPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 前缀对应的目录不存在，结果为空
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 跳过上传过程中的临时文件和元数据文件，以及分片上传的临时目录
+		if strings.HasPrefix(d.Name(), ".") && fullPath != base {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(p.config.RootPath, fullPath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, opts.Prefix) || key <= opts.Marker {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, p.info(key, fullPath, fi))
+		return nil
+	})
+	if err != nil {
+		return storage.ListResult{}, fmt.Errorf("local list failed: %w", err)
+	}
+	// WalkDir 按照目录逐层的字典序遍历，"a/b" 和 "a.txt" 这种情况和 key 的字典序不一致，需要重新排序
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	res := storage.ListResult{Objects: objects}
+	if len(objects) > limit {
+		res.Objects = objects[:limit]
+		res.Truncated = true
+		res.NextMarker = res.Objects[limit-1].Key
+	}
+	return res, nil
+}
+
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	r, info, err := p.Get(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	srcPath, _ := p.path(src)
+	uploadOpts := p.readSidecar(srcPath).options()
+	uploadOpts.ACL = opts.ACL
+	_, err = p.Upload(ctx, dst, r, info.Size, uploadOpts)
+	return err
 }
 
 func (p *Provider) Delete(ctx context.Context, key string) error {
 	// 安全检查
-	if strings.Contains(key, "..") {
-		return fmt.Errorf("invalid key")
+	fullPath, err := p.path(key)
+	if err != nil {
+		return err
 	}
 
-	fullPath := filepath.Join(p.config.RootPath, key)
-
-	err := os.Remove(fullPath)
+	err = os.Remove(fullPath)
 	// 如果文件本来就不存在，通常视为删除成功，不报错
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("local delete failed: %w", err)
 	}
+	err = os.Remove(sidecarPath(fullPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("local delete metadata failed: %w", err)
+	}
 	return nil
 }
 
+// GetPrivateURL 签发一个带 HMAC 签名的下载链接，过期之后 storage.NewHTTPHandler 会拒绝访问
+// expire <= 0 时使用 storage.DefaultPresignExpire
 func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64) (string, error) {
-	// 本地存储通常是静态文件服务，很难实现真正的“带签名的临时 URL”
-	// 简单实现：直接返回公开 URL，或者报错表示不支持
-	// 这里为了开发方便，直接返回公开链接
-	return p.Upload(ctx, key, nil, 0) // 复用 URL 拼接逻辑，但不做上传
-	// 或者: return "", fmt.Errorf("local storage does not support signed URLs")
+	if p.config.Secret == "" {
+		return "", storage.ErrPresignUnsupported
+	}
+	if _, err := p.path(key); err != nil {
+		return "", err
+	}
+	ttl := time.Duration(expire) * time.Second
+	if ttl <= 0 {
+		ttl = storage.DefaultPresignExpire
+	}
+	q := storage.SignDownload([]byte(p.config.Secret), key, time.Now().Add(ttl))
+	return p.URL(key) + "?" + q.Encode(), nil
+}
+
+// VerifyDownload 带了签名就校验签名；没有签名时只允许下载公开的对象
+func (p *Provider) VerifyDownload(key string, query url.Values) error {
+	fullPath, err := p.path(key)
+	if err != nil {
+		return err
+	}
+	if query.Has("signature") {
+		if p.config.Secret == "" {
+			return storage.ErrPresignUnsupported
+		}
+		return storage.VerifyDownloadSignature([]byte(p.config.Secret), key, query, time.Now())
+	}
+	switch p.readSidecar(fullPath).ACL {
+	case storage.ACLPublicRead:
+		return nil
+	case storage.ACLPrivate:
+		return storage.ErrInvalidSignature
+	}
+	if p.config.Private {
+		return storage.ErrInvalidSignature
+	}
+	for _, prefix := range p.config.PrivatePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return storage.ErrInvalidSignature
+		}
+	}
+	return nil
+}
+
+// PresignUpload 签发一个 PUT 到 BaseURL 的链接，由 storage.NewHTTPHandler 校验签名之后写入本地
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	if p.config.Secret == "" {
+		return storage.PresignedUpload{}, storage.ErrPresignUnsupported
+	}
+	if _, err := p.path(key); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if expire <= 0 {
+		expire = storage.DefaultPresignExpire
+	}
+	expiresAt := time.Now().Add(expire)
+	q := storage.SignUpload([]byte(p.config.Secret), key, policy, expiresAt)
+	res := storage.PresignedUpload{
+		Method:    http.MethodPut,
+		URL:       p.URL(key) + "?" + q.Encode(),
+		ExpiresAt: expiresAt,
+	}
+	if policy.ContentType != "" {
+		res.Headers = map[string]string{"Content-Type": policy.ContentType}
+	}
+	return res, nil
+}
+
+func (p *Provider) VerifyUpload(key string, query url.Values) (storage.UploadPolicy, error) {
+	if p.config.Secret == "" {
+		return storage.UploadPolicy{}, storage.ErrPresignUnsupported
+	}
+	return storage.VerifyUploadSignature([]byte(p.config.Secret), key, query, time.Now())
+}
+
+// path 校验 key 并返回对应的物理路径
+// 以 "." 开头的文件名留给临时文件和元数据文件使用，不允许作为 key
+func (p *Provider) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("%w: %q", storage.ErrInvalidKey, key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return "", fmt.Errorf("%w: %q", storage.ErrInvalidKey, key)
+		}
+	}
+	return filepath.Join(p.config.RootPath, filepath.FromSlash(key)), nil
+}
+
+// URL 使用字符串拼接而不是 filepath.Join，因为 URL 必须使用 "/" 分隔符
+func (p *Provider) URL(key string) string {
+	return strings.TrimRight(p.config.BaseURL, "/") + "/" + strings.TrimLeft(key, "/")
+}
+
+func (p *Provider) info(key, fullPath string, fi fs.FileInfo) storage.ObjectInfo {
+	sc := p.readSidecar(fullPath)
+	contentType := sc.ContentType
+	if contentType == "" {
+		// 没有元数据文件的历史文件，只能根据扩展名推断
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return storage.ObjectInfo{
+		Key:                key,
+		Size:               fi.Size(),
+		ContentType:        contentType,
+		ContentDisposition: sc.ContentDisposition,
+		CacheControl:       sc.CacheControl,
+		// 本地文件没有现成的 ETag，参考 nginx 使用修改时间和大小生成
+		ETag:         fmt.Sprintf(`"%x-%x"`, fi.ModTime().Unix(), fi.Size()),
+		LastModified: fi.ModTime(),
+		Metadata:     sc.Metadata,
+	}
+}
+
+// sidecar 元数据文件的内容，和数据文件放在同一个目录下，文件名为 ".<文件名>.meta"
+type sidecar struct {
+	ContentType        string            `json:"contentType,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	ACL                storage.ACL       `json:"acl,omitempty"`
+}
+
+func newSidecar(opts storage.UploadOptions) sidecar {
+	return sidecar{
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+		CacheControl:       opts.CacheControl,
+		Metadata:           opts.Metadata,
+		ACL:                opts.ACL,
+	}
+}
+
+func (s sidecar) options() storage.UploadOptions {
+	return storage.UploadOptions{
+		ContentType:        s.ContentType,
+		ContentDisposition: s.ContentDisposition,
+		CacheControl:       s.CacheControl,
+		Metadata:           s.Metadata,
+		ACL:                s.ACL,
+	}
+}
+
+func sidecarPath(fullPath string) string {
+	return filepath.Join(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".meta")
+}
+
+// readSidecar 元数据文件不存在或者损坏时返回零值，由调用方使用默认值
+func (p *Provider) readSidecar(fullPath string) sidecar {
+	var sc sidecar
+	data, err := os.ReadFile(sidecarPath(fullPath))
+	if err != nil {
+		return sc
+	}
+	_ = json.Unmarshal(data, &sc)
+	return sc
+}
+
+func (p *Provider) writeSidecar(fullPath string, sc sidecar) error {
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("local create metadata failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("local write metadata failed: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("local write metadata failed: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), sidecarPath(fullPath)); err != nil {
+		return fmt.Errorf("local rename metadata failed: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) wrapErr(msg string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrNotFound
+	}
+	return fmt.Errorf("%s: %w", msg, err)
 }
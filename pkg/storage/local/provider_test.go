@@ -0,0 +1,185 @@
+package local
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/storagetest"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Conformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) storage.Provider {
+		return NewProvider(Config{
+			RootPath: t.TempDir(),
+			BaseURL:  "http://localhost:8080/uploads",
+		})
+	})
+}
+
+func TestProvider_PathTraversal(t *testing.T) {
+	t.Parallel()
+	p := NewProvider(Config{RootPath: t.TempDir()})
+	ctx := context.Background()
+	_, err := p.Upload(ctx, "../escape.txt", strings.NewReader("x"), 1, storage.UploadOptions{})
+	assert.True(t, errors.Is(err, storage.ErrInvalidKey))
+	_, _, err = p.Get(ctx, "a/../../etc/passwd")
+	assert.True(t, errors.Is(err, storage.ErrInvalidKey))
+	_, err = p.List(ctx, storage.ListOptions{Prefix: "../"})
+	assert.True(t, errors.Is(err, storage.ErrInvalidKey))
+}
+
+func TestProvider_GetPrivateURL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	p := NewProvider(Config{RootPath: t.TempDir(), BaseURL: "/uploads/", Secret: "secret", Private: true})
+	server := httptest.NewServer(http.StripPrefix("/uploads", storage.NewHTTPHandler(p)))
+	defer server.Close()
+	for key, acl := range map[string]storage.ACL{
+		"files/default.txt": storage.ACLDefault,
+		"files/private.txt": storage.ACLPrivate,
+		"files/public.txt":  storage.ACLPublicRead,
+	} {
+		_, err := p.Upload(ctx, key, strings.NewReader("hello"), 5, storage.UploadOptions{ACL: acl})
+		require.NoError(t, err)
+	}
+	do := func(method, u string) int {
+		req, err := http.NewRequest(method, server.URL+u, strings.NewReader("evil"))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	signed, err := p.GetPrivateURL(ctx, "files/private.txt", 60)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/uploads/files/private\.txt\?expires=\d+&signature=[0-9a-f]{64}$`, signed)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, signed))
+	assert.Equal(t, http.StatusOK, do(http.MethodHead, signed))
+
+	// 没有签名时只有公开的对象可以下载，Private 为 true 时没有设置 ACL 的也不行
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/uploads/files/private.txt"))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/uploads/files/default.txt"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/uploads/files/public.txt"))
+
+	// 篡改 key
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, strings.Replace(signed, "private.txt", "default.txt", 1)))
+	// 过期
+	expired := storage.SignDownload([]byte("secret"), "files/private.txt", time.Now().Add(-time.Second))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/uploads/files/private.txt?"+expired.Encode()))
+	// 下载链接不能用来上传
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, signed))
+	assert.Equal(t, []byte("hello"), readAll(t, p, "files/private.txt"))
+
+	// 只有 PrivatePrefixes 下没有设置 ACL 的对象需要签名
+	p = NewProvider(Config{RootPath: t.TempDir(), Secret: "secret", PrivatePrefixes: []string{"files/private/"}})
+	assert.NoError(t, p.(storage.DownloadVerifier).VerifyDownload("files/public/a.txt", nil))
+	assert.ErrorIs(t, p.(storage.DownloadVerifier).VerifyDownload("files/private/a.txt", nil), storage.ErrInvalidSignature)
+
+	// 没有配置密钥时不支持签名链接，也不会退化成公开链接
+	_, err = NewProvider(Config{RootPath: t.TempDir()}).GetPrivateURL(ctx, "a.txt", 60)
+	assert.Equal(t, storage.ErrPresignUnsupported, err)
+}
+
+func readAll(t *testing.T, p storage.Provider, key string) []byte {
+	r, _, err := p.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func TestProvider_ServeMetadata(t *testing.T) {
+	t.Parallel()
+	p := NewProvider(Config{RootPath: t.TempDir()})
+	_, err := p.Upload(context.Background(), "files/report", strings.NewReader("%PDF-1.7"), 8, storage.UploadOptions{
+		ContentDisposition: `attachment; filename="report.pdf"`,
+		CacheControl:       "public, max-age=3600",
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.StripPrefix("/uploads", storage.NewHTTPHandler(p)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/uploads/files/report")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "%PDF-1.7", string(body))
+	// 元数据文件里记录的是嗅探出来的类型
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="report.pdf"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "public, max-age=3600", resp.Header.Get("Cache-Control"))
+
+	// 元数据文件不能被当作对象访问
+	resp, err = http.Get(server.URL + "/uploads/files/.report.meta")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestProvider_PresignUpload(t *testing.T) {
+	t.Parallel()
+	p := NewProvider(Config{RootPath: t.TempDir(), BaseURL: "/uploads", Secret: "secret"})
+	server := httptest.NewServer(http.StripPrefix("/uploads", storage.NewHTTPHandler(p)))
+	defer server.Close()
+	ctx := context.Background()
+
+	put := func(ticket storage.PresignedUpload, contentType, body string) int {
+		req, err := http.NewRequest(ticket.Method, server.URL+ticket.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	ticket, err := p.PresignUpload(ctx, "avatars/1/a.png", storage.UploadPolicy{ContentType: "image/png", MaxSize: 8}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, ticket.Method)
+	assert.Equal(t, map[string]string{"Content-Type": "image/png"}, ticket.Headers)
+
+	// ContentType 和签名时不一致
+	assert.Equal(t, http.StatusForbidden, put(ticket, "text/html", "12345678"))
+	// 超过大小限制
+	assert.Equal(t, http.StatusRequestEntityTooLarge, put(ticket, "image/png", "123456789"))
+	ok, err := p.Exists(ctx, "avatars/1/a.png")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, put(ticket, "image/png", "12345678"))
+	info, err := p.Stat(ctx, "avatars/1/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	// 篡改 key
+	tampered := ticket
+	tampered.URL = strings.Replace(ticket.URL, "avatars/1/", "avatars/2/", 1)
+	assert.Equal(t, http.StatusForbidden, put(tampered, "image/png", "12345678"))
+
+	// 过期
+	u, err := url.Parse(ticket.URL)
+	require.NoError(t, err)
+	_, err = storage.VerifyUploadSignature([]byte("secret"), "avatars/1/a.png", u.Query(), time.Now().Add(time.Hour))
+	assert.Equal(t, storage.ErrInvalidSignature, err)
+
+	// 没有配置密钥时不支持直传
+	_, err = NewProvider(Config{RootPath: t.TempDir()}).PresignUpload(ctx, "a.png", storage.UploadPolicy{}, time.Minute)
+	assert.Equal(t, storage.ErrPresignUnsupported, err)
+}
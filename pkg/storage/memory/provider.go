@@ -0,0 +1,304 @@
+// Package memory 提供一个基于内存的 storage.Provider，用于单元测试和本地开发
+package memory
+
+import (
+	"bedrock/pkg/storage"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type object struct {
+	data []byte
+	info storage.ObjectInfo
+	opts storage.UploadOptions
+}
+
+// multipart 进行中的分片上传
+type multipart struct {
+	key   string
+	opts  storage.UploadOptions
+	parts map[int][]byte
+}
+
+type Provider struct {
+	mu      sync.RWMutex
+	baseURL string
+	objects map[string]object
+	uploads map[string]*multipart
+	// secret 直传链接的签名密钥，内存实现只用于测试，固定即可
+	secret []byte
+}
+
+// NewProvider baseURL 用来拼接 Upload 和 GetPrivateURL 返回的链接
+func NewProvider(baseURL string) storage.Provider {
+	return &Provider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		objects: make(map[string]object),
+		uploads: make(map[string]*multipart),
+		secret:  []byte("memory"),
+	}
+}
+
+var _ storage.Provider = (*Provider)(nil)
+var _ storage.UploadVerifier = (*Provider)(nil)
+
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	if err := validate(key); err != nil {
+		return "", err
+	}
+	reader, opts, err := storage.ResolveOptions(key, reader, opts)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("memory read failed: %w", err)
+	}
+	p.put(key, data, opts)
+	return p.URL(key), nil
+}
+
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	o, err := p.get(key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), o.info, nil
+}
+
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	o, err := p.get(key)
+	return o.info, err
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	if err := validate(key); err != nil {
+		return false, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.objects[key]
+	return ok, nil
+}
+
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	p.mu.RLock()
+	keys := make([]string, 0, len(p.objects))
+	for key := range p.objects {
+		if strings.HasPrefix(key, opts.Prefix) && key > opts.Marker {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	var res storage.ListResult
+	for _, key := range keys {
+		if len(res.Objects) == limit {
+			res.Truncated = true
+			res.NextMarker = res.Objects[limit-1].Key
+			break
+		}
+		res.Objects = append(res.Objects, p.objects[key].info)
+	}
+	p.mu.RUnlock()
+	return res, nil
+}
+
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	if err := validate(dst); err != nil {
+		return err
+	}
+	o, err := p.get(src)
+	if err != nil {
+		return err
+	}
+	o.opts.ACL = opts.ACL
+	p.put(dst, o.data, o.opts)
+	return nil
+}
+
+func (p *Provider) Delete(ctx context.Context, key string) error {
+	if err := validate(key); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.objects, key)
+	return nil
+}
+
+func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64) (string, error) {
+	if err := validate(key); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s?expires=%d", p.URL(key), time.Now().Unix()+expire), nil
+}
+
+// PresignUpload 和 local 一样签发 PUT 链接，配合 storage.NewHTTPHandler 使用
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	if err := validate(key); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if expire <= 0 {
+		expire = storage.DefaultPresignExpire
+	}
+	expiresAt := time.Now().Add(expire)
+	q := storage.SignUpload(p.secret, key, policy, expiresAt)
+	res := storage.PresignedUpload{
+		Method:    http.MethodPut,
+		URL:       p.URL(key) + "?" + q.Encode(),
+		ExpiresAt: expiresAt,
+	}
+	if policy.ContentType != "" {
+		res.Headers = map[string]string{"Content-Type": policy.ContentType}
+	}
+	return res, nil
+}
+
+func (p *Provider) VerifyUpload(key string, query url.Values) (storage.UploadPolicy, error) {
+	return storage.VerifyUploadSignature(p.secret, key, query, time.Now())
+}
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	if err := validate(key); err != nil {
+		return "", err
+	}
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	uploadID := hex.EncodeToString(buf[:])
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.uploads[uploadID] = &multipart{
+		key:   key,
+		opts:  storage.ResolveMultipartOptions(key, opts),
+		parts: make(map[int][]byte),
+	}
+	return uploadID, nil
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	if err := storage.ValidatePartNumber(number); err != nil {
+		return storage.Part{}, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return storage.Part{}, fmt.Errorf("memory read failed: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, err := p.upload(key, uploadID)
+	if err != nil {
+		return storage.Part{}, err
+	}
+	m.parts[number] = data
+	return storage.Part{Number: number, ETag: etag(data), Size: int64(len(data))}, nil
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	if err := storage.ValidateParts(parts); err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	m, err := p.upload(key, uploadID)
+	if err != nil {
+		p.mu.Unlock()
+		return "", err
+	}
+	var data []byte
+	for _, part := range parts {
+		d, ok := m.parts[part.Number]
+		if !ok || etag(d) != part.ETag {
+			p.mu.Unlock()
+			return "", fmt.Errorf("%w: part %d", storage.ErrInvalidPart, part.Number)
+		}
+		data = append(data, d...)
+	}
+	delete(p.uploads, uploadID)
+	p.mu.Unlock()
+	p.put(key, data, m.opts)
+	return p.URL(key), nil
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.upload(key, uploadID)
+	if errors.Is(err, storage.ErrUploadNotFound) {
+		return nil
+	}
+	delete(p.uploads, uploadID)
+	return err
+}
+
+// upload 调用方需要持有锁
+func (p *Provider) upload(key, uploadID string) (*multipart, error) {
+	m, ok := p.uploads[uploadID]
+	if !ok || m.key != key {
+		return nil, storage.ErrUploadNotFound
+	}
+	return m, nil
+}
+
+func (p *Provider) get(key string) (object, error) {
+	if err := validate(key); err != nil {
+		return object{}, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	o, ok := p.objects[key]
+	if !ok {
+		return object{}, storage.ErrNotFound
+	}
+	return o, nil
+}
+
+func (p *Provider) put(key string, data []byte, opts storage.UploadOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.objects[key] = object{
+		data: data,
+		opts: opts,
+		info: storage.ObjectInfo{
+			Key:                key,
+			Size:               int64(len(data)),
+			ContentType:        opts.ContentType,
+			ContentDisposition: opts.ContentDisposition,
+			CacheControl:       opts.CacheControl,
+			ETag:               etag(data),
+			LastModified:       time.Now(),
+			Metadata:           opts.Metadata,
+		},
+	}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (p *Provider) URL(key string) string {
+	return p.baseURL + "/" + key
+}
+
+// validate 和 local 的规则保持一致，方便用它代替 local 做测试
+func validate(key string) error {
+	if key == "" || strings.Contains(key, "..") {
+		return fmt.Errorf("%w: %q", storage.ErrInvalidKey, key)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/storagetest"
+	"testing"
+)
+
+func TestProvider_Conformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) storage.Provider {
+		return NewProvider("http://localhost:8080/uploads")
+	})
+}
@@ -0,0 +1,267 @@
+// Package mirror 提供一个把数据同时写到多个存储的 storage.Provider
+// 典型的用法是迁移期间同时写本地磁盘和 S3，或者在 CDN 源站之外再保留一个备份 bucket
+package mirror
+
+import (
+	"bedrock/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Config 镜像存储的配置
+type Config struct {
+	// Cooldown 读取失败之后，这个存储在多长时间内不再优先读取，<= 0 时为 30 秒
+	Cooldown time.Duration
+}
+
+// Provider 把写操作同步到所有存储，读操作按照顺序从第一个健康的存储读取
+//
+// 第一个存储是主存储：URL、GetPrivateURL、PresignUpload 和分片上传都交给它处理，
+// 所以对外暴露的域名由主存储决定。其余的存储只是副本。
+//
+// 写入时先流式写入主存储，再从主存储读出来复制到各个副本，任何一个失败都返回错误。
+// 失败时不会回滚已经写入的存储，重试同一个 key 会覆盖，结果是一致的。
+// 直传（PresignUpload）和分片上传的文件只在完成之后才会复制到副本，直传的文件需要靠迁移工具补齐。
+//
+// 读取时某个存储里没有这个对象（例如迁移还没完成）会继续读下一个。
+type Provider struct {
+	backends []storage.Provider
+	cooldown time.Duration
+
+	mu sync.Mutex
+	// unhealthy 读取失败的存储以及它恢复的时间
+	unhealthy map[int]time.Time
+	now       func() time.Time
+}
+
+var _ storage.Provider = (*Provider)(nil)
+var _ storage.UploadVerifier = (*Provider)(nil)
+var _ storage.DownloadVerifier = (*Provider)(nil)
+
+// NewProvider primary 是主存储，replicas 是副本
+func NewProvider(c Config, primary storage.Provider, replicas ...storage.Provider) storage.Provider {
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	return &Provider{
+		backends:  append([]storage.Provider{primary}, replicas...),
+		cooldown:  c.Cooldown,
+		unhealthy: make(map[int]time.Time),
+		now:       time.Now,
+	}
+}
+
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	res, err := p.backends[0].Upload(ctx, key, reader, size, opts)
+	if err != nil {
+		return "", err
+	}
+	return res, p.replicate(ctx, key)
+}
+
+// replicate 把主存储里的对象复制到所有副本
+func (p *Provider) replicate(ctx context.Context, key string) error {
+	var errs []error
+	for i, b := range p.backends[1:] {
+		if _, err := storage.Transfer(ctx, p.backends[0], b, key, key); err != nil {
+			errs = append(errs, fmt.Errorf("mirror: replica %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	var (
+		r    io.ReadCloser
+		info storage.ObjectInfo
+	)
+	err := p.read(func(b storage.Provider) error {
+		var err error
+		r, info, err = b.Get(ctx, key)
+		return err
+	})
+	return r, info, err
+}
+
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	var info storage.ObjectInfo
+	err := p.read(func(b storage.Provider) error {
+		var err error
+		info, err = b.Stat(ctx, key)
+		return err
+	})
+	return info, err
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := p.Stat(ctx, key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// List 只列举第一个健康的存储，迁移期间副本里的对象可能不全
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	var res storage.ListResult
+	err := p.read(func(b storage.Provider) error {
+		var err error
+		res, err = b.List(ctx, opts)
+		return err
+	})
+	return res, err
+}
+
+// Copy 在每个存储里分别复制，副本里没有 src 时从主存储补齐
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	if err := p.backends[0].Copy(ctx, src, dst, opts); err != nil {
+		return err
+	}
+	var errs []error
+	for i, b := range p.backends[1:] {
+		err := b.Copy(ctx, src, dst, opts)
+		if errors.Is(err, storage.ErrNotFound) {
+			err = p.fill(ctx, b, dst, opts.ACL)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mirror: replica %d: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fill 从主存储把 key 补到副本里，Transfer 不带 ACL，复制时指定的权限要在这里补上
+func (p *Provider) fill(ctx context.Context, b storage.Provider, key string, acl storage.ACL) error {
+	r, info, err := p.backends[0].Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	opts := storage.OptionsOf(info)
+	opts.ACL = acl
+	_, err = b.Upload(ctx, key, r, info.Size, opts)
+	return err
+}
+
+// Delete 从所有存储里删除，部分失败时返回全部错误
+func (p *Provider) Delete(ctx context.Context, key string) error {
+	var errs []error
+	for _, b := range p.backends {
+		if err := b.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64) (string, error) {
+	return p.backends[0].GetPrivateURL(ctx, key, expire)
+}
+
+func (p *Provider) URL(key string) string {
+	return p.backends[0].URL(key)
+}
+
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	return p.backends[0].PresignUpload(ctx, key, policy, expire)
+}
+
+// VerifyUpload 主存储自己接收直传时（例如 local），由 storage.NewHTTPHandler 调用
+// 直传的请求最终调用 Upload，所以这种情况下直传的文件也会复制到副本
+func (p *Provider) VerifyUpload(key string, query url.Values) (storage.UploadPolicy, error) {
+	v, ok := p.backends[0].(storage.UploadVerifier)
+	if !ok {
+		return storage.UploadPolicy{}, storage.ErrPresignUnsupported
+	}
+	return v.VerifyUpload(key, query)
+}
+
+// VerifyDownload 主存储自己提供下载服务时（例如 local）检查签名，否则拒绝通过 storage.NewHTTPHandler 下载
+func (p *Provider) VerifyDownload(key string, query url.Values) error {
+	v, ok := p.backends[0].(storage.DownloadVerifier)
+	if !ok {
+		return storage.ErrPresignUnsupported
+	}
+	return v.VerifyDownload(key, query)
+}
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	return p.backends[0].InitiateMultipart(ctx, key, opts)
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	return p.backends[0].UploadPart(ctx, key, uploadID, number, reader, size)
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	res, err := p.backends[0].CompleteMultipart(ctx, key, uploadID, parts)
+	if err != nil {
+		return "", err
+	}
+	return res, p.replicate(ctx, key)
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return p.backends[0].AbortMultipart(ctx, key, uploadID)
+}
+
+// read 按照顺序尝试各个存储，健康的排在前面
+// ErrNotFound 和 ErrInvalidKey 不算故障，但是 ErrNotFound 会继续尝试下一个存储
+func (p *Provider) read(fn func(b storage.Provider) error) error {
+	var firstErr error
+	for _, i := range p.order() {
+		err := fn(p.backends[i])
+		switch {
+		case err == nil:
+			p.markHealthy(i)
+			return nil
+		case errors.Is(err, storage.ErrInvalidKey):
+			return err
+		case errors.Is(err, storage.ErrNotFound):
+		default:
+			p.markUnhealthy(i)
+		}
+		if firstErr == nil || errors.Is(firstErr, storage.ErrNotFound) {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// order 健康的存储按原来的顺序排在前面，冷却中的排在后面，全部都不健康时仍然会逐个尝试
+func (p *Provider) order() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	healthy := make([]int, 0, len(p.backends))
+	var cooling []int
+	for i := range p.backends {
+		if until, ok := p.unhealthy[i]; ok && now.Before(until) {
+			cooling = append(cooling, i)
+			continue
+		}
+		healthy = append(healthy, i)
+	}
+	return append(healthy, cooling...)
+}
+
+func (p *Provider) markHealthy(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.unhealthy, i)
+}
+
+func (p *Provider) markUnhealthy(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthy[i] = p.now().Add(p.cooldown)
+}
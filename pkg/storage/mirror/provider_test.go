@@ -0,0 +1,153 @@
+package mirror
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
+	"bedrock/pkg/storage/storagetest"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Conformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) storage.Provider {
+		return NewProvider(Config{}, memory.NewProvider("https://cdn.example.com"), memory.NewProvider("https://backup.example.com"))
+	})
+}
+
+// flakyProvider 在 down 为 true 时所有读操作都失败，模拟存储故障
+type flakyProvider struct {
+	storage.Provider
+	down  bool
+	reads int
+}
+
+var errDown = errors.New("connection refused")
+
+func (f *flakyProvider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	f.reads++
+	if f.down {
+		return nil, storage.ObjectInfo{}, errDown
+	}
+	return f.Provider.Get(ctx, key)
+}
+
+func TestProvider_Upload(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	primary := memory.NewProvider("https://cdn.example.com")
+	backup := memory.NewProvider("https://backup.example.com")
+	p := NewProvider(Config{}, primary, backup)
+
+	res, err := p.Upload(ctx, "docs/a.txt", strings.NewReader("hello"), 5, storage.UploadOptions{
+		CacheControl: "no-cache",
+	})
+	require.NoError(t, err)
+	// 对外的地址由主存储决定
+	assert.Equal(t, "https://cdn.example.com/docs/a.txt", res)
+	assert.Equal(t, "https://cdn.example.com/docs/a.txt", p.URL("docs/a.txt"))
+	for _, b := range []storage.Provider{primary, backup} {
+		info, err := b.Stat(ctx, "docs/a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "no-cache", info.CacheControl)
+		assert.Equal(t, "text/plain; charset=utf-8", info.ContentType)
+	}
+
+	require.NoError(t, p.Copy(ctx, "docs/a.txt", "docs/b.txt", storage.CopyOptions{}))
+	ok, err := backup.Exists(ctx, "docs/b.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, p.Delete(ctx, "docs/a.txt"))
+	for _, b := range []storage.Provider{primary, backup} {
+		ok, err = b.Exists(ctx, "docs/a.txt")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
+
+func TestProvider_Copy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	primary := memory.NewProvider("https://cdn.example.com")
+	backup := memory.NewProvider("https://backup.example.com")
+	p := NewProvider(Config{}, primary, backup)
+	// 加入镜像之前就有的对象，副本里没有
+	_, err := primary.Upload(ctx, "old.txt", strings.NewReader("old"), 3, storage.UploadOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, p.Copy(ctx, "old.txt", "new.txt", storage.CopyOptions{}))
+	ok, err := backup.Exists(ctx, "new.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestProvider_Failover(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	primary := &flakyProvider{Provider: memory.NewProvider("https://cdn.example.com")}
+	backup := &flakyProvider{Provider: memory.NewProvider("https://backup.example.com")}
+	p := NewProvider(Config{Cooldown: time.Minute}, primary, backup).(*Provider)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	_, err := p.Upload(ctx, "a.txt", strings.NewReader("hello"), 5, storage.UploadOptions{})
+	require.NoError(t, err)
+	primary.reads, backup.reads = 0, 0
+
+	// 主存储故障，从副本读取
+	primary.down = true
+	assert.Equal(t, "hello", read(t, p, "a.txt"))
+	assert.Equal(t, 1, primary.reads)
+	// 冷却期间不再先读主存储
+	assert.Equal(t, "hello", read(t, p, "a.txt"))
+	assert.Equal(t, 1, primary.reads)
+	assert.Equal(t, 2, backup.reads)
+
+	// 全部故障时返回真正的错误，而不是 ErrNotFound
+	backup.down = true
+	_, _, err = p.Get(ctx, "a.txt")
+	assert.ErrorIs(t, err, errDown)
+
+	// 冷却结束之后重新优先读主存储
+	primary.down, backup.down = false, false
+	now = now.Add(2 * time.Minute)
+	primary.reads, backup.reads = 0, 0
+	assert.Equal(t, "hello", read(t, p, "a.txt"))
+	assert.Equal(t, 1, primary.reads)
+	assert.Equal(t, 0, backup.reads)
+}
+
+func TestProvider_ReadThrough(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	primary := memory.NewProvider("https://cdn.example.com")
+	backup := memory.NewProvider("https://backup.example.com")
+	// 迁移期间新存储做主存储，旧存储里的对象还没有复制过去
+	_, err := backup.Upload(ctx, "legacy.txt", strings.NewReader("legacy"), 6, storage.UploadOptions{})
+	require.NoError(t, err)
+	p := NewProvider(Config{}, primary, backup)
+
+	assert.Equal(t, "legacy", read(t, p, "legacy.txt"))
+	ok, err := p.Exists(ctx, "legacy.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = p.Stat(ctx, "missing.txt")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func read(t *testing.T, p storage.Provider, key string) string {
+	r, _, err := p.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -3,16 +3,18 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./type.go -package=mocks -destination=./mocks/provider_mock.go Provider
+//	mockgen -source=./type.go -package=mocks -destination=./mocks/provider_mock.go
 //
 
 // Package mocks is a generated GoMock package.
 package mocks
 
 import (
+	storage "bedrock/pkg/storage"
 	context "context"
 	io "io"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,6 +43,49 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 	return m.recorder
 }
 
+// AbortMultipart mocks base method.
+func (m *MockProvider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortMultipart", ctx, key, uploadID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortMultipart indicates an expected call of AbortMultipart.
+func (mr *MockProviderMockRecorder) AbortMultipart(ctx, key, uploadID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortMultipart", reflect.TypeOf((*MockProvider)(nil).AbortMultipart), ctx, key, uploadID)
+}
+
+// CompleteMultipart mocks base method.
+func (m *MockProvider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteMultipart", ctx, key, uploadID, parts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteMultipart indicates an expected call of CompleteMultipart.
+func (mr *MockProviderMockRecorder) CompleteMultipart(ctx, key, uploadID, parts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteMultipart", reflect.TypeOf((*MockProvider)(nil).CompleteMultipart), ctx, key, uploadID, parts)
+}
+
+// Copy mocks base method.
+func (m *MockProvider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Copy", ctx, src, dst, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Copy indicates an expected call of Copy.
+func (mr *MockProviderMockRecorder) Copy(ctx, src, dst, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Copy", reflect.TypeOf((*MockProvider)(nil).Copy), ctx, src, dst, opts)
+}
+
 // Delete mocks base method.
 func (m *MockProvider) Delete(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
@@ -55,6 +100,37 @@ func (mr *MockProviderMockRecorder) Delete(ctx, key any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockProvider)(nil).Delete), ctx, key)
 }
 
+// Exists mocks base method.
+func (m *MockProvider) Exists(ctx context.Context, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MockProviderMockRecorder) Exists(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockProvider)(nil).Exists), ctx, key)
+}
+
+// Get mocks base method.
+func (m *MockProvider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(storage.ObjectInfo)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockProviderMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockProvider)(nil).Get), ctx, key)
+}
+
 // GetPrivateURL mocks base method.
 func (m *MockProvider) GetPrivateURL(ctx context.Context, key string, expire int64) (string, error) {
 	m.ctrl.T.Helper()
@@ -70,17 +146,106 @@ func (mr *MockProviderMockRecorder) GetPrivateURL(ctx, key, expire any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateURL", reflect.TypeOf((*MockProvider)(nil).GetPrivateURL), ctx, key, expire)
 }
 
+// InitiateMultipart mocks base method.
+func (m *MockProvider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateMultipart", ctx, key, opts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InitiateMultipart indicates an expected call of InitiateMultipart.
+func (mr *MockProviderMockRecorder) InitiateMultipart(ctx, key, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateMultipart", reflect.TypeOf((*MockProvider)(nil).InitiateMultipart), ctx, key, opts)
+}
+
+// List mocks base method.
+func (m *MockProvider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].(storage.ListResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockProviderMockRecorder) List(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockProvider)(nil).List), ctx, opts)
+}
+
+// PresignUpload mocks base method.
+func (m *MockProvider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PresignUpload", ctx, key, policy, expire)
+	ret0, _ := ret[0].(storage.PresignedUpload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PresignUpload indicates an expected call of PresignUpload.
+func (mr *MockProviderMockRecorder) PresignUpload(ctx, key, policy, expire any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PresignUpload", reflect.TypeOf((*MockProvider)(nil).PresignUpload), ctx, key, policy, expire)
+}
+
+// Stat mocks base method.
+func (m *MockProvider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stat", ctx, key)
+	ret0, _ := ret[0].(storage.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stat indicates an expected call of Stat.
+func (mr *MockProviderMockRecorder) Stat(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stat", reflect.TypeOf((*MockProvider)(nil).Stat), ctx, key)
+}
+
+// URL mocks base method.
+func (m *MockProvider) URL(key string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "URL", key)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// URL indicates an expected call of URL.
+func (mr *MockProviderMockRecorder) URL(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URL", reflect.TypeOf((*MockProvider)(nil).URL), key)
+}
+
 // Upload mocks base method.
-func (m *MockProvider) Upload(ctx context.Context, key string, reader io.Reader, size int64) (string, error) {
+func (m *MockProvider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Upload", ctx, key, reader, size)
+	ret := m.ctrl.Call(m, "Upload", ctx, key, reader, size, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Upload indicates an expected call of Upload.
-func (mr *MockProviderMockRecorder) Upload(ctx, key, reader, size any) *gomock.Call {
+func (mr *MockProviderMockRecorder) Upload(ctx, key, reader, size, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockProvider)(nil).Upload), ctx, key, reader, size, opts)
+}
+
+// UploadPart mocks base method.
+func (m *MockProvider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadPart", ctx, key, uploadID, number, reader, size)
+	ret0, _ := ret[0].(storage.Part)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadPart indicates an expected call of UploadPart.
+func (mr *MockProviderMockRecorder) UploadPart(ctx, key, uploadID, number, reader, size any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockProvider)(nil).Upload), ctx, key, reader, size)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadPart", reflect.TypeOf((*MockProvider)(nil).UploadPart), ctx, key, uploadID, number, reader, size)
 }
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUploadNotFound 分片上传不存在，可能已经完成、取消或者过期
+	ErrUploadNotFound = errors.New("storage: multipart upload not found")
+	// ErrInvalidPart 分片编号不合法，或者完成时提交的分片和已经上传的对不上
+	ErrInvalidPart = errors.New("storage: invalid part")
+)
+
+const (
+	// MinPartSize S3 要求除了最后一个分片，每个分片至少 5MB，OSS 的下限更小，统一按照 S3 的来
+	MinPartSize = 5 << 20
+	// MaxPartNumber 分片编号的范围是 1~10000
+	MaxPartNumber = 10000
+)
+
+// Part 一个已经上传的分片，完成分片上传时需要按照编号从小到大提交
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// ValidatePartNumber 检查分片编号是否在 1~MaxPartNumber 之间
+func ValidatePartNumber(number int) error {
+	if number < 1 || number > MaxPartNumber {
+		return fmt.Errorf("%w: part number %d", ErrInvalidPart, number)
+	}
+	return nil
+}
+
+// ValidateParts 检查完成分片上传时提交的分片：不能为空，编号必须合法并且严格递增
+func ValidateParts(parts []Part) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("%w: no parts", ErrInvalidPart)
+	}
+	for i, part := range parts {
+		if err := ValidatePartNumber(part.Number); err != nil {
+			return err
+		}
+		if i > 0 && part.Number <= parts[i-1].Number {
+			return fmt.Errorf("%w: parts must be in ascending order", ErrInvalidPart)
+		}
+	}
+	return nil
+}
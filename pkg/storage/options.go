@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ACL 对象的访问权限
+type ACL string
+
+const (
+	// ACLDefault 继承 bucket 的权限
+	ACLDefault ACL = ""
+	// ACLPrivate 只能通过签名链接访问
+	ACLPrivate ACL = "private"
+	// ACLPublicRead 任何人都可以读取
+	ACLPublicRead ACL = "public-read"
+)
+
+// UploadOptions 上传时附带的元数据，零值表示全部使用默认值
+type UploadOptions struct {
+	// ContentType 为空时先嗅探文件内容，嗅探不出来再根据 key 的扩展名推断
+	ContentType string
+	// ContentDisposition 例如 `attachment; filename="report.pdf"`，让浏览器下载而不是直接打开
+	ContentDisposition string
+	// CacheControl 例如 "public, max-age=31536000, immutable"
+	CacheControl string
+	// Metadata 用户自定义元数据，key 不区分大小写，读取时统一返回小写
+	Metadata map[string]string
+	ACL      ACL
+}
+
+// CopyOptions 复制时 dst 的属性，零值表示使用默认值
+type CopyOptions struct {
+	// ACL dst 的权限，不会沿用 src 的（S3、OSS 的服务端复制本身也不会带上 ACL），为空时继承默认权限
+	// dst 必须私有的时候要显式指定 ACLPrivate，不能依赖 bucket 或者目录的配置
+	ACL ACL
+}
+
+// sniffLen http.DetectContentType 最多只看前 512 个字节
+const sniffLen = 512
+
+// ResolveOptions 补全 UploadOptions，主要是推断 ContentType
+// 嗅探需要预读一部分内容，所以会返回一个新的 reader，调用方必须用它代替原来的 reader
+func ResolveOptions(key string, reader io.Reader, opts UploadOptions) (io.Reader, UploadOptions, error) {
+	opts.Metadata = lowerKeys(opts.Metadata)
+	if opts.ContentType != "" {
+		return reader, opts, nil
+	}
+	br := bufio.NewReaderSize(reader, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, opts, err
+	}
+	opts.ContentType = DetectContentType(key, head)
+	return br, opts, nil
+}
+
+// ResolveMultipartOptions 分片上传发起的时候还拿不到文件内容，ContentType 只能根据 key 的扩展名推断
+func ResolveMultipartOptions(key string, opts UploadOptions) UploadOptions {
+	opts.Metadata = lowerKeys(opts.Metadata)
+	if opts.ContentType == "" {
+		opts.ContentType = DetectContentType(key, nil)
+	}
+	return opts
+}
+
+func lowerKeys(md map[string]string) map[string]string {
+	if len(md) == 0 {
+		return md
+	}
+	res := make(map[string]string, len(md))
+	for k, v := range md {
+		res[strings.ToLower(k)] = v
+	}
+	return res
+}
+
+// DetectContentType 根据文件头和 key 的扩展名推断 ContentType
+// 二进制格式（图片、PDF、压缩包）以嗅探结果为准，防止伪造扩展名；
+// 文本格式嗅探只能得到 text/plain，这时扩展名（.css、.js、.json）更准确
+func DetectContentType(key string, head []byte) string {
+	sniffed := "application/octet-stream"
+	if len(head) > 0 {
+		sniffed = http.DetectContentType(head)
+	}
+	if sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/plain") {
+		return sniffed
+	}
+	if byExt := mime.TypeByExtension(path.Ext(key)); byExt != "" {
+		return byExt
+	}
+	return sniffed
+}
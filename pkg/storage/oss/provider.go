@@ -3,9 +3,14 @@ package oss
 import (
 	"bedrock/pkg/storage"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
@@ -51,21 +56,19 @@ func NewProvider(c Config) (*Provider, error) {
 var _ storage.Provider = (*Provider)(nil)
 
 // Upload 上传文件
-func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64) (string, error) {
-	// 组装 Options
-	// oss.WithContext 让 SDK 感知上下文（超时/取消）
-	options := []oss.Option{
-		oss.WithContext(ctx),
-		oss.ContentType("application/octet-stream"), // 最好由上层根据文件名推断并传入
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	reader, opts, err := storage.ResolveOptions(key, reader, opts)
+	if err != nil {
+		return "", fmt.Errorf("oss detect content type failed: %w", err)
 	}
-
+	options := objectOptions(ctx, opts)
 	// 阿里云建议明确设置 ContentLength，否则可能会采用分片上传或内存缓冲
 	if size > 0 {
 		options = append(options, oss.ContentLength(size))
 	}
 
 	// 执行上传
-	err := p.bucket.PutObject(key, reader, options...)
+	err = p.bucket.PutObject(key, reader, options...)
 	if err != nil {
 		return "", fmt.Errorf("oss upload failed: %w", err)
 	}
@@ -74,6 +77,79 @@ func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, siz
 	return p.buildPublicURL(key), nil
 }
 
+// Get 流式下载文件
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	res, err := p.bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: key}, []oss.Option{oss.WithContext(ctx)})
+	if err != nil {
+		return nil, storage.ObjectInfo{}, wrapErr("oss get object failed", err)
+	}
+	return res.Response.Body, objectInfo(key, res.Response.Headers), nil
+}
+
+// Stat 查询元数据，使用 HEAD 请求，不会下载文件内容
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	header, err := p.bucket.GetObjectDetailedMeta(key, oss.WithContext(ctx))
+	if err != nil {
+		return storage.ObjectInfo{}, wrapErr("oss stat object failed", err)
+	}
+	return objectInfo(key, header), nil
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	ok, err := p.bucket.IsObjectExist(key, oss.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("oss check object failed: %w", err)
+	}
+	return ok, nil
+}
+
+// List 分页列举，OSS 的 Marker 语义和 storage.ListOptions 一致
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	res, err := p.bucket.ListObjects(
+		oss.WithContext(ctx),
+		oss.Prefix(opts.Prefix),
+		oss.Marker(opts.Marker),
+		oss.MaxKeys(limit),
+	)
+	if err != nil {
+		return storage.ListResult{}, fmt.Errorf("oss list objects failed: %w", err)
+	}
+	objects := make([]storage.ObjectInfo, 0, len(res.Objects))
+	for _, o := range res.Objects {
+		objects = append(objects, storage.ObjectInfo{
+			Key:          o.Key,
+			Size:         o.Size,
+			ETag:         o.ETag,
+			LastModified: o.LastModified,
+		})
+	}
+	return storage.ListResult{
+		Objects:    objects,
+		Truncated:  res.IsTruncated,
+		NextMarker: res.NextMarker,
+	}, nil
+}
+
+// Copy 服务端复制，不经过本机流量，元数据沿用 src，权限只能通过 opts 指定
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	options := []oss.Option{oss.WithContext(ctx)}
+	switch opts.ACL {
+	case storage.ACLPrivate:
+		options = append(options, oss.ObjectACL(oss.ACLPrivate))
+	case storage.ACLPublicRead:
+		options = append(options, oss.ObjectACL(oss.ACLPublicRead))
+	}
+	_, err := p.bucket.CopyObject(src, dst, options...)
+	if err != nil {
+		return wrapErr("oss copy object failed", err)
+	}
+	return nil
+}
+
 // Delete 删除文件
 func (p *Provider) Delete(ctx context.Context, key string) error {
 	err := p.bucket.DeleteObject(key, oss.WithContext(ctx))
@@ -97,6 +173,107 @@ func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64)
 	return signedURL, nil
 }
 
+// URL 返回公开访问的地址
+func (p *Provider) URL(key string) string {
+	return p.buildPublicURL(key)
+}
+
+// PresignUpload 签发一个 PUT 的签名 URL
+// OSS 的签名 URL 只能约束 ContentType，无法限制大小，上传完成之后需要 Stat 检查
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	if expire <= 0 {
+		expire = storage.DefaultPresignExpire
+	}
+	var options []oss.Option
+	var headers map[string]string
+	if policy.ContentType != "" {
+		// ContentType 参与签名，客户端必须带上同样的请求头
+		options = append(options, oss.ContentType(policy.ContentType))
+		headers = map[string]string{"Content-Type": policy.ContentType}
+	}
+	signedURL, err := p.bucket.SignURL(key, oss.HTTPPut, int64(expire.Seconds()), options...)
+	if err != nil {
+		return storage.PresignedUpload{}, fmt.Errorf("oss sign upload url failed: %w", err)
+	}
+	return storage.PresignedUpload{
+		Method:    http.MethodPut,
+		URL:       signedURL,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expire),
+	}, nil
+}
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	res, err := p.bucket.InitiateMultipartUpload(key, objectOptions(ctx, storage.ResolveMultipartOptions(key, opts))...)
+	if err != nil {
+		return "", fmt.Errorf("oss initiate multipart failed: %w", err)
+	}
+	return res.UploadID, nil
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	if err := storage.ValidatePartNumber(number); err != nil {
+		return storage.Part{}, err
+	}
+	part, err := p.bucket.UploadPart(p.imur(key, uploadID), reader, size, number, oss.WithContext(ctx))
+	if err != nil {
+		return storage.Part{}, wrapMultipartErr("oss upload part failed", err)
+	}
+	return storage.Part{Number: part.PartNumber, ETag: part.ETag, Size: size}, nil
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	if err := storage.ValidateParts(parts); err != nil {
+		return "", err
+	}
+	uploadParts := make([]oss.UploadPart, 0, len(parts))
+	for _, part := range parts {
+		uploadParts = append(uploadParts, oss.UploadPart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	if _, err := p.bucket.CompleteMultipartUpload(p.imur(key, uploadID), uploadParts, oss.WithContext(ctx)); err != nil {
+		return "", wrapMultipartErr("oss complete multipart failed", err)
+	}
+	return p.buildPublicURL(key), nil
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	err := wrapMultipartErr("oss abort multipart failed", p.bucket.AbortMultipartUpload(p.imur(key, uploadID), oss.WithContext(ctx)))
+	if err == nil || errors.Is(err, storage.ErrUploadNotFound) {
+		return nil
+	}
+	return err
+}
+
+// imur SDK 用 InitiateMultipartUploadResult 来标识一个分片上传
+func (p *Provider) imur(key, uploadID string) oss.InitiateMultipartUploadResult {
+	return oss.InitiateMultipartUploadResult{Bucket: p.config.BucketName, Key: key, UploadID: uploadID}
+}
+
+// objectOptions 把 UploadOptions 转换成 SDK 的 Option
+// oss.WithContext 让 SDK 感知上下文（超时/取消）
+func objectOptions(ctx context.Context, opts storage.UploadOptions) []oss.Option {
+	options := []oss.Option{
+		oss.WithContext(ctx),
+		oss.ContentType(opts.ContentType),
+	}
+	if opts.ContentDisposition != "" {
+		options = append(options, oss.ContentDisposition(opts.ContentDisposition))
+	}
+	if opts.CacheControl != "" {
+		options = append(options, oss.CacheControl(opts.CacheControl))
+	}
+	for k, v := range opts.Metadata {
+		options = append(options, oss.Meta(k, v))
+	}
+	switch opts.ACL {
+	case storage.ACLPrivate:
+		options = append(options, oss.ObjectACL(oss.ACLPrivate))
+	case storage.ACLPublicRead:
+		options = append(options, oss.ObjectACL(oss.ACLPublicRead))
+	}
+	return options
+}
+
 // buildPublicURL 组装公开访问的 URL
 func (p *Provider) buildPublicURL(key string) string {
 	// 如果配置了自定义域名 (CDN)，直接拼接
@@ -119,3 +296,54 @@ func removeProtocol(endpoint string) string {
 	}
 	return endpoint
 }
+
+// objectInfo 从响应头中解析元数据
+func objectInfo(key string, header http.Header) storage.ObjectInfo {
+	size, _ := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	lastModified, _ := time.Parse(http.TimeFormat, header.Get(oss.HTTPHeaderLastModified))
+	var md map[string]string
+	for k := range header {
+		if name, ok := strings.CutPrefix(strings.ToLower(k), strings.ToLower(oss.HTTPHeaderOssMetaPrefix)); ok {
+			if md == nil {
+				md = make(map[string]string)
+			}
+			md[name] = header.Get(k)
+		}
+	}
+	return storage.ObjectInfo{
+		Key:                key,
+		Size:               size,
+		ContentType:        header.Get(oss.HTTPHeaderContentType),
+		ContentDisposition: header.Get(oss.HTTPHeaderContentDisposition),
+		CacheControl:       header.Get(oss.HTTPHeaderCacheControl),
+		ETag:               header.Get(oss.HTTPHeaderEtag),
+		LastModified:       lastModified,
+		Metadata:           md,
+	}
+}
+
+// wrapErr 把 OSS 的 404 转换成 storage.ErrNotFound
+func wrapErr(msg string, err error) error {
+	var se oss.ServiceError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return storage.ErrNotFound
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// wrapMultipartErr 把分片上传相关的错误码转换成 storage 包里的错误
+func wrapMultipartErr(msg string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var se oss.ServiceError
+	if errors.As(err, &se) {
+		switch se.Code {
+		case "NoSuchUpload":
+			return storage.ErrUploadNotFound
+		case "InvalidPart", "InvalidPartOrder", "EntityTooSmall":
+			return fmt.Errorf("%w: %w", storage.ErrInvalidPart, err)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
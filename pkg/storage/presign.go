@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature 签名不对或者已经过期
+	ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+	// ErrPresignUnsupported 当前配置下不支持签名链接（直传或者私有下载），例如 local 没有配置签名密钥
+	ErrPresignUnsupported = errors.New("storage: presigned url unsupported")
+)
+
+// DefaultPresignExpire 直传链接默认的有效期
+const DefaultPresignExpire = 15 * time.Minute
+
+// UploadPolicy 直传链接的限制条件
+type UploadPolicy struct {
+	// ContentType 客户端上传时必须使用这个 ContentType，为空表示不限制
+	ContentType string
+	// MaxSize 允许上传的最大字节数，<= 0 表示不限制
+	MaxSize int64
+}
+
+// PresignedUpload 客户端直传需要的全部信息
+type PresignedUpload struct {
+	// Method PUT 或者 POST
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Headers 使用 PUT 时必须带上的请求头
+	Headers map[string]string `json:"headers,omitempty"`
+	// FormData 使用 POST 时的表单字段，文件本身放在最后一个字段 "file" 里
+	FormData  map[string]string `json:"formData,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// UploadVerifier 由自己接收上传请求的 Provider 实现（例如 local），
+// NewHTTPHandler 用它来校验 PresignUpload 签发的链接
+type UploadVerifier interface {
+	VerifyUpload(key string, query url.Values) (UploadPolicy, error)
+}
+
+// DownloadVerifier 由自己提供下载服务的 Provider 实现（例如 local），
+// NewHTTPHandler 在读取对象之前用它检查 GetPrivateURL 签发的链接，私有对象没有签名时拒绝访问
+type DownloadVerifier interface {
+	VerifyDownload(key string, query url.Values) error
+}
+
+// SignUpload 使用 HMAC-SHA256 为直传链接签名，返回需要附加在 URL 上的查询参数
+func SignUpload(secret []byte, key string, policy UploadPolicy, expiresAt time.Time) url.Values {
+	q := url.Values{}
+	if policy.ContentType != "" {
+		q.Set("content-type", policy.ContentType)
+	}
+	if policy.MaxSize > 0 {
+		q.Set("max-size", strconv.FormatInt(policy.MaxSize, 10))
+	}
+	q.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	q.Set("signature", uploadSignature(secret, key, q))
+	return q
+}
+
+// VerifyUploadSignature 校验 SignUpload 生成的查询参数，成功时返回签名里的限制条件
+func VerifyUploadSignature(secret []byte, key string, query url.Values, now time.Time) (UploadPolicy, error) {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return UploadPolicy{}, ErrInvalidSignature
+	}
+	want := uploadSignature(secret, key, query)
+	if !hmac.Equal([]byte(want), []byte(query.Get("signature"))) {
+		return UploadPolicy{}, ErrInvalidSignature
+	}
+	policy := UploadPolicy{ContentType: query.Get("content-type")}
+	if s := query.Get("max-size"); s != "" {
+		if policy.MaxSize, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return UploadPolicy{}, ErrInvalidSignature
+		}
+	}
+	return policy, nil
+}
+
+// SignDownload 为私有对象的下载链接签名，返回需要附加在 URL 上的查询参数
+// 签名的内容里有请求方法，下载链接不能用来上传，反过来也一样
+func SignDownload(secret []byte, key string, expiresAt time.Time) url.Values {
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	q.Set("signature", downloadSignature(secret, key, q))
+	return q
+}
+
+// VerifyDownloadSignature 校验 SignDownload 生成的查询参数
+func VerifyDownloadSignature(secret []byte, key string, query url.Values, now time.Time) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return ErrInvalidSignature
+	}
+	want := downloadSignature(secret, key, query)
+	if !hmac.Equal([]byte(want), []byte(query.Get("signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func downloadSignature(secret []byte, key string, q url.Values) string {
+	mac := hmac.New(sha256.New, secret)
+	for _, s := range []string{"GET", key, q.Get("expires")} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func uploadSignature(secret []byte, key string, q url.Values) string {
+	mac := hmac.New(sha256.New, secret)
+	// 用 \n 分隔，避免字段拼接之后产生歧义
+	for _, s := range []string{"PUT", key, q.Get("content-type"), q.Get("max-size"), q.Get("expires")} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,8 +3,11 @@ package s3
 import (
 	"bedrock/pkg/storage"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	"time"
 
@@ -26,6 +29,8 @@ type Config struct {
 
 type Provider struct {
 	client *minio.Client
+	// core 分片上传需要用到的底层 API
+	core   minio.Core
 	config Config
 }
 
@@ -42,6 +47,7 @@ func NewProvider(c Config) storage.Provider {
 
 	return &Provider{
 		client: client,
+		core:   minio.Core{Client: client},
 		config: c,
 	}
 }
@@ -49,21 +55,169 @@ func NewProvider(c Config) storage.Provider {
 // 编译时检查：确保 Provider 实现了 storage.Provider 接口
 var _ storage.Provider = (*Provider)(nil)
 
-func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64) (string, error) {
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	reader, opts, err := storage.ResolveOptions(key, reader, opts)
+	if err != nil {
+		return "", err
+	}
 	// 自动处理分片上传逻辑
-	_, err := p.client.PutObject(ctx, p.config.BucketName, key, reader, size, minio.PutObjectOptions{
-		ContentType: "application/octet-stream", // 建议由上层业务探测并传入，这里简写
-	})
+	_, err = p.client.PutObject(ctx, p.config.BucketName, key, reader, size, putOptions(opts))
 	if err != nil {
 		return "", err
 	}
 
-	// 如果配置了自定义域名（CDN），则拼接 CDN 域名
+	return p.URL(key), nil
+}
+
+// URL 配置了自定义域名（CDN）时拼接 CDN 域名，否则返回 key 本身
+func (p *Provider) URL(key string) string {
 	if p.config.Domain != "" {
-		return fmt.Sprintf("%s/%s", p.config.Domain, key), nil
+		return fmt.Sprintf("%s/%s", p.config.Domain, key)
+	}
+	return key
+}
+
+// PresignUpload 限制了大小时使用 POST Policy，S3 会在服务端校验 content-length-range；
+// 否则使用更简单的预签名 PUT
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	if expire <= 0 {
+		expire = storage.DefaultPresignExpire
+	}
+	expiresAt := time.Now().Add(expire)
+	if policy.MaxSize <= 0 {
+		u, err := p.client.PresignHeader(ctx, http.MethodPut, p.config.BucketName, key, expire, nil, contentTypeHeader(policy.ContentType))
+		if err != nil {
+			return storage.PresignedUpload{}, err
+		}
+		res := storage.PresignedUpload{
+			Method:    http.MethodPut,
+			URL:       u.String(),
+			ExpiresAt: expiresAt,
+		}
+		if policy.ContentType != "" {
+			res.Headers = map[string]string{"Content-Type": policy.ContentType}
+		}
+		return res, nil
+	}
+	pp := minio.NewPostPolicy()
+	if err := pp.SetBucket(p.config.BucketName); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if err := pp.SetKey(key); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if err := pp.SetExpires(expiresAt.UTC()); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if err := pp.SetContentLengthRange(0, policy.MaxSize); err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	if policy.ContentType != "" {
+		if err := pp.SetContentType(policy.ContentType); err != nil {
+			return storage.PresignedUpload{}, err
+		}
+	}
+	u, form, err := p.client.PresignedPostPolicy(ctx, pp)
+	if err != nil {
+		return storage.PresignedUpload{}, err
+	}
+	return storage.PresignedUpload{
+		Method:    http.MethodPost,
+		URL:       u.String(),
+		FormData:  form,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	// GetObject 是懒加载的，第一次 Read 或者 Stat 才会真正发请求
+	// 这里先 Stat 一次，让"不存在"之类的错误在 Get 的时候就暴露出来
+	obj, err := p.client.GetObject(ctx, p.config.BucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, storage.ObjectInfo{}, wrapErr(err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, storage.ObjectInfo{}, wrapErr(err)
+	}
+	return obj, objectInfo(info), nil
+}
+
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	info, err := p.client.StatObject(ctx, p.config.BucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		return storage.ObjectInfo{}, wrapErr(err)
+	}
+	return objectInfo(info), nil
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := p.Stat(ctx, key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = storage.DefaultListLimit
+	}
+	// minio 的 ListObjects 会自动翻页直到列举完，这里多取一个用来判断是否还有下一页，
+	// 取够了就取消 ctx 让它停下来
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := p.client.ListObjects(ctx, p.config.BucketName, minio.ListObjectsOptions{
+		Prefix:     opts.Prefix,
+		StartAfter: opts.Marker,
+		MaxKeys:    limit + 1,
+		Recursive:  true,
+	})
+	var res storage.ListResult
+	for o := range ch {
+		if o.Err != nil {
+			return storage.ListResult{}, o.Err
+		}
+		if len(res.Objects) == limit {
+			res.Truncated = true
+			res.NextMarker = res.Objects[limit-1].Key
+			break
+		}
+		res.Objects = append(res.Objects, objectInfo(o))
+	}
+	return res, nil
+}
+
+// Copy 服务端复制，不经过本机流量
+// S3 复制时不会带上 ACL，指定了 opts.ACL 时只能用 REPLACE 模式重新设置元数据，所以要先查询 src 的元数据
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	dstOpts := minio.CopyDestOptions{Bucket: p.config.BucketName, Object: dst}
+	if opts.ACL != storage.ACLDefault {
+		info, err := p.client.StatObject(ctx, p.config.BucketName, src, minio.StatObjectOptions{})
+		if err != nil {
+			return wrapErr(err)
+		}
+		md := make(map[string]string, len(info.UserMetadata)+1)
+		for k, v := range info.UserMetadata {
+			md[k] = v
+		}
+		md["x-amz-acl"] = string(opts.ACL)
+		dstOpts.ReplaceMetadata = true
+		dstOpts.UserMetadata = md
+		dstOpts.ContentType = info.ContentType
+		dstOpts.ContentDisposition = info.Metadata.Get("Content-Disposition")
+		dstOpts.CacheControl = info.Metadata.Get("Cache-Control")
 	}
-	// 否则返回 path
-	return key, nil
+	_, err := p.client.CopyObject(ctx, dstOpts,
+		minio.CopySrcOptions{Bucket: p.config.BucketName, Object: src},
+	)
+	return wrapErr(err)
 }
 
 func (p *Provider) Delete(ctx context.Context, key string) error {
@@ -77,3 +231,107 @@ func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64)
 	}
 	return u.String(), nil
 }
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	return p.core.NewMultipartUpload(ctx, p.config.BucketName, key, putOptions(storage.ResolveMultipartOptions(key, opts)))
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	if err := storage.ValidatePartNumber(number); err != nil {
+		return storage.Part{}, err
+	}
+	part, err := p.core.PutObjectPart(ctx, p.config.BucketName, key, uploadID, number, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return storage.Part{}, wrapErr(err)
+	}
+	return storage.Part{Number: part.PartNumber, ETag: part.ETag, Size: part.Size}, nil
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	if err := storage.ValidateParts(parts); err != nil {
+		return "", err
+	}
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	_, err := p.core.CompleteMultipartUpload(ctx, p.config.BucketName, key, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return "", wrapErr(err)
+	}
+	return p.URL(key), nil
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	err := wrapErr(p.core.AbortMultipartUpload(ctx, p.config.BucketName, key, uploadID))
+	if errors.Is(err, storage.ErrUploadNotFound) {
+		return nil
+	}
+	return err
+}
+
+func putOptions(opts storage.UploadOptions) minio.PutObjectOptions {
+	md := make(map[string]string, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		md[k] = v
+	}
+	// minio 会把 x-amz-acl 当作请求头而不是自定义元数据
+	if opts.ACL != storage.ACLDefault {
+		md["x-amz-acl"] = string(opts.ACL)
+	}
+	return minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+		CacheControl:       opts.CacheControl,
+		UserMetadata:       md,
+	}
+}
+
+func contentTypeHeader(contentType string) http.Header {
+	if contentType == "" {
+		return nil
+	}
+	return http.Header{"Content-Type": []string{contentType}}
+}
+
+// metaPrefix 自定义元数据在响应头里的前缀
+const metaPrefix = "x-amz-meta-"
+
+func objectInfo(info minio.ObjectInfo) storage.ObjectInfo {
+	var md map[string]string
+	for k := range info.Metadata {
+		if name, ok := strings.CutPrefix(strings.ToLower(k), metaPrefix); ok {
+			if md == nil {
+				md = make(map[string]string)
+			}
+			md[name] = info.Metadata.Get(k)
+		}
+	}
+	return storage.ObjectInfo{
+		Key:                info.Key,
+		Size:               info.Size,
+		ContentType:        info.ContentType,
+		ContentDisposition: info.Metadata.Get("Content-Disposition"),
+		CacheControl:       info.Metadata.Get("Cache-Control"),
+		ETag:               info.ETag,
+		LastModified:       info.LastModified,
+		Metadata:           md,
+	}
+}
+
+// wrapErr 把 S3 的 NoSuchKey、NoSuchUpload、InvalidPart 转换成 storage 包里的错误
+func wrapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	// HEAD 请求没有响应体，minio 会根据 404 和 object 名字补上 NoSuchKey
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return storage.ErrNotFound
+	case "NoSuchUpload":
+		return storage.ErrUploadNotFound
+	case "InvalidPart", "InvalidPartOrder", "EntityTooSmall":
+		return fmt.Errorf("%w: %w", storage.ErrInvalidPart, err)
+	}
+	return err
+}
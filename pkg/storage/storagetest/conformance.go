@@ -0,0 +1,383 @@
+// Package storagetest 提供 storage.Provider 的一致性测试
+// 每个实现都应该在自己的测试里调用 Run，保证行为和其他实现一致，上层业务才能随意切换
+package storagetest
+
+import (
+	"bedrock/pkg/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory 为每个用例创建一个空的 Provider
+type Factory func(t *testing.T) storage.Provider
+
+// Run 运行全部一致性测试
+func Run(t *testing.T, newProvider Factory) {
+	testCases := []struct {
+		name string
+		fn   func(t *testing.T, p storage.Provider)
+	}{
+		{name: "上传之后可以读出", fn: testUploadGet},
+		{name: "覆盖上传", fn: testOverwrite},
+		{name: "读取不存在的对象", fn: testGetNotFound},
+		{name: "查询元数据", fn: testStat},
+		{name: "判断是否存在", fn: testExists},
+		{name: "删除", fn: testDelete},
+		{name: "复制", fn: testCopy},
+		{name: "分页列举", fn: testListPagination},
+		{name: "按前缀列举", fn: testListPrefix},
+		{name: "上传元数据", fn: testUploadOptions},
+		{name: "推断 ContentType", fn: testDetectContentType},
+		{name: "分片上传", fn: testMultipart},
+		{name: "分片上传覆盖分片", fn: testMultipartReupload},
+		{name: "分片上传提交错误的分片", fn: testMultipartInvalidParts},
+		{name: "取消分片上传", fn: testMultipartAbort},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newProvider(t))
+		})
+	}
+}
+
+func testUploadGet(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	content := []byte("hello bedrock")
+	upload(t, p, "docs/hello.txt", content)
+
+	r, info, err := p.Get(ctx, "docs/hello.txt")
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.Equal(t, "docs/hello.txt", info.Key)
+	assert.Equal(t, int64(len(content)), info.Size)
+	assert.False(t, info.LastModified.IsZero())
+}
+
+func testOverwrite(t *testing.T, p storage.Provider) {
+	upload(t, p, "a.txt", []byte("first"))
+	upload(t, p, "a.txt", []byte("second version"))
+	assert.Equal(t, []byte("second version"), read(t, p, "a.txt"))
+}
+
+func testGetNotFound(t *testing.T, p storage.Provider) {
+	_, _, err := p.Get(context.Background(), "missing.txt")
+	assert.True(t, errors.Is(err, storage.ErrNotFound), "err = %v", err)
+}
+
+func testStat(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	upload(t, p, "img/1.png", []byte("not really a png"))
+
+	info, err := p.Stat(ctx, "img/1.png")
+	require.NoError(t, err)
+	assert.Equal(t, "img/1.png", info.Key)
+	assert.Equal(t, int64(16), info.Size)
+	assert.NotEmpty(t, info.ETag)
+
+	_, err = p.Stat(ctx, "img/2.png")
+	assert.True(t, errors.Is(err, storage.ErrNotFound), "err = %v", err)
+	// 目录（或者说公共前缀）不是对象
+	_, err = p.Stat(ctx, "img")
+	assert.True(t, errors.Is(err, storage.ErrNotFound), "err = %v", err)
+}
+
+func testExists(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	upload(t, p, "exists.txt", []byte("1"))
+
+	ok, err := p.Exists(ctx, "exists.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = p.Exists(ctx, "not-exists.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testDelete(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	upload(t, p, "tmp/delete.txt", []byte("bye"))
+
+	require.NoError(t, p.Delete(ctx, "tmp/delete.txt"))
+	ok, err := p.Exists(ctx, "tmp/delete.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// 删除不存在的对象不报错
+	assert.NoError(t, p.Delete(ctx, "tmp/delete.txt"))
+}
+
+func testCopy(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	upload(t, p, "src/a.txt", []byte("copy me"))
+	upload(t, p, "dst/a.txt", []byte("old"))
+
+	require.NoError(t, p.Copy(ctx, "src/a.txt", "dst/a.txt", storage.CopyOptions{}))
+	assert.Equal(t, []byte("copy me"), read(t, p, "dst/a.txt"))
+	// 源对象保持不变
+	assert.Equal(t, []byte("copy me"), read(t, p, "src/a.txt"))
+
+	err := p.Copy(ctx, "src/missing.txt", "dst/b.txt", storage.CopyOptions{})
+	assert.True(t, errors.Is(err, storage.ErrNotFound), "err = %v", err)
+}
+
+func testListPagination(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	var want []string
+	for i := 1; i <= 5; i++ {
+		key := fmt.Sprintf("page/%d.txt", i)
+		upload(t, p, key, []byte(key))
+		want = append(want, key)
+	}
+
+	var got []string
+	opts := storage.ListOptions{Prefix: "page/", Limit: 2}
+	for pages := 1; ; pages++ {
+		require.LessOrEqual(t, pages, 3, "分页没有结束")
+		res, err := p.List(ctx, opts)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(res.Objects), 2)
+		for _, o := range res.Objects {
+			got = append(got, o.Key)
+			assert.Equal(t, int64(len(o.Key)), o.Size)
+		}
+		if !res.Truncated {
+			break
+		}
+		opts.Marker = res.NextMarker
+	}
+	assert.Equal(t, want, got)
+}
+
+func testListPrefix(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	for _, key := range []string{"avatar/1.png", "avatar/10.png", "avatar/2/small.png", "avatars.txt", "banner/1.png"} {
+		upload(t, p, key, []byte("x"))
+	}
+
+	res, err := p.List(ctx, storage.ListOptions{Prefix: "avatar/1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"avatar/1.png", "avatar/10.png"}, keys(res))
+	assert.False(t, res.Truncated)
+
+	// 前缀不一定是目录，嵌套的对象也要列出来，并且按照字典序排列
+	res, err = p.List(ctx, storage.ListOptions{Prefix: "avatar"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"avatar/1.png", "avatar/10.png", "avatar/2/small.png", "avatars.txt"}, keys(res))
+
+	res, err = p.List(ctx, storage.ListOptions{Prefix: "nothing/"})
+	require.NoError(t, err)
+	assert.Empty(t, res.Objects)
+}
+
+func testUploadOptions(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	content := []byte("%PDF-1.7 fake report")
+	opts := storage.UploadOptions{
+		ContentType:        "application/pdf",
+		ContentDisposition: `attachment; filename="report.pdf"`,
+		CacheControl:       "private, max-age=60",
+		Metadata:           map[string]string{"Owner": "1001"},
+		ACL:                storage.ACLPrivate,
+	}
+	_, err := p.Upload(ctx, "reports/1", bytes.NewReader(content), int64(len(content)), opts)
+	require.NoError(t, err)
+
+	assertInfo := func(info storage.ObjectInfo) {
+		t.Helper()
+		assert.Equal(t, "application/pdf", info.ContentType)
+		assert.Equal(t, `attachment; filename="report.pdf"`, info.ContentDisposition)
+		assert.Equal(t, "private, max-age=60", info.CacheControl)
+		// 自定义元数据的 key 统一为小写
+		assert.Equal(t, map[string]string{"owner": "1001"}, info.Metadata)
+	}
+	info, err := p.Stat(ctx, "reports/1")
+	require.NoError(t, err)
+	assertInfo(info)
+
+	r, info, err := p.Get(ctx, "reports/1")
+	require.NoError(t, err)
+	r.Close()
+	assertInfo(info)
+
+	// 复制的时候元数据跟着一起复制
+	require.NoError(t, p.Copy(ctx, "reports/1", "reports/2", storage.CopyOptions{}))
+	info, err = p.Stat(ctx, "reports/2")
+	require.NoError(t, err)
+	assertInfo(info)
+}
+
+func testDetectContentType(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	testCases := []struct {
+		key     string
+		content []byte
+		opts    storage.UploadOptions
+		want    string
+	}{
+		// 二进制内容以嗅探结果为准，即使扩展名是错的
+		{key: "sniff/no-ext", content: png, want: "image/png"},
+		{key: "sniff/fake.jpg", content: png, want: "image/png"},
+		// 文本内容嗅探不出具体类型，使用扩展名
+		{key: "sniff/site.css", content: []byte("body { color: red; }"), want: "text/css; charset=utf-8"},
+		{key: "sniff/readme", content: []byte("just text"), want: "text/plain; charset=utf-8"},
+		// 空文件
+		{key: "sniff/empty", content: nil, want: "application/octet-stream"},
+		// 显式指定的优先
+		{key: "sniff/explicit", content: png, opts: storage.UploadOptions{ContentType: "image/x-custom"}, want: "image/x-custom"},
+	}
+	for _, tc := range testCases {
+		_, err := p.Upload(ctx, tc.key, bytes.NewReader(tc.content), int64(len(tc.content)), tc.opts)
+		require.NoError(t, err)
+		info, err := p.Stat(ctx, tc.key)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, info.ContentType, tc.key)
+		// 嗅探不能吞掉文件开头的内容
+		assert.Equal(t, len(tc.content), len(read(t, p, tc.key)), tc.key)
+	}
+}
+
+// 一致性测试只覆盖不检查分片大小的实现，S3 和 OSS 需要对接真实环境测试
+func testMultipart(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	uploadID, err := p.InitiateMultipart(ctx, "big/video.mp4", storage.UploadOptions{
+		CacheControl: "public, max-age=60",
+		Metadata:     map[string]string{"Owner": "1001"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, uploadID)
+
+	// 分片可以乱序上传，完成的时候按编号排列
+	chunks := []string{"first-", "second-", "third"}
+	parts := make([]storage.Part, len(chunks))
+	for _, i := range []int{2, 0, 1} {
+		parts[i], err = p.UploadPart(ctx, "big/video.mp4", uploadID, i+1, bytes.NewReader([]byte(chunks[i])), int64(len(chunks[i])))
+		require.NoError(t, err)
+		assert.Equal(t, i+1, parts[i].Number)
+		assert.Equal(t, int64(len(chunks[i])), parts[i].Size)
+		assert.NotEmpty(t, parts[i].ETag)
+	}
+	// 完成之前对象不存在，也不会被列举出来
+	ok, err := p.Exists(ctx, "big/video.mp4")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	res, err := p.List(ctx, storage.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Objects)
+
+	u, err := p.CompleteMultipart(ctx, "big/video.mp4", uploadID, parts)
+	require.NoError(t, err)
+	assert.Equal(t, p.URL("big/video.mp4"), u)
+	assert.Equal(t, []byte("first-second-third"), read(t, p, "big/video.mp4"))
+	info, err := p.Stat(ctx, "big/video.mp4")
+	require.NoError(t, err)
+	// 发起的时候拿不到内容，按扩展名推断
+	assert.Equal(t, "video/mp4", info.ContentType)
+	assert.Equal(t, "public, max-age=60", info.CacheControl)
+	assert.Equal(t, map[string]string{"owner": "1001"}, info.Metadata)
+
+	// 完成之后 uploadID 就失效了
+	_, err = p.UploadPart(ctx, "big/video.mp4", uploadID, 4, bytes.NewReader([]byte("x")), 1)
+	assert.True(t, errors.Is(err, storage.ErrUploadNotFound), "err = %v", err)
+	_, err = p.CompleteMultipart(ctx, "big/video.mp4", uploadID, parts)
+	assert.True(t, errors.Is(err, storage.ErrUploadNotFound), "err = %v", err)
+}
+
+func testMultipartReupload(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	uploadID, err := p.InitiateMultipart(ctx, "big/a.bin", storage.UploadOptions{})
+	require.NoError(t, err)
+	_, err = p.UploadPart(ctx, "big/a.bin", uploadID, 1, bytes.NewReader([]byte("broken")), 6)
+	require.NoError(t, err)
+	part, err := p.UploadPart(ctx, "big/a.bin", uploadID, 1, bytes.NewReader([]byte("fixed")), 5)
+	require.NoError(t, err)
+	_, err = p.CompleteMultipart(ctx, "big/a.bin", uploadID, []storage.Part{part})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("fixed"), read(t, p, "big/a.bin"))
+}
+
+func testMultipartInvalidParts(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	uploadID, err := p.InitiateMultipart(ctx, "big/b.bin", storage.UploadOptions{})
+	require.NoError(t, err)
+	_, err = p.UploadPart(ctx, "big/b.bin", uploadID, 0, bytes.NewReader([]byte("x")), 1)
+	assert.True(t, errors.Is(err, storage.ErrInvalidPart), "err = %v", err)
+	p1, err := p.UploadPart(ctx, "big/b.bin", uploadID, 1, bytes.NewReader([]byte("1")), 1)
+	require.NoError(t, err)
+	p2, err := p.UploadPart(ctx, "big/b.bin", uploadID, 2, bytes.NewReader([]byte("2")), 1)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		parts []storage.Part
+	}{
+		{name: "没有分片", parts: nil},
+		{name: "顺序不对", parts: []storage.Part{p2, p1}},
+		{name: "ETag 不对", parts: []storage.Part{p1, {Number: 2, ETag: p1.ETag}}},
+		{name: "分片不存在", parts: []storage.Part{p1, p2, {Number: 3, ETag: p1.ETag}}},
+	}
+	for _, tc := range testCases {
+		_, err = p.CompleteMultipart(ctx, "big/b.bin", uploadID, tc.parts)
+		assert.True(t, errors.Is(err, storage.ErrInvalidPart), "%s: err = %v", tc.name, err)
+	}
+	// key 和发起时不一致
+	_, err = p.CompleteMultipart(ctx, "big/c.bin", uploadID, []storage.Part{p1, p2})
+	assert.True(t, errors.Is(err, storage.ErrUploadNotFound), "err = %v", err)
+
+	// 失败之后还可以正常完成
+	_, err = p.CompleteMultipart(ctx, "big/b.bin", uploadID, []storage.Part{p1, p2})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12"), read(t, p, "big/b.bin"))
+}
+
+func testMultipartAbort(t *testing.T, p storage.Provider) {
+	ctx := context.Background()
+	uploadID, err := p.InitiateMultipart(ctx, "big/d.bin", storage.UploadOptions{})
+	require.NoError(t, err)
+	part, err := p.UploadPart(ctx, "big/d.bin", uploadID, 1, bytes.NewReader([]byte("x")), 1)
+	require.NoError(t, err)
+
+	require.NoError(t, p.AbortMultipart(ctx, "big/d.bin", uploadID))
+	_, err = p.CompleteMultipart(ctx, "big/d.bin", uploadID, []storage.Part{part})
+	assert.True(t, errors.Is(err, storage.ErrUploadNotFound), "err = %v", err)
+	ok, err := p.Exists(ctx, "big/d.bin")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// 重复取消、取消不存在的上传都不报错
+	assert.NoError(t, p.AbortMultipart(ctx, "big/d.bin", uploadID))
+	assert.NoError(t, p.AbortMultipart(ctx, "big/d.bin", "not-exists"))
+}
+
+func upload(t *testing.T, p storage.Provider, key string, content []byte) {
+	t.Helper()
+	_, err := p.Upload(context.Background(), key, bytes.NewReader(content), int64(len(content)), storage.UploadOptions{})
+	require.NoError(t, err)
+}
+
+func read(t *testing.T, p storage.Provider, key string) []byte {
+	t.Helper()
+	r, _, err := p.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func keys(res storage.ListResult) []string {
+	ks := make([]string, 0, len(res.Objects))
+	for _, o := range res.Objects {
+		ks = append(ks, o.Key)
+	}
+	return ks
+}
@@ -0,0 +1,243 @@
+// Package tiered 提供一个分层的 storage.Provider：新写入和最近访问的对象放在热存储（例如本地磁盘），
+// 一段时间没有访问的对象由 Demote 挪到冷存储（例如 S3）
+package tiered
+
+import (
+	"bedrock/pkg/storage"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Config 分层存储的配置
+type Config struct {
+	// PromoteMaxSize 从冷存储读取的对象不超过这个大小时挪回热存储，<= 0 时不挪回
+	// 挪回是同步的，先完整地复制到热存储再返回，所以只适合比较小的对象
+	PromoteMaxSize int64
+}
+
+// Provider 写入都进入热存储，读取时先读热存储，没有再读冷存储
+//
+// URL、GetPrivateURL、PresignUpload 和分片上传都交给热存储处理，对象挪到冷存储之后 URL 不变，
+// 所以热存储的 URL 必须由挂载了这个 Provider 的 storage.NewHTTPHandler 提供服务，而不是直接读磁盘
+// 挪到冷存储时不会带上 ACL，热存储是 local 时建议用 Private 或者 PrivatePrefixes 控制访问，而不是依赖 ACL
+type Provider struct {
+	hot  storage.Provider
+	cold storage.Provider
+	cfg  Config
+
+	// accessed 热存储里的对象最近一次被读取的时间，本地磁盘一般不记录访问时间
+	// 只保存在内存里，重启之后以对象的修改时间为准
+	accessed sync.Map
+	now      func() time.Time
+}
+
+var _ storage.Provider = (*Provider)(nil)
+var _ storage.UploadVerifier = (*Provider)(nil)
+var _ storage.DownloadVerifier = (*Provider)(nil)
+
+func NewProvider(c Config, hot, cold storage.Provider) *Provider {
+	return &Provider{
+		hot:  hot,
+		cold: cold,
+		cfg:  c,
+		now:  time.Now,
+	}
+}
+
+// Upload 只写热存储，冷存储里的旧版本被热存储遮住，下一次 Demote 时覆盖
+func (p *Provider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	return p.hot.Upload(ctx, key, reader, size, opts)
+}
+
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	r, info, err := p.hot.Get(ctx, key)
+	if err == nil {
+		p.touch(key)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return r, info, err
+	}
+	r, info, err = p.cold.Get(ctx, key)
+	if err != nil || p.cfg.PromoteMaxSize <= 0 || info.Size > p.cfg.PromoteMaxSize {
+		return r, info, err
+	}
+	// 挪回热存储，失败了不影响这次读取
+	defer r.Close()
+	if _, err = p.hot.Upload(ctx, key, r, info.Size, storage.OptionsOf(info)); err == nil {
+		if r, info, err = p.hot.Get(ctx, key); err == nil {
+			p.touch(key)
+			return r, info, nil
+		}
+	}
+	return p.cold.Get(ctx, key)
+}
+
+func (p *Provider) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	info, err := p.hot.Stat(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return p.cold.Stat(ctx, key)
+	}
+	return info, err
+}
+
+func (p *Provider) Exists(ctx context.Context, key string) (bool, error) {
+	ok, err := p.hot.Exists(ctx, key)
+	if err != nil || ok {
+		return ok, err
+	}
+	return p.cold.Exists(ctx, key)
+}
+
+// List 合并两层的列举结果，同一个 key 两层都有时以热存储为准
+func (p *Provider) List(ctx context.Context, opts storage.ListOptions) (storage.ListResult, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = storage.DefaultListLimit
+	}
+	hot, err := p.hot.List(ctx, opts)
+	if err != nil {
+		return storage.ListResult{}, err
+	}
+	cold, err := p.cold.List(ctx, opts)
+	if err != nil {
+		return storage.ListResult{}, err
+	}
+	// 两边都是从 Marker 之后按顺序取的前 Limit 个，合并之后的前 Limit 个就是这一页
+	var res storage.ListResult
+	i, j := 0, 0
+	for len(res.Objects) < opts.Limit && (i < len(hot.Objects) || j < len(cold.Objects)) {
+		switch {
+		case j == len(cold.Objects) || (i < len(hot.Objects) && hot.Objects[i].Key < cold.Objects[j].Key):
+			res.Objects = append(res.Objects, hot.Objects[i])
+			i++
+		case i == len(hot.Objects) || cold.Objects[j].Key < hot.Objects[i].Key:
+			res.Objects = append(res.Objects, cold.Objects[j])
+			j++
+		default:
+			res.Objects = append(res.Objects, hot.Objects[i])
+			i++
+			j++
+		}
+	}
+	more := i < len(hot.Objects) || j < len(cold.Objects) || hot.Truncated || cold.Truncated
+	if more && len(res.Objects) > 0 {
+		res.Truncated = true
+		res.NextMarker = res.Objects[len(res.Objects)-1].Key
+	}
+	return res, nil
+}
+
+// Copy 在 src 所在的那一层复制，dst 在热存储里的旧版本会被删掉，避免遮住新复制的对象
+func (p *Provider) Copy(ctx context.Context, src, dst string, opts storage.CopyOptions) error {
+	err := p.hot.Copy(ctx, src, dst, opts)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if err = p.cold.Copy(ctx, src, dst, opts); err != nil {
+		return err
+	}
+	return p.hot.Delete(ctx, dst)
+}
+
+func (p *Provider) Delete(ctx context.Context, key string) error {
+	p.accessed.Delete(key)
+	return errors.Join(p.hot.Delete(ctx, key), p.cold.Delete(ctx, key))
+}
+
+func (p *Provider) GetPrivateURL(ctx context.Context, key string, expire int64) (string, error) {
+	return p.hot.GetPrivateURL(ctx, key, expire)
+}
+
+func (p *Provider) URL(key string) string {
+	return p.hot.URL(key)
+}
+
+func (p *Provider) PresignUpload(ctx context.Context, key string, policy storage.UploadPolicy, expire time.Duration) (storage.PresignedUpload, error) {
+	return p.hot.PresignUpload(ctx, key, policy, expire)
+}
+
+// VerifyUpload 热存储自己接收直传时（例如 local），由 storage.NewHTTPHandler 调用
+func (p *Provider) VerifyUpload(key string, query url.Values) (storage.UploadPolicy, error) {
+	v, ok := p.hot.(storage.UploadVerifier)
+	if !ok {
+		return storage.UploadPolicy{}, storage.ErrPresignUnsupported
+	}
+	return v.VerifyUpload(key, query)
+}
+
+// VerifyDownload 热存储自己提供下载服务时（例如 local）检查签名，否则拒绝通过 storage.NewHTTPHandler 下载
+func (p *Provider) VerifyDownload(key string, query url.Values) error {
+	v, ok := p.hot.(storage.DownloadVerifier)
+	if !ok {
+		return storage.ErrPresignUnsupported
+	}
+	return v.VerifyDownload(key, query)
+}
+
+func (p *Provider) InitiateMultipart(ctx context.Context, key string, opts storage.UploadOptions) (string, error) {
+	return p.hot.InitiateMultipart(ctx, key, opts)
+}
+
+func (p *Provider) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (storage.Part, error) {
+	return p.hot.UploadPart(ctx, key, uploadID, number, reader, size)
+}
+
+func (p *Provider) CompleteMultipart(ctx context.Context, key, uploadID string, parts []storage.Part) (string, error) {
+	return p.hot.CompleteMultipart(ctx, key, uploadID, parts)
+}
+
+func (p *Provider) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return p.hot.AbortMultipart(ctx, key, uploadID)
+}
+
+// Demote 把热存储里 before 之后没有写入也没有读取过的对象挪到冷存储，返回挪动的数量
+// 复制完成之后如果热存储里的对象被覆盖了（ETag 变了）就保留它，避免删掉新写入的数据
+func (p *Provider) Demote(ctx context.Context, before time.Time) (int, error) {
+	var (
+		moved int
+		opts  storage.ListOptions
+	)
+	for {
+		res, err := p.hot.List(ctx, opts)
+		if err != nil {
+			return moved, err
+		}
+		for _, obj := range res.Objects {
+			if !p.lastAccess(obj).Before(before) {
+				continue
+			}
+			info, err := storage.Transfer(ctx, p.hot, p.cold, obj.Key, obj.Key)
+			if err != nil {
+				return moved, err
+			}
+			cur, err := p.hot.Stat(ctx, obj.Key)
+			if err != nil || cur.ETag != info.ETag || !p.lastAccess(cur).Before(before) {
+				continue
+			}
+			if err = p.hot.Delete(ctx, obj.Key); err != nil {
+				return moved, err
+			}
+			p.accessed.Delete(obj.Key)
+			moved++
+		}
+		if !res.Truncated {
+			return moved, nil
+		}
+		opts.Marker = res.NextMarker
+	}
+}
+
+func (p *Provider) touch(key string) {
+	p.accessed.Store(key, p.now())
+}
+
+// lastAccess 最近一次写入或者读取的时间
+func (p *Provider) lastAccess(obj storage.ObjectInfo) time.Time {
+	if v, ok := p.accessed.Load(obj.Key); ok && v.(time.Time).After(obj.LastModified) {
+		return v.(time.Time)
+	}
+	return obj.LastModified
+}
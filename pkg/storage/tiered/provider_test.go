@@ -0,0 +1,136 @@
+package tiered
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
+	"bedrock/pkg/storage/storagetest"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Conformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) storage.Provider {
+		return NewProvider(Config{}, memory.NewProvider("http://localhost:8080/uploads"), memory.NewProvider("https://s3.example.com"))
+	})
+}
+
+func TestProvider_Demote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	hot := memory.NewProvider("http://localhost:8080/uploads")
+	cold := memory.NewProvider("https://s3.example.com")
+	p := NewProvider(Config{}, hot, cold)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	for _, key := range []string{"a.txt", "b.txt", "c.txt"} {
+		upload(t, p, key, key)
+	}
+	// 刚刚读过的不挪
+	p.now = func() time.Time { return now.Add(time.Hour) }
+	assert.Equal(t, "b.txt", read(t, p, "b.txt"))
+
+	moved, err := p.Demote(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+	assert.Equal(t, []string{"b.txt"}, keys(t, hot))
+	assert.Equal(t, []string{"a.txt", "c.txt"}, keys(t, cold))
+
+	// 挪走之后读取、列举、地址都不受影响
+	assert.Equal(t, "a.txt", read(t, p, "a.txt"))
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, keys(t, p))
+	assert.Equal(t, "http://localhost:8080/uploads/a.txt", p.URL("a.txt"))
+	info, err := p.Stat(ctx, "c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size)
+
+	// 重新写入之后以热存储为准
+	upload(t, p, "a.txt", "new a")
+	assert.Equal(t, "new a", read(t, p, "a.txt"))
+
+	// 两层都要删掉
+	require.NoError(t, p.Delete(ctx, "a.txt"))
+	_, _, err = p.Get(ctx, "a.txt")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestProvider_Promote(t *testing.T) {
+	t.Parallel()
+	hot := memory.NewProvider("http://localhost:8080/uploads")
+	cold := memory.NewProvider("https://s3.example.com")
+	p := NewProvider(Config{PromoteMaxSize: 5}, hot, cold)
+	upload(t, cold, "small.txt", "small")
+	upload(t, cold, "large.txt", "large!")
+
+	assert.Equal(t, "small", read(t, p, "small.txt"))
+	assert.Equal(t, "large!", read(t, p, "large.txt"))
+	// 只有小文件挪回热存储
+	assert.Equal(t, []string{"small.txt"}, keys(t, hot))
+}
+
+func TestProvider_List(t *testing.T) {
+	t.Parallel()
+	hot := memory.NewProvider("http://localhost:8080/uploads")
+	cold := memory.NewProvider("https://s3.example.com")
+	p := NewProvider(Config{}, hot, cold)
+	for _, key := range []string{"a", "c", "e"} {
+		upload(t, hot, key, "hot")
+	}
+	for _, key := range []string{"b", "c", "d", "f"} {
+		upload(t, cold, key, "cold")
+	}
+
+	var (
+		got   []string
+		pages int
+		opts  = storage.ListOptions{Limit: 2}
+	)
+	for {
+		res, err := p.List(context.Background(), opts)
+		require.NoError(t, err)
+		pages++
+		for _, obj := range res.Objects {
+			got = append(got, obj.Key)
+			// 两层都有的时候用热存储的
+			if obj.Key == "c" {
+				assert.Equal(t, int64(3), obj.Size)
+			}
+		}
+		if !res.Truncated {
+			break
+		}
+		opts.Marker = res.NextMarker
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, got)
+	assert.Equal(t, 3, pages)
+}
+
+func upload(t *testing.T, p storage.Provider, key, content string) {
+	_, err := p.Upload(context.Background(), key, strings.NewReader(content), int64(len(content)), storage.UploadOptions{})
+	require.NoError(t, err)
+}
+
+func read(t *testing.T, p storage.Provider, key string) string {
+	r, _, err := p.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func keys(t *testing.T, p storage.Provider) []string {
+	res, err := p.List(context.Background(), storage.ListOptions{})
+	require.NoError(t, err)
+	var keys []string
+	for _, obj := range res.Objects {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transfer 把 src 里的对象流式复制到 dst，连同 ContentType 等元数据一起复制
+// 和 Provider.Copy 不同，src 和 dst 可以是不同的存储，例如从本地磁盘复制到 S3
+// ObjectInfo 里没有 ACL，复制之后的对象继承 dst 的默认权限
+func Transfer(ctx context.Context, src, dst Provider, srcKey, dstKey string) (ObjectInfo, error) {
+	r, info, err := src.Get(ctx, srcKey)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer r.Close()
+	_, err = dst.Upload(ctx, dstKey, r, info.Size, OptionsOf(info))
+	return info, err
+}
+
+// OptionsOf 把对象的元数据转换成重新上传时的 UploadOptions
+func OptionsOf(info ObjectInfo) UploadOptions {
+	return UploadOptions{
+		ContentType:        info.ContentType,
+		ContentDisposition: info.ContentDisposition,
+		CacheControl:       info.CacheControl,
+		Metadata:           info.Metadata,
+	}
+}
+
+// MigrateOptions 迁移的参数
+type MigrateOptions struct {
+	// Prefix 只迁移以它开头的对象
+	Prefix string
+	// Concurrency 同时复制的对象数量，<= 0 时为 4
+	Concurrency int
+	// SkipExisting 目标里已经有同样大小的对象时跳过，中断之后重新执行可以从断点继续
+	// 不同存储的 ETag 算法不一样，只能比较大小
+	SkipExisting bool
+	// DryRun 只统计，不复制，这时 Copied 和 Bytes 是需要复制的数量
+	DryRun bool
+	// Progress 每处理完一个对象调用一次，可能被多个 goroutine 同时调用
+	Progress func(p MigrateProgress)
+}
+
+// MigrateProgress 迁移进度，数量都是累计值
+type MigrateProgress struct {
+	// Key 刚处理完的对象
+	Key string
+	// Err 这个对象复制失败的原因
+	Err error
+
+	Listed  int64
+	Copied  int64
+	Skipped int64
+	Failed  int64
+	// Bytes 已经复制的字节数
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// Migrate 把 src 里的对象全部复制到 dst，key 保持不变
+// 单个对象复制失败不会中断迁移，只会计入 Failed，全部处理完之后返回第一个错误
+// 列举失败或者 ctx 被取消时立即停止
+func Migrate(ctx context.Context, src, dst Provider, opts MigrateOptions) (MigrateProgress, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	var (
+		start = time.Now()
+		stats struct {
+			listed, copied, skipped, failed, bytes atomic.Int64
+		}
+		mu       sync.Mutex
+		firstErr error
+	)
+	snapshot := func(key string, err error) MigrateProgress {
+		return MigrateProgress{
+			Key:     key,
+			Err:     err,
+			Listed:  stats.listed.Load(),
+			Copied:  stats.copied.Load(),
+			Skipped: stats.skipped.Load(),
+			Failed:  stats.failed.Load(),
+			Bytes:   stats.bytes.Load(),
+			Elapsed: time.Since(start),
+		}
+	}
+	migrateOne := func(obj ObjectInfo) {
+		err := migrateObject(ctx, src, dst, obj, opts)
+		switch {
+		case errors.Is(err, errSkipped):
+			stats.skipped.Add(1)
+			err = nil
+		case err != nil:
+			stats.failed.Add(1)
+			err = fmt.Errorf("migrate %s: %w", obj.Key, err)
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		default:
+			stats.copied.Add(1)
+			stats.bytes.Add(obj.Size)
+		}
+		if opts.Progress != nil {
+			opts.Progress(snapshot(obj.Key, err))
+		}
+	}
+
+	objs := make(chan ObjectInfo)
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range objs {
+				migrateOne(obj)
+			}
+		}()
+	}
+
+	listErr := func() error {
+		defer close(objs)
+		lo := ListOptions{Prefix: opts.Prefix}
+		for {
+			res, err := src.List(ctx, lo)
+			if err != nil {
+				return err
+			}
+			for _, obj := range res.Objects {
+				stats.listed.Add(1)
+				select {
+				case objs <- obj:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if !res.Truncated {
+				return nil
+			}
+			lo.Marker = res.NextMarker
+		}
+	}()
+	wg.Wait()
+
+	res := snapshot("", nil)
+	if listErr != nil {
+		return res, listErr
+	}
+	return res, firstErr
+}
+
+var errSkipped = errors.New("skipped")
+
+func migrateObject(ctx context.Context, src, dst Provider, obj ObjectInfo, opts MigrateOptions) error {
+	if opts.SkipExisting {
+		info, err := dst.Stat(ctx, obj.Key)
+		switch {
+		case err == nil && info.Size == obj.Size:
+			return errSkipped
+		case err != nil && !errors.Is(err, ErrNotFound):
+			return err
+		}
+	}
+	if opts.DryRun {
+		return nil
+	}
+	_, err := Transfer(ctx, src, dst, obj.Key, obj.Key)
+	return err
+}
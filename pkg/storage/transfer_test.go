@@ -0,0 +1,136 @@
+package storage_test
+
+import (
+	"bedrock/pkg/storage"
+	"bedrock/pkg/storage/memory"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenProvider 上传 key 为 bad 的对象时失败
+type brokenProvider struct {
+	storage.Provider
+	bad string
+}
+
+func (b *brokenProvider) Upload(ctx context.Context, key string, reader io.Reader, size int64, opts storage.UploadOptions) (string, error) {
+	if key == b.bad {
+		return "", errors.New("disk full")
+	}
+	return b.Provider.Upload(ctx, key, reader, size, opts)
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	newSrc := func(t *testing.T) storage.Provider {
+		src := memory.NewProvider("https://old.example.com")
+		for i := range 10 {
+			_, err := src.Upload(ctx, fmt.Sprintf("files/%02d.txt", i), strings.NewReader("0123456789"), 10, storage.UploadOptions{
+				CacheControl: "max-age=60",
+			})
+			require.NoError(t, err)
+		}
+		_, err := src.Upload(ctx, "tmp/x", strings.NewReader("x"), 1, storage.UploadOptions{})
+		require.NoError(t, err)
+		return src
+	}
+
+	testCases := []struct {
+		name string
+		// before 迁移之前在目标里准备数据
+		before func(t *testing.T, dst storage.Provider) storage.Provider
+		opts   storage.MigrateOptions
+
+		want     storage.MigrateProgress
+		wantKeys int
+		wantErr  string
+	}{
+		{
+			name:     "全部迁移",
+			opts:     storage.MigrateOptions{Prefix: "files/", Concurrency: 3},
+			want:     storage.MigrateProgress{Listed: 10, Copied: 10, Bytes: 100},
+			wantKeys: 10,
+		},
+		{
+			name: "跳过已经迁移的",
+			before: func(t *testing.T, dst storage.Provider) storage.Provider {
+				_, err := dst.Upload(ctx, "files/00.txt", strings.NewReader("0123456789"), 10, storage.UploadOptions{})
+				require.NoError(t, err)
+				// 大小不一样的要重新复制
+				_, err = dst.Upload(ctx, "files/01.txt", strings.NewReader("012"), 3, storage.UploadOptions{})
+				require.NoError(t, err)
+				return dst
+			},
+			opts:     storage.MigrateOptions{Prefix: "files/", SkipExisting: true},
+			want:     storage.MigrateProgress{Listed: 10, Copied: 9, Skipped: 1, Bytes: 90},
+			wantKeys: 10,
+		},
+		{
+			name:     "只统计",
+			opts:     storage.MigrateOptions{DryRun: true},
+			want:     storage.MigrateProgress{Listed: 11, Copied: 11, Bytes: 101},
+			wantKeys: 0,
+		},
+		{
+			name: "部分失败",
+			before: func(t *testing.T, dst storage.Provider) storage.Provider {
+				return &brokenProvider{Provider: dst, bad: "files/03.txt"}
+			},
+			opts:     storage.MigrateOptions{Prefix: "files/"},
+			want:     storage.MigrateProgress{Listed: 10, Copied: 9, Failed: 1, Bytes: 90},
+			wantKeys: 9,
+			wantErr:  "migrate files/03.txt: disk full",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			src := newSrc(t)
+			dst := memory.NewProvider("https://new.example.com")
+			target := dst
+			if tc.before != nil {
+				target = tc.before(t, dst)
+			}
+			var (
+				mu      sync.Mutex
+				reports []storage.MigrateProgress
+			)
+			tc.opts.Progress = func(p storage.MigrateProgress) {
+				mu.Lock()
+				defer mu.Unlock()
+				reports = append(reports, p)
+			}
+
+			got, err := storage.Migrate(ctx, src, target, tc.opts)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			got.Elapsed = 0
+			assert.Equal(t, tc.want, got)
+			assert.Len(t, reports, int(tc.want.Listed))
+
+			res, err := dst.List(ctx, storage.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, res.Objects, tc.wantKeys)
+			if tc.wantKeys > 0 {
+				// 元数据一起复制
+				info, err := dst.Stat(ctx, "files/09.txt")
+				require.NoError(t, err)
+				assert.Equal(t, "max-age=60", info.CacheControl)
+			}
+		})
+	}
+}
@@ -2,9 +2,54 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
+	"strings"
+	"time"
 )
 
+var (
+	// ErrNotFound 对象不存在，各个实现需要把自己的"不存在"错误转换成它
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey key 不合法，例如包含 ".."
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// ObjectInfo 对象的元数据
+type ObjectInfo struct {
+	Key                string
+	Size               int64
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	ETag               string
+	LastModified       time.Time
+	// Metadata 上传时设置的用户自定义元数据，key 统一为小写
+	Metadata map[string]string
+}
+
+// ListOptions 列举对象的参数，结果按照 key 的字典序排列
+type ListOptions struct {
+	// Prefix 只列举以它开头的 key
+	Prefix string
+	// Marker 从这个 key 之后开始列举（不包含它本身），一般传上一页的 NextMarker
+	Marker string
+	// Limit 每页最多返回的数量，<= 0 时使用 DefaultListLimit
+	Limit int
+}
+
+// DefaultListLimit 和 S3、OSS 单次列举的上限保持一致
+const DefaultListLimit = 1000
+
+// ListResult 一页列举结果
+type ListResult struct {
+	Objects []ObjectInfo
+	// Truncated 为 true 时表示还有下一页
+	Truncated bool
+	// NextMarker 下一页的 Marker
+	NextMarker string
+}
+
 // Provider 定义存储行为的标准接口
 // 设计原则：依赖抽象，不依赖具体实现
 //
@@ -15,13 +60,66 @@ type Provider interface {
 	// key: 存储路径 (不包含域名)，如 "avatar/1001.jpg"
 	// reader: 使用 io.Reader 而不是 []byte，支持大文件流式上传，节省内存
 	// size: 文件大小，某些 SDK（如 S3）在预知大小时能优化分片上传，如果未知可传 -1
+	// opts: ContentType、缓存策略等元数据，实现需要先调用 ResolveOptions 补全
 	// 返回值: (相对路径或绝对URL, 错误)
-	Upload(ctx context.Context, key string, reader io.Reader, size int64) (string, error)
+	Upload(ctx context.Context, key string, reader io.Reader, size int64, opts UploadOptions) (string, error)
+
+	// Get 流式读取文件，调用方负责关闭返回的 io.ReadCloser
+	// 对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+
+	// Stat 只查询元数据，对象不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
 
-	// Delete 删除文件
+	// Exists 判断对象是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// List 分页列举对象
+	List(ctx context.Context, opts ListOptions) (ListResult, error)
+
+	// Copy 在同一个存储内复制对象，连同元数据一起复制，dst 已经存在时会被覆盖
+	// 权限不会跟着复制，由 opts 指定；src 不存在时返回 ErrNotFound
+	Copy(ctx context.Context, src, dst string, opts CopyOptions) error
+
+	// Delete 删除文件，对象不存在时不报错
 	Delete(ctx context.Context, key string) error
 
 	// GetPrivateURL 获取私有文件的临时访问链接
 	// expire: 有效期(秒)
 	GetPrivateURL(ctx context.Context, key string, expire int64) (string, error)
+
+	// URL 返回对象的公开访问地址（不带签名），和 Upload 的返回值一致
+	URL(key string) string
+
+	// PresignUpload 签发一个客户端直传链接，文件内容不再经过我们的服务
+	// policy 限制上传的 ContentType 和大小，expire <= 0 时使用 DefaultPresignExpire
+	// 注意：不是所有实现都能在存储侧限制大小，上传完成之后仍然需要 Stat 检查
+	PresignUpload(ctx context.Context, key string, policy UploadPolicy, expire time.Duration) (PresignedUpload, error)
+
+	// InitiateMultipart 发起分片上传，返回 uploadID，opts 和 Upload 的含义一样
+	// 大文件分成多个分片分别上传，失败时只需要重传失败的分片
+	// 没有指定 ContentType 时根据 key 的扩展名推断，因为这时候还拿不到文件内容
+	InitiateMultipart(ctx context.Context, key string, opts UploadOptions) (string, error)
+
+	// UploadPart 上传一个分片，number 从 1 开始，同一个编号重复上传时后面的覆盖前面的
+	// 除了最后一个分片，每个分片不能小于 MinPartSize（local 和 memory 不检查）
+	// uploadID 不存在时返回 ErrUploadNotFound
+	UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (Part, error)
+
+	// CompleteMultipart 按照 parts 的顺序把分片合并成一个对象，返回值和 Upload 一致
+	// parts 必须按照编号从小到大排列，并且和上传时返回的 ETag 一致，否则返回 ErrInvalidPart
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) (string, error)
+
+	// AbortMultipart 取消分片上传并删除已经上传的分片，uploadID 不存在时不报错
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+// KeyFromURL 按照 Provider.URL 的规则从访问地址反推 key，不是这个存储的地址时返回 false
+// 用来兼容早期直接把 URL 存进数据库的数据
+func KeyFromURL(p Provider, url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, p.URL(""))
+	if !ok || key == "" {
+		return "", false
+	}
+	return key, true
 }
@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"bedrock/pkg/phone"
 	"fmt"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/locales/en"
@@ -13,9 +14,12 @@ import (
 	"strings"
 )
 
+// Trans 默认语言的翻译器，按请求翻译时用 TransFor
 var Trans ut.Translator
 
-// InitTrans 初始化翻译器
+var uni *ut.UniversalTranslator
+
+// InitTrans 初始化翻译器，中文和英文的翻译都会注册，locale 是默认语言
 func InitTrans(locale string) (err error) {
 	// 修改gin框架中的Validator引擎属性，实现自定制
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
@@ -34,29 +38,66 @@ func InitTrans(locale string) (err error) {
 
 		// 第一个参数是备用（fallback）的语言环境
 		// 后面的参数是应该支持的语言环境（支持多个）
-		// uni := ut.New(zhT, zhT) 也是可以的
-		uni := ut.New(enT, zhT, enT)
+		uni = ut.New(enT, zhT, enT)
 
-		// locale 通常取决于 http 请求头的 'Accept-Language'
-		var ok bool
-		// 也可以使用 uni.FindTranslator(...) 传入多个locale进行查找
+		zhTrans, _ := uni.GetTranslator("zh")
+		if err = zhTranslations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+			return
+		}
+		enTrans, _ := uni.GetTranslator("en")
+		if err = enTranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+			return
+		}
+		if err = registerPhone(v); err != nil {
+			return
+		}
+		// locale 通常取决于 http 请求头的 'Accept-Language'，这里只是默认值
 		Trans, ok = uni.GetTranslator(locale)
 		if !ok {
 			return fmt.Errorf("uni.GetTranslator(%s) failed", locale)
 		}
+	}
+	return
+}
+
+// TransFor 请求语言对应的翻译器，例如 zh、en，不支持的语言使用默认语言
+func TransFor(locale string) ut.Translator {
+	if uni == nil {
+		return Trans
+	}
+	if t, ok := uni.GetTranslator(locale); ok {
+		return t
+	}
+	return Trans
+}
+
+// phoneMsgs phone 校验规则各个语言的提示
+var phoneMsgs = map[string]string{
+	"zh": "{0}必须是有效的手机号码",
+	"en": "{0} must be a valid phone number",
+}
 
-		// 注册翻译器
-		switch locale {
-		case "en":
-			err = enTranslations.RegisterDefaultTranslations(v, Trans)
-		case "zh":
-			err = zhTranslations.RegisterDefaultTranslations(v, Trans)
-		default:
-			err = enTranslations.RegisterDefaultTranslations(v, Trans)
+// registerPhone 注册 phone 校验规则：支持 E.164 格式和不带国家码的中国大陆手机号
+func registerPhone(v *validator.Validate) error {
+	err := v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return phone.Valid(fl.Field().String(), "")
+	})
+	if err != nil {
+		return err
+	}
+	for locale, msg := range phoneMsgs {
+		trans, _ := uni.GetTranslator(locale)
+		err = v.RegisterTranslation("phone", trans, func(ut ut.Translator) error {
+			return ut.Add("phone", msg, true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T("phone", fe.Field())
+			return t
+		})
+		if err != nil {
+			return err
 		}
-		return
 	}
-	return
+	return nil
 }
 
 // removeTopStruct 移除json标签中的结构体名称
@@ -0,0 +1,8 @@
+-- 将历史数据中 11 位的中国大陆手机号迁移为 E.164 格式，例如 13800138000 -> +8613800138000
+-- 应用在查询时也会按旧格式兜底并逐条迁移，这个脚本用于一次性批量迁移
+-- 可以重复执行，每次最多处理 10000 行，直到影响行数为 0
+UPDATE users
+SET phone = CONCAT('+86', phone),
+    utime = UNIX_TIMESTAMP(NOW(3)) * 1000
+WHERE phone REGEXP '^1[0-9]{10}$'
+LIMIT 10000;
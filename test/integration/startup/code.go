@@ -0,0 +1,22 @@
+package startup
+
+import (
+	"bedrock/internal/repository"
+	"bedrock/internal/service"
+	"bedrock/internal/service/channel"
+	emailmemory "bedrock/internal/service/email/memory"
+	"bedrock/internal/service/sms"
+	voicememory "bedrock/internal/service/voice/memory"
+)
+
+func InitCodePolicies() *service.CodePolicyRegistry {
+	return service.NewCodePolicyRegistry(service.DefaultCodePolicy)
+}
+
+func InitCodeChannels(smsSvc sms.Service, users repository.UserRepository) []channel.Channel {
+	return []channel.Channel{
+		channel.NewSMSChannel(smsSvc),
+		channel.NewVoiceChannel(voicememory.NewService()),
+		channel.NewEmailChannel(emailmemory.NewService(), users),
+	}
+}
@@ -0,0 +1,19 @@
+package startup
+
+import (
+	"bedrock/internal/service/sms/simulator"
+	"sync"
+)
+
+var (
+	smsSimulator     *simulator.Service
+	smsSimulatorOnce sync.Once
+)
+
+// InitSMSSimulator 整个测试进程共用一个模拟器，测试用例可以直接拿到它来读取发送的验证码
+func InitSMSSimulator() *simulator.Service {
+	smsSimulatorOnce.Do(func() {
+		smsSimulator = simulator.NewService(simulator.Config{})
+	})
+	return smsSimulator
+}
@@ -1,7 +1,17 @@
 package startup
 
-import "bedrock/pkg/storage"
+import (
+	"bedrock/internal/service"
+	"bedrock/pkg/logger"
+	"bedrock/pkg/storage"
+)
 
 func InitStorageService() storage.Provider {
 	return nil
 }
+
+func InitAvatarService(storageSvc storage.Provider, userSvc service.UserService, l logger.Logger) service.AvatarService {
+	// 测试环境不扫描，上传的头像直接通过
+	moderationSvc := service.NewModerationService(nil, storageSvc, nil, l, service.ModerationConfig{})
+	return service.NewAvatarService(storageSvc, userSvc, moderationSvc, l, service.AvatarConfig{})
+}
@@ -2,17 +2,28 @@ package startup
 
 import (
 	"bedrock/internal/web"
+	"bedrock/internal/web/locales"
 	"bedrock/internal/web/middleware"
 	jwtware "bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx"
+	"bedrock/pkg/ginx/router"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InitGinServer(hdl *web.UserHandler, jwtHdl jwtware.Handler) *gin.Engine {
+func InitGinServer(hdl *web.UserHandler, jwtHdl jwtware.Handler) *router.Router {
 	gin.SetMode(gin.ReleaseMode)
+	bundle, err := locales.NewBundle(locales.Default)
+	if err != nil {
+		panic(err)
+	}
+	ginx.SetI18n(bundle)
 	server := gin.Default()
-	m := middleware.NewJWTAuth(jwtHdl)
-	server.Use(m.Middleware())
-	hdl.RegisterRoutes(server)
-	return server
+	// 测试用的还是不带版本号的老路径
+	r := router.New(server, router.Config{Prefix: "/api", Legacy: true}, router.Version{
+		Name:        "v1",
+		Middlewares: []gin.HandlerFunc{middleware.NewJWTAuth(jwtHdl).Middleware()},
+	})
+	hdl.RegisterRoutes(r.Group("v1"))
+	return r
 }
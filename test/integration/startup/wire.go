@@ -7,11 +7,12 @@ import (
 	"bedrock/internal/repository/cache"
 	"bedrock/internal/repository/dao"
 	"bedrock/internal/service"
-	"bedrock/internal/service/sms/memory"
+	"bedrock/internal/service/sms"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/middleware/jwt"
+	"bedrock/pkg/ginx/router"
 
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
 
@@ -27,13 +28,17 @@ var userSvc = wire.NewSet(
 	dao.NewGORMUserDAO,
 	repository.NewCachedUserRepository,
 	service.NewUserService,
+	InitAvatarService,
 )
 
 var codeSvc = wire.NewSet(
 	cache.NewRedisCodeCache,
 	repository.NewCachedCodeRepository,
 	service.NewCodeService,
-	memory.NewService,
+	InitCodePolicies,
+	InitCodeChannels,
+	InitSMSSimulator,
+	wire.Bind(new(sms.Service), new(*simulator.Service)),
 )
 
 func InitUserHandler() *web.UserHandler {
@@ -47,7 +52,7 @@ func InitUserHandler() *web.UserHandler {
 	return new(web.UserHandler)
 }
 
-func InitWebServer() *gin.Engine {
+func InitWebServer() *router.Router {
 	wire.Build(
 		thirdParty,
 		userSvc,
@@ -56,5 +61,5 @@ func InitWebServer() *gin.Engine {
 		web.NewUserHandler,
 		InitGinServer,
 	)
-	return new(gin.Engine)
+	return new(router.Router)
 }
@@ -11,10 +11,11 @@ import (
 	"bedrock/internal/repository/cache"
 	"bedrock/internal/repository/dao"
 	"bedrock/internal/service"
-	"bedrock/internal/service/sms/memory"
+	"bedrock/internal/service/sms"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/middleware/jwt"
-	"github.com/gin-gonic/gin"
+	"bedrock/pkg/ginx/router"
 	"github.com/google/wire"
 )
 
@@ -30,15 +31,18 @@ func InitUserHandler() *web.UserHandler {
 	userService := service.NewUserService(logger, userRepository)
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
-	smsService := memory.NewService()
-	codeService := service.NewCodeService(codeRepository, smsService)
+	simulatorService := InitSMSSimulator()
+	v := InitCodeChannels(simulatorService, userRepository)
+	codePolicyRegistry := InitCodePolicies()
+	codeService := service.NewCodeService(codeRepository, v, codePolicyRegistry)
 	provider := InitStorageService()
+	avatarService := InitAvatarService(provider, userService, logger)
 	handler := jwt.NewRedisJWTHandler(cmdable)
-	userHandler := web.NewUserHandler(logger, userService, codeService, provider, handler)
+	userHandler := web.NewUserHandler(logger, userService, codeService, provider, avatarService, handler)
 	return userHandler
 }
 
-func InitWebServer() *gin.Engine {
+func InitWebServer() *router.Router {
 	logger := InitLogger()
 	db := InitMySQL()
 	userDAO := dao.NewGORMUserDAO(db)
@@ -48,11 +52,14 @@ func InitWebServer() *gin.Engine {
 	userService := service.NewUserService(logger, userRepository)
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
-	smsService := memory.NewService()
-	codeService := service.NewCodeService(codeRepository, smsService)
+	simulatorService := InitSMSSimulator()
+	v := InitCodeChannels(simulatorService, userRepository)
+	codePolicyRegistry := InitCodePolicies()
+	codeService := service.NewCodeService(codeRepository, v, codePolicyRegistry)
 	provider := InitStorageService()
+	avatarService := InitAvatarService(provider, userService, logger)
 	handler := jwt.NewRedisJWTHandler(cmdable)
-	userHandler := web.NewUserHandler(logger, userService, codeService, provider, handler)
+	userHandler := web.NewUserHandler(logger, userService, codeService, provider, avatarService, handler)
 	engine := InitGinServer(userHandler, handler)
 	return engine
 }
@@ -66,6 +73,9 @@ var thirdParty = wire.NewSet(
 	InitStorageService,
 )
 
-var userSvc = wire.NewSet(cache.NewRedisUserCache, dao.NewGORMUserDAO, repository.NewCachedUserRepository, service.NewUserService)
+var userSvc = wire.NewSet(cache.NewRedisUserCache, dao.NewGORMUserDAO, repository.NewCachedUserRepository, service.NewUserService, InitAvatarService)
 
-var codeSvc = wire.NewSet(cache.NewRedisCodeCache, repository.NewCachedCodeRepository, service.NewCodeService, memory.NewService)
+var codeSvc = wire.NewSet(cache.NewRedisCodeCache, repository.NewCachedCodeRepository, service.NewCodeService, InitCodePolicies,
+	InitCodeChannels,
+	InitSMSSimulator, wire.Bind(new(sms.Service), new(*simulator.Service)),
+)
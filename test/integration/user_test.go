@@ -11,11 +11,13 @@ import (
 	"time"
 
 	"bedrock/internal/repository/dao"
+	"bedrock/internal/service/sms/simulator"
 	"bedrock/internal/web"
 	"bedrock/internal/web/errs"
+	"bedrock/pkg/ginx/router"
+	"bedrock/pkg/phone"
 	"bedrock/test/integration/startup"
 
-	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -24,15 +26,17 @@ import (
 
 type UserTestSuite struct {
 	suite.Suite
-	server *gin.Engine
+	server *router.Router
 	db     *gorm.DB
 	rdb    redis.Cmdable
+	sms    *simulator.Service
 }
 
 func (s *UserTestSuite) SetupSuite() {
 	s.server = startup.InitWebServer()
 	s.db = startup.InitMySQL()
 	s.rdb = startup.InitRedis()
+	s.sms = startup.InitSMSSimulator()
 	// 初始化数据
 	// 确保测试数据不存在
 	// 初始化表结构
@@ -170,12 +174,14 @@ func (s *UserTestSuite) TestLogin() {
 	t := s.T()
 
 	testCases := []struct {
-		name     string
-		before   func(t *testing.T)
-		after    func(t *testing.T)
-		req      web.LoginJWTReq
-		wantCode int
-		wantMsg  string
+		name   string
+		before func(t *testing.T)
+		after  func(t *testing.T)
+		req    web.LoginJWTReq
+		// wantStatus HTTP 状态码
+		wantStatus int
+		wantCode   int
+		wantMsg    string
 	}{
 		{
 			name: "登录成功",
@@ -201,8 +207,9 @@ func (s *UserTestSuite) TestLogin() {
 				Email:    "test_login_success@example.com",
 				Password: "Password123!",
 			},
-			wantCode: 200,
-			wantMsg:  "登录成功",
+			wantStatus: http.StatusOK,
+			wantCode:   200,
+			wantMsg:    "登录成功",
 		},
 		{
 			name: "用户不存在",
@@ -214,8 +221,9 @@ func (s *UserTestSuite) TestLogin() {
 				Email:    "test_login_not_found@example.com",
 				Password: "Password123!",
 			},
-			wantCode: errs.UserInvalidOrPassword,
-			wantMsg:  "用户名或者密码错误",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   errs.UserInvalidOrPassword,
+			wantMsg:    "用户名或者密码错误",
 		},
 		{
 			name: "密码错误",
@@ -239,8 +247,9 @@ func (s *UserTestSuite) TestLogin() {
 				Email:    "test_login_wrong_password@example.com",
 				Password: "WrongPassword!",
 			},
-			wantCode: errs.UserInvalidOrPassword,
-			wantMsg:  "用户名或者密码错误",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   errs.UserInvalidOrPassword,
+			wantMsg:    "用户名或者密码错误",
 		},
 	}
 
@@ -266,7 +275,7 @@ func (s *UserTestSuite) TestLogin() {
 			w := httptest.NewRecorder()
 			s.server.ServeHTTP(w, req)
 
-			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.wantStatus, w.Code)
 			var res map[string]interface{}
 			err = json.Unmarshal(w.Body.Bytes(), &res)
 			if err != nil {
@@ -478,6 +487,19 @@ func (s *UserTestSuite) TestLoginSMS() {
 			wantCode: 200,
 			wantMsg:  "登录成功",
 		},
+		{
+			name: "国际号码登录成功",
+			before: func(t *testing.T) {
+			},
+			after: func(t *testing.T) {
+				var user dao.User
+				err := s.db.Where("phone = ?", "+447911123456").First(&user).Error
+				assert.NoError(t, err)
+			},
+			phone:    "+447911123456",
+			wantCode: 200,
+			wantMsg:  "登录成功",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -500,13 +522,18 @@ func (s *UserTestSuite) TestLoginSMS() {
 			s.server.ServeHTTP(w, req)
 			assert.Equal(t, http.StatusOK, w.Code)
 
-			// 2. 从 Redis 获取验证码
+			// 2. 从短信模拟器获取验证码，号码会被规范化为 E.164 格式
+			number, err := phone.Normalize(tc.phone, "")
+			assert.NoError(t, err)
+			code := s.lastSMSCode(t, number)
+
+			// Redis 中保存的验证码应该和发出去的一致
 			// key format: phone_code:login:PHONE
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
-			code, err := s.rdb.Get(ctx, "phone_code:login:"+tc.phone).Result()
+			cachedCode, err := s.rdb.Get(ctx, "phone_code:login:"+number).Result()
 			assert.NoError(t, err)
-			assert.NotEmpty(t, code)
+			assert.Equal(t, cachedCode, code)
 
 			// 3. 登录
 			loginReqBody := map[string]string{
@@ -745,6 +772,15 @@ func (s *UserTestSuite) loginWithRefreshToken(t *testing.T, uid int64) (string,
 	return "Bearer " + token, refreshToken
 }
 
+// 辅助方法：读取短信模拟器发给某个手机号的最后一个验证码
+func (s *UserTestSuite) lastSMSCode(t *testing.T, phone string) string {
+	code, ok := s.sms.LastCode(phone)
+	if !ok {
+		t.Fatal("没有收到验证码短信", phone)
+	}
+	return code
+}
+
 func TestUser(t *testing.T) {
 	suite.Run(t, new(UserTestSuite))
 }